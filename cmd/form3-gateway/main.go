@@ -0,0 +1,135 @@
+// Command form3-gateway is a reverse proxy that lets trusted local callers,
+// such as services without a form3 client, call the form3 API.
+//
+// Callers authenticate with bearer tokens listed in a policy file, which
+// also lists the methods and paths each caller may use. Requests are
+// forwarded through form3.Client, configured like form3.NewFromConfig and
+// form3.NewFromEnv, so they are signed and retried, and one audit record
+// is written per request.
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"github.com/vivangkumar/form3-http-go/internal/gateway"
+	"github.com/vivangkumar/form3-http-go/pkg/form3"
+)
+
+// shutdownTimeout is how long requests in flight are waited for on exit.
+const shutdownTimeout = 30 * time.Second
+
+func main() {
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	err := run(ctx, os.Args[1:], os.Stdout, os.Stderr)
+	stop()
+
+	if errors.Is(err, flag.ErrHelp) {
+		return
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "form3-gateway: %s\n", err)
+		os.Exit(1)
+	}
+}
+
+func run(ctx context.Context, args []string, stdout io.Writer, stderr io.Writer) error {
+	var (
+		listen     string
+		policyPath string
+		auditPath  string
+		configPath string
+		profile    string
+	)
+
+	fs := flag.NewFlagSet("form3-gateway", flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	fs.StringVar(&listen, "listen", "127.0.0.1:8081", "`address` to listen on")
+	fs.StringVar(&policyPath, "policy", "", "policy file `path` listing callers and what they may call")
+	fs.StringVar(&auditPath, "audit-log", "-", "`path` audit records are appended to, - for standard output")
+	fs.StringVar(&configPath, "config", os.Getenv("FORM3_CONFIG"), "client config file `path`, overridden by FORM3_ environment variables")
+	fs.StringVar(&profile, "profile", "", "config file profile (default $FORM3_PROFILE, or the file's default)")
+
+	err := fs.Parse(args)
+	if err != nil {
+		return err
+	}
+
+	if policyPath == "" {
+		return fmt.Errorf("--policy is required")
+	}
+
+	policy, err := gateway.LoadPolicy(policyPath)
+	if err != nil {
+		return err
+	}
+
+	if profile == "" {
+		profile = os.Getenv("FORM3_PROFILE")
+	}
+
+	cfg := &form3.Config{}
+	if configPath != "" {
+		cfg, err = form3.LoadConfig(configPath, profile)
+		if err != nil {
+			return fmt.Errorf("load config: %w", err)
+		}
+	}
+
+	env, err := form3.LoadEnv()
+	if err != nil {
+		return fmt.Errorf("load env: %w", err)
+	}
+	cfg = cfg.Merge(env)
+
+	auditLog := stdout
+	if auditPath != "-" {
+		f, err := os.OpenFile(auditPath, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0o600)
+		if err != nil {
+			return fmt.Errorf("open audit log: %w", err)
+		}
+		defer f.Close()
+
+		auditLog = f
+	}
+
+	errorLog := log.New(stderr, "", log.LstdFlags)
+
+	gw, err := gateway.New(policy, cfg, gateway.WithAuditLog(auditLog), gateway.WithErrorLog(errorLog))
+	if err != nil {
+		return err
+	}
+
+	srv := &http.Server{
+		Addr:              listen,
+		Handler:           gw,
+		ErrorLog:          errorLog,
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+
+	errs := make(chan error, 1)
+	go func() {
+		errorLog.Printf("form3-gateway: listening on %s", listen)
+		errs <- srv.ListenAndServe()
+	}()
+
+	select {
+	case err := <-errs:
+		return err
+	case <-ctx.Done():
+	}
+
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
+	defer cancel()
+
+	return srv.Shutdown(shutdownCtx)
+}
@@ -0,0 +1,18 @@
+// Command form3 manages form3 accounts from the command line.
+//
+// It is configured by the same config file profiles and FORM3_ environment
+// variables as form3.NewFromConfig and form3.NewFromEnv. Run form3 -h for
+// the available commands.
+package main
+
+import (
+	"context"
+	"os"
+
+	"github.com/vivangkumar/form3-http-go/internal/cli"
+)
+
+func main() {
+	// Run cancels commands on Ctrl-C itself, so the shell can survive it.
+	os.Exit(cli.Run(context.Background(), os.Args[1:], os.Stdin, os.Stdout, os.Stderr))
+}
@@ -0,0 +1,405 @@
+package cli
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/google/uuid"
+	"gopkg.in/yaml.v3"
+
+	"github.com/vivangkumar/form3-http-go/pkg/account"
+)
+
+func accountsCommand() *command {
+	return &command{
+		name:    "accounts",
+		summary: "create, fetch, list, delete, import and export accounts",
+		subcommands: []*command{
+			{name: "create", summary: "create an account", run: runAccountsCreate},
+			{name: "fetch", summary: "fetch an account by ID", run: runAccountsFetch},
+			{name: "list", summary: "list a page of accounts", run: runAccountsList},
+			{name: "delete", summary: "delete an account by ID", run: runAccountsDelete},
+			{name: "import", summary: "create accounts read from a CSV or NDJSON file", run: runAccountsImport},
+			{name: "export", summary: "write all accounts as CSV or NDJSON", run: runAccountsExport},
+		},
+	}
+}
+
+// attributeFlags set account attributes.
+type attributeFlags struct {
+	file string
+
+	country                 string
+	baseCurrency            string
+	bankID                  string
+	bankIDCode              string
+	bic                     string
+	accountNumber           string
+	iban                    string
+	classification          string
+	customerID              string
+	secondaryIdentification string
+	status                  string
+	names                   stringsFlag
+	alternativeNames        stringsFlag
+	jointAccount            bool
+	accountMatchingOptOut   bool
+	switched                bool
+}
+
+func (f *attributeFlags) register(fs *flag.FlagSet) {
+	fs.StringVar(&f.file, "file", "", "JSON or YAML `path` holding the attributes, overridden by attribute flags")
+	fs.StringVar(&f.country, "country", "", "country code, for example GB")
+	fs.StringVar(&f.baseCurrency, "base-currency", "", "currency code, for example GBP")
+	fs.StringVar(&f.bankID, "bank-id", "", "bank ID")
+	fs.StringVar(&f.bankIDCode, "bank-id-code", "", "bank ID code, for example GBDSC")
+	fs.StringVar(&f.bic, "bic", "", "BIC")
+	fs.StringVar(&f.accountNumber, "account-number", "", "account number")
+	fs.StringVar(&f.iban, "iban", "", "IBAN")
+	fs.StringVar(&f.classification, "classification", "", "account classification: Personal or Business")
+	fs.StringVar(&f.customerID, "customer-id", "", "customer ID")
+	fs.StringVar(&f.secondaryIdentification, "secondary-identification", "", "secondary identification")
+	fs.StringVar(&f.status, "status", "", "status: pending, confirmed or failed")
+	fs.Var(&f.names, "name", "account holder name line, may be repeated")
+	fs.Var(&f.alternativeNames, "alternative-name", "alternative name, may be repeated")
+	fs.BoolVar(&f.jointAccount, "joint-account", false, "whether the account is joint")
+	fs.BoolVar(&f.accountMatchingOptOut, "account-matching-opt-out", false, "whether the account opts out of matching")
+	fs.BoolVar(&f.switched, "switched", false, "whether the account has been switched")
+}
+
+// attributes returns the attributes read from the file, if any, with the
+// attribute flags set on fs applied.
+func (f *attributeFlags) attributes(fs *flag.FlagSet) (*account.Attributes, error) {
+	attrs := &account.Attributes{}
+
+	if f.file != "" {
+		var err error
+		attrs, err = readAttributes(f.file)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	fs.Visit(func(fl *flag.Flag) {
+		switch fl.Name {
+		case "country":
+			attrs.Country = f.country
+		case "base-currency":
+			attrs.BaseCurrency = f.baseCurrency
+		case "bank-id":
+			attrs.BankID = f.bankID
+		case "bank-id-code":
+			attrs.BankIDCode = account.BankIDCode(f.bankIDCode)
+		case "bic":
+			attrs.Bic = f.bic
+		case "account-number":
+			attrs.AccountNumber = f.accountNumber
+		case "iban":
+			attrs.Iban = f.iban
+		case "classification":
+			attrs.WithAccountClassificationValue(account.Classification(f.classification))
+		case "customer-id":
+			attrs.WithCustomerID(&f.customerID)
+		case "secondary-identification":
+			attrs.SecondaryIdentification = f.secondaryIdentification
+		case "status":
+			attrs.WithStatusValue(account.Status(f.status))
+		case "name":
+			attrs.Name = f.names
+		case "alternative-name":
+			attrs.AlternativeNames = f.alternativeNames
+		case "joint-account":
+			attrs.WithJointAccount(&f.jointAccount)
+		case "account-matching-opt-out":
+			attrs.WithAccountMatchingOptOut(&f.accountMatchingOptOut)
+		case "switched":
+			attrs.WithSwitched(&f.switched)
+		}
+	})
+
+	// Enum values are checked before anything is sent.
+	err := attrs.Validate()
+	if err != nil {
+		return nil, &usageError{err: err}
+	}
+
+	return attrs, nil
+}
+
+// readAttributes reads attributes from a JSON or YAML file, using the JSON
+// names of the attributes.
+func readAttributes(path string) (*account.Attributes, error) {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return nil, usageErrorf("read attributes: %w", err)
+	}
+
+	// YAML is a superset of JSON, so both are decoded as YAML first.
+	var generic map[string]any
+	err = yaml.Unmarshal(b, &generic)
+	if err != nil {
+		return nil, usageErrorf("decode attributes %s: %w", path, err)
+	}
+
+	b, err = json.Marshal(generic)
+	if err != nil {
+		return nil, usageErrorf("decode attributes %s: %w", path, err)
+	}
+
+	attrs := &account.Attributes{}
+	err = json.Unmarshal(b, attrs)
+	if err != nil {
+		return nil, usageErrorf("decode attributes %s: %w", path, err)
+	}
+
+	unknown := (&account.Account{Attributes: attrs}).UnknownFields()
+	if len(unknown) > 0 {
+		return nil, usageErrorf("decode attributes %s: unknown fields %s", path, strings.Join(unknown, ", "))
+	}
+
+	return attrs, nil
+}
+
+func runAccountsCreate(ctx context.Context, a *app, args []string) error {
+	var (
+		cf    clientFlags
+		of    outputFlags
+		af    attributeFlags
+		id    string
+		orgID string
+	)
+
+	fs := a.newFlagSet("form3 accounts create", "[flags]")
+	cf.register(fs)
+	of.register(fs)
+	af.register(fs)
+	fs.StringVar(&id, "id", "", "account ID (default a generated UUID)")
+	fs.StringVar(&orgID, "organisation-id", "", "organisation ID (default the configured organisation)")
+
+	pos, err := parseFlags(fs, args)
+	if err != nil {
+		return err
+	}
+
+	if len(pos) > 0 {
+		return usageErrorf("unexpected arguments %v", pos)
+	}
+
+	p, err := of.printer(a.stdout)
+	if err != nil {
+		return err
+	}
+
+	attrs, err := af.attributes(fs)
+	if err != nil {
+		return err
+	}
+
+	c, err := cf.newClient(a.stdout)
+	if err != nil {
+		return err
+	}
+
+	if orgID == "" {
+		orgID = c.OrganisationID
+	}
+	if orgID == "" {
+		return usageErrorf("--organisation-id is required when no organisation is configured")
+	}
+
+	// A generated ID gives the request an idempotency key.
+	if id == "" {
+		id = uuid.NewString()
+	}
+
+	acc := account.New(orgID).WithID(id).WithAttributes(attrs)
+
+	resp, err := c.Accounts.Create(ctx, acc)
+	if errors.Is(err, errDryRun) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	return p.accounts(resp.Data, resp.Data)
+}
+
+func runAccountsFetch(ctx context.Context, a *app, args []string) error {
+	var (
+		cf clientFlags
+		of outputFlags
+	)
+
+	fs := a.newFlagSet("form3 accounts fetch", "<id> [flags]")
+	cf.register(fs)
+	of.register(fs)
+
+	pos, err := parseFlags(fs, args)
+	if err != nil {
+		return err
+	}
+
+	if len(pos) != 1 {
+		return usageErrorf("accounts fetch requires one account ID")
+	}
+
+	p, err := of.printer(a.stdout)
+	if err != nil {
+		return err
+	}
+
+	c, err := cf.newClient(a.stdout)
+	if err != nil {
+		return err
+	}
+
+	resp, err := c.Accounts.Fetch(ctx, account.FetchAccountParams{ID: pos[0]})
+	if errors.Is(err, errDryRun) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	return p.accounts(resp.Data, resp.Data)
+}
+
+func runAccountsList(ctx context.Context, a *app, args []string) error {
+	var (
+		cf         clientFlags
+		of         outputFlags
+		pageNumber int
+		pageSize   int
+		filters    stringsFlag
+	)
+
+	fs := a.newFlagSet("form3 accounts list", "[flags]")
+	cf.register(fs)
+	of.register(fs)
+	fs.IntVar(&pageNumber, "page-number", 0, "page to return, starting at 0")
+	fs.IntVar(&pageSize, "page-size", 0, "accounts per page (default the API default)")
+	fs.Var(&filters, "filter", "`attribute=value` to filter by, may be repeated")
+
+	pos, err := parseFlags(fs, args)
+	if err != nil {
+		return err
+	}
+
+	if len(pos) > 0 {
+		return usageErrorf("unexpected arguments %v", pos)
+	}
+
+	filter, err := keyValues("filter", filters)
+	if err != nil {
+		return err
+	}
+
+	p, err := of.printer(a.stdout)
+	if err != nil {
+		return err
+	}
+
+	c, err := cf.newClient(a.stdout)
+	if err != nil {
+		return err
+	}
+
+	resp, err := c.Accounts.List(ctx, account.ListAccountParams{
+		PageNumber: pageNumber,
+		PageSize:   pageSize,
+		Filter:     filter,
+	})
+	if errors.Is(err, errDryRun) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	accs := make([]*account.Account, len(resp.Data))
+	for i := range resp.Data {
+		accs[i] = &resp.Data[i]
+	}
+
+	data := resp.Data
+	if data == nil {
+		data = []account.Account{}
+	}
+
+	return p.accounts(data, accs...)
+}
+
+func runAccountsDelete(ctx context.Context, a *app, args []string) error {
+	var (
+		cf      clientFlags
+		of      outputFlags
+		version string
+	)
+
+	fs := a.newFlagSet("form3 accounts delete", "<id> [flags]")
+	cf.register(fs)
+	of.register(fs)
+	fs.StringVar(&version, "version", "", "version to delete (default the latest version)")
+
+	pos, err := parseFlags(fs, args)
+	if err != nil {
+		return err
+	}
+
+	if len(pos) != 1 {
+		return usageErrorf("accounts delete requires one account ID")
+	}
+	id := pos[0]
+
+	p, err := of.printer(a.stdout)
+	if err != nil {
+		return err
+	}
+
+	var v int64
+	if version != "" {
+		v, err = strconv.ParseInt(version, 10, 64)
+		if err != nil || v < 0 {
+			return usageErrorf("--version %q is not a version number", version)
+		}
+	} else if cf.dryRun {
+		return usageErrorf("--dry-run requires --version, as the latest version is only known by fetching it")
+	}
+
+	c, err := cf.newClient(a.stdout)
+	if err != nil {
+		return err
+	}
+
+	if version == "" {
+		_, err = c.Accounts.DeleteLatest(ctx, id)
+		if err != nil {
+			return err
+		}
+
+		return p.message(
+			fmt.Sprintf("Deleted account %s", id),
+			map[string]any{"id": id, "deleted": true},
+		)
+	}
+
+	_, err = c.Accounts.Delete(ctx, account.DeleteAccountParams{ID: id, Version: v})
+	if errors.Is(err, errDryRun) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	return p.message(
+		fmt.Sprintf("Deleted account %s at version %d", id, v),
+		map[string]any{"id": id, "version": v, "deleted": true},
+	)
+}
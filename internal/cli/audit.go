@@ -0,0 +1,67 @@
+package cli
+
+import (
+	"context"
+	"fmt"
+	"os"
+
+	"github.com/vivangkumar/form3-http-go/pkg/client/audit"
+)
+
+func auditCommand() *command {
+	return &command{
+		name:    "audit",
+		summary: "check audit logs",
+		subcommands: []*command{
+			{name: "verify", summary: "check the hash chain of an audit log file", run: runAuditVerify},
+		},
+	}
+}
+
+func runAuditVerify(_ context.Context, a *app, args []string) error {
+	var (
+		of      outputFlags
+		keyFile string
+	)
+
+	fs := a.newFlagSet("form3 audit verify", "<file> [flags]")
+	of.register(fs)
+	fs.StringVar(&keyFile, "key-file", "", "`path` of the file holding the key the log was written with")
+
+	pos, err := parseFlags(fs, args)
+	if err != nil {
+		return err
+	}
+
+	if len(pos) != 1 {
+		return usageErrorf("audit verify requires one audit log file")
+	}
+	file := pos[0]
+
+	if keyFile == "" {
+		return usageErrorf("--key-file is required")
+	}
+
+	key, err := os.ReadFile(keyFile)
+	if err != nil {
+		return usageErrorf("read key: %w", err)
+	}
+
+	p, err := of.printer(a.stdout)
+	if err != nil {
+		return err
+	}
+
+	v, err := audit.VerifyFile(file, key)
+	if err != nil {
+		return fmt.Errorf("%s: %w", file, err)
+	}
+
+	out := struct {
+		File     string `json:"file"`
+		Records  int    `json:"records"`
+		LastHash string `json:"last_hash"`
+	}{file, v.Records, v.LastHash}
+
+	return p.message(fmt.Sprintf("Verified %d records, last hash %s", v.Records, v.LastHash), out)
+}
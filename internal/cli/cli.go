@@ -0,0 +1,188 @@
+// Package cli implements the form3 command-line tool.
+//
+// Commands are built on form3.Client, so they are configured by the same
+// profiles and environment variables as the library.
+package cli
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"sort"
+	"strings"
+)
+
+// command is a named command or group of subcommands.
+type command struct {
+	name    string
+	summary string
+
+	// run runs the command with the arguments following its name.
+	run func(ctx context.Context, app *app, args []string) error
+
+	// subcommands are dispatched to if run is nil.
+	subcommands []*command
+
+	// ownsInterrupt is set if the command handles Ctrl-C itself, instead
+	// of being cancelled by it.
+	ownsInterrupt bool
+}
+
+// app holds the streams commands read from and write to.
+type app struct {
+	stdin  io.Reader
+	stdout io.Writer
+	stderr io.Writer
+}
+
+// commands are the top level commands of the tool.
+func commands() []*command {
+	return []*command{
+		accountsCommand(),
+		auditCommand(),
+		reconcileCommand(),
+		requestCommand(),
+		shellCommand(),
+	}
+}
+
+// Run runs the tool with the given arguments, excluding the program name,
+// and returns the exit code.
+//
+// Ctrl-C cancels the command, except in the shell, where it only cancels
+// the line being run.
+func Run(ctx context.Context, args []string, stdin io.Reader, stdout io.Writer, stderr io.Writer) int {
+	a := &app{stdin: stdin, stdout: stdout, stderr: stderr}
+
+	root := &command{name: "form3", subcommands: commands()}
+
+	err := a.dispatch(ctx, root, args, root.name)
+	if err == nil || errors.Is(err, flag.ErrHelp) {
+		return ExitOK
+	}
+
+	fmt.Fprintf(stderr, "form3: %s\n", err)
+
+	return ExitCode(err)
+}
+
+// dispatch runs cmd, or the subcommand of cmd named by the first argument.
+func (a *app) dispatch(ctx context.Context, cmd *command, args []string, path string) error {
+	if cmd.run != nil {
+		if !cmd.ownsInterrupt {
+			var stop context.CancelFunc
+			ctx, stop = signal.NotifyContext(ctx, os.Interrupt)
+			defer stop()
+		}
+
+		return cmd.run(ctx, a, args)
+	}
+
+	if len(args) == 0 || args[0] == "-h" || args[0] == "--help" || args[0] == "help" {
+		a.printUsage(cmd, path)
+
+		if len(args) == 0 {
+			return usageErrorf("%s requires a command", path)
+		}
+
+		return flag.ErrHelp
+	}
+
+	for _, sub := range cmd.subcommands {
+		if sub.name == args[0] {
+			return a.dispatch(ctx, sub, args[1:], path+" "+sub.name)
+		}
+	}
+
+	a.printUsage(cmd, path)
+
+	return usageErrorf("unknown command %q", path+" "+args[0])
+}
+
+// printUsage lists the subcommands of cmd.
+func (a *app) printUsage(cmd *command, path string) {
+	subs := make([]*command, len(cmd.subcommands))
+	copy(subs, cmd.subcommands)
+	sort.Slice(subs, func(i, j int) bool { return subs[i].name < subs[j].name })
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "Usage: %s <command> [flags]\n\nCommands:\n", path)
+	for _, sub := range subs {
+		fmt.Fprintf(&b, "  %-10s %s\n", sub.name, sub.summary)
+	}
+	fmt.Fprintf(&b, "\nRun '%s <command> -h' for the flags of a command.\n", path)
+
+	fmt.Fprint(a.stderr, b.String())
+}
+
+// newFlagSet returns a flag set for the command at path that reports
+// errors rather than exiting.
+func (a *app) newFlagSet(path string, usage string) *flag.FlagSet {
+	fs := flag.NewFlagSet(path, flag.ContinueOnError)
+	fs.SetOutput(a.stderr)
+	fs.Usage = func() {
+		fmt.Fprintf(a.stderr, "Usage: %s %s\n\nFlags:\n", path, usage)
+		fs.PrintDefaults()
+	}
+
+	return fs
+}
+
+// parseFlags parses args with fs, allowing flags to follow positional
+// arguments. It returns the positional arguments.
+func parseFlags(fs *flag.FlagSet, args []string) ([]string, error) {
+	var positional []string
+
+	for {
+		err := fs.Parse(args)
+		if err != nil {
+			if errors.Is(err, flag.ErrHelp) {
+				return nil, err
+			}
+
+			return nil, &usageError{err: err}
+		}
+
+		args = fs.Args()
+		if len(args) == 0 {
+			return positional, nil
+		}
+
+		positional = append(positional, args[0])
+		args = args[1:]
+	}
+}
+
+// stringsFlag is a flag that may be repeated.
+type stringsFlag []string
+
+func (f *stringsFlag) String() string {
+	return strings.Join(*f, ", ")
+}
+
+func (f *stringsFlag) Set(v string) error {
+	*f = append(*f, v)
+	return nil
+}
+
+// keyValues parses repeated key=value flags.
+func keyValues(name string, values []string) (map[string]string, error) {
+	if len(values) == 0 {
+		return nil, nil
+	}
+
+	m := make(map[string]string, len(values))
+	for _, v := range values {
+		k, val, ok := strings.Cut(v, "=")
+		if !ok || k == "" {
+			return nil, usageErrorf("--%s %q must be written as key=value", name, v)
+		}
+		m[k] = val
+	}
+
+	return m, nil
+}
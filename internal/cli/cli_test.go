@@ -0,0 +1,998 @@
+package cli_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"github.com/vivangkumar/form3-http-go/internal/cli"
+	"github.com/vivangkumar/form3-http-go/pkg/client/audit"
+)
+
+const (
+	orgID     = "eb0bd6f5-c3f5-44b2-b677-acd23cdde73c"
+	accountID = "ad27e265-9605-4b4b-a0e5-3003ea9cc4dc"
+
+	accountJSON = `{
+		"id": "` + accountID + `",
+		"organisation_id": "` + orgID + `",
+		"type": "accounts",
+		"version": 0,
+		"attributes": {
+			"country": "GB",
+			"base_currency": "GBP",
+			"bank_id": "400300",
+			"bank_id_code": "GBDSC",
+			"bic": "NWBKGB22",
+			"name": ["Samantha Holder"]
+		}
+	}`
+)
+
+// recordedRequest is a request received by the test server.
+type recordedRequest struct {
+	method string
+	path   string
+	query  string
+	header http.Header
+	body   []byte
+}
+
+// apiServer is a fake accounts API replying with canned responses.
+type apiServer struct {
+	*httptest.Server
+
+	mu       sync.Mutex
+	requests []recordedRequest
+
+	// reply returns the status and body for a request.
+	reply func(r *http.Request) (int, string)
+}
+
+func newAPIServer() *apiServer {
+	s := &apiServer{
+		reply: func(r *http.Request) (int, string) {
+			switch r.Method {
+			case http.MethodGet:
+				if strings.HasSuffix(r.URL.Path, "/accounts/") {
+					return http.StatusOK, `{"data": [` + accountJSON + `], "links": {}}`
+				}
+				return http.StatusOK, `{"data": ` + accountJSON + `}`
+			case http.MethodPost:
+				return http.StatusCreated, `{"data": ` + accountJSON + `}`
+			}
+
+			return http.StatusNoContent, ""
+		},
+	}
+
+	s.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+
+		s.mu.Lock()
+		s.requests = append(s.requests, recordedRequest{
+			method: r.Method,
+			path:   r.URL.Path,
+			query:  r.URL.RawQuery,
+			header: r.Header.Clone(),
+			body:   body,
+		})
+		reply := s.reply
+		s.mu.Unlock()
+
+		status, resp := reply(r)
+		w.Header().Set("Content-Type", "application/vnd.api+json")
+		w.WriteHeader(status)
+		io.WriteString(w, resp)
+	}))
+
+	return s
+}
+
+func (s *apiServer) received() []recordedRequest {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return append([]recordedRequest(nil), s.requests...)
+}
+
+// setenv sets an environment variable for the current spec.
+func setenv(key string, value string) {
+	prev, ok := os.LookupEnv(key)
+	Expect(os.Setenv(key, value)).To(Succeed())
+
+	DeferCleanup(func() {
+		if ok {
+			os.Setenv(key, prev)
+		} else {
+			os.Unsetenv(key)
+		}
+	})
+}
+
+var _ = Describe("form3", func() {
+	var (
+		dir    string
+		server *apiServer
+		stdout *bytes.Buffer
+		stderr *bytes.Buffer
+	)
+
+	BeforeEach(func() {
+		dir = GinkgoT().TempDir()
+
+		// Keep the config of the user running the tests out of the way.
+		for _, key := range []string{
+			"FORM3_CONFIG", "FORM3_PROFILE", "FORM3_ENVIRONMENT",
+			"FORM3_BASE_URL", "FORM3_ORGANISATION_ID",
+		} {
+			setenv(key, "")
+			os.Unsetenv(key)
+		}
+		setenv("HOME", dir)
+		setenv("XDG_CONFIG_HOME", dir)
+
+		server = newAPIServer()
+		DeferCleanup(server.Close)
+
+		stdout = new(bytes.Buffer)
+		stderr = new(bytes.Buffer)
+	})
+
+	run := func(args ...string) int {
+		return cli.Run(context.Background(), args, strings.NewReader(""), stdout, stderr)
+	}
+
+	writeFile := func(name string, content string) string {
+		path := filepath.Join(dir, name)
+		Expect(os.WriteFile(path, []byte(content), 0o600)).To(Succeed())
+
+		return path
+	}
+
+	Describe("Commands", func() {
+		It("should list commands without arguments", func() {
+			Expect(run()).To(Equal(cli.ExitUsage))
+			Expect(stderr.String()).To(ContainSubstring("accounts"))
+		})
+
+		It("should reject unknown commands", func() {
+			Expect(run("payments", "list")).To(Equal(cli.ExitUsage))
+			Expect(stderr.String()).To(ContainSubstring(`unknown command "form3 payments"`))
+		})
+
+		It("should print help", func() {
+			Expect(run("accounts", "fetch", "-h")).To(Equal(cli.ExitOK))
+			Expect(stderr.String()).To(ContainSubstring("-dry-run"))
+		})
+	})
+
+	Describe("accounts create", func() {
+		It("should create an account from flags", func() {
+			code := run("accounts", "create",
+				"--base-url", server.URL,
+				"--organisation-id", orgID,
+				"--id", accountID,
+				"--country", "GB",
+				"--base-currency", "GBP",
+				"--bank-id-code", "GBDSC",
+				"--name", "Samantha", "--name", "Holder",
+				"--joint-account",
+			)
+			Expect(code).To(Equal(cli.ExitOK), stderr.String())
+
+			reqs := server.received()
+			Expect(reqs).To(HaveLen(1))
+			Expect(reqs[0].method).To(Equal(http.MethodPost))
+			Expect(reqs[0].path).To(Equal("/v1/organisation/accounts/"))
+			Expect(reqs[0].header.Get("Idempotency-Key")).To(Not(BeEmpty()))
+
+			var body struct {
+				Data struct {
+					ID             string         `json:"id"`
+					OrganisationID string         `json:"organisation_id"`
+					Attributes     map[string]any `json:"attributes"`
+				} `json:"data"`
+			}
+			Expect(json.Unmarshal(reqs[0].body, &body)).To(Succeed())
+			Expect(body.Data.ID).To(Equal(accountID))
+			Expect(body.Data.OrganisationID).To(Equal(orgID))
+			Expect(body.Data.Attributes).To(Equal(map[string]any{
+				"country":       "GB",
+				"base_currency": "GBP",
+				"bank_id_code":  "GBDSC",
+				"name":          []any{"Samantha", "Holder"},
+				"joint_account": true,
+			}))
+
+			Expect(stdout.String()).To(ContainSubstring("ID"))
+			Expect(stdout.String()).To(ContainSubstring(accountID))
+			Expect(stdout.String()).To(ContainSubstring("Samantha Holder"))
+		})
+
+		It("should read attributes from a file, overridden by flags", func() {
+			path := writeFile("attrs.yaml", "country: GB\nbank_id: \"400300\"\nname:\n  - Samantha Holder\n")
+
+			code := run("accounts", "create",
+				"--base-url", server.URL,
+				"--organisation-id", orgID,
+				"--file", path,
+				"--bank-id", "400301",
+				"-o", "json",
+			)
+			Expect(code).To(Equal(cli.ExitOK), stderr.String())
+
+			var body struct {
+				Data struct {
+					Attributes map[string]any `json:"attributes"`
+				} `json:"data"`
+			}
+			Expect(json.Unmarshal(server.received()[0].body, &body)).To(Succeed())
+			Expect(body.Data.Attributes).To(Equal(map[string]any{
+				"country": "GB",
+				"bank_id": "400301",
+				"name":    []any{"Samantha Holder"},
+			}))
+
+			var out map[string]any
+			Expect(json.Unmarshal(stdout.Bytes(), &out)).To(Succeed())
+			Expect(out["id"]).To(Equal(accountID))
+		})
+
+		It("should reject unknown attributes in the file", func() {
+			path := writeFile("attrs.json", `{"country": "GB", "sort_code": "400300"}`)
+
+			code := run("accounts", "create", "--base-url", server.URL, "--organisation-id", orgID, "--file", path)
+			Expect(code).To(Equal(cli.ExitUsage))
+			Expect(stderr.String()).To(ContainSubstring("attributes.sort_code"))
+			Expect(server.received()).To(BeEmpty())
+		})
+
+		It("should reject unknown enum values before sending", func() {
+			code := run("accounts", "create", "--base-url", server.URL, "--organisation-id", orgID, "--status", "open")
+			Expect(code).To(Equal(cli.ExitUsage))
+			Expect(server.received()).To(BeEmpty())
+		})
+
+		It("should require an organisation", func() {
+			code := run("accounts", "create", "--base-url", server.URL, "--country", "GB")
+			Expect(code).To(Equal(cli.ExitUsage))
+			Expect(stderr.String()).To(ContainSubstring("--organisation-id"))
+		})
+
+		It("should use the configured organisation", func() {
+			setenv("FORM3_ORGANISATION_ID", orgID)
+
+			code := run("accounts", "create", "--base-url", server.URL, "--country", "GB")
+			Expect(code).To(Equal(cli.ExitOK), stderr.String())
+			Expect(string(server.received()[0].body)).To(ContainSubstring(orgID))
+		})
+	})
+
+	Describe("Dry runs", func() {
+		It("should print the request instead of sending it", func() {
+			code := run("accounts", "create",
+				"--base-url", server.URL,
+				"--organisation-id", orgID,
+				"--id", accountID,
+				"--country", "GB",
+				"--dry-run",
+			)
+			Expect(code).To(Equal(cli.ExitOK), stderr.String())
+			Expect(server.received()).To(BeEmpty())
+
+			out := stdout.String()
+			Expect(out).To(HavePrefix("POST /v1/organisation/accounts/ HTTP/1.1\n"))
+			Expect(out).To(ContainSubstring("Host: " + strings.TrimPrefix(server.URL, "http://")))
+			Expect(out).To(ContainSubstring("Content-Type: application/vnd.api+json"))
+			Expect(out).To(ContainSubstring("Idempotency-Key: "))
+			Expect(out).To(ContainSubstring(`"id":"` + accountID + `"`))
+		})
+
+		It("should print delete requests", func() {
+			code := run("accounts", "delete", accountID, "--version", "3", "--base-url", server.URL, "--dry-run")
+			Expect(code).To(Equal(cli.ExitOK), stderr.String())
+			Expect(server.received()).To(BeEmpty())
+			Expect(stdout.String()).To(HavePrefix("DELETE /v1/organisation/accounts/" + accountID + "?version=3 HTTP/1.1\n"))
+		})
+
+		It("should require a version to print delete requests", func() {
+			code := run("accounts", "delete", accountID, "--base-url", server.URL, "--dry-run")
+			Expect(code).To(Equal(cli.ExitUsage))
+		})
+	})
+
+	Describe("accounts fetch", func() {
+		It("should print the account as YAML", func() {
+			code := run("accounts", "fetch", accountID, "--base-url", server.URL, "--output", "yaml")
+			Expect(code).To(Equal(cli.ExitOK), stderr.String())
+
+			Expect(server.received()[0].path).To(Equal("/v1/organisation/accounts/" + accountID))
+			Expect(stdout.String()).To(ContainSubstring("id: " + accountID))
+			Expect(stdout.String()).To(ContainSubstring("bank_id: \"400300\""))
+		})
+
+		It("should require an ID", func() {
+			Expect(run("accounts", "fetch", "--base-url", server.URL)).To(Equal(cli.ExitUsage))
+		})
+
+		It("should reject unknown output formats", func() {
+			Expect(run("accounts", "fetch", accountID, "--base-url", server.URL, "-o", "xml")).To(Equal(cli.ExitUsage))
+		})
+	})
+
+	Describe("accounts list", func() {
+		It("should list a page of accounts", func() {
+			code := run("accounts", "list",
+				"--base-url", server.URL,
+				"--page-size", "10",
+				"--filter", "country=GB",
+			)
+			Expect(code).To(Equal(cli.ExitOK), stderr.String())
+
+			Expect(server.received()[0].query).To(Equal("filter%5Bcountry%5D=GB&page%5Bsize%5D=10"))
+
+			lines := strings.Split(strings.TrimSpace(stdout.String()), "\n")
+			Expect(lines).To(HaveLen(2))
+			Expect(lines[1]).To(ContainSubstring(accountID))
+		})
+
+		It("should print an empty list as JSON", func() {
+			server.reply = func(r *http.Request) (int, string) {
+				return http.StatusOK, `{"data": []}`
+			}
+
+			code := run("accounts", "list", "--base-url", server.URL, "-o", "json")
+			Expect(code).To(Equal(cli.ExitOK), stderr.String())
+			Expect(strings.TrimSpace(stdout.String())).To(Equal("[]"))
+		})
+
+		It("should reject malformed filters", func() {
+			Expect(run("accounts", "list", "--base-url", server.URL, "--filter", "GB")).To(Equal(cli.ExitUsage))
+		})
+	})
+
+	Describe("accounts delete", func() {
+		It("should delete the given version", func() {
+			code := run("accounts", "delete", accountID, "--version", "2", "--base-url", server.URL)
+			Expect(code).To(Equal(cli.ExitOK), stderr.String())
+
+			reqs := server.received()
+			Expect(reqs).To(HaveLen(1))
+			Expect(reqs[0].method).To(Equal(http.MethodDelete))
+			Expect(reqs[0].query).To(Equal("version=2"))
+			Expect(stdout.String()).To(Equal("Deleted account " + accountID + " at version 2\n"))
+		})
+
+		It("should delete the latest version", func() {
+			code := run("accounts", "delete", accountID, "--base-url", server.URL)
+			Expect(code).To(Equal(cli.ExitOK), stderr.String())
+
+			reqs := server.received()
+			Expect(reqs).To(HaveLen(2))
+			Expect(reqs[0].method).To(Equal(http.MethodGet))
+			Expect(reqs[1].method).To(Equal(http.MethodDelete))
+			Expect(reqs[1].query).To(Equal("version=0"))
+		})
+	})
+
+	Describe("accounts import", func() {
+		const (
+			firstID  = "7a4a2b1e-0c4e-4d7b-9a47-1f1c3d1a6f01"
+			secondID = "7a4a2b1e-0c4e-4d7b-9a47-1f1c3d1a6f02"
+		)
+
+		posted := func() []map[string]any {
+			var accs []map[string]any
+			for _, req := range server.received() {
+				if req.method != http.MethodPost {
+					continue
+				}
+
+				var body struct {
+					Data map[string]any `json:"data"`
+				}
+				Expect(json.Unmarshal(req.body, &body)).To(Succeed())
+				accs = append(accs, body.Data)
+			}
+
+			return accs
+		}
+
+		It("should import valid rows and reject the others", func() {
+			path := writeFile("accounts.csv", "Account ID,country,bank_id,name[0],name[1],switched\n"+
+				firstID+",GB,400300,Samantha,Holder,true\n"+
+				secondID+",,400301,Missing Country,,\n"+
+				","+"GB,400302,No ID,,\n"+
+				firstID+"3,GB,400303,Bad Bool,,maybe\n",
+			)
+
+			code := run("accounts", "import",
+				"--base-url", server.URL,
+				"--organisation-id", orgID,
+				"--file", path,
+				"--map", "Account ID=id",
+				"--concurrency", "1",
+			)
+			Expect(code).To(Equal(cli.ExitError))
+			Expect(stderr.String()).To(ContainSubstring("2 rows rejected"))
+			Expect(stdout.String()).To(ContainSubstring("Imported 2 accounts, skipped 0 already imported, rejected 2"))
+
+			accs := posted()
+			Expect(accs).To(HaveLen(2))
+			Expect(accs[0]["id"]).To(Equal(firstID))
+			Expect(accs[0]["organisation_id"]).To(Equal(orgID))
+			Expect(accs[0]["attributes"]).To(HaveKeyWithValue("name", []any{"Samantha", "Holder"}))
+			Expect(accs[0]["attributes"]).To(HaveKeyWithValue("switched", true))
+			Expect(accs[1]["id"]).NotTo(BeEmpty())
+
+			rejects, err := os.ReadFile(filepath.Join(dir, "accounts.rejects.csv"))
+			Expect(err).NotTo(HaveOccurred())
+
+			lines := strings.Split(strings.TrimSpace(string(rejects)), "\n")
+			Expect(lines).To(HaveLen(3))
+			Expect(lines[0]).To(Equal("Account ID,country,bank_id,name[0],name[1],switched,error"))
+			Expect(lines[1]).To(HavePrefix(secondID + ",,400301,Missing Country,,,line 3: country is required"))
+			Expect(lines[2]).To(ContainSubstring("line 5: column switched"))
+
+			checkpoint, err := os.ReadFile(path + ".checkpoint")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(strings.Fields(string(checkpoint))).To(Equal([]string{firstID, accs[1]["id"].(string)}))
+		})
+
+		It("should resume from the checkpoint", func() {
+			path := writeFile("accounts.csv", "id,country,bank_id\n"+
+				firstID+",GB,400300\n"+
+				",GB,400301\n",
+			)
+
+			args := []string{"accounts", "import", "--base-url", server.URL, "--organisation-id", orgID, "--file", path}
+			Expect(run(args...)).To(Equal(cli.ExitOK), stderr.String())
+			Expect(posted()).To(HaveLen(2))
+
+			stdout.Reset()
+			Expect(run(args...)).To(Equal(cli.ExitOK), stderr.String())
+			Expect(posted()).To(HaveLen(2))
+			Expect(stdout.String()).To(ContainSubstring("Imported 0 accounts, skipped 2 already imported, rejected 0"))
+
+			_, err := os.Stat(filepath.Join(dir, "accounts.rejects.csv"))
+			Expect(os.IsNotExist(err)).To(BeTrue())
+		})
+
+		It("should derive the same IDs when rows move", func() {
+			path := writeFile("accounts.csv", "id,country,bank_id\n"+
+				",GB,400301\n",
+			)
+
+			args := []string{"accounts", "import", "--base-url", server.URL, "--organisation-id", orgID, "--file", path}
+			Expect(run(args...)).To(Equal(cli.ExitOK), stderr.String())
+			Expect(posted()).To(HaveLen(1))
+
+			writeFile("accounts.csv", "id,country,bank_id\n"+
+				",GB,400300\n"+
+				",GB,400301\n",
+			)
+
+			stdout.Reset()
+			Expect(run(args...)).To(Equal(cli.ExitOK), stderr.String())
+			Expect(stdout.String()).To(ContainSubstring("Imported 1 accounts, skipped 1 already imported, rejected 0"))
+
+			accs := posted()
+			Expect(accs).To(HaveLen(2))
+			Expect(accs[1]["attributes"]).To(HaveKeyWithValue("bank_id", "400300"))
+			Expect(accs[1]["id"]).NotTo(Equal(accs[0]["id"]))
+		})
+
+		It("should read lines of any length", func() {
+			path := writeFile("accounts.ndjson",
+				`{"id": "`+firstID+`", "attributes": {"country": "GB"}}`+strings.Repeat(" ", 100_000)+"\n",
+			)
+
+			code := run("accounts", "import", "--base-url", server.URL, "--organisation-id", orgID, "--file", path)
+			Expect(code).To(Equal(cli.ExitOK), stderr.String())
+			Expect(posted()).To(HaveLen(1))
+		})
+
+		It("should reject rows the API refuses", func() {
+			server.reply = func(r *http.Request) (int, string) {
+				return http.StatusConflict, `{"error_message": "duplicate"}`
+			}
+
+			path := writeFile("accounts.ndjson",
+				`{"id": "`+firstID+`", "attributes": {"country": "GB"}}`+"\n"+
+					"\n"+
+					`{"id": "`+secondID+`", "attributes": {"country": "GB", "colour": "red"}}`+"\n"+
+					`not json`+"\n",
+			)
+
+			code := run("accounts", "import",
+				"--base-url", server.URL,
+				"--organisation-id", orgID,
+				"--file", path,
+				"-o", "json",
+			)
+			Expect(code).To(Equal(cli.ExitError))
+			Expect(posted()).To(HaveLen(1))
+
+			var summary map[string]any
+			Expect(json.Unmarshal(stdout.Bytes(), &summary)).To(Succeed())
+			Expect(summary).To(HaveKeyWithValue("imported", BeNumerically("==", 0)))
+			Expect(summary).To(HaveKeyWithValue("rejected", BeNumerically("==", 3)))
+
+			rejects, err := os.ReadFile(filepath.Join(dir, "accounts.rejects.ndjson"))
+			Expect(err).NotTo(HaveOccurred())
+
+			var lines []map[string]any
+			for _, line := range strings.Split(strings.TrimSpace(string(rejects)), "\n") {
+				var reject map[string]any
+				Expect(json.Unmarshal([]byte(line), &reject)).To(Succeed())
+				lines = append(lines, reject)
+			}
+
+			Expect(lines).To(HaveLen(3))
+			Expect(lines).To(ContainElement(SatisfyAll(
+				HaveKeyWithValue("line", BeNumerically("==", 3)),
+				HaveKeyWithValue("error", ContainSubstring("unknown fields attributes.colour")),
+			)))
+			Expect(lines).To(ContainElement(SatisfyAll(
+				HaveKeyWithValue("line", BeNumerically("==", 4)),
+				HaveKeyWithValue("record", "not json"),
+			)))
+			Expect(lines).To(ContainElement(SatisfyAll(
+				HaveKeyWithValue("line", BeNumerically("==", 1)),
+				HaveKeyWithValue("error", ContainSubstring("409")),
+			)))
+		})
+
+		It("should reject unknown columns before sending", func() {
+			path := writeFile("accounts.csv", "id,colour\n"+firstID+",red\n")
+
+			Expect(run("accounts", "import", "--base-url", server.URL, "--file", path)).To(Equal(cli.ExitUsage))
+			Expect(server.received()).To(BeEmpty())
+		})
+	})
+
+	Describe("accounts export", func() {
+		BeforeEach(func() {
+			server.reply = func(r *http.Request) (int, string) {
+				if r.URL.Query().Get("page[number]") == "1" {
+					return http.StatusOK, `{"data": [` + accountJSON + `], "links": {"self": "/"}}`
+				}
+
+				return http.StatusOK, `{"data": [` + accountJSON + `, ` + accountJSON + `], "links": {"next": "/?page[number]=1"}}`
+			}
+		})
+
+		It("should write every page as CSV", func() {
+			code := run("accounts", "export",
+				"--base-url", server.URL,
+				"--page-size", "2",
+				"--columns", "id,country,name[0]",
+			)
+			Expect(code).To(Equal(cli.ExitOK), stderr.String())
+
+			reqs := server.received()
+			Expect(reqs).To(HaveLen(2))
+			Expect(reqs[0].query).To(Equal("page%5Bsize%5D=2"))
+			Expect(reqs[1].query).To(Equal("page%5Bnumber%5D=1&page%5Bsize%5D=2"))
+
+			row := accountID + ",GB,Samantha Holder"
+			Expect(stdout.String()).To(Equal("id,country,name[0]\n" + row + "\n" + row + "\n" + row + "\n"))
+		})
+
+		It("should write NDJSON to a file", func() {
+			path := filepath.Join(dir, "accounts.ndjson")
+
+			code := run("accounts", "export", "--base-url", server.URL, "--page-size", "2", "--file", path)
+			Expect(code).To(Equal(cli.ExitOK), stderr.String())
+
+			b, err := os.ReadFile(path)
+			Expect(err).NotTo(HaveOccurred())
+
+			lines := strings.Split(strings.TrimSpace(string(b)), "\n")
+			Expect(lines).To(HaveLen(3))
+			Expect(lines[0]).To(MatchJSON(accountJSON))
+		})
+
+		It("should reject columns for NDJSON", func() {
+			Expect(run("accounts", "export", "--format", "ndjson", "--columns", "id")).To(Equal(cli.ExitUsage))
+		})
+	})
+
+	Describe("request", func() {
+		It("should send the request and print the response", func() {
+			code := run("request", "get", "/v1/organisation/accounts/{id}",
+				"--base-url", server.URL,
+				"--param", "id="+accountID,
+				"--query", "include=master",
+				"--header", "X-Trace: abc",
+			)
+			Expect(code).To(Equal(cli.ExitOK), stderr.String())
+
+			reqs := server.received()
+			Expect(reqs).To(HaveLen(1))
+			Expect(reqs[0].method).To(Equal(http.MethodGet))
+			Expect(reqs[0].path).To(Equal("/v1/organisation/accounts/" + accountID))
+			Expect(reqs[0].query).To(Equal("include=master"))
+			Expect(reqs[0].header.Get("X-Trace")).To(Equal("abc"))
+			Expect(reqs[0].header.Get("Accept")).To(Equal("application/vnd.api+json"))
+
+			Expect(stderr.String()).To(HavePrefix("HTTP/1.1 200 OK\n"))
+			Expect(stderr.String()).To(ContainSubstring("\nContent-Type: application/vnd.api+json\n"))
+			Expect(stdout.String()).To(MatchJSON(`{"data": ` + accountJSON + `}`))
+			Expect(stdout.String()).To(HavePrefix("{\n  \"data\": {\n"))
+		})
+
+		It("should send every value of a repeated query key", func() {
+			code := run("request", "get", "/v1/organisation/accounts?filter[country]=GB",
+				"--base-url", server.URL,
+				"--query", "filter[bank_id]=400300",
+				"--query", "filter[bank_id]=400301",
+			)
+			Expect(code).To(Equal(cli.ExitOK), stderr.String())
+
+			reqs := server.received()
+			Expect(reqs).To(HaveLen(1))
+
+			q, err := url.ParseQuery(reqs[0].query)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(q).To(Equal(url.Values{
+				"filter[bank_id]": {"400300", "400301"},
+				"filter[country]": {"GB"},
+			}))
+		})
+
+		It("should send a body read from a file", func() {
+			path := writeFile("body.json", `{"data": {"id": "`+accountID+`"}}`)
+
+			code := run("request", "POST", "/v1/organisation/{organisation_id}/things",
+				"--base-url", server.URL,
+				"--body", "@"+path,
+				"--idempotency-key", "key-1",
+			)
+			Expect(code).To(Equal(cli.ExitUsage))
+			Expect(stderr.String()).To(ContainSubstring("path parameters organisation_id are not set"))
+
+			setenv("FORM3_ORGANISATION_ID", orgID)
+			Expect(run("request", "POST", "/v1/organisation/{organisation_id}/things",
+				"--base-url", server.URL,
+				"--body", "@"+path,
+				"--idempotency-key", "key-1",
+			)).To(Equal(cli.ExitOK), stderr.String())
+
+			reqs := server.received()
+			Expect(reqs).To(HaveLen(1))
+			Expect(reqs[0].path).To(Equal("/v1/organisation/" + orgID + "/things"))
+			Expect(reqs[0].body).To(MatchJSON(`{"data": {"id": "` + accountID + `"}}`))
+			Expect(reqs[0].header.Get("Idempotency-Key")).To(Equal("key-1"))
+		})
+
+		It("should reject bodies that are not JSON", func() {
+			Expect(run("request", "POST", "/v1/x", "--base-url", server.URL, "--body", "{")).To(Equal(cli.ExitUsage))
+			Expect(server.received()).To(BeEmpty())
+		})
+
+		It("should print the status of failed requests", func() {
+			server.reply = func(r *http.Request) (int, string) {
+				return http.StatusNotFound, ""
+			}
+
+			Expect(run("request", "GET", "/v1/organisation/accounts/x", "--base-url", server.URL)).To(Equal(cli.ExitNotFound))
+			Expect(stderr.String()).To(HavePrefix("HTTP/1.1 404 Not Found\n"))
+		})
+
+		It("should follow next links and concatenate the data", func() {
+			server.reply = func(r *http.Request) (int, string) {
+				switch r.URL.Query().Get("page[number]") {
+				case "":
+					return http.StatusOK, `{"data": [{"id": "1"}, {"id": "2"}], "links": {"next": "/v1/organisation/accounts?page[number]=1"}}`
+				case "1":
+					return http.StatusOK, `{"data": [{"id": "3"}], "links": {"next": "` + server.URL + `/v1/organisation/accounts?page[number]=2"}}`
+				}
+
+				return http.StatusOK, `{"data": [], "links": {}}`
+			}
+
+			code := run("request", "GET", "/v1/organisation/accounts", "--base-url", server.URL, "--query", "page[size]=2", "--paginate")
+			Expect(code).To(Equal(cli.ExitOK), stderr.String())
+
+			reqs := server.received()
+			Expect(reqs).To(HaveLen(3))
+			Expect(reqs[0].query).To(Equal("page%5Bsize%5D=2"))
+			Expect(reqs[1].query).To(Equal("page[number]=1"))
+			Expect(reqs[2].query).To(Equal("page[number]=2"))
+
+			Expect(stdout.String()).To(MatchJSON(`[{"id": "1"}, {"id": "2"}, {"id": "3"}]`))
+		})
+
+		It("should not follow links to other hosts", func() {
+			server.reply = func(r *http.Request) (int, string) {
+				return http.StatusOK, `{"data": [], "links": {"next": "https://example.com/v1/organisation/accounts"}}`
+			}
+
+			Expect(run("request", "GET", "/v1/organisation/accounts", "--base-url", server.URL, "--paginate")).To(Equal(cli.ExitError))
+			Expect(stderr.String()).To(ContainSubstring("is not on the API host"))
+			Expect(server.received()).To(HaveLen(1))
+		})
+	})
+
+	Describe("shell", func() {
+		shell := func(input string) int {
+			return cli.Run(context.Background(), []string{"shell", "--base-url", server.URL}, strings.NewReader(input), stdout, stderr)
+		}
+
+		It("should run each line with the client", func() {
+			code := shell("get /v1/organisation/accounts/" + accountID + "\n" +
+				"\n" +
+				"# comments are ignored\n" +
+				`post /v1/organisation/accounts {"data": {"id": "` + accountID + `", "type": "accounts"}}` + "\n" +
+				"accounts fetch " + accountID + "\n" +
+				"delete /v1/organisation/accounts/" + accountID + "?version=0\n" +
+				"history\n",
+			)
+			Expect(code).To(Equal(cli.ExitOK), stderr.String())
+
+			reqs := server.received()
+			Expect(reqs).To(HaveLen(4))
+			Expect(reqs[0].method).To(Equal(http.MethodGet))
+			Expect(reqs[0].path).To(Equal("/v1/organisation/accounts/" + accountID))
+			Expect(reqs[1].method).To(Equal(http.MethodPost))
+			Expect(reqs[1].body).To(MatchJSON(`{"data": {"id": "` + accountID + `", "type": "accounts"}}`))
+			Expect(reqs[1].header.Get("Content-Type")).To(Equal("application/vnd.api+json"))
+			Expect(reqs[2].path).To(Equal("/v1/organisation/accounts/" + accountID))
+			Expect(reqs[3].method).To(Equal(http.MethodDelete))
+			Expect(reqs[3].query).To(Equal("version=0"))
+
+			out := stdout.String()
+			Expect(out).To(MatchRegexp(`(?m)^200 OK in \S+\n\{\n  "data": \{\n`))
+			Expect(out).To(MatchRegexp(`(?m)^201 Created in \S+$`))
+			Expect(out).To(MatchRegexp(`(?m)^Fetched in \S+$`))
+			Expect(out).To(MatchRegexp(`(?m)^204 No Content in \S+$`))
+			Expect(out).To(HaveSuffix("   3  accounts fetch " + accountID + "\n" +
+				"   4  delete /v1/organisation/accounts/" + accountID + "?version=0\n" +
+				"   5  history\n"))
+		})
+
+		It("should print errors and carry on", func() {
+			server.reply = func(r *http.Request) (int, string) {
+				if r.Method == http.MethodGet {
+					return http.StatusConflict, `{"error_message": "version conflict"}`
+				}
+				return http.StatusCreated, `{"data": ` + accountJSON + `}`
+			}
+
+			code := shell("get /v1/organisation/accounts/" + accountID + "\n" +
+				"post /v1/organisation/accounts {not json}\n" +
+				"fetch\n" +
+				"exit\n" +
+				"get /v1/organisation/accounts/\n",
+			)
+			Expect(code).To(Equal(cli.ExitOK), stderr.String())
+			Expect(server.received()).To(HaveLen(1))
+
+			out := stdout.String()
+			Expect(out).To(MatchRegexp(`(?m)^409 Conflict in \S+\nerror: .*version conflict$`))
+			Expect(out).To(ContainSubstring("error: body is not valid JSON\n"))
+			Expect(out).To(ContainSubstring(`error: unknown command "fetch", see help` + "\n"))
+		})
+
+		It("should print requests on dry runs", func() {
+			code := cli.Run(context.Background(),
+				[]string{"shell", "--base-url", server.URL, "--dry-run"},
+				strings.NewReader("get /v1/organisation/accounts/"+accountID+"\n"),
+				stdout, stderr,
+			)
+			Expect(code).To(Equal(cli.ExitOK), stderr.String())
+			Expect(server.received()).To(BeEmpty())
+			Expect(stdout.String()).To(HavePrefix("GET /v1/organisation/accounts/" + accountID + " HTTP/1.1\n"))
+		})
+	})
+
+	Describe("audit verify", func() {
+		var file, keyFile string
+
+		BeforeEach(func() {
+			file = filepath.Join(dir, "audit.log")
+			keyFile = writeFile("audit.key", "audit-key")
+
+			sink, err := audit.OpenFile(file, []byte("audit-key"))
+			Expect(err).NotTo(HaveOccurred())
+			defer sink.Close()
+
+			for _, method := range []string{http.MethodPost, http.MethodDelete} {
+				rec := audit.Record{Method: method, Path: "/v1/organisation/accounts/" + accountID}
+				Expect(sink.Write(context.Background(), rec)).To(Succeed())
+			}
+		})
+
+		It("should verify an intact log", func() {
+			Expect(run("audit", "verify", file, "--key-file", keyFile)).To(Equal(cli.ExitOK), stderr.String())
+			Expect(stdout.String()).To(HavePrefix("Verified 2 records, last hash "))
+		})
+
+		It("should print the result as JSON", func() {
+			Expect(run("audit", "verify", file, "--key-file", keyFile, "-o", "json")).To(Equal(cli.ExitOK), stderr.String())
+
+			var out map[string]any
+			Expect(json.Unmarshal(stdout.Bytes(), &out)).To(Succeed())
+			Expect(out).To(HaveKeyWithValue("records", BeEquivalentTo(2)))
+			Expect(out).To(HaveKeyWithValue("last_hash", HaveLen(64)))
+		})
+
+		It("should fail for a tampered log", func() {
+			b, err := os.ReadFile(file)
+			Expect(err).NotTo(HaveOccurred())
+			writeFile("audit.log", strings.Replace(string(b), `"DELETE"`, `"PATCH"`, 1))
+
+			Expect(run("audit", "verify", file, "--key-file", keyFile)).To(Equal(cli.ExitError))
+			Expect(stderr.String()).To(ContainSubstring("line 2: hash does not match the record"))
+		})
+
+		It("should require a file", func() {
+			Expect(run("audit", "verify")).To(Equal(cli.ExitUsage))
+		})
+
+		It("should require the key", func() {
+			Expect(run("audit", "verify", file)).To(Equal(cli.ExitUsage))
+			Expect(stderr.String()).To(ContainSubstring("--key-file is required"))
+		})
+	})
+
+	Describe("reconcile", func() {
+		const missingID = "c9b1a4f2-5d8e-4f7a-9b3c-2e6d1f0a8b47"
+
+		BeforeEach(func() {
+			writeFile("expected.ndjson", `{"id": "`+accountID+`", "attributes": {"country": "GB", "bic": "NWBKGB21", "name": ["Samantha Holder"]}}
+{"id": "`+missingID+`", "attributes": {"country": "FR"}}
+`)
+		})
+
+		It("should report the differences and exit with code 1", func() {
+			code := run("reconcile", "--base-url", server.URL, "--organisation-id", orgID, "--file", filepath.Join(dir, "expected.ndjson"))
+			Expect(code).To(Equal(cli.ExitError))
+
+			Expect(stdout.String()).To(HavePrefix("Expected 2, listed 1: 0 matched, 1 missing, 0 unexpected, 1 mismatched\n"))
+			Expect(stdout.String()).To(MatchRegexp(`missing +` + missingID))
+			Expect(stdout.String()).To(MatchRegexp(`mismatched +` + accountID + ` +attributes.bic +"NWBKGB21" +"NWBKGB22"`))
+			Expect(stderr.String()).To(ContainSubstring("1 missing, 0 unexpected and 1 mismatched accounts"))
+		})
+
+		It("should print the report as JSON and write a plan", func() {
+			plan := filepath.Join(dir, "plan.ndjson")
+
+			code := run("reconcile",
+				"--base-url", server.URL,
+				"--organisation-id", orgID,
+				"--file", filepath.Join(dir, "expected.ndjson"),
+				"--plan", plan,
+				"-o", "json",
+			)
+			Expect(code).To(Equal(cli.ExitError))
+
+			var report map[string]any
+			Expect(json.Unmarshal(stdout.Bytes(), &report)).To(Succeed())
+			Expect(report).To(HaveKeyWithValue("matched", BeEquivalentTo(0)))
+			Expect(report).To(HaveKeyWithValue("unexpected", BeEmpty()))
+
+			b, err := os.ReadFile(plan)
+			Expect(err).NotTo(HaveOccurred())
+
+			lines := strings.Split(strings.TrimSpace(string(b)), "\n")
+			Expect(lines).To(HaveLen(2))
+			Expect(lines[0]).To(ContainSubstring(`"op":"create"`))
+			Expect(lines[1]).To(MatchJSON(`{
+				"op": "update",
+				"id": "` + accountID + `",
+				"version": 0,
+				"fields": ["attributes.bic"],
+				"account": {
+					"id": "` + accountID + `",
+					"organisation_id": "` + orgID + `",
+					"type": "accounts",
+					"version": 0,
+					"attributes": {"bic": "NWBKGB21"}
+				}
+			}`))
+		})
+
+		It("should succeed when the accounts match", func() {
+			path := writeFile("expected.csv", "id,country,bic\n"+accountID+",GB,NWBKGB22\n")
+
+			code := run("reconcile", "--base-url", server.URL, "--organisation-id", orgID, "--file", path)
+			Expect(code).To(Equal(cli.ExitOK), stderr.String())
+			Expect(stdout.String()).To(Equal("Expected 1, listed 1: 1 matched, 0 missing, 0 unexpected, 0 mismatched\n"))
+		})
+
+		It("should reject rows without an ID", func() {
+			path := writeFile("expected.ndjson", `{"attributes": {"country": "GB"}}`)
+
+			Expect(run("reconcile", "--base-url", server.URL, "--file", path)).To(Equal(cli.ExitUsage))
+			Expect(stderr.String()).To(ContainSubstring("expected.ndjson:1: id is required"))
+		})
+	})
+
+	Describe("Configuration", func() {
+		It("should read the profile of the config file", func() {
+			path := writeFile("form3.yaml", "profiles:\n  test:\n    base_url: "+server.URL+"\n")
+
+			code := run("accounts", "fetch", accountID, "--config", path, "--profile", "test")
+			Expect(code).To(Equal(cli.ExitOK), stderr.String())
+			Expect(server.received()).To(HaveLen(1))
+		})
+
+		It("should read the default config file", func() {
+			Expect(os.MkdirAll(filepath.Join(dir, "form3"), 0o700)).To(Succeed())
+			writeFile("form3/config.yaml", "profiles:\n  default:\n    base_url: "+server.URL+"\n")
+
+			// The user config directory differs by platform.
+			configDir, err := os.UserConfigDir()
+			Expect(err).To(BeNil())
+			if configDir != dir {
+				Skip("user config directory is not configurable on this platform")
+			}
+
+			code := run("accounts", "fetch", accountID)
+			Expect(code).To(Equal(cli.ExitOK), stderr.String())
+			Expect(server.received()).To(HaveLen(1))
+		})
+
+		It("should let env vars override the profile", func() {
+			path := writeFile("form3.yaml", "profiles:\n  default:\n    base_url: http://localhost:1\n")
+			setenv("FORM3_CONFIG", path)
+			setenv("FORM3_BASE_URL", server.URL)
+
+			code := run("accounts", "fetch", accountID)
+			Expect(code).To(Equal(cli.ExitOK), stderr.String())
+		})
+
+		It("should report invalid settings with a config exit code", func() {
+			setenv("FORM3_TIMEOUT", "soon")
+
+			code := run("accounts", "fetch", accountID, "--base-url", server.URL)
+			Expect(code).To(Equal(cli.ExitConfig))
+			Expect(stderr.String()).To(ContainSubstring("FORM3_TIMEOUT"))
+		})
+
+		It("should require a config file for profiles", func() {
+			Expect(run("accounts", "fetch", accountID, "--profile", "sandbox")).To(Equal(cli.ExitUsage))
+		})
+	})
+
+	Describe("Exit codes", func() {
+		DescribeTable("should map API errors",
+			func(status int, want int) {
+				server.reply = func(r *http.Request) (int, string) {
+					return status, `{"error_message": "failed"}`
+				}
+
+				Expect(run("accounts", "fetch", accountID, "--base-url", server.URL)).To(Equal(want))
+				Expect(stderr.String()).To(ContainSubstring("returned status"))
+			},
+			Entry("bad request", http.StatusBadRequest, cli.ExitInvalid),
+			Entry("unauthorised", http.StatusUnauthorized, cli.ExitUnauthorised),
+			Entry("forbidden", http.StatusForbidden, cli.ExitUnauthorised),
+			Entry("not found", http.StatusNotFound, cli.ExitNotFound),
+			Entry("conflict", http.StatusConflict, cli.ExitConflict),
+			Entry("rate limited", http.StatusTooManyRequests, cli.ExitRateLimited),
+			Entry("server error", http.StatusInternalServerError, cli.ExitServer),
+			Entry("other", http.StatusTeapot, cli.ExitError),
+		)
+
+		It("should report unreachable APIs", func() {
+			server.Close()
+
+			Expect(run("accounts", "fetch", accountID, "--base-url", server.URL)).To(Equal(cli.ExitUnavailable))
+		})
+	})
+})
@@ -0,0 +1,171 @@
+package cli
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httputil"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/vivangkumar/form3-http-go/pkg/client"
+	"github.com/vivangkumar/form3-http-go/pkg/form3"
+)
+
+const (
+	// configEnv names the config file, if --config is not used.
+	configEnv = "FORM3_CONFIG"
+
+	// profileEnv names the profile, if --profile is not used.
+	profileEnv = "FORM3_PROFILE"
+)
+
+// errDryRun stops a request built for --dry-run from being sent.
+var errDryRun = errors.New("dry run")
+
+// clientFlags are the flags that configure the client, shared by all
+// commands that call the API.
+type clientFlags struct {
+	config      string
+	profile     string
+	environment string
+	baseURL     string
+	dryRun      bool
+}
+
+func (f *clientFlags) register(fs *flag.FlagSet) {
+	fs.StringVar(&f.config, "config", "", "config file `path` (default $FORM3_CONFIG, or form3/config.yaml in the user config directory)")
+	fs.StringVar(&f.profile, "profile", "", "config file profile (default $FORM3_PROFILE, or the file's default)")
+	fs.StringVar(&f.environment, "environment", "", "environment: local, sandbox or production")
+	fs.StringVar(&f.baseURL, "base-url", "", "base `URL` of the API, overriding the environment")
+	fs.BoolVar(&f.dryRun, "dry-run", false, "print the request instead of sending it")
+}
+
+// loadConfig layers the config file profile, FORM3_ environment variables
+// and flags, in increasing order of precedence.
+func (f *clientFlags) loadConfig() (*form3.Config, error) {
+	cfg := &form3.Config{}
+
+	path, err := f.configPath()
+	if err != nil {
+		return nil, err
+	}
+
+	profile := f.profile
+	if profile == "" {
+		profile = os.Getenv(profileEnv)
+	}
+
+	if path != "" {
+		cfg, err = form3.LoadConfig(path, profile)
+		if err != nil {
+			return nil, fmt.Errorf("load config: %w", err)
+		}
+	} else if profile != "" {
+		return nil, usageErrorf("profile %q requires a config file, see --config", profile)
+	}
+
+	env, err := form3.LoadEnv()
+	if err != nil {
+		return nil, fmt.Errorf("load env: %w", err)
+	}
+	cfg = cfg.Merge(env)
+
+	if f.environment != "" {
+		if _, ok := form3.Environment(f.environment).BaseURL(); !ok {
+			return nil, usageErrorf("--environment %q is not local, sandbox or production", f.environment)
+		}
+	}
+
+	if f.baseURL != "" {
+		u, err := url.Parse(f.baseURL)
+		if err != nil || u.Scheme == "" || u.Host == "" {
+			return nil, usageErrorf("--base-url %q is not an absolute URL", f.baseURL)
+		}
+	}
+
+	cfg = cfg.Merge(&form3.Config{
+		Environment: form3.Environment(f.environment),
+		BaseURL:     f.baseURL,
+	})
+
+	// A base URL set by a lower layer must not override an environment
+	// chosen with a flag.
+	if f.environment != "" && f.baseURL == "" {
+		cfg.BaseURL = ""
+	}
+
+	return cfg, nil
+}
+
+// configPath returns the config file to read, if any.
+func (f *clientFlags) configPath() (string, error) {
+	if f.config != "" {
+		return f.config, nil
+	}
+
+	if path := os.Getenv(configEnv); path != "" {
+		return path, nil
+	}
+
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return "", nil
+	}
+
+	path := filepath.Join(dir, "form3", "config.yaml")
+	if _, err := os.Stat(path); err != nil {
+		return "", nil
+	}
+
+	return path, nil
+}
+
+// newClient returns a client configured by the flags.
+//
+// With --dry-run, requests are written to w instead of being sent.
+func (f *clientFlags) newClient(w io.Writer) (*form3.Client, error) {
+	cfg, err := f.loadConfig()
+	if err != nil {
+		return nil, err
+	}
+
+	var opts []form3.Opt
+	if f.dryRun {
+		// The first request is printed, so it must not be retried.
+		// Nothing is sent, so the transport settings are not needed, and
+		// printing in place of the transport shows the signed request.
+		cfg = cfg.Unset("max_retries", "timeout", "tls_client_cert_path", "tls_client_key_path", "root_ca_path")
+		opts = append(opts, client.WithHTTPClient(dryRun(w)))
+	}
+
+	c, err := form3.NewWithConfig(cfg, opts...)
+	if err != nil {
+		return nil, fmt.Errorf("create client: %w", err)
+	}
+
+	return c, nil
+}
+
+// dryRun returns a Doer that prints each request, as it would be sent,
+// and returns errDryRun instead of sending it.
+func dryRun(w io.Writer) client.Doer {
+	return client.DoerFunc(func(req *http.Request) (*http.Response, error) {
+		dump, err := httputil.DumpRequest(req, true)
+		if err != nil {
+			return nil, fmt.Errorf("dump request: %w", err)
+		}
+
+		out := strings.ReplaceAll(string(dump), "\r\n", "\n")
+		if !strings.HasSuffix(out, "\n") {
+			out += "\n"
+		}
+		fmt.Fprint(w, out)
+
+		return nil, errDryRun
+	})
+}
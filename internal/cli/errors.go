@@ -0,0 +1,105 @@
+package cli
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+
+	"github.com/vivangkumar/form3-http-go/pkg/client"
+	"github.com/vivangkumar/form3-http-go/pkg/form3"
+)
+
+// Exit codes returned by the tool, by class of error.
+const (
+	// ExitOK is returned on success.
+	ExitOK = 0
+
+	// ExitError is returned for errors not covered by another code.
+	ExitError = 1
+
+	// ExitUsage is returned for invalid commands, flags and input files.
+	ExitUsage = 2
+
+	// ExitConfig is returned for invalid profiles and environment variables.
+	ExitConfig = 3
+
+	// ExitNotFound is returned when the API responds 404 Not Found.
+	ExitNotFound = 4
+
+	// ExitConflict is returned when the API responds 409 Conflict.
+	ExitConflict = 5
+
+	// ExitInvalid is returned when the API rejects a request as invalid.
+	ExitInvalid = 6
+
+	// ExitUnauthorised is returned when the API responds 401 or 403.
+	ExitUnauthorised = 7
+
+	// ExitRateLimited is returned when the API responds 429 Too Many Requests.
+	ExitRateLimited = 8
+
+	// ExitServer is returned when the API responds with a 5xx status.
+	ExitServer = 9
+
+	// ExitUnavailable is returned when the API cannot be reached.
+	ExitUnavailable = 10
+)
+
+// usageError is returned for invalid commands, flags and input.
+type usageError struct {
+	err error
+}
+
+func (e *usageError) Error() string {
+	return e.err.Error()
+}
+
+func (e *usageError) Unwrap() error {
+	return e.err
+}
+
+func usageErrorf(format string, args ...any) error {
+	return &usageError{err: fmt.Errorf(format, args...)}
+}
+
+// ExitCode returns the exit code for err.
+func ExitCode(err error) int {
+	if err == nil {
+		return ExitOK
+	}
+
+	var ue *usageError
+	if errors.As(err, &ue) {
+		return ExitUsage
+	}
+
+	var se *form3.SettingError
+	if errors.As(err, &se) {
+		return ExitConfig
+	}
+
+	switch sc := client.StatusCode(err); {
+	case sc == http.StatusNotFound:
+		return ExitNotFound
+	case sc == http.StatusConflict:
+		return ExitConflict
+	case sc == http.StatusBadRequest || sc == http.StatusUnprocessableEntity:
+		return ExitInvalid
+	case sc == http.StatusUnauthorized || sc == http.StatusForbidden:
+		return ExitUnauthorised
+	case sc == http.StatusTooManyRequests:
+		return ExitRateLimited
+	case sc >= 500:
+		return ExitServer
+	case sc != 0:
+		return ExitError
+	}
+
+	var ne net.Error
+	if errors.As(err, &ne) {
+		return ExitUnavailable
+	}
+
+	return ExitError
+}
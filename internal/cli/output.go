@@ -0,0 +1,147 @@
+package cli
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+
+	"gopkg.in/yaml.v3"
+
+	"github.com/vivangkumar/form3-http-go/pkg/account"
+)
+
+// Output formats.
+const (
+	formatTable = "table"
+	formatJSON  = "json"
+	formatYAML  = "yaml"
+)
+
+// outputFlags select how results are printed.
+type outputFlags struct {
+	format string
+}
+
+func (f *outputFlags) register(fs *flag.FlagSet) {
+	fs.StringVar(&f.format, "output", formatTable, "output `format`: table, json or yaml")
+	fs.StringVar(&f.format, "o", formatTable, "shorthand for --output")
+}
+
+// printer returns a printer writing to w in the selected format.
+func (f *outputFlags) printer(w io.Writer) (*printer, error) {
+	switch f.format {
+	case formatTable, formatJSON, formatYAML:
+		return &printer{w: w, format: f.format}, nil
+	}
+
+	return nil, usageErrorf("--output %q is not table, json or yaml", f.format)
+}
+
+// printer writes results in a format.
+type printer struct {
+	w      io.Writer
+	format string
+}
+
+// accountColumns are the columns of account tables.
+var accountColumns = []string{
+	"ID", "VERSION", "COUNTRY", "CURRENCY", "BANK ID", "BIC", "ACCOUNT NUMBER", "IBAN", "STATUS", "NAME",
+}
+
+// accounts prints accounts. In JSON and YAML, v is printed as is.
+func (p *printer) accounts(v any, accs ...*account.Account) error {
+	if p.format != formatTable {
+		return p.value(v)
+	}
+
+	tw := tabwriter.NewWriter(p.w, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(tw, strings.Join(accountColumns, "\t"))
+
+	for _, acc := range accs {
+		fmt.Fprintln(tw, strings.Join(accountRow(acc), "\t"))
+	}
+
+	return tw.Flush()
+}
+
+// accountRow returns the table cells of acc.
+func accountRow(acc *account.Account) []string {
+	var version string
+	if acc.Version != nil {
+		version = strconv.FormatInt(*acc.Version, 10)
+	}
+
+	attrs := acc.Attributes
+	if attrs == nil {
+		attrs = &account.Attributes{}
+	}
+
+	var status string
+	if attrs.Status != nil {
+		status = string(*attrs.Status)
+	}
+
+	row := []string{
+		acc.ID,
+		version,
+		attrs.Country,
+		attrs.BaseCurrency,
+		attrs.BankID,
+		attrs.Bic,
+		attrs.AccountNumber,
+		attrs.Iban,
+		status,
+		strings.Join(attrs.Name, "; "),
+	}
+
+	for i, cell := range row {
+		if cell == "" {
+			row[i] = "-"
+		}
+	}
+
+	return row
+}
+
+// message prints a message in table format, or v in other formats.
+func (p *printer) message(msg string, v any) error {
+	if p.format != formatTable {
+		return p.value(v)
+	}
+
+	_, err := fmt.Fprintln(p.w, msg)
+	return err
+}
+
+// value prints v as JSON or YAML, using its JSON field names.
+func (p *printer) value(v any) error {
+	b, err := json.MarshalIndent(v, "", "  ")
+	if err != nil {
+		return fmt.Errorf("encode output: %w", err)
+	}
+
+	if p.format == formatJSON {
+		_, err := fmt.Fprintf(p.w, "%s\n", b)
+		return err
+	}
+
+	var generic any
+	err = json.Unmarshal(b, &generic)
+	if err != nil {
+		return fmt.Errorf("encode output: %w", err)
+	}
+
+	enc := yaml.NewEncoder(p.w)
+	enc.SetIndent(2)
+
+	err = enc.Encode(generic)
+	if err != nil {
+		return fmt.Errorf("encode output: %w", err)
+	}
+
+	return enc.Close()
+}
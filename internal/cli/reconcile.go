@@ -0,0 +1,289 @@
+package cli
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"text/tabwriter"
+
+	"github.com/vivangkumar/form3-http-go/pkg/account"
+	accountcsv "github.com/vivangkumar/form3-http-go/pkg/account/csv"
+	"github.com/vivangkumar/form3-http-go/pkg/reconcile"
+)
+
+func reconcileCommand() *command {
+	return &command{
+		name:    "reconcile",
+		summary: "compare a file of expected accounts with the API",
+		run:     runReconcile,
+	}
+}
+
+func runReconcile(ctx context.Context, a *app, args []string) error {
+	var (
+		cf       clientFlags
+		of       outputFlags
+		file     string
+		format   string
+		mappings stringsFlag
+		orgID    string
+		pageSize int
+		filters  stringsFlag
+		ignored  stringsFlag
+		planPath string
+		deletes  bool
+	)
+
+	fs := a.newFlagSet("form3 reconcile", "--file <path> [flags]")
+	cf.register(fs)
+	of.register(fs)
+	fs.StringVar(&file, "file", "", "CSV or NDJSON `path` of the expected accounts")
+	fs.StringVar(&format, "format", "", "input format: csv or ndjson (default from the file extension)")
+	fs.Var(&mappings, "map", "`header=column` mapping a CSV header to a column, may be repeated; map to - to ignore a header")
+	fs.StringVar(&orgID, "organisation-id", "", "organisation to reconcile (default the configured organisation)")
+	fs.IntVar(&pageSize, "page-size", defaultExportPageSize, "accounts fetched per request")
+	fs.Var(&filters, "filter", "`attribute=value` to filter by, may be repeated")
+	fs.Var(&ignored, "ignore", "`field` not to compare, such as attributes.status, may be repeated")
+	fs.StringVar(&planPath, "plan", "", "`path` to write the calls that remove the differences to, as NDJSON")
+	fs.BoolVar(&deletes, "plan-deletes", false, "plan deletes of accounts that are not expected")
+
+	pos, err := parseFlags(fs, args)
+	if err != nil {
+		return err
+	}
+
+	if len(pos) > 0 {
+		return usageErrorf("unexpected arguments %v", pos)
+	}
+
+	if file == "" {
+		return usageErrorf("--file is required")
+	}
+
+	format, err = fileFormat(format, file)
+	if err != nil {
+		return err
+	}
+
+	mapping, err := keyValues("map", mappings)
+	if err != nil {
+		return err
+	}
+
+	if mapping != nil && format != formatCSV {
+		return usageErrorf("--map only applies to CSV files")
+	}
+
+	if pageSize < 1 {
+		return usageErrorf("--page-size must be at least 1")
+	}
+
+	if deletes && planPath == "" {
+		return usageErrorf("--plan-deletes requires --plan")
+	}
+
+	filter, err := keyValues("filter", filters)
+	if err != nil {
+		return err
+	}
+
+	p, err := of.printer(a.stdout)
+	if err != nil {
+		return err
+	}
+
+	c, err := cf.newClient(a.stdout)
+	if err != nil {
+		return err
+	}
+
+	if orgID == "" {
+		orgID = c.OrganisationID
+	}
+
+	expected, err := readExpected(file, format, mapping, orgID)
+	if err != nil {
+		return err
+	}
+
+	report, err := reconcile.Run(ctx, c.Accounts, expected,
+		reconcile.WithOrganisation(orgID),
+		reconcile.WithPageSize(pageSize),
+		reconcile.WithFilter(filter),
+		reconcile.WithIgnoredFields(ignored...),
+	)
+	if errors.Is(err, errDryRun) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("reconcile: %w", err)
+	}
+
+	if planPath != "" {
+		var opts []reconcile.PlanOpt
+		if deletes {
+			opts = append(opts, reconcile.WithDeletes())
+		}
+
+		actions, err := report.Plan(opts...)
+		if err != nil {
+			return err
+		}
+
+		err = writePlan(planPath, actions)
+		if err != nil {
+			return err
+		}
+	}
+
+	if p.format == formatTable {
+		err = printReport(a.stdout, report)
+	} else {
+		err = p.value(report)
+	}
+	if err != nil {
+		return err
+	}
+
+	if !report.Clean() {
+		return fmt.Errorf("%d missing, %d unexpected and %d mismatched accounts",
+			len(report.Missing), len(report.Unexpected), len(report.Mismatched))
+	}
+
+	return nil
+}
+
+// readExpected reads the expected accounts of file. Accounts without an
+// organisation are given orgID.
+func readExpected(file string, format string, mapping map[string]string, orgID string) ([]*account.Account, error) {
+	in, err := os.Open(file)
+	if err != nil {
+		return nil, usageErrorf("open expected accounts: %w", err)
+	}
+	defer in.Close()
+
+	var src rowSource
+	if format == formatCSV {
+		r := accountcsv.NewReader(in, accountcsv.WithMapping(mapping))
+
+		_, err = r.Header()
+		if err != nil {
+			return nil, usageErrorf("%s: %w", file, err)
+		}
+
+		src = &csvSource{r: r}
+	} else {
+		src = newNDJSONSource(in)
+	}
+
+	var accs []*account.Account
+	lines := make(map[string]int)
+
+	for {
+		row, err := src.next()
+		if errors.Is(err, io.EOF) {
+			return accs, nil
+		}
+		if err != nil {
+			return nil, fmt.Errorf("read %s: %w", file, err)
+		}
+
+		if row.err != nil {
+			return nil, usageErrorf("%s:%d: %w", file, row.line, row.err)
+		}
+
+		acc := row.acc
+		if acc.ID == "" {
+			return nil, usageErrorf("%s:%d: id is required", file, row.line)
+		}
+
+		if line, ok := lines[acc.ID]; ok {
+			return nil, usageErrorf("%s:%d: account %s is already on line %d", file, row.line, acc.ID, line)
+		}
+		lines[acc.ID] = row.line
+
+		if acc.OrganisationID == "" {
+			acc.OrganisationID = orgID
+		}
+
+		if acc.Type == "" {
+			acc.Type = "accounts"
+		}
+
+		accs = append(accs, acc)
+	}
+}
+
+// writePlan writes the actions of a plan to path, one per line.
+func writePlan(path string, actions []reconcile.Action) error {
+	f, err := os.Create(path)
+	if err != nil {
+		return fmt.Errorf("create plan file: %w", err)
+	}
+	defer f.Close()
+
+	bw := bufio.NewWriter(f)
+	enc := json.NewEncoder(bw)
+
+	for _, action := range actions {
+		err := enc.Encode(action)
+		if err != nil {
+			return fmt.Errorf("write plan: %w", err)
+		}
+	}
+
+	err = bw.Flush()
+	if err != nil {
+		return fmt.Errorf("write plan: %w", err)
+	}
+
+	return f.Close()
+}
+
+// printReport prints a summary of report, followed by a table of the
+// differences.
+func printReport(w io.Writer, report *reconcile.Report) error {
+	fmt.Fprintf(w, "Expected %d, listed %d: %d matched, %d missing, %d unexpected, %d mismatched\n",
+		report.Expected, report.Actual, report.Matched,
+		len(report.Missing), len(report.Unexpected), len(report.Mismatched))
+
+	if report.Clean() {
+		return nil
+	}
+
+	fmt.Fprintln(w)
+
+	tw := tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(tw, "STATUS\tID\tFIELD\tEXPECTED\tACTUAL")
+
+	for _, acc := range report.Missing {
+		fmt.Fprintf(tw, "missing\t%s\t-\t-\t-\n", acc.ID)
+	}
+
+	for _, acc := range report.Unexpected {
+		fmt.Fprintf(tw, "unexpected\t%s\t-\t-\t-\n", acc.ID)
+	}
+
+	for _, m := range report.Mismatched {
+		for _, f := range m.Fields {
+			fmt.Fprintf(tw, "mismatched\t%s\t%s\t%s\t%s\n", m.ID, f.Field, cell(f.Expected), cell(f.Actual))
+		}
+	}
+
+	return tw.Flush()
+}
+
+// cell formats a field value as JSON, so that strings, lists and missing
+// values can be told apart.
+func cell(v any) string {
+	b, err := json.Marshal(v)
+	if err != nil {
+		return fmt.Sprint(v)
+	}
+
+	return string(b)
+}
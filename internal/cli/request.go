@@ -0,0 +1,362 @@
+package cli
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/vivangkumar/form3-http-go/pkg/client"
+	"github.com/vivangkumar/form3-http-go/pkg/form3"
+)
+
+// pathParam matches the parameters of path templates, such as {id}.
+var pathParam = regexp.MustCompile(`\{([A-Za-z_][A-Za-z0-9_]*)\}`)
+
+// maxPages stops --paginate following links forever.
+const maxPages = 10000
+
+func requestCommand() *command {
+	return &command{name: "request", summary: "send a request to any endpoint", run: runRequest}
+}
+
+// expandPath fills the {name} parameters of path from params, escaping
+// their values. The organisation_id parameter defaults to orgID.
+func expandPath(path string, params map[string]string, orgID string) (string, error) {
+	var missing []string
+
+	expanded := pathParam.ReplaceAllStringFunc(path, func(m string) string {
+		name := m[1 : len(m)-1]
+
+		v, ok := params[name]
+		if !ok && name == "organisation_id" && orgID != "" {
+			v, ok = orgID, true
+		}
+		if !ok {
+			missing = append(missing, name)
+			return m
+		}
+
+		return url.PathEscape(v)
+	})
+
+	if len(missing) > 0 {
+		return "", usageErrorf("path parameters %s are not set, see --param", strings.Join(missing, ", "))
+	}
+
+	return expanded, nil
+}
+
+// readBody returns the JSON body given by --body: inline JSON, @path to read
+// a file, or @- to read standard input.
+func readBody(value string, stdin io.Reader) (json.RawMessage, error) {
+	b := []byte(value)
+
+	if strings.HasPrefix(value, "@") {
+		var err error
+		if value == "@-" {
+			b, err = io.ReadAll(stdin)
+		} else {
+			b, err = os.ReadFile(value[1:])
+		}
+		if err != nil {
+			return nil, usageErrorf("read body: %w", err)
+		}
+	}
+
+	if !json.Valid(b) {
+		return nil, usageErrorf("--body is not valid JSON")
+	}
+
+	return json.RawMessage(b), nil
+}
+
+func runRequest(ctx context.Context, a *app, args []string) error {
+	var (
+		cf             clientFlags
+		queries        stringsFlag
+		headers        stringsFlag
+		params         stringsFlag
+		body           string
+		idempotencyKey string
+		paginate       bool
+	)
+
+	fs := a.newFlagSet("form3 request", "<method> <path> [flags]")
+	cf.register(fs)
+	fs.Var(&queries, "query", "`key=value` query parameter, may be repeated, also with the same key")
+	fs.Var(&headers, "header", "`name:value` header, may be repeated")
+	fs.Var(&params, "param", "`name=value` filling {name} in the path, may be repeated; {organisation_id} defaults to the configured organisation")
+	fs.StringVar(&body, "body", "", "JSON `body`, or @path to read it from a file, or @- from standard input")
+	fs.StringVar(&idempotencyKey, "idempotency-key", "", "idempotency `key` of POST and PATCH requests, which allows them to be retried")
+	fs.BoolVar(&paginate, "paginate", false, "follow links.next and print the data of every page as one array")
+
+	pos, err := parseFlags(fs, args)
+	if err != nil {
+		return err
+	}
+
+	if len(pos) != 2 {
+		return usageErrorf("request requires a method and a path")
+	}
+	method := strings.ToUpper(pos[0])
+
+	query, err := queryValues(queries)
+	if err != nil {
+		return err
+	}
+
+	paramValues, err := keyValues("param", params)
+	if err != nil {
+		return err
+	}
+
+	header := make(http.Header)
+	for _, h := range headers {
+		name, value, ok := strings.Cut(h, ":")
+		if !ok || strings.TrimSpace(name) == "" {
+			return usageErrorf("--header %q is not name:value", h)
+		}
+		header.Add(strings.TrimSpace(name), strings.TrimSpace(value))
+	}
+
+	if paginate && method != http.MethodGet {
+		return usageErrorf("--paginate only applies to GET requests")
+	}
+
+	var reqBody any
+	if body != "" {
+		reqBody, err = readBody(body, a.stdin)
+		if err != nil {
+			return err
+		}
+	}
+
+	c, err := cf.newClient(a.stdout)
+	if err != nil {
+		return err
+	}
+
+	path, err := expandPath(pos[1], paramValues, c.OrganisationID)
+	if err != nil {
+		return err
+	}
+
+	if idempotencyKey != "" {
+		ctx = client.ContextWithIdempotencyKey(ctx, idempotencyKey)
+	}
+
+	r := &rawRequester{c: c, header: header, stderr: a.stderr}
+
+	if !paginate {
+		raw, err := r.do(ctx, method, path, query, reqBody)
+		if errors.Is(err, errDryRun) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+
+		return printJSON(a.stdout, raw)
+	}
+
+	data, err := r.paginate(ctx, path, query)
+	if errors.Is(err, errDryRun) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	b, err := json.Marshal(data)
+	if err != nil {
+		return fmt.Errorf("encode data: %w", err)
+	}
+
+	return printJSON(a.stdout, b)
+}
+
+// queryValues parses key=value query parameters. Keys may be repeated.
+func queryValues(values []string) (url.Values, error) {
+	query := make(url.Values, len(values))
+	for _, v := range values {
+		k, val, ok := strings.Cut(v, "=")
+		if !ok || k == "" {
+			return nil, usageErrorf("--query %q must be written as key=value", v)
+		}
+		query.Add(k, val)
+	}
+
+	return query, nil
+}
+
+// rawRequester sends requests with the client, printing the status and
+// headers of each response.
+type rawRequester struct {
+	c      *form3.Client
+	header http.Header
+	stderr io.Writer
+
+	// last is the URL of the last request sent.
+	last *url.URL
+}
+
+// do sends a request and returns the response body.
+func (r *rawRequester) do(
+	ctx context.Context,
+	method string,
+	path string,
+	query url.Values,
+	body any,
+) (json.RawMessage, error) {
+	req, err := r.c.NewRequest(ctx, method, path, nil, body)
+	if err != nil {
+		return nil, err
+	}
+
+	// Keys may be repeated, so the query is added to any in path here,
+	// rather than by NewRequest.
+	if len(query) > 0 {
+		q := req.URL.Query()
+		for k, vs := range query {
+			q[k] = append(q[k], vs...)
+		}
+		req.URL.RawQuery = q.Encode()
+	}
+
+	for name, values := range r.header {
+		req.Header[name] = values
+	}
+	r.last = req.URL
+
+	var raw json.RawMessage
+
+	resp, err := r.c.Do(req, &raw)
+	if err != nil {
+		var e interface{ HTTPResponse() *http.Response }
+		if errors.As(err, &e) {
+			printResponseHead(r.stderr, e.HTTPResponse())
+		}
+
+		return nil, err
+	}
+
+	printResponseHead(r.stderr, resp)
+
+	return raw, nil
+}
+
+// paginate gets path and every page following it by links.next, and
+// returns the data of all pages.
+func (r *rawRequester) paginate(ctx context.Context, path string, query url.Values) ([]json.RawMessage, error) {
+	all := []json.RawMessage{}
+	seen := make(map[string]bool)
+
+	for page := 0; page < maxPages; page++ {
+		raw, err := r.do(ctx, http.MethodGet, path, query, nil)
+		if err != nil {
+			return nil, err
+		}
+
+		var doc struct {
+			Data  json.RawMessage `json:"data"`
+			Links *struct {
+				Next *string `json:"next"`
+			} `json:"links"`
+		}
+		err = json.Unmarshal(raw, &doc)
+		if err != nil {
+			return nil, fmt.Errorf("decode page %d: %w", page, err)
+		}
+
+		var data []json.RawMessage
+		err = json.Unmarshal(doc.Data, &data)
+		if err != nil {
+			return nil, fmt.Errorf("page %d: data is not a list", page)
+		}
+		all = append(all, data...)
+
+		if doc.Links == nil || doc.Links.Next == nil || *doc.Links.Next == "" {
+			return all, nil
+		}
+
+		seen[r.last.RequestURI()] = true
+
+		path, err = r.nextPath(*doc.Links.Next)
+		if err != nil {
+			return nil, err
+		}
+
+		// The next link carries the query of the page.
+		query = nil
+
+		if seen[path] {
+			return all, nil
+		}
+	}
+
+	return nil, fmt.Errorf("stopped after %d pages", maxPages)
+}
+
+// nextPath returns the path and query of a next link, resolved against the
+// page it was returned with. Links to other hosts are not followed, so that
+// credentials are only sent to the API.
+func (r *rawRequester) nextPath(link string) (string, error) {
+	u, err := url.Parse(link)
+	if err != nil {
+		return "", fmt.Errorf("parse next link %q: %w", link, err)
+	}
+
+	u = r.last.ResolveReference(u)
+	if u.Host != r.last.Host {
+		return "", fmt.Errorf("next link %q is not on the API host", link)
+	}
+
+	return u.RequestURI(), nil
+}
+
+// printResponseHead prints the status and headers of resp, sorted by name.
+func printResponseHead(w io.Writer, resp *http.Response) {
+	var b strings.Builder
+	fmt.Fprintf(&b, "%s %s\n", resp.Proto, resp.Status)
+
+	names := make([]string, 0, len(resp.Header))
+	for name := range resp.Header {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		for _, v := range resp.Header[name] {
+			fmt.Fprintf(&b, "%s: %s\n", name, v)
+		}
+	}
+	b.WriteString("\n")
+
+	io.WriteString(w, b.String())
+}
+
+// printJSON prints a JSON body indented. Empty bodies print nothing.
+func printJSON(w io.Writer, raw []byte) error {
+	if len(bytes.TrimSpace(raw)) == 0 {
+		return nil
+	}
+
+	var buf bytes.Buffer
+	err := json.Indent(&buf, raw, "", "  ")
+	if err != nil {
+		return fmt.Errorf("format body: %w", err)
+	}
+	buf.WriteByte('\n')
+
+	_, err = w.Write(buf.Bytes())
+	return err
+}
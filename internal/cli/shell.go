@@ -0,0 +1,492 @@
+package cli
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+	"unicode"
+
+	"github.com/vivangkumar/form3-http-go/internal/lineedit"
+	"github.com/vivangkumar/form3-http-go/pkg/account"
+	"github.com/vivangkumar/form3-http-go/pkg/client"
+	"github.com/vivangkumar/form3-http-go/pkg/form3"
+)
+
+const (
+	shellPrompt = "form3> "
+
+	// accountsPath is the collection path of accounts.
+	accountsPath = "/v1/organisation/accounts/"
+)
+
+// shellHelp lists the commands of the shell.
+const shellHelp = `Commands:
+  get <path>             send a GET request, for example get /v1/organisation/accounts/<id>
+  post <path> <json>     send a POST request with the JSON body
+  delete <path>          send a DELETE request, for example delete /v1/organisation/accounts/<id>?version=0
+  accounts fetch <id>    fetch an account
+  history                list the lines entered
+  help                   print this help
+  exit                   leave the shell, as does Ctrl-D
+
+Tab completes commands, and the resource types and IDs seen in responses.
+Ctrl-C cancels the command being run.
+`
+
+func shellCommand() *command {
+	return &command{
+		name:          "shell",
+		summary:       "explore the API interactively",
+		run:           runShell,
+		ownsInterrupt: true,
+	}
+}
+
+// lineReader reads the lines entered in the shell.
+type lineReader interface {
+	readLine(prompt string) (string, error)
+}
+
+// scanReader reads lines from input that is not a terminal, such as a
+// script piped to the shell. No prompt is printed.
+type scanReader struct {
+	s *bufio.Scanner
+}
+
+func (r *scanReader) readLine(string) (string, error) {
+	if !r.s.Scan() {
+		if err := r.s.Err(); err != nil {
+			return "", err
+		}
+
+		return "", io.EOF
+	}
+
+	return r.s.Text(), nil
+}
+
+// termReader reads lines from a terminal with editing, history and
+// completion.
+type termReader struct {
+	f  *os.File
+	ed *lineedit.Editor
+
+	// history is appended to with each line, if set.
+	history *os.File
+}
+
+func (r *termReader) readLine(prompt string) (string, error) {
+	restore, err := lineedit.MakeRaw(r.f)
+	if err != nil {
+		return "", err
+	}
+
+	line, err := r.ed.ReadLine(prompt)
+
+	// The terminal is restored before anything else is printed.
+	rerr := restore()
+	if err != nil {
+		return "", err
+	}
+	if rerr != nil {
+		return "", fmt.Errorf("restore terminal mode: %w", rerr)
+	}
+
+	if strings.TrimSpace(line) != "" {
+		r.ed.AddHistory(line)
+		if r.history != nil {
+			fmt.Fprintln(r.history, line)
+		}
+	}
+
+	return line, nil
+}
+
+// shell runs the commands entered in a session.
+type shell struct {
+	c   *form3.Client
+	out io.Writer
+
+	// history holds the lines entered, oldest first.
+	history []string
+
+	// seen holds the IDs of resources seen in responses, by type.
+	seen map[string][]string
+}
+
+func runShell(ctx context.Context, a *app, args []string) error {
+	var (
+		cf          clientFlags
+		historyPath string
+		noHistory   bool
+	)
+
+	fs := a.newFlagSet("form3 shell", "[flags]")
+	cf.register(fs)
+	fs.StringVar(&historyPath, "history", "", "history file `path` (default form3/shell_history in the user config directory)")
+	fs.BoolVar(&noHistory, "no-history", false, "do not read or write the history file")
+
+	pos, err := parseFlags(fs, args)
+	if err != nil {
+		return err
+	}
+
+	if len(pos) > 0 {
+		return usageErrorf("unexpected arguments %v", pos)
+	}
+
+	c, err := cf.newClient(a.stdout)
+	if err != nil {
+		return err
+	}
+
+	sh := &shell{
+		c:    c,
+		out:  a.stdout,
+		seen: map[string][]string{"accounts": nil},
+	}
+
+	var lr lineReader = &scanReader{s: bufio.NewScanner(a.stdin)}
+
+	// Lines are only edited, and kept in the history file, at a terminal.
+	if f, ok := a.stdin.(*os.File); ok && lineedit.IsTerminal(f) {
+		tr := &termReader{f: f}
+
+		var lines []string
+		if !noHistory {
+			tr.history, lines, err = openHistory(historyPath)
+			if err != nil {
+				fmt.Fprintf(a.stderr, "form3: %s\n", err)
+			}
+		}
+		if tr.history != nil {
+			defer tr.history.Close()
+		}
+
+		sh.history = lines
+		tr.ed = lineedit.New(f, a.stdout,
+			lineedit.WithHistory(lines),
+			lineedit.WithCompleter(sh.complete),
+		)
+
+		lr = tr
+		fmt.Fprintln(a.stdout, "Type help for the commands, and Ctrl-D to leave.")
+	}
+
+	for ctx.Err() == nil {
+		line, err := lr.readLine(shellPrompt)
+		if errors.Is(err, lineedit.ErrInterrupted) {
+			continue
+		}
+		if errors.Is(err, io.EOF) {
+			return nil
+		}
+		if err != nil {
+			return fmt.Errorf("read line: %w", err)
+		}
+
+		line = strings.TrimSpace(line)
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		sh.history = append(sh.history, line)
+
+		// Ctrl-C cancels the line being run, and the shell carries on.
+		lineCtx, stop := signal.NotifyContext(ctx, os.Interrupt)
+		done, err := sh.exec(lineCtx, line)
+		interrupted := lineCtx.Err() != nil && ctx.Err() == nil
+		stop()
+
+		switch {
+		case err != nil && interrupted:
+			fmt.Fprintln(a.stdout, "interrupted")
+		case err != nil:
+			fmt.Fprintf(a.stdout, "error: %s\n", err)
+		}
+		if done {
+			return nil
+		}
+	}
+
+	return nil
+}
+
+// openHistory reads the history file and opens it for appending.
+func openHistory(path string) (*os.File, []string, error) {
+	if path == "" {
+		dir, err := os.UserConfigDir()
+		if err != nil {
+			return nil, nil, fmt.Errorf("history: %w", err)
+		}
+
+		path = filepath.Join(dir, "form3", "shell_history")
+	}
+
+	b, err := os.ReadFile(path)
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return nil, nil, fmt.Errorf("read history: %w", err)
+	}
+
+	var lines []string
+	for _, line := range strings.Split(string(b), "\n") {
+		if line != "" {
+			lines = append(lines, line)
+		}
+	}
+
+	err = os.MkdirAll(filepath.Dir(path), 0o700)
+	if err != nil {
+		return nil, lines, fmt.Errorf("create history: %w", err)
+	}
+
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0o600)
+	if err != nil {
+		return nil, lines, fmt.Errorf("open history: %w", err)
+	}
+
+	return f, lines, nil
+}
+
+// exec runs a line of the shell. It reports whether the shell should exit.
+func (sh *shell) exec(ctx context.Context, line string) (bool, error) {
+	name, rest := cutWord(line)
+
+	switch name {
+	case "exit", "quit":
+		return true, nil
+	case "help":
+		fmt.Fprint(sh.out, shellHelp)
+		return false, nil
+	case "history":
+		for i, l := range sh.history {
+			fmt.Fprintf(sh.out, "%4d  %s\n", i+1, l)
+		}
+		return false, nil
+	case "get", "delete":
+		path, extra := cutWord(rest)
+		if path == "" || extra != "" {
+			return false, fmt.Errorf("usage: %s <path>", name)
+		}
+
+		return false, sh.request(ctx, strings.ToUpper(name), path, nil)
+	case "post":
+		path, body := cutWord(rest)
+		if path == "" || body == "" {
+			return false, fmt.Errorf("usage: post <path> <json>")
+		}
+
+		if !json.Valid([]byte(body)) {
+			return false, fmt.Errorf("body is not valid JSON")
+		}
+
+		return false, sh.request(ctx, http.MethodPost, path, json.RawMessage(body))
+	case "accounts":
+		sub, args := cutWord(rest)
+		id, extra := cutWord(args)
+		if sub != "fetch" || id == "" || extra != "" {
+			return false, fmt.Errorf("usage: accounts fetch <id>")
+		}
+
+		return false, sh.fetchAccount(ctx, id)
+	}
+
+	return false, fmt.Errorf("unknown command %q, see help", name)
+}
+
+// request sends a request with the client, so that it is signed, retried
+// and logged as the client is configured to, and prints the response.
+func (sh *shell) request(ctx context.Context, method string, path string, body any) error {
+	req, err := sh.c.NewRequest(ctx, method, path, nil, body)
+	if err != nil {
+		return err
+	}
+
+	var raw json.RawMessage
+
+	start := time.Now()
+	resp, err := sh.c.Do(req, &raw)
+	took := time.Since(start)
+
+	if errors.Is(err, errDryRun) {
+		return nil
+	}
+	if err != nil {
+		sh.printFailure(err, took)
+		return err
+	}
+
+	fmt.Fprintf(sh.out, "%s in %s\n", resp.Status, formatDuration(took))
+
+	return sh.printBody(raw)
+}
+
+func (sh *shell) fetchAccount(ctx context.Context, id string) error {
+	start := time.Now()
+	resp, err := sh.c.Accounts.Fetch(ctx, account.FetchAccountParams{ID: id})
+	took := time.Since(start)
+
+	if errors.Is(err, errDryRun) {
+		return nil
+	}
+	if err != nil {
+		sh.printFailure(err, took)
+		return err
+	}
+
+	b, err := json.Marshal(resp)
+	if err != nil {
+		return fmt.Errorf("encode response: %w", err)
+	}
+
+	fmt.Fprintf(sh.out, "Fetched in %s\n", formatDuration(took))
+
+	return sh.printBody(b)
+}
+
+// printFailure prints the status of a failed request, if it got a response.
+func (sh *shell) printFailure(err error, took time.Duration) {
+	if code := client.StatusCode(err); code != 0 {
+		fmt.Fprintf(sh.out, "%d %s in %s\n", code, http.StatusText(code), formatDuration(took))
+		return
+	}
+
+	fmt.Fprintf(sh.out, "Failed in %s\n", formatDuration(took))
+}
+
+// printBody prints a JSON response body indented, and notes the resources
+// it holds for completion.
+func (sh *shell) printBody(raw []byte) error {
+	if len(bytes.TrimSpace(raw)) == 0 {
+		return nil
+	}
+
+	var v any
+	if err := json.Unmarshal(raw, &v); err == nil {
+		sh.note(v)
+	}
+
+	var buf bytes.Buffer
+	if err := json.Indent(&buf, raw, "", "  "); err != nil {
+		// Bodies that are not JSON are printed as they are.
+		buf.Reset()
+		buf.Write(raw)
+	}
+	buf.WriteByte('\n')
+
+	_, err := sh.out.Write(buf.Bytes())
+	return err
+}
+
+// note records the type and ID of every resource in v.
+func (sh *shell) note(v any) {
+	switch v := v.(type) {
+	case map[string]any:
+		typ, _ := v["type"].(string)
+		id, _ := v["id"].(string)
+
+		if typ != "" && id != "" && !contains(sh.seen[typ], id) {
+			sh.seen[typ] = append(sh.seen[typ], id)
+		}
+
+		for _, child := range v {
+			sh.note(child)
+		}
+	case []any:
+		for _, child := range v {
+			sh.note(child)
+		}
+	}
+}
+
+// complete returns the completions of the last word of line.
+func (sh *shell) complete(line string) []string {
+	words := strings.Fields(line)
+	if len(words) == 0 || strings.HasSuffix(line, " ") {
+		words = append(words, "")
+	}
+
+	switch len(words) {
+	case 1:
+		return []string{"accounts", "delete", "exit", "get", "help", "history", "post"}
+	case 2:
+		switch words[0] {
+		case "get", "post", "delete":
+			return sh.paths()
+		case "accounts":
+			return []string{"fetch"}
+		}
+	case 3:
+		if words[0] == "accounts" && words[1] == "fetch" {
+			return sh.seen["accounts"]
+		}
+	}
+
+	return nil
+}
+
+// paths returns the paths of the resource types and resources seen.
+func (sh *shell) paths() []string {
+	types := make([]string, 0, len(sh.seen))
+	for typ := range sh.seen {
+		types = append(types, typ)
+	}
+	sort.Strings(types)
+
+	var paths []string
+	for _, typ := range types {
+		base := "/v1/organisation/" + typ + "/"
+		if typ == "accounts" {
+			base = accountsPath
+		}
+
+		paths = append(paths, base)
+		for _, id := range sh.seen[typ] {
+			paths = append(paths, base+id)
+		}
+	}
+
+	return paths
+}
+
+// cutWord returns the first word of s, and the rest of s with leading space
+// removed.
+func cutWord(s string) (string, string) {
+	s = strings.TrimLeftFunc(s, unicode.IsSpace)
+
+	i := strings.IndexFunc(s, unicode.IsSpace)
+	if i < 0 {
+		return s, ""
+	}
+
+	return s[:i], strings.TrimLeftFunc(s[i:], unicode.IsSpace)
+}
+
+// formatDuration rounds d for printing.
+func formatDuration(d time.Duration) string {
+	if d < time.Millisecond {
+		return d.Round(time.Microsecond).String()
+	}
+
+	return d.Round(time.Millisecond).String()
+}
+
+func contains(s []string, v string) bool {
+	for _, e := range s {
+		if e == v {
+			return true
+		}
+	}
+
+	return false
+}
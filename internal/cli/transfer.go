@@ -0,0 +1,667 @@
+package cli
+
+import (
+	"bufio"
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	"github.com/google/uuid"
+
+	"github.com/vivangkumar/form3-http-go/pkg/account"
+	accountclient "github.com/vivangkumar/form3-http-go/pkg/account/client"
+	accountcsv "github.com/vivangkumar/form3-http-go/pkg/account/csv"
+)
+
+// File formats of imports and exports.
+const (
+	formatCSV    = "csv"
+	formatNDJSON = "ndjson"
+
+	defaultExportPageSize = 100
+)
+
+// importNamespace derives the IDs of imported rows without one from their
+// content, so that they are the same when an import is resumed, even if
+// rows were added or moved.
+var importNamespace = uuid.MustParse("5d4f2e6c-3f1b-4a8e-9c77-1f0a6b2d9e41")
+
+// fileFormat returns the format of path, using format if set.
+func fileFormat(format string, path string) (string, error) {
+	if format == "" {
+		switch strings.ToLower(filepath.Ext(path)) {
+		case ".ndjson", ".jsonl":
+			format = formatNDJSON
+		default:
+			format = formatCSV
+		}
+	}
+
+	if format != formatCSV && format != formatNDJSON {
+		return "", usageErrorf("--format %q is not csv or ndjson", format)
+	}
+
+	return format, nil
+}
+
+// importRow is a row of an import file.
+type importRow struct {
+	line   int
+	record []string
+	raw    string
+
+	acc *account.Account
+	err error
+}
+
+// rowSource reads the rows of an import file.
+type rowSource interface {
+	next() (*importRow, error)
+}
+
+type csvSource struct {
+	r *accountcsv.Reader
+}
+
+func (s *csvSource) next() (*importRow, error) {
+	acc, err := s.r.Read()
+	if errors.Is(err, io.EOF) {
+		return nil, io.EOF
+	}
+
+	var re *accountcsv.RowError
+	if err != nil && !errors.As(err, &re) {
+		return nil, err
+	}
+
+	row := &importRow{line: s.r.Line(), acc: acc, err: err}
+	if record := s.r.Record(); record != nil {
+		row.record = append([]string(nil), record...)
+		row.raw = strings.Join(row.record, ",")
+	}
+
+	return row, nil
+}
+
+// ndjsonSource reads rows from lines of JSON, of any length.
+type ndjsonSource struct {
+	r    *bufio.Reader
+	line int
+}
+
+func newNDJSONSource(r io.Reader) *ndjsonSource {
+	return &ndjsonSource{r: bufio.NewReader(r)}
+}
+
+func (s *ndjsonSource) next() (*importRow, error) {
+	for {
+		b, err := s.r.ReadBytes('\n')
+		if err != nil && (!errors.Is(err, io.EOF) || len(b) == 0) {
+			return nil, err
+		}
+		s.line++
+
+		raw := strings.TrimSpace(string(b))
+		if raw == "" {
+			continue
+		}
+
+		row := &importRow{line: s.line, raw: raw}
+
+		acc := &account.Account{}
+		err = json.Unmarshal([]byte(raw), acc)
+		if err != nil {
+			row.err = fmt.Errorf("decode: %w", err)
+		} else {
+			row.acc = acc
+		}
+
+		return row, nil
+	}
+}
+
+// validate checks an imported account before it is sent, filling in the
+// organisation and ID if they are not set.
+func (row *importRow) validate(orgID string) error {
+	acc := row.acc
+
+	if acc.OrganisationID == "" {
+		if orgID == "" {
+			return fmt.Errorf("organisation_id is required when no organisation is configured")
+		}
+		acc.OrganisationID = orgID
+	}
+
+	if acc.Type == "" {
+		acc.Type = "accounts"
+	}
+
+	if acc.Attributes == nil || acc.Attributes.Country == "" {
+		return fmt.Errorf("country is required")
+	}
+
+	if fields := acc.UnknownFields(); len(fields) > 0 {
+		return fmt.Errorf("unknown fields %s", strings.Join(fields, ", "))
+	}
+
+	err := acc.Validate()
+	if err != nil {
+		return err
+	}
+
+	if acc.ID == "" {
+		content, err := json.Marshal(acc)
+		if err != nil {
+			return fmt.Errorf("encode: %w", err)
+		}
+		acc.ID = uuid.NewSHA1(importNamespace, content).String()
+	}
+
+	return nil
+}
+
+// checkpoint records the IDs of imported accounts, so that an import can
+// be resumed.
+type checkpoint struct {
+	done map[string]bool
+	f    *os.File
+}
+
+// openCheckpoint reads the IDs already imported and opens the file for
+// appending.
+func openCheckpoint(path string) (*checkpoint, error) {
+	cp := &checkpoint{done: make(map[string]bool)}
+
+	b, err := os.ReadFile(path)
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return nil, fmt.Errorf("read checkpoint: %w", err)
+	}
+
+	for _, line := range strings.Split(string(b), "\n") {
+		if id := strings.TrimSpace(line); id != "" {
+			cp.done[id] = true
+		}
+	}
+
+	cp.f, err = os.OpenFile(path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0o600)
+	if err != nil {
+		return nil, fmt.Errorf("open checkpoint: %w", err)
+	}
+
+	return cp, nil
+}
+
+// record records an imported account. Each ID is synced so that it
+// survives a crash.
+func (cp *checkpoint) record(id string) error {
+	_, err := fmt.Fprintln(cp.f, id)
+	if err != nil {
+		return fmt.Errorf("write checkpoint: %w", err)
+	}
+
+	return cp.f.Sync()
+}
+
+func (cp *checkpoint) Close() error {
+	return cp.f.Close()
+}
+
+// rejects writes rejected rows, with the reason, in the input format.
+type rejects struct {
+	path   string
+	format string
+	header []string
+
+	f   *os.File
+	csv *csv.Writer
+}
+
+func (r *rejects) write(row *importRow, reason error) error {
+	if r.f == nil {
+		f, err := os.Create(r.path)
+		if err != nil {
+			return fmt.Errorf("create rejects: %w", err)
+		}
+		r.f = f
+
+		if r.format == formatCSV {
+			r.csv = csv.NewWriter(f)
+			err := r.csv.Write(append(append([]string(nil), r.header...), "error"))
+			if err != nil {
+				return fmt.Errorf("write rejects: %w", err)
+			}
+		}
+	}
+
+	if r.format == formatCSV {
+		record := row.record
+		if record == nil {
+			// Rows that could not be parsed are kept by line number only.
+			record = make([]string, len(r.header))
+		}
+
+		// Row errors of the CSV reader already name the line.
+		msg := reason.Error()
+		var re *accountcsv.RowError
+		if !errors.As(reason, &re) {
+			msg = fmt.Sprintf("line %d: %s", row.line, msg)
+		}
+
+		err := r.csv.Write(append(append([]string(nil), record...), msg))
+		if err != nil {
+			return fmt.Errorf("write rejects: %w", err)
+		}
+
+		return nil
+	}
+
+	b, err := json.Marshal(struct {
+		Line   int             `json:"line"`
+		Error  string          `json:"error"`
+		Record json.RawMessage `json:"record,omitempty"`
+	}{row.line, reason.Error(), rawJSON(row.raw)})
+	if err != nil {
+		return fmt.Errorf("write rejects: %w", err)
+	}
+
+	_, err = fmt.Fprintf(r.f, "%s\n", b)
+	if err != nil {
+		return fmt.Errorf("write rejects: %w", err)
+	}
+
+	return nil
+}
+
+func (r *rejects) Close() error {
+	if r.f == nil {
+		return nil
+	}
+
+	if r.csv != nil {
+		r.csv.Flush()
+		if err := r.csv.Error(); err != nil {
+			r.f.Close()
+			return fmt.Errorf("write rejects: %w", err)
+		}
+	}
+
+	return r.f.Close()
+}
+
+// rawJSON returns s if it is valid JSON, so that it can be embedded.
+func rawJSON(s string) json.RawMessage {
+	if !json.Valid([]byte(s)) {
+		b, _ := json.Marshal(s)
+		return b
+	}
+
+	return json.RawMessage(s)
+}
+
+// importSummary reports the outcome of an import.
+type importSummary struct {
+	Imported    int    `json:"imported"`
+	Skipped     int    `json:"skipped"`
+	Rejected    int    `json:"rejected"`
+	RejectsFile string `json:"rejects_file,omitempty"`
+}
+
+func runAccountsImport(ctx context.Context, a *app, args []string) error {
+	var (
+		cf             clientFlags
+		of             outputFlags
+		file           string
+		format         string
+		mappings       stringsFlag
+		orgID          string
+		concurrency    int
+		checkpointPath string
+		rejectsPath    string
+	)
+
+	fs := a.newFlagSet("form3 accounts import", "--file <path> [flags]")
+	cf.register(fs)
+	of.register(fs)
+	fs.StringVar(&file, "file", "", "CSV or NDJSON `path` of the accounts to import")
+	fs.StringVar(&format, "format", "", "input format: csv or ndjson (default from the file extension)")
+	fs.Var(&mappings, "map", "`header=column` mapping a CSV header to a column, may be repeated; map to - to ignore a header")
+	fs.StringVar(&orgID, "organisation-id", "", "organisation of rows without one (default the configured organisation)")
+	fs.IntVar(&concurrency, "concurrency", 4, "number of accounts created at once")
+	fs.StringVar(&checkpointPath, "checkpoint", "", "`path` recording imported accounts, to resume an import (default <file>.checkpoint)")
+	fs.StringVar(&rejectsPath, "rejects", "", "`path` to write rejected rows to (default <file>.rejects.<format>)")
+
+	pos, err := parseFlags(fs, args)
+	if err != nil {
+		return err
+	}
+
+	if len(pos) > 0 {
+		return usageErrorf("unexpected arguments %v", pos)
+	}
+
+	if file == "" {
+		return usageErrorf("--file is required")
+	}
+
+	format, err = fileFormat(format, file)
+	if err != nil {
+		return err
+	}
+
+	mapping, err := keyValues("map", mappings)
+	if err != nil {
+		return err
+	}
+
+	if mapping != nil && format != formatCSV {
+		return usageErrorf("--map only applies to CSV files")
+	}
+
+	if concurrency < 1 {
+		return usageErrorf("--concurrency must be at least 1")
+	}
+
+	p, err := of.printer(a.stdout)
+	if err != nil {
+		return err
+	}
+
+	if checkpointPath == "" {
+		checkpointPath = file + ".checkpoint"
+	}
+
+	if rejectsPath == "" {
+		rejectsPath = strings.TrimSuffix(file, filepath.Ext(file)) + ".rejects." + format
+	}
+
+	c, err := cf.newClient(a.stdout)
+	if err != nil {
+		return err
+	}
+
+	if orgID == "" {
+		orgID = c.OrganisationID
+	}
+
+	in, err := os.Open(file)
+	if err != nil {
+		return usageErrorf("open import file: %w", err)
+	}
+	defer in.Close()
+
+	var (
+		src    rowSource
+		header []string
+	)
+
+	if format == formatCSV {
+		r := accountcsv.NewReader(in, accountcsv.WithMapping(mapping))
+
+		header, err = r.Header()
+		if err != nil {
+			return usageErrorf("%s: %w", file, err)
+		}
+
+		src = &csvSource{r: r}
+	} else {
+		src = newNDJSONSource(in)
+	}
+
+	// Requests printed by a dry run are neither recorded nor retried, and
+	// are printed one at a time.
+	var cp *checkpoint
+	if cf.dryRun {
+		cp = &checkpoint{done: make(map[string]bool)}
+		concurrency = 1
+	} else {
+		cp, err = openCheckpoint(checkpointPath)
+		if err != nil {
+			return err
+		}
+		defer cp.Close()
+	}
+
+	rej := &rejects{path: rejectsPath, format: format, header: header}
+
+	var (
+		summary importSummary
+
+		mu       sync.Mutex
+		sent     []*importRow
+		writeErr error
+	)
+
+	fail := func(row *importRow, reason error) {
+		summary.Rejected++
+
+		if err := rej.write(row, reason); err != nil && writeErr == nil {
+			writeErr = err
+		}
+	}
+
+	// Rows are no longer read once the import stops.
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	accs := make(chan *account.Account)
+	readErr := make(chan error, 1)
+
+	go func() {
+		defer close(accs)
+
+		for {
+			row, err := src.next()
+			if errors.Is(err, io.EOF) {
+				readErr <- nil
+				return
+			}
+			if err != nil {
+				readErr <- fmt.Errorf("read %s: %w", file, err)
+				return
+			}
+
+			mu.Lock()
+			if row.err == nil {
+				row.err = row.validate(orgID)
+			}
+
+			switch {
+			case row.err != nil:
+				fail(row, row.err)
+				mu.Unlock()
+				continue
+			case cp.done[row.acc.ID]:
+				summary.Skipped++
+				mu.Unlock()
+				continue
+			}
+
+			sent = append(sent, row)
+			mu.Unlock()
+
+			select {
+			case accs <- row.acc:
+			case <-ctx.Done():
+				readErr <- ctx.Err()
+				return
+			}
+		}
+	}()
+
+	_, err = c.Accounts.CreateManyFrom(ctx, accs,
+		accountclient.Concurrency(concurrency),
+		accountclient.OnResult(func(r accountclient.BulkResult) {
+			mu.Lock()
+			defer mu.Unlock()
+
+			row := sent[r.Index]
+
+			switch {
+			case errors.Is(r.Err, errDryRun):
+			case r.Err != nil:
+				fail(row, r.Err)
+			default:
+				summary.Imported++
+
+				if err := cp.record(row.acc.ID); err != nil && writeErr == nil {
+					writeErr = err
+				}
+			}
+		}),
+	)
+	if err != nil {
+		return err
+	}
+
+	if err := <-readErr; err != nil {
+		return err
+	}
+
+	if err := rej.Close(); err != nil {
+		return err
+	}
+
+	if writeErr != nil {
+		return writeErr
+	}
+
+	if summary.Rejected > 0 {
+		summary.RejectsFile = rejectsPath
+	}
+
+	if cf.dryRun {
+		return nil
+	}
+
+	msg := fmt.Sprintf("Imported %d accounts, skipped %d already imported, rejected %d",
+		summary.Imported, summary.Skipped, summary.Rejected)
+	if summary.Rejected > 0 {
+		msg += " (see " + rejectsPath + ")"
+	}
+
+	err = p.message(msg, summary)
+	if err != nil {
+		return err
+	}
+
+	if summary.Rejected > 0 {
+		return fmt.Errorf("%d rows rejected", summary.Rejected)
+	}
+
+	return nil
+}
+
+func runAccountsExport(ctx context.Context, a *app, args []string) error {
+	var (
+		cf       clientFlags
+		file     string
+		format   string
+		columns  string
+		pageSize int
+		filters  stringsFlag
+	)
+
+	fs := a.newFlagSet("form3 accounts export", "[flags]")
+	cf.register(fs)
+	fs.StringVar(&file, "file", "", "`path` to write to (default standard output)")
+	fs.StringVar(&format, "format", "", "output format: csv or ndjson (default from the file extension, or csv)")
+	fs.StringVar(&columns, "columns", "", "comma separated CSV `columns` to write (default all)")
+	fs.IntVar(&pageSize, "page-size", defaultExportPageSize, "accounts fetched per request")
+	fs.Var(&filters, "filter", "`attribute=value` to filter by, may be repeated")
+
+	pos, err := parseFlags(fs, args)
+	if err != nil {
+		return err
+	}
+
+	if len(pos) > 0 {
+		return usageErrorf("unexpected arguments %v", pos)
+	}
+
+	format, err = fileFormat(format, file)
+	if err != nil {
+		return err
+	}
+
+	if columns != "" && format != formatCSV {
+		return usageErrorf("--columns only applies to CSV output")
+	}
+
+	if pageSize < 1 {
+		return usageErrorf("--page-size must be at least 1")
+	}
+
+	filter, err := keyValues("filter", filters)
+	if err != nil {
+		return err
+	}
+
+	c, err := cf.newClient(a.stdout)
+	if err != nil {
+		return err
+	}
+
+	out := a.stdout
+	if file != "" && !cf.dryRun {
+		f, err := os.Create(file)
+		if err != nil {
+			return fmt.Errorf("create export file: %w", err)
+		}
+		defer f.Close()
+
+		out = f
+	}
+
+	var write func(acc *account.Account) error
+	var flush func() error
+
+	if format == formatCSV {
+		var opts []accountcsv.WriterOpt
+		if columns != "" {
+			opts = append(opts, accountcsv.WithColumns(strings.Split(columns, ",")...))
+		}
+
+		w := accountcsv.NewWriter(out, opts...)
+		write, flush = w.Write, w.Flush
+	} else {
+		bw := bufio.NewWriter(out)
+		enc := json.NewEncoder(bw)
+		write = func(acc *account.Account) error { return enc.Encode(acc) }
+		flush = bw.Flush
+	}
+
+	pages := c.Accounts.Pages(account.ListAccountParams{PageSize: pageSize, Filter: filter})
+	for {
+		resp, err := pages.Next(ctx)
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if errors.Is(err, errDryRun) {
+			return nil
+		}
+		if err != nil {
+			return fmt.Errorf("export: %w", err)
+		}
+
+		for i := range resp.Data {
+			err := write(&resp.Data[i])
+			if err != nil {
+				return fmt.Errorf("export account %s: %w", resp.Data[i].ID, err)
+			}
+		}
+	}
+
+	err = flush()
+	if err != nil {
+		return fmt.Errorf("write export: %w", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,480 @@
+// Package gateway implements form3-gateway, a reverse proxy that lets
+// trusted local callers reach the form3 API without a form3 client.
+//
+// Callers authenticate with a bearer token. Requests allowed by the
+// caller's policy are forwarded through form3.Client, which signs, retries
+// and rate limits them, and one audit record is written per request.
+package gateway
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"math"
+	"net/http"
+	"path"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/vivangkumar/form3-http-go/pkg/client"
+	"github.com/vivangkumar/form3-http-go/pkg/client/audit"
+	"github.com/vivangkumar/form3-http-go/pkg/form3"
+)
+
+// defaultMaxBodySize is the largest request body accepted by default.
+const defaultMaxBodySize = 1 << 20
+
+// Outcomes of requests, as recorded in audit records.
+const (
+	OutcomeForwarded       = "forwarded"
+	OutcomeUnauthenticated = "unauthenticated"
+	OutcomeForbidden       = "forbidden"
+	OutcomeRateLimited     = "rate_limited"
+	OutcomeInvalid         = "invalid"
+	OutcomeFailed          = "failed"
+)
+
+// idempotencyNamespace is the namespace of the keys derived from the
+// idempotency keys of callers.
+var idempotencyNamespace = uuid.MustParse("e313d3c1-65c3-4fc5-9c36-b546e7443821")
+
+// skipHeaders are request headers that are not forwarded. The gateway
+// authenticates callers itself, and the client sets the others.
+var skipHeaders = map[string]bool{
+	"Accept":              true,
+	"Accept-Encoding":     true,
+	"Authorization":       true,
+	"Connection":          true,
+	"Content-Length":      true,
+	"Content-Type":        true,
+	"Cookie":              true,
+	"Date":                true,
+	"Digest":              true,
+	"Host":                true,
+	"Idempotency-Key":     true,
+	"Keep-Alive":          true,
+	"Proxy-Authorization": true,
+	"Proxy-Connection":    true,
+	"Te":                  true,
+	"Trailer":             true,
+	"Transfer-Encoding":   true,
+	"Upgrade":             true,
+	"User-Agent":          true,
+}
+
+// hopHeaders are response headers that are not passed back to callers.
+var hopHeaders = map[string]bool{
+	"Connection":        true,
+	"Content-Length":    true,
+	"Keep-Alive":        true,
+	"Proxy-Connection":  true,
+	"Te":                true,
+	"Trailer":           true,
+	"Transfer-Encoding": true,
+	"Upgrade":           true,
+}
+
+// Record is the audit record of a request to the gateway.
+type Record struct {
+	Time       time.Time `json:"time"`
+	Caller     string    `json:"caller,omitempty"`
+	RemoteAddr string    `json:"remote_addr"`
+	Method     string    `json:"method"`
+	Path       string    `json:"path"`
+	Query      string    `json:"query,omitempty"`
+
+	// Outcome is one of the Outcome constants.
+	Outcome string `json:"outcome"`
+
+	// Status is the status returned to the caller.
+	Status int `json:"status"`
+
+	// IdempotencyKey is the key sent with POST and PATCH requests, either
+	// derived from the caller's or generated by the gateway.
+	IdempotencyKey string `json:"idempotency_key,omitempty"`
+
+	// CallerIdempotencyKey is the key sent by the caller, if any.
+	CallerIdempotencyKey string `json:"caller_idempotency_key,omitempty"`
+
+	// Attempts is the number of requests made to the API, including
+	// retries.
+	Attempts int `json:"attempts,omitempty"`
+
+	DurationMS int64  `json:"duration_ms"`
+	Error      string `json:"error,omitempty"`
+}
+
+// Opt represents an option that can be passed to New.
+type Opt func(g *Gateway)
+
+// WithAuditLog writes audit records to w, as JSON, one per line.
+func WithAuditLog(w io.Writer) Opt {
+	return func(g *Gateway) {
+		g.auditLog = w
+	}
+}
+
+// WithErrorLog logs errors writing audit records to l, instead of the
+// standard logger.
+func WithErrorLog(l *log.Logger) Opt {
+	return func(g *Gateway) {
+		g.errorLog = l
+	}
+}
+
+// WithMaxBodySize sets the largest request body accepted, in bytes.
+//
+// It is 1 MiB by default.
+func WithMaxBodySize(n int64) Opt {
+	return func(g *Gateway) {
+		g.maxBodySize = n
+	}
+}
+
+// WithClientOpts adds options of the client requests are forwarded with.
+// They are applied after those of the config.
+func WithClientOpts(opts ...form3.Opt) Opt {
+	return func(g *Gateway) {
+		g.clientOpts = append(g.clientOpts, opts...)
+	}
+}
+
+// Gateway is an http.Handler forwarding requests to the form3 API.
+type Gateway struct {
+	c       *form3.Client
+	callers callers
+	limiter *limiter
+
+	auditMu  sync.Mutex
+	auditLog io.Writer
+	errorLog *log.Logger
+
+	maxBodySize int64
+	clientOpts  []form3.Opt
+
+	// now returns the current time.
+	now func() time.Time
+}
+
+// New returns a gateway enforcing p, forwarding requests with a client
+// configured by cfg.
+func New(p *Policy, cfg *form3.Config, opts ...Opt) (*Gateway, error) {
+	err := p.Validate()
+	if err != nil {
+		return nil, fmt.Errorf("validate policy: %w", err)
+	}
+
+	cs, err := newCallers(p)
+	if err != nil {
+		return nil, err
+	}
+
+	g := &Gateway{
+		callers:     cs,
+		limiter:     newLimiter(p.RateLimit, p.Burst),
+		auditLog:    io.Discard,
+		errorLog:    log.Default(),
+		maxBodySize: defaultMaxBodySize,
+		now:         time.Now,
+	}
+
+	for _, opt := range opts {
+		opt(g)
+	}
+
+	// Responses are captured as they are received, so that they can be
+	// passed back as they are, error bodies included.
+	clientOpts := append(append([]form3.Opt(nil), g.clientOpts...), client.WithMiddleware(captureResponses))
+
+	g.c, err = form3.NewWithConfig(cfg, clientOpts...)
+	if err != nil {
+		return nil, fmt.Errorf("create client: %w", err)
+	}
+
+	return g, nil
+}
+
+// ServeHTTP forwards r to the API if the caller is allowed to make it.
+func (g *Gateway) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	start := g.now()
+
+	rec := &Record{
+		Time:       start.UTC(),
+		RemoteAddr: r.RemoteAddr,
+		Method:     r.Method,
+		Path:       r.URL.Path,
+		Query:      r.URL.RawQuery,
+	}
+
+	defer func() {
+		rec.DurationMS = g.now().Sub(start).Milliseconds()
+		g.audit(rec)
+	}()
+
+	c := g.callers.authenticate(r)
+	if c == nil {
+		w.Header().Set("WWW-Authenticate", "Bearer")
+		g.reject(w, rec, http.StatusUnauthorized, OutcomeUnauthenticated, "missing or unknown bearer token")
+		return
+	}
+	rec.Caller = c.Name
+
+	if !cleanPath(r.URL.Path) {
+		g.reject(w, rec, http.StatusBadRequest, OutcomeInvalid, "path is not clean")
+		return
+	}
+
+	if !c.allows(r.Method, r.URL.Path) {
+		g.reject(w, rec, http.StatusForbidden, OutcomeForbidden,
+			fmt.Sprintf("caller %s may not %s %s", c.Name, r.Method, r.URL.Path))
+		return
+	}
+
+	if !g.allow(w, c) {
+		g.reject(w, rec, http.StatusTooManyRequests, OutcomeRateLimited, "rate limit exceeded")
+		return
+	}
+
+	body, err := readBody(w, r, g.maxBodySize)
+	if err != nil {
+		status := http.StatusBadRequest
+
+		var tooLarge *http.MaxBytesError
+		if errors.As(err, &tooLarge) {
+			status = http.StatusRequestEntityTooLarge
+		}
+
+		g.reject(w, rec, status, OutcomeInvalid, err.Error())
+		return
+	}
+
+	g.forward(w, r, rec, body)
+}
+
+// allow takes a token from the caller's rate limit and the gateway's. If
+// either is exhausted, it sets Retry-After and returns false.
+func (g *Gateway) allow(w http.ResponseWriter, c *caller) bool {
+	now := g.now()
+
+	ok, wait := c.limiter.allow(now)
+	if ok {
+		ok, wait = g.limiter.allow(now)
+		if !ok {
+			// The request is not sent, so it does not count against the
+			// caller.
+			c.limiter.give()
+		}
+	}
+
+	if !ok {
+		w.Header().Set("Retry-After", strconv.Itoa(int(math.Ceil(wait.Seconds()))))
+	}
+
+	return ok
+}
+
+// readBody reads the request body, which must be JSON if it is not empty.
+func readBody(w http.ResponseWriter, r *http.Request, limit int64) (json.RawMessage, error) {
+	b, err := io.ReadAll(http.MaxBytesReader(w, r.Body, limit))
+	if err != nil {
+		return nil, fmt.Errorf("read body: %w", err)
+	}
+
+	if len(bytes.TrimSpace(b)) == 0 {
+		return nil, nil
+	}
+
+	if !json.Valid(b) {
+		return nil, fmt.Errorf("body is not valid JSON")
+	}
+
+	return b, nil
+}
+
+// callerIdempotencyKey returns the idempotency key sent for key from caller.
+func callerIdempotencyKey(caller, key string) string {
+	return uuid.NewSHA1(idempotencyNamespace, []byte(caller+"\x00"+key)).String()
+}
+
+// forward sends the request with the client and passes the response back.
+func (g *Gateway) forward(w http.ResponseWriter, r *http.Request, rec *Record, body json.RawMessage) {
+	ctx, cp := withCapture(r.Context())
+
+	// Client middleware, such as audit.Middleware, sees the caller too.
+	ctx = audit.ContextWithCaller(ctx, rec.Caller)
+
+	// Mutating requests always carry an idempotency key, so that the client
+	// may retry them. Callers share the gateway's credentials, so a key
+	// from a caller is namespaced by the caller. Otherwise two callers
+	// choosing the same key would be answered with each other's requests.
+	if r.Method == http.MethodPost || r.Method == http.MethodPatch {
+		key := uuid.NewString()
+		if ck := r.Header.Get("Idempotency-Key"); ck != "" {
+			rec.CallerIdempotencyKey = ck
+			key = callerIdempotencyKey(rec.Caller, ck)
+		}
+		rec.IdempotencyKey = key
+
+		ctx = client.ContextWithIdempotencyKey(ctx, key)
+	}
+
+	var reqBody any
+	if body != nil {
+		reqBody = body
+	}
+
+	// The path is forwarded as it was escaped, so that it names the same
+	// resource the allow-list was checked against.
+	req, err := g.c.NewRequest(ctx, r.Method, r.URL.EscapedPath(), nil, reqBody)
+	if err != nil {
+		g.reject(w, rec, http.StatusBadRequest, OutcomeInvalid, err.Error())
+		return
+	}
+	req.URL.RawQuery = r.URL.RawQuery
+
+	for name, values := range r.Header {
+		if !skipHeaders[http.CanonicalHeaderKey(name)] {
+			req.Header[name] = values
+		}
+	}
+
+	_, err = g.c.Do(req, nil)
+	if err != nil {
+		rec.Error = err.Error()
+	}
+
+	resp := cp.response()
+	rec.Attempts = cp.attempts()
+
+	if resp == nil {
+		g.reject(w, rec, http.StatusBadGateway, OutcomeFailed, "form3 API request failed")
+		return
+	}
+
+	for name, values := range resp.header {
+		if !hopHeaders[name] {
+			w.Header()[name] = values
+		}
+	}
+	w.Header().Set("Content-Length", strconv.Itoa(len(resp.body)))
+	w.WriteHeader(resp.status)
+	w.Write(resp.body)
+
+	rec.Outcome = OutcomeForwarded
+	rec.Status = resp.status
+}
+
+// reject responds with an error in the format of API errors.
+func (g *Gateway) reject(w http.ResponseWriter, rec *Record, status int, outcome string, msg string) {
+	rec.Outcome = outcome
+	rec.Status = status
+	if rec.Error == "" {
+		rec.Error = msg
+	}
+
+	b, _ := json.Marshal(map[string]string{"error_message": msg})
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	w.Write(b)
+}
+
+// audit writes rec to the audit log.
+func (g *Gateway) audit(rec *Record) {
+	b, err := json.Marshal(rec)
+	if err == nil {
+		g.auditMu.Lock()
+		_, err = fmt.Fprintf(g.auditLog, "%s\n", b)
+		g.auditMu.Unlock()
+	}
+
+	if err != nil {
+		g.errorLog.Printf("form3-gateway: write audit record of %s %s: %s", rec.Method, rec.Path, err)
+	}
+}
+
+// cleanPath reports whether p is absolute and has no . or .. segments,
+// repeated slashes, or decoded ? or # characters, so that allow-lists
+// cannot be sidestepped.
+func cleanPath(p string) bool {
+	if !strings.HasPrefix(p, "/") || strings.ContainsAny(p, "?#") {
+		return false
+	}
+
+	return path.Clean(p) == strings.TrimSuffix(p, "/") || p == "/"
+}
+
+// capturedResponse is a response received from the API.
+type capturedResponse struct {
+	status int
+	header http.Header
+	body   []byte
+}
+
+// capture holds the last response received for a request.
+type capture struct {
+	mu    sync.Mutex
+	resp  *capturedResponse
+	count int
+}
+
+func (c *capture) response() *capturedResponse {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return c.resp
+}
+
+func (c *capture) attempts() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return c.count
+}
+
+type captureKey struct{}
+
+func withCapture(ctx context.Context) (context.Context, *capture) {
+	c := &capture{}
+	return context.WithValue(ctx, captureKey{}, c), c
+}
+
+// captureResponses is middleware that records the response of each attempt
+// of requests with a capture in their context.
+func captureResponses(next client.Doer) client.Doer {
+	return client.DoerFunc(func(req *http.Request) (*http.Response, error) {
+		c, _ := req.Context().Value(captureKey{}).(*capture)
+
+		resp, err := next.Do(req)
+		if c == nil {
+			return resp, err
+		}
+
+		var captured *capturedResponse
+		if err == nil {
+			body, rerr := io.ReadAll(resp.Body)
+			resp.Body.Close()
+			if rerr != nil {
+				resp, err = nil, fmt.Errorf("read response body: %w", rerr)
+			} else {
+				resp.Body = io.NopCloser(bytes.NewReader(body))
+				captured = &capturedResponse{status: resp.StatusCode, header: resp.Header.Clone(), body: body}
+			}
+		}
+
+		c.mu.Lock()
+		c.count++
+		c.resp = captured
+		c.mu.Unlock()
+
+		return resp, err
+	})
+}
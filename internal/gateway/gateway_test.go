@@ -0,0 +1,385 @@
+package gateway_test
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"github.com/vivangkumar/form3-http-go/internal/gateway"
+	"github.com/vivangkumar/form3-http-go/pkg/client"
+	"github.com/vivangkumar/form3-http-go/pkg/client/audit"
+	"github.com/vivangkumar/form3-http-go/pkg/form3"
+)
+
+// upstreamRequest is a request received by the fake API.
+type upstreamRequest struct {
+	method string
+	path   string
+	query  string
+	header http.Header
+	body   []byte
+}
+
+var _ = Describe("Gateway", func() {
+	var (
+		mu        sync.Mutex
+		received  []upstreamRequest
+		reply     func(n int, r *http.Request) (int, string)
+		upstream  *httptest.Server
+		policy    *gateway.Policy
+		auditLog  *bytes.Buffer
+		gatewayFn func() *httptest.Server
+	)
+
+	BeforeEach(func() {
+		received = nil
+		reply = func(int, *http.Request) (int, string) {
+			return http.StatusOK, `{"data": {"id": "1"}}`
+		}
+
+		upstream = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			body, _ := io.ReadAll(r.Body)
+
+			mu.Lock()
+			received = append(received, upstreamRequest{r.Method, r.URL.Path, r.URL.RawQuery, r.Header.Clone(), body})
+			n := len(received)
+			mu.Unlock()
+
+			status, resp := reply(n, r)
+			w.Header().Set("Content-Type", "application/vnd.api+json")
+			w.Header().Set("X-Request-Id", "req-1")
+			w.WriteHeader(status)
+			io.WriteString(w, resp)
+		}))
+		DeferCleanup(upstream.Close)
+
+		policy = &gateway.Policy{
+			Callers: []gateway.Caller{
+				{
+					Name:  "onboarding",
+					Token: "secret-1",
+					Allow: []gateway.Rule{
+						{Methods: []string{"GET", "POST"}, Paths: []string{"/v1/organisation/accounts", "/v1/organisation/accounts/*"}},
+					},
+				},
+				{
+					Name:  "reporting",
+					Token: "secret-2",
+					Allow: []gateway.Rule{
+						{Methods: []string{"*"}, Paths: []string{"/v1/organisation/**"}},
+					},
+				},
+			},
+		}
+
+		auditLog = new(bytes.Buffer)
+
+		gatewayFn = func() *httptest.Server {
+			gw, err := gateway.New(policy,
+				&form3.Config{BaseURL: upstream.URL, MaxRetries: 1, RetryBackoff: time.Millisecond},
+				gateway.WithAuditLog(auditLog),
+				gateway.WithMaxBodySize(64),
+			)
+			Expect(err).NotTo(HaveOccurred())
+
+			srv := httptest.NewServer(gw)
+			DeferCleanup(srv.Close)
+
+			return srv
+		}
+	})
+
+	send := func(srv *httptest.Server, method string, target string, token string, body string) (*http.Response, string) {
+		req, err := http.NewRequest(method, srv.URL+target, strings.NewReader(body))
+		Expect(err).NotTo(HaveOccurred())
+
+		if token != "" {
+			req.Header.Set("Authorization", "Bearer "+token)
+		}
+		req.Header.Set("X-Correlation-Id", "abc")
+
+		resp, err := http.DefaultClient.Do(req)
+		Expect(err).NotTo(HaveOccurred())
+		defer resp.Body.Close()
+
+		b, err := io.ReadAll(resp.Body)
+		Expect(err).NotTo(HaveOccurred())
+
+		return resp, string(b)
+	}
+
+	records := func() []gateway.Record {
+		var recs []gateway.Record
+		for _, line := range strings.Split(strings.TrimSpace(auditLog.String()), "\n") {
+			var rec gateway.Record
+			Expect(json.Unmarshal([]byte(line), &rec)).To(Succeed())
+			recs = append(recs, rec)
+		}
+
+		return recs
+	}
+
+	It("should forward allowed requests and pass the response back", func() {
+		srv := gatewayFn()
+
+		resp, body := send(srv, http.MethodGet, "/v1/organisation/accounts/1?include=x", "secret-1", "")
+		Expect(resp.StatusCode).To(Equal(http.StatusOK))
+		Expect(resp.Header.Get("X-Request-Id")).To(Equal("req-1"))
+		Expect(body).To(MatchJSON(`{"data": {"id": "1"}}`))
+
+		Expect(received).To(HaveLen(1))
+		Expect(received[0].method).To(Equal(http.MethodGet))
+		Expect(received[0].path).To(Equal("/v1/organisation/accounts/1"))
+		Expect(received[0].query).To(Equal("include=x"))
+		Expect(received[0].header.Get("X-Correlation-Id")).To(Equal("abc"))
+		Expect(received[0].header.Get("Accept")).To(Equal("application/vnd.api+json"))
+		Expect(received[0].header.Get("Authorization")).To(BeEmpty())
+
+		recs := records()
+		Expect(recs).To(HaveLen(1))
+		Expect(recs[0].Caller).To(Equal("onboarding"))
+		Expect(recs[0].Method).To(Equal(http.MethodGet))
+		Expect(recs[0].Path).To(Equal("/v1/organisation/accounts/1"))
+		Expect(recs[0].Query).To(Equal("include=x"))
+		Expect(recs[0].Outcome).To(Equal(gateway.OutcomeForwarded))
+		Expect(recs[0].Status).To(Equal(http.StatusOK))
+		Expect(recs[0].Attempts).To(Equal(1))
+	})
+
+	It("should send POST requests with an idempotency key", func() {
+		srv := gatewayFn()
+
+		resp, _ := send(srv, http.MethodPost, "/v1/organisation/accounts", "secret-1", `{"data": {"id": "1"}}`)
+		Expect(resp.StatusCode).To(Equal(http.StatusOK))
+
+		Expect(received[0].body).To(MatchJSON(`{"data": {"id": "1"}}`))
+		Expect(received[0].header.Get("Content-Type")).To(Equal("application/vnd.api+json"))
+
+		key := received[0].header.Get("Idempotency-Key")
+		Expect(key).NotTo(BeEmpty())
+		Expect(records()[0].IdempotencyKey).To(Equal(key))
+	})
+
+	It("should pass the caller to client middleware", func() {
+		ch := make(chan audit.Record, 1)
+
+		gw, err := gateway.New(policy,
+			&form3.Config{BaseURL: upstream.URL},
+			gateway.WithClientOpts(client.WithMiddleware(audit.Middleware(audit.ChanSink(ch)))),
+		)
+		Expect(err).NotTo(HaveOccurred())
+
+		srv := httptest.NewServer(gw)
+		DeferCleanup(srv.Close)
+
+		resp, _ := send(srv, http.MethodPost, "/v1/organisation/accounts", "secret-1", `{"data": {"id": "1"}}`)
+		Expect(resp.StatusCode).To(Equal(http.StatusOK))
+
+		var rec audit.Record
+		Expect(ch).To(Receive(&rec))
+		Expect(rec.Caller).To(Equal("onboarding"))
+		Expect(rec.ResourceID).To(Equal("1"))
+	})
+
+	It("should retry with the same idempotency key", func() {
+		reply = func(n int, r *http.Request) (int, string) {
+			if n == 1 {
+				return http.StatusServiceUnavailable, ""
+			}
+			return http.StatusCreated, `{"data": {"id": "1"}}`
+		}
+		srv := gatewayFn()
+
+		req, err := http.NewRequest(http.MethodPost, srv.URL+"/v1/organisation/accounts", strings.NewReader(`{}`))
+		Expect(err).NotTo(HaveOccurred())
+		req.Header.Set("Authorization", "Bearer secret-1")
+		req.Header.Set("Idempotency-Key", "caller-key")
+
+		resp, err := http.DefaultClient.Do(req)
+		Expect(err).NotTo(HaveOccurred())
+		resp.Body.Close()
+		Expect(resp.StatusCode).To(Equal(http.StatusCreated))
+
+		Expect(received).To(HaveLen(2))
+		key := received[0].header.Get("Idempotency-Key")
+		Expect(key).NotTo(BeEmpty())
+		Expect(received[1].header.Get("Idempotency-Key")).To(Equal(key))
+		Expect(received[1].body).To(MatchJSON(`{}`))
+		Expect(records()[0].Attempts).To(Equal(2))
+		Expect(records()[0].IdempotencyKey).To(Equal(key))
+		Expect(records()[0].CallerIdempotencyKey).To(Equal("caller-key"))
+	})
+
+	It("should namespace idempotency keys by caller", func() {
+		srv := gatewayFn()
+
+		post := func(token string) {
+			req, err := http.NewRequest(http.MethodPost, srv.URL+"/v1/organisation/accounts", strings.NewReader(`{}`))
+			Expect(err).NotTo(HaveOccurred())
+			req.Header.Set("Authorization", "Bearer "+token)
+			req.Header.Set("Idempotency-Key", "caller-key")
+
+			resp, err := http.DefaultClient.Do(req)
+			Expect(err).NotTo(HaveOccurred())
+			resp.Body.Close()
+		}
+
+		post("secret-1")
+		post("secret-1")
+		post("secret-2")
+
+		Expect(received).To(HaveLen(3))
+		Expect(received[1].header.Get("Idempotency-Key")).To(Equal(received[0].header.Get("Idempotency-Key")))
+		Expect(received[2].header.Get("Idempotency-Key")).NotTo(Equal(received[0].header.Get("Idempotency-Key")))
+		Expect(received[0].header.Get("Idempotency-Key")).NotTo(Equal("caller-key"))
+	})
+
+	It("should pass error responses back as they are", func() {
+		reply = func(int, *http.Request) (int, string) {
+			return http.StatusConflict, `{"error_message": "duplicate"}`
+		}
+		srv := gatewayFn()
+
+		resp, body := send(srv, http.MethodPost, "/v1/organisation/accounts", "secret-1", `{}`)
+		Expect(resp.StatusCode).To(Equal(http.StatusConflict))
+		Expect(body).To(MatchJSON(`{"error_message": "duplicate"}`))
+
+		rec := records()[0]
+		Expect(rec.Outcome).To(Equal(gateway.OutcomeForwarded))
+		Expect(rec.Status).To(Equal(http.StatusConflict))
+		Expect(rec.Error).To(ContainSubstring("duplicate"))
+	})
+
+	DescribeTable("should reject requests the caller may not make",
+		func(method string, target string, token string, body string, status int, outcome string) {
+			srv := gatewayFn()
+
+			resp, respBody := send(srv, method, target, token, body)
+			Expect(resp.StatusCode).To(Equal(status))
+			Expect(respBody).To(ContainSubstring("error_message"))
+			Expect(received).To(BeEmpty())
+
+			rec := records()[0]
+			Expect(rec.Outcome).To(Equal(outcome))
+			Expect(rec.Status).To(Equal(status))
+		},
+		Entry("no token", http.MethodGet, "/v1/organisation/accounts/1", "", "", http.StatusUnauthorized, gateway.OutcomeUnauthenticated),
+		Entry("unknown token", http.MethodGet, "/v1/organisation/accounts/1", "secret-3", "", http.StatusUnauthorized, gateway.OutcomeUnauthenticated),
+		Entry("method not allowed", http.MethodDelete, "/v1/organisation/accounts/1", "secret-1", "", http.StatusForbidden, gateway.OutcomeForbidden),
+		Entry("path not allowed", http.MethodGet, "/v1/transaction/payments", "secret-1", "", http.StatusForbidden, gateway.OutcomeForbidden),
+		Entry("path below a match", http.MethodGet, "/v1/organisation/accounts/1/x", "secret-1", "", http.StatusForbidden, gateway.OutcomeForbidden),
+		Entry("path escaping the allow-list", http.MethodGet, "/v1/organisation/accounts/../../transaction/payments", "secret-1", "", http.StatusBadRequest, gateway.OutcomeInvalid),
+		Entry("path with an escaped ?", http.MethodGet, "/v1/organisation/accounts/%3F", "secret-1", "", http.StatusBadRequest, gateway.OutcomeInvalid),
+		Entry("path with an escaped #", http.MethodGet, "/v1/organisation/accounts/%23", "secret-1", "", http.StatusBadRequest, gateway.OutcomeInvalid),
+		Entry("body not JSON", http.MethodPost, "/v1/organisation/accounts", "secret-1", "{", http.StatusBadRequest, gateway.OutcomeInvalid),
+		Entry("body too large", http.MethodPost, "/v1/organisation/accounts", "secret-1", `"`+strings.Repeat("x", 100)+`"`, http.StatusRequestEntityTooLarge, gateway.OutcomeInvalid),
+	)
+
+	It("should allow every path below a /** pattern", func() {
+		srv := gatewayFn()
+
+		resp, _ := send(srv, http.MethodDelete, "/v1/organisation/accounts/1/x?version=0", "secret-2", "")
+		Expect(resp.StatusCode).To(Equal(http.StatusOK))
+		Expect(received[0].method).To(Equal(http.MethodDelete))
+	})
+
+	It("should rate limit callers", func() {
+		policy.Callers[0].RateLimit = 0.001
+		srv := gatewayFn()
+
+		resp, _ := send(srv, http.MethodGet, "/v1/organisation/accounts/1", "secret-1", "")
+		Expect(resp.StatusCode).To(Equal(http.StatusOK))
+
+		resp, _ = send(srv, http.MethodGet, "/v1/organisation/accounts/1", "secret-1", "")
+		Expect(resp.StatusCode).To(Equal(http.StatusTooManyRequests))
+		Expect(resp.Header.Get("Retry-After")).To(Equal("1000"))
+
+		// Other callers have their own limit.
+		resp, _ = send(srv, http.MethodGet, "/v1/organisation/accounts/1", "secret-2", "")
+		Expect(resp.StatusCode).To(Equal(http.StatusOK))
+
+		Expect(records()[1].Outcome).To(Equal(gateway.OutcomeRateLimited))
+	})
+
+	It("should rate limit all callers together", func() {
+		policy.RateLimit = 0.001
+		srv := gatewayFn()
+
+		resp, _ := send(srv, http.MethodGet, "/v1/organisation/accounts/1", "secret-1", "")
+		Expect(resp.StatusCode).To(Equal(http.StatusOK))
+
+		resp, _ = send(srv, http.MethodGet, "/v1/organisation/accounts/1", "secret-2", "")
+		Expect(resp.StatusCode).To(Equal(http.StatusTooManyRequests))
+	})
+
+	It("should report unreachable APIs", func() {
+		srv := gatewayFn()
+		upstream.Close()
+
+		resp, _ := send(srv, http.MethodGet, "/v1/organisation/accounts/1", "secret-1", "")
+		Expect(resp.StatusCode).To(Equal(http.StatusBadGateway))
+
+		rec := records()[0]
+		Expect(rec.Outcome).To(Equal(gateway.OutcomeFailed))
+		Expect(rec.Attempts).To(Equal(2))
+		Expect(rec.Error).To(ContainSubstring("do request"))
+	})
+})
+
+var _ = Describe("LoadPolicy", func() {
+	write := func(content string) string {
+		path := filepath.Join(GinkgoT().TempDir(), "policy.yaml")
+		Expect(os.WriteFile(path, []byte(content), 0o600)).To(Succeed())
+
+		return path
+	}
+
+	It("should read callers and rules", func() {
+		sum := sha256.Sum256([]byte("secret"))
+
+		p, err := gateway.LoadPolicy(write(`
+rate_limit: 50
+callers:
+  - name: onboarding
+    token_sha256: ` + hex.EncodeToString(sum[:]) + `
+    rate_limit: 5
+    burst: 10
+    allow:
+      - methods: [GET, POST]
+        paths: [/v1/organisation/accounts/*]
+`))
+		Expect(err).NotTo(HaveOccurred())
+		Expect(p.RateLimit).To(Equal(50.0))
+		Expect(p.Callers).To(HaveLen(1))
+		Expect(p.Callers[0].Burst).To(Equal(10))
+		Expect(p.Callers[0].Allow[0].Paths).To(Equal([]string{"/v1/organisation/accounts/*"}))
+	})
+
+	DescribeTable("should reject invalid policies",
+		func(content string, want string) {
+			_, err := gateway.LoadPolicy(write(content))
+			Expect(err).To(MatchError(ContainSubstring(want)))
+		},
+		Entry("empty", "", "is empty"),
+		Entry("unknown field", "callers:\n  - name: a\n    tokn: x\n", "field tokn not found"),
+		Entry("no name", "callers:\n  - token: x\n", "name is required"),
+		Entry("no token", "callers:\n  - name: a\n", "token or token_sha256 is required"),
+		Entry("both tokens", "callers:\n  - name: a\n    token: x\n    token_sha256: ab\n", "only one of"),
+		Entry("bad digest", "callers:\n  - name: a\n    token_sha256: xyz\n", "not a hex SHA-256"),
+		Entry("same token", "callers:\n  - name: a\n    token: x\n  - name: b\n    token: x\n", "token is not unique"),
+		Entry("relative path", "callers:\n  - name: a\n    token: x\n    allow:\n      - methods: [GET]\n        paths: [v1]\n", "must start with /"),
+		Entry("bad pattern", "callers:\n  - name: a\n    token: x\n    allow:\n      - methods: [GET]\n        paths: [\"/v1/[\"]\n", "syntax error in pattern"),
+	)
+})
@@ -0,0 +1,72 @@
+package gateway
+
+import (
+	"math"
+	"sync"
+	"time"
+)
+
+// limiter is a token bucket allowing rate requests per second, with bursts
+// of up to burst requests.
+type limiter struct {
+	rate  float64
+	burst float64
+
+	mu     sync.Mutex
+	tokens float64
+	last   time.Time
+}
+
+// newLimiter returns a limiter, or nil if rate is 0, which allows every
+// request.
+func newLimiter(rate float64, burst int) *limiter {
+	if rate == 0 {
+		return nil
+	}
+
+	if burst < 1 {
+		burst = 1
+	}
+
+	return &limiter{rate: rate, burst: float64(burst), tokens: float64(burst)}
+}
+
+// allow takes a token at now. If none is left, it returns false and how
+// long until one is.
+func (l *limiter) allow(now time.Time) (bool, time.Duration) {
+	if l == nil {
+		return true, 0
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if !l.last.IsZero() {
+		elapsed := now.Sub(l.last).Seconds()
+		if elapsed > 0 {
+			l.tokens = math.Min(l.burst, l.tokens+elapsed*l.rate)
+		}
+	}
+	l.last = now
+
+	if l.tokens >= 1 {
+		l.tokens--
+		return true, 0
+	}
+
+	wait := (1 - l.tokens) / l.rate
+
+	return false, time.Duration(wait * float64(time.Second))
+}
+
+// give returns a token taken by allow, for requests that were not sent.
+func (l *limiter) give() {
+	if l == nil {
+		return
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	l.tokens = math.Min(l.burst, l.tokens+1)
+}
@@ -0,0 +1,292 @@
+package gateway
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Policy lists the callers of the gateway and what they may call.
+type Policy struct {
+	// RateLimit caps the requests per second forwarded for all callers
+	// together. It is unlimited if 0.
+	RateLimit float64 `yaml:"rate_limit"`
+
+	// Burst is the number of requests allowed at once above RateLimit.
+	// It is 1 if 0.
+	Burst int `yaml:"burst"`
+
+	Callers []Caller `yaml:"callers"`
+}
+
+// Caller is a caller of the gateway, identified by its bearer token.
+type Caller struct {
+	// Name identifies the caller in audit records.
+	Name string `yaml:"name"`
+
+	// Token is the bearer token of the caller.
+	Token string `yaml:"token"`
+
+	// TokenSHA256 is the hex SHA-256 of the token, so that the policy
+	// need not hold the token itself. Only one of Token and TokenSHA256
+	// may be set.
+	TokenSHA256 string `yaml:"token_sha256"`
+
+	// RateLimit caps the requests per second of the caller. It is
+	// unlimited if 0.
+	RateLimit float64 `yaml:"rate_limit"`
+
+	// Burst is the number of requests allowed at once above RateLimit.
+	// It is 1 if 0.
+	Burst int `yaml:"burst"`
+
+	// Allow lists the requests the caller may make.
+	Allow []Rule `yaml:"allow"`
+}
+
+// Rule allows requests with one of its methods to one of its paths.
+type Rule struct {
+	// Methods are HTTP methods, such as GET. "*" allows any method.
+	Methods []string `yaml:"methods"`
+
+	// Paths are path.Match patterns, such as /v1/organisation/accounts/*.
+	// A pattern ending in /** also matches every path below it.
+	Paths []string `yaml:"paths"`
+}
+
+// LoadPolicy reads a YAML or JSON policy file.
+func LoadPolicy(file string) (*Policy, error) {
+	b, err := os.ReadFile(file)
+	if err != nil {
+		return nil, fmt.Errorf("read policy: %w", err)
+	}
+
+	p := &Policy{}
+
+	dec := yaml.NewDecoder(bytes.NewReader(b))
+	dec.KnownFields(true)
+
+	err = dec.Decode(p)
+	if errors.Is(err, io.EOF) {
+		return nil, fmt.Errorf("policy %s is empty", file)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("decode policy %s: %w", file, err)
+	}
+
+	err = p.Validate()
+	if err != nil {
+		return nil, fmt.Errorf("policy %s: %w", file, err)
+	}
+
+	return p, nil
+}
+
+// Validate checks that every caller has a name, a unique token and valid
+// rules.
+func (p *Policy) Validate() error {
+	if p.RateLimit < 0 || p.Burst < 0 {
+		return fmt.Errorf("rate_limit and burst must not be negative")
+	}
+
+	names := make(map[string]bool)
+	digests := make(map[string]bool)
+
+	for i, c := range p.Callers {
+		if c.Name == "" {
+			return fmt.Errorf("callers[%d]: name is required", i)
+		}
+		if names[c.Name] {
+			return fmt.Errorf("caller %s: name is not unique", c.Name)
+		}
+		names[c.Name] = true
+
+		digest, err := c.digest()
+		if err != nil {
+			return fmt.Errorf("caller %s: %w", c.Name, err)
+		}
+		if digests[string(digest)] {
+			return fmt.Errorf("caller %s: token is not unique", c.Name)
+		}
+		digests[string(digest)] = true
+
+		if c.RateLimit < 0 || c.Burst < 0 {
+			return fmt.Errorf("caller %s: rate_limit and burst must not be negative", c.Name)
+		}
+
+		for j, r := range c.Allow {
+			err := r.validate()
+			if err != nil {
+				return fmt.Errorf("caller %s: allow[%d]: %w", c.Name, j, err)
+			}
+		}
+	}
+
+	return nil
+}
+
+// digest returns the SHA-256 of the caller's token.
+func (c *Caller) digest() ([]byte, error) {
+	switch {
+	case c.Token != "" && c.TokenSHA256 != "":
+		return nil, fmt.Errorf("only one of token and token_sha256 may be set")
+	case c.Token != "":
+		sum := sha256.Sum256([]byte(c.Token))
+		return sum[:], nil
+	case c.TokenSHA256 != "":
+		b, err := hex.DecodeString(c.TokenSHA256)
+		if err != nil || len(b) != sha256.Size {
+			return nil, fmt.Errorf("token_sha256 is not a hex SHA-256 digest")
+		}
+		return b, nil
+	}
+
+	return nil, fmt.Errorf("token or token_sha256 is required")
+}
+
+func (r *Rule) validate() error {
+	if len(r.Methods) == 0 || len(r.Paths) == 0 {
+		return fmt.Errorf("methods and paths are required")
+	}
+
+	for _, p := range r.Paths {
+		if !strings.HasPrefix(p, "/") {
+			return fmt.Errorf("path %q must start with /", p)
+		}
+
+		_, err := path.Match(strings.TrimSuffix(p, "/**"), "")
+		if err != nil {
+			return fmt.Errorf("path %q: %w", p, err)
+		}
+	}
+
+	return nil
+}
+
+// allows reports whether the rule allows method on p.
+func (r *Rule) allows(method string, p string) bool {
+	methodOK := false
+	for _, m := range r.Methods {
+		if m == "*" || strings.EqualFold(m, method) {
+			methodOK = true
+			break
+		}
+	}
+	if !methodOK {
+		return false
+	}
+
+	for _, pattern := range r.Paths {
+		if matchPath(pattern, p) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// matchPath matches p against a pattern of Rule.Paths. Trailing slashes
+// are ignored.
+func matchPath(pattern string, p string) bool {
+	p = trimSlash(p)
+
+	if strings.HasSuffix(pattern, "/**") {
+		prefix := strings.TrimSuffix(pattern, "/**")
+
+		// p or any of its parents must match the prefix.
+		for ; p != "/" && p != "."; p = path.Dir(p) {
+			if ok, _ := path.Match(prefix, p); ok {
+				return true
+			}
+		}
+
+		return false
+	}
+
+	ok, _ := path.Match(trimSlash(pattern), p)
+	return ok
+}
+
+func trimSlash(p string) string {
+	if len(p) > 1 {
+		return strings.TrimSuffix(p, "/")
+	}
+
+	return p
+}
+
+// caller is a caller with its token digest and rate limiter.
+type caller struct {
+	*Caller
+
+	digest  []byte
+	limiter *limiter
+}
+
+// callers authenticates bearer tokens.
+type callers []*caller
+
+func newCallers(p *Policy) (callers, error) {
+	cs := make(callers, len(p.Callers))
+
+	for i := range p.Callers {
+		c := &p.Callers[i]
+
+		digest, err := c.digest()
+		if err != nil {
+			return nil, fmt.Errorf("caller %s: %w", c.Name, err)
+		}
+
+		cs[i] = &caller{Caller: c, digest: digest, limiter: newLimiter(c.RateLimit, c.Burst)}
+	}
+
+	return cs, nil
+}
+
+// authenticate returns the caller of r, if its bearer token is known.
+//
+// Every caller is compared in constant time, so that timing does not reveal
+// which tokens are close to a valid one.
+func (cs callers) authenticate(r *http.Request) *caller {
+	auth := r.Header.Get("Authorization")
+	if !strings.HasPrefix(auth, "Bearer ") {
+		return nil
+	}
+
+	token := strings.TrimPrefix(auth, "Bearer ")
+	if token == "" {
+		return nil
+	}
+
+	sum := sha256.Sum256([]byte(token))
+
+	var found *caller
+	for _, c := range cs {
+		if subtle.ConstantTimeCompare(sum[:], c.digest) == 1 {
+			found = c
+		}
+	}
+
+	return found
+}
+
+// allows reports whether the caller may make a request with method to p.
+func (c *caller) allows(method string, p string) bool {
+	for i := range c.Allow {
+		if c.Allow[i].allows(method, p) {
+			return true
+		}
+	}
+
+	return false
+}
@@ -10,6 +10,7 @@ import (
 	. "github.com/onsi/gomega"
 
 	"github.com/vivangkumar/form3-http-go/pkg/account"
+	accountclient "github.com/vivangkumar/form3-http-go/pkg/account/client"
 	"github.com/vivangkumar/form3-http-go/pkg/client"
 	"github.com/vivangkumar/form3-http-go/pkg/form3"
 )
@@ -189,6 +190,38 @@ var _ = Describe("Accounts API", func() {
 			})
 		})
 
+		When("the latest version is deleted", func() {
+			It("should delete the account without a version", func() {
+				resp, err := cl.Accounts.DeleteLatest(ctx, created.ID)
+				Expect(err).To(BeNil())
+				Expect(resp).To(Not(BeNil()))
+
+				_, err = cl.Accounts.Fetch(ctx, account.FetchAccountParams{ID: created.ID})
+				Expect(err).To(Not(BeNil()))
+			})
+
+			Context("and the account is already deleted", func() {
+				BeforeEach(func() {
+					_, err := cl.Accounts.DeleteLatest(ctx, created.ID)
+					Expect(err).To(BeNil())
+				})
+
+				It("should return a not found error", func() {
+					_, err := cl.Accounts.DeleteLatest(ctx, created.ID)
+					Expect(err).To(Not(BeNil()))
+
+					var e errResponse
+					Expect(errors.As(err, &e)).To(BeTrue())
+					Expect(e.HTTPResponse().StatusCode).To(Equal(http.StatusNotFound))
+				})
+
+				It("should succeed when not found is ignored", func() {
+					_, err := cl.Accounts.DeleteLatest(ctx, created.ID, accountclient.IgnoreNotFound())
+					Expect(err).To(BeNil())
+				})
+			})
+		})
+
 		When("an unknown account ID is passed", func() {
 			It("should return a not found error", func() {
 				resp, err := cl.Accounts.Delete(ctx, account.DeleteAccountParams{
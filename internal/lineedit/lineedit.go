@@ -0,0 +1,367 @@
+// Package lineedit reads lines from a terminal with editing, history and
+// tab completion.
+//
+// The editor reads raw key presses, so the terminal must be put in raw mode,
+// see MakeRaw, while a line is read.
+package lineedit
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"unicode"
+)
+
+// ErrInterrupted is returned by ReadLine when Ctrl-C is pressed.
+var ErrInterrupted = errors.New("interrupted")
+
+// defaultMaxHistory is the number of lines kept in the history.
+const defaultMaxHistory = 500
+
+// Key presses.
+const (
+	keyCtrlA     = 0x01
+	keyCtrlC     = 0x03
+	keyCtrlD     = 0x04
+	keyCtrlE     = 0x05
+	keyCtrlH     = 0x08
+	keyTab       = 0x09
+	keyLF        = 0x0a
+	keyCtrlK     = 0x0b
+	keyCtrlL     = 0x0c
+	keyCR        = 0x0d
+	keyCtrlN     = 0x0e
+	keyCtrlP     = 0x10
+	keyCtrlU     = 0x15
+	keyCtrlW     = 0x17
+	keyEscape    = 0x1b
+	keyBackspace = 0x7f
+)
+
+// Completer returns the completions of the last word of line, which holds
+// the text before the cursor.
+//
+// Completions replace the whole word.
+type Completer func(line string) []string
+
+// Opt represents an option that can be passed to New.
+type Opt func(e *Editor)
+
+// WithCompleter completes words when tab is pressed.
+func WithCompleter(c Completer) Opt {
+	return func(e *Editor) {
+		e.complete = c
+	}
+}
+
+// WithHistory sets the initial history, oldest line first.
+func WithHistory(lines []string) Opt {
+	return func(e *Editor) {
+		for _, line := range lines {
+			e.AddHistory(line)
+		}
+	}
+}
+
+// WithMaxHistory sets the number of lines kept in the history.
+//
+// It is 500 by default.
+func WithMaxHistory(n int) Opt {
+	return func(e *Editor) {
+		e.maxHistory = n
+	}
+}
+
+// Editor reads lines from a terminal.
+type Editor struct {
+	in  *bufio.Reader
+	out io.Writer
+
+	complete   Completer
+	history    []string
+	maxHistory int
+
+	// State of the line being read.
+	prompt string
+	buf    []rune
+	pos    int
+}
+
+// New returns an editor reading key presses from in and echoing to out.
+func New(in io.Reader, out io.Writer, opts ...Opt) *Editor {
+	e := &Editor{
+		in:         bufio.NewReader(in),
+		out:        out,
+		maxHistory: defaultMaxHistory,
+	}
+
+	for _, opt := range opts {
+		opt(e)
+	}
+
+	return e
+}
+
+// AddHistory adds line to the history.
+//
+// Empty lines and repeats of the previous line are not added.
+func (e *Editor) AddHistory(line string) {
+	if strings.TrimSpace(line) == "" {
+		return
+	}
+
+	if n := len(e.history); n > 0 && e.history[n-1] == line {
+		return
+	}
+
+	e.history = append(e.history, line)
+	if len(e.history) > e.maxHistory {
+		e.history = e.history[len(e.history)-e.maxHistory:]
+	}
+}
+
+// History returns the history, oldest line first.
+func (e *Editor) History() []string {
+	return append([]string(nil), e.history...)
+}
+
+// ReadLine prints prompt and returns the line typed, without the newline.
+//
+// It returns io.EOF when the input ends, or Ctrl-D is pressed on an empty
+// line, and ErrInterrupted when Ctrl-C is pressed. Lines are not added to
+// the history; use AddHistory.
+func (e *Editor) ReadLine(prompt string) (string, error) {
+	e.prompt = prompt
+	e.buf = e.buf[:0]
+	e.pos = 0
+
+	// Index of the history entry shown, len(history) being the new line.
+	hist := len(e.history)
+	var draft []rune
+
+	e.refresh()
+
+	for {
+		r, _, err := e.in.ReadRune()
+		if err != nil {
+			if errors.Is(err, io.EOF) && len(e.buf) > 0 {
+				e.write("\r\n")
+				return string(e.buf), nil
+			}
+
+			return "", err
+		}
+
+		switch r {
+		case keyCR, keyLF:
+			e.write("\r\n")
+			return string(e.buf), nil
+		case keyCtrlC:
+			e.write("^C\r\n")
+			return "", ErrInterrupted
+		case keyCtrlD:
+			if len(e.buf) == 0 {
+				e.write("\r\n")
+				return "", io.EOF
+			}
+			e.deleteRune()
+		case keyBackspace, keyCtrlH:
+			if e.pos > 0 {
+				e.pos--
+				e.deleteRune()
+			}
+		case keyTab:
+			e.completeWord()
+		case keyCtrlA:
+			e.pos = 0
+		case keyCtrlE:
+			e.pos = len(e.buf)
+		case keyCtrlK:
+			e.buf = e.buf[:e.pos]
+		case keyCtrlU:
+			e.buf = append(e.buf[:0], e.buf[e.pos:]...)
+			e.pos = 0
+		case keyCtrlW:
+			e.deleteWord()
+		case keyCtrlL:
+			e.write("\x1b[H\x1b[2J")
+		case keyCtrlP:
+			hist, draft = e.showHistory(hist, hist-1, draft)
+		case keyCtrlN:
+			hist, draft = e.showHistory(hist, hist+1, draft)
+		case keyEscape:
+			switch e.readEscape() {
+			case 'A':
+				hist, draft = e.showHistory(hist, hist-1, draft)
+			case 'B':
+				hist, draft = e.showHistory(hist, hist+1, draft)
+			case 'C':
+				if e.pos < len(e.buf) {
+					e.pos++
+				}
+			case 'D':
+				if e.pos > 0 {
+					e.pos--
+				}
+			case 'H':
+				e.pos = 0
+			case 'F':
+				e.pos = len(e.buf)
+			case '3':
+				e.deleteRune()
+			}
+		default:
+			if unicode.IsPrint(r) {
+				e.insert([]rune{r})
+			}
+		}
+
+		e.refresh()
+	}
+}
+
+// readEscape reads the rest of an escape sequence and returns its final
+// byte, or the parameter of sequences such as the delete key, ESC [ 3 ~.
+func (e *Editor) readEscape() rune {
+	r, _, err := e.in.ReadRune()
+	if err != nil || (r != '[' && r != 'O') {
+		return 0
+	}
+
+	var param rune
+	for {
+		r, _, err := e.in.ReadRune()
+		if err != nil {
+			return 0
+		}
+
+		switch {
+		case r >= '0' && r <= '9' || r == ';':
+			if param == 0 {
+				param = r
+			}
+		case r == '~':
+			return param
+		default:
+			return r
+		}
+	}
+}
+
+// showHistory replaces the line with the history entry at index to, keeping
+// the new line as a draft while the history is browsed.
+func (e *Editor) showHistory(from int, to int, draft []rune) (int, []rune) {
+	if to < 0 || to > len(e.history) {
+		return from, draft
+	}
+
+	if from == len(e.history) {
+		draft = append([]rune(nil), e.buf...)
+	}
+
+	if to == len(e.history) {
+		e.buf = append(e.buf[:0], draft...)
+	} else {
+		e.buf = append(e.buf[:0], []rune(e.history[to])...)
+	}
+	e.pos = len(e.buf)
+
+	return to, draft
+}
+
+func (e *Editor) insert(rs []rune) {
+	tail := append([]rune(nil), e.buf[e.pos:]...)
+	e.buf = append(append(e.buf[:e.pos], rs...), tail...)
+	e.pos += len(rs)
+}
+
+// deleteRune deletes the rune under the cursor.
+func (e *Editor) deleteRune() {
+	if e.pos < len(e.buf) {
+		e.buf = append(e.buf[:e.pos], e.buf[e.pos+1:]...)
+	}
+}
+
+// deleteWord deletes the word before the cursor.
+func (e *Editor) deleteWord() {
+	start := e.pos
+	for start > 0 && e.buf[start-1] == ' ' {
+		start--
+	}
+	for start > 0 && e.buf[start-1] != ' ' {
+		start--
+	}
+
+	e.buf = append(e.buf[:start], e.buf[e.pos:]...)
+	e.pos = start
+}
+
+// completeWord completes the word before the cursor. If the completions
+// share no longer prefix than the word, they are listed instead.
+func (e *Editor) completeWord() {
+	if e.complete == nil {
+		return
+	}
+
+	line := string(e.buf[:e.pos])
+	word := line[strings.LastIndexByte(line, ' ')+1:]
+
+	var matches []string
+	for _, c := range e.complete(line) {
+		if strings.HasPrefix(c, word) {
+			matches = append(matches, c)
+		}
+	}
+
+	switch len(matches) {
+	case 0:
+		return
+	case 1:
+		completion := matches[0][len(word):]
+		// Words are finished with a space, unless they are a path that may
+		// continue.
+		if !strings.HasSuffix(matches[0], "/") {
+			completion += " "
+		}
+		e.insert([]rune(completion))
+
+		return
+	}
+
+	prefix := commonPrefix(matches)
+	if len(prefix) > len(word) {
+		e.insert([]rune(prefix[len(word):]))
+		return
+	}
+
+	sort.Strings(matches)
+	e.write("\r\n" + strings.Join(matches, "  ") + "\r\n")
+}
+
+func commonPrefix(words []string) string {
+	prefix := words[0]
+	for _, w := range words[1:] {
+		for !strings.HasPrefix(w, prefix) {
+			prefix = prefix[:len(prefix)-1]
+		}
+	}
+
+	return prefix
+}
+
+// refresh redraws the line and places the cursor.
+func (e *Editor) refresh() {
+	s := "\r" + e.prompt + string(e.buf) + "\x1b[K"
+	if n := len(e.buf) - e.pos; n > 0 {
+		s += fmt.Sprintf("\x1b[%dD", n)
+	}
+
+	e.write(s)
+}
+
+func (e *Editor) write(s string) {
+	io.WriteString(e.out, s)
+}
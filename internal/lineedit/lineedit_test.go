@@ -0,0 +1,118 @@
+package lineedit_test
+
+import (
+	"bytes"
+	"io"
+	"strings"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"github.com/vivangkumar/form3-http-go/internal/lineedit"
+)
+
+const (
+	up    = "\x1b[A"
+	down  = "\x1b[B"
+	left  = "\x1b[D"
+	del   = "\x1b[3~"
+	tab   = "\t"
+	enter = "\r"
+)
+
+var _ = Describe("Editor", func() {
+	var out *bytes.Buffer
+
+	BeforeEach(func() {
+		out = new(bytes.Buffer)
+	})
+
+	readLines := func(e *lineedit.Editor, n int) []string {
+		var lines []string
+		for i := 0; i < n; i++ {
+			line, err := e.ReadLine("> ")
+			Expect(err).NotTo(HaveOccurred())
+			lines = append(lines, line)
+		}
+
+		return lines
+	}
+
+	It("should edit the line", func() {
+		e := lineedit.New(strings.NewReader("helo"+left+left+"l"+"\x7f"+"l"+"\x05!"+enter+
+			"abc"+left+left+del+enter+
+			"get /v1/accounts"+"\x17"+"x"+enter), out)
+
+		Expect(readLines(e, 3)).To(Equal([]string{"hello!", "ac", "get x"}))
+		Expect(out.String()).To(HavePrefix("\r> \x1b[K"))
+	})
+
+	It("should browse the history", func() {
+		e := lineedit.New(
+			strings.NewReader(up+up+enter+up+up+up+down+enter+"dra"+up+down+"ft"+enter),
+			out,
+			lineedit.WithHistory([]string{"first", "second", "second", ""}),
+		)
+		Expect(e.History()).To(Equal([]string{"first", "second"}))
+
+		lines := readLines(e, 1)
+		Expect(lines).To(Equal([]string{"first"}))
+
+		e.AddHistory(lines[0])
+		Expect(readLines(e, 2)).To(Equal([]string{"second", "draft"}))
+	})
+
+	It("should keep the most recent history", func() {
+		e := lineedit.New(strings.NewReader(""), out, lineedit.WithMaxHistory(2))
+		for _, line := range []string{"a", "b", "c"} {
+			e.AddHistory(line)
+		}
+
+		Expect(e.History()).To(Equal([]string{"b", "c"}))
+	})
+
+	It("should complete the word before the cursor", func() {
+		var seen []string
+
+		e := lineedit.New(
+			strings.NewReader("ge"+tab+"/v1/o"+tab+"ac"+tab+tab+"1"+tab+enter+"x"+tab+enter),
+			out,
+			lineedit.WithCompleter(func(line string) []string {
+				seen = append(seen, line)
+
+				if !strings.Contains(line, " ") {
+					return []string{"get", "post"}
+				}
+
+				return []string{"/v1/organisation/", "/v1/organisation/accounts/1a", "/v1/organisation/accounts/2b"}
+			}),
+		)
+
+		Expect(readLines(e, 2)).To(Equal([]string{"get /v1/organisation/accounts/1a ", "x"}))
+		Expect(seen[0]).To(Equal("ge"))
+		Expect(seen[1]).To(Equal("get /v1/o"))
+
+		// Ambiguous completions are listed.
+		Expect(out.String()).To(ContainSubstring("\r\n/v1/organisation/accounts/1a  /v1/organisation/accounts/2b\r\n"))
+	})
+
+	It("should report Ctrl-C and Ctrl-D", func() {
+		e := lineedit.New(strings.NewReader("abc\x03x\x01\x04"+enter+"\x04"), out)
+
+		_, err := e.ReadLine("> ")
+		Expect(err).To(MatchError(lineedit.ErrInterrupted))
+
+		Expect(readLines(e, 1)).To(Equal([]string{""}))
+
+		_, err = e.ReadLine("> ")
+		Expect(err).To(MatchError(io.EOF))
+	})
+
+	It("should return the last line at the end of the input", func() {
+		e := lineedit.New(strings.NewReader("last"), out)
+		Expect(readLines(e, 1)).To(Equal([]string{"last"}))
+
+		_, err := e.ReadLine("> ")
+		Expect(err).To(MatchError(io.EOF))
+	})
+})
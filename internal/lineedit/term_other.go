@@ -0,0 +1,20 @@
+//go:build !linux && !darwin
+
+package lineedit
+
+import (
+	"errors"
+	"os"
+)
+
+// IsTerminal reports whether f is a terminal.
+//
+// It is always false on this platform, so lines are read without editing.
+func IsTerminal(f *os.File) bool {
+	return false
+}
+
+// MakeRaw is not supported on this platform.
+func MakeRaw(f *os.File) (restore func() error, err error) {
+	return nil, errors.New("raw terminal mode is not supported")
+}
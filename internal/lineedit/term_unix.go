@@ -0,0 +1,46 @@
+//go:build linux || darwin
+
+package lineedit
+
+import (
+	"fmt"
+	"os"
+
+	"golang.org/x/sys/unix"
+)
+
+// IsTerminal reports whether f is a terminal.
+func IsTerminal(f *os.File) bool {
+	_, err := unix.IoctlGetTermios(int(f.Fd()), ioctlGetTermios)
+	return err == nil
+}
+
+// MakeRaw puts the terminal f in raw mode, so that key presses are read as
+// they are typed and are not echoed. The returned function restores the
+// previous mode.
+func MakeRaw(f *os.File) (restore func() error, err error) {
+	fd := int(f.Fd())
+
+	prev, err := unix.IoctlGetTermios(fd, ioctlGetTermios)
+	if err != nil {
+		return nil, fmt.Errorf("get terminal mode: %w", err)
+	}
+
+	raw := *prev
+	raw.Iflag &^= unix.IGNBRK | unix.BRKINT | unix.PARMRK | unix.ISTRIP | unix.INLCR | unix.IGNCR | unix.ICRNL | unix.IXON
+	raw.Oflag &^= unix.OPOST
+	raw.Lflag &^= unix.ECHO | unix.ECHONL | unix.ICANON | unix.ISIG | unix.IEXTEN
+	raw.Cflag &^= unix.CSIZE | unix.PARENB
+	raw.Cflag |= unix.CS8
+	raw.Cc[unix.VMIN] = 1
+	raw.Cc[unix.VTIME] = 0
+
+	err = unix.IoctlSetTermios(fd, ioctlSetTermios, &raw)
+	if err != nil {
+		return nil, fmt.Errorf("set terminal mode: %w", err)
+	}
+
+	return func() error {
+		return unix.IoctlSetTermios(fd, ioctlSetTermios, prev)
+	}, nil
+}
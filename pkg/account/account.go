@@ -2,18 +2,84 @@
 package account
 
 import (
+	"encoding/json"
+
 	"github.com/google/uuid"
+
+	"github.com/vivangkumar/form3-http-go/pkg/client/jsonapi"
 )
 
 const accountsType = "accounts"
 
 // Account represents the domain model for a bank account.
 type Account struct {
-	Attributes     *Attributes `json:"attributes,omitempty"`
-	ID             string      `json:"id,omitempty"`
-	OrganisationID string      `json:"organisation_id,omitempty"`
-	Type           string      `json:"type,omitempty"`
-	Version        *int64      `json:"version,omitempty"`
+	Attributes     *Attributes    `json:"attributes,omitempty"`
+	ID             string         `json:"id,omitempty"`
+	OrganisationID string         `json:"organisation_id,omitempty"`
+	Type           string         `json:"type,omitempty"`
+	Version        *int64         `json:"version,omitempty"`
+	Relationships  *Relationships `json:"relationships,omitempty"`
+
+	// Extra holds fields returned by the API that are not modelled.
+	//
+	// They are sent back to the API when the account is encoded.
+	Extra map[string]json.RawMessage `json:"-"`
+}
+
+// UnmarshalJSON implements json.Unmarshaler.
+//
+// Fields that are not modelled are kept in Extra.
+func (a *Account) UnmarshalJSON(data []byte) error {
+	type account Account
+
+	var acc account
+	extra, err := unmarshalWithExtra(data, &acc)
+	if err != nil {
+		return err
+	}
+	acc.Extra = extra
+
+	*a = Account(acc)
+
+	return nil
+}
+
+// MarshalJSON implements json.Marshaler.
+//
+// Fields held in Extra are included.
+func (a Account) MarshalJSON() ([]byte, error) {
+	type account Account
+	return marshalWithExtra(account(a), a.Extra)
+}
+
+// UnknownFields returns the JSON paths of fields held in Extra,
+// including those of the attributes, the identifications they hold and
+// the relationships.
+func (a *Account) UnknownFields() []string {
+	fields := extraKeys("", a.Extra)
+	if a.Attributes != nil {
+		fields = append(fields, a.Attributes.unknownFields("attributes")...)
+	}
+
+	if a.Relationships != nil {
+		fields = append(fields, extraKeys("relationships", a.Relationships.Extra)...)
+	}
+
+	return fields
+}
+
+// Validate returns an *UnknownEnumError if the account holds an enum
+// value that is not known.
+func (a *Account) Validate() error {
+	if a.Attributes == nil {
+		return nil
+	}
+
+	if e := a.Attributes.unknownEnum().in("attributes"); e != nil {
+		return e
+	}
+
+	return nil
 }
 
 // NewAccountWithID returns a builder for Account with a generated
@@ -58,14 +124,115 @@ func (a *Account) WithAttributes(attrs *Attributes) *Account {
 	return a
 }
 
+// WithRelationships sets the relationships of an account.
+func (a *Account) WithRelationships(rels *Relationships) *Account {
+	a.Relationships = rels
+	return a
+}
+
+// WithMasterAccount relates the account to its master account.
+func (a *Account) WithMasterAccount(id string) *Account {
+	if a.Relationships == nil {
+		a.Relationships = &Relationships{}
+	}
+
+	a.Relationships.MasterAccount = &Relationship{
+		Data: []ResourceIdentifier{{Type: accountsType, ID: id}},
+	}
+
+	return a
+}
+
+// Relationships represents the resources related to an account.
+type Relationships struct {
+	MasterAccount *Relationship `json:"master_account,omitempty"`
+	AccountEvents *Relationship `json:"account_events,omitempty"`
+
+	// Extra holds relationships returned by the API that are not modelled.
+	Extra map[string]json.RawMessage `json:"-"`
+}
+
+// UnmarshalJSON implements json.Unmarshaler.
+//
+// Fields that are not modelled are kept in Extra.
+func (r *Relationships) UnmarshalJSON(data []byte) error {
+	type relationships Relationships
+
+	var v relationships
+	extra, err := unmarshalWithExtra(data, &v)
+	if err != nil {
+		return err
+	}
+	v.Extra = extra
+
+	*r = Relationships(v)
+
+	return nil
+}
+
+// MarshalJSON implements json.Marshaler.
+//
+// Fields held in Extra are included.
+func (r Relationships) MarshalJSON() ([]byte, error) {
+	type relationships Relationships
+	return marshalWithExtra(relationships(r), r.Extra)
+}
+
+// Relationship holds identifiers of related resources.
+type Relationship = jsonapi.Relationship
+
+// ResourceIdentifier identifies a resource by type and ID.
+type ResourceIdentifier = jsonapi.ResourceIdentifier
+
 // Response returns the response from account creation and fetch requests.
-type Response struct {
-	// Data contains the account returned as part of the response
-	Data *Account `json:"data,omitempty"`
+//
+// Data contains the account returned as part of the response.
+// Links are always returned as part of the response, except in cases of
+// no content responses.
+type Response jsonapi.Document[Account]
 
-	// Links are always returned as part of the response.
-	// Except in cases of no content responses.
-	Links *Links `json:"links,omitempty"`
+// ListResponse returns the response from account list requests.
+//
+// Links can be used to page through the accounts.
+type ListResponse jsonapi.ListDocument[Account]
+
+// Validate returns an error wrapping an *UnknownEnumError if the response
+// holds an enum value that is not known. As for jsonapi.Document, the
+// error is prefixed with "data", and the field is relative to the account.
+func (r *Response) Validate() error {
+	return (*jsonapi.Document[Account])(r).Validate()
+}
+
+// UnknownFields returns the JSON paths of fields in the response that
+// are not modelled.
+func (r *Response) UnknownFields() []string {
+	if r.Data == nil {
+		return nil
+	}
+
+	var fields []string
+	for _, f := range r.Data.UnknownFields() {
+		fields = append(fields, joinPath("data", f))
+	}
+
+	return fields
+}
+
+// MasterAccount returns the master account of the primary account.
+//
+// It returns nil if the account has no master account. Otherwise the master
+// account must be part of the included resources.
+func (r *Response) MasterAccount() (*Account, error) {
+	if r.Data == nil || r.Data.Relationships == nil {
+		return nil, nil
+	}
+
+	id, ok := r.Data.Relationships.MasterAccount.One()
+	if !ok {
+		return nil, nil
+	}
+
+	return jsonapi.Resolve[Account](r.Included, id)
 }
 
 // DeleteResponse is an empty response type to convey
@@ -73,10 +240,4 @@ type Response struct {
 type DeleteResponse struct{}
 
 // Links represents the HATEOAS convention links sent as part of responses.
-type Links struct {
-	Self  string  `json:"self"`
-	First *string `json:"first,omitempty"`
-	Last  *string `json:"last,omitempty"`
-	Next  *string `json:"next,omitempty"`
-	Prev  *string `json:"prev,omitempty"`
-}
+type Links = jsonapi.Links
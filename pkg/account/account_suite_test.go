@@ -0,0 +1,13 @@
+package account_test
+
+import (
+	"testing"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+func TestAccount(t *testing.T) {
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "Account Suite")
+}
@@ -1,22 +1,179 @@
 package account
 
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"unicode"
+)
+
 // Attributes represents the domain model for account attributes.
 type Attributes struct {
-	AccountClassification   *string  `json:"account_classification,omitempty"`
-	AccountMatchingOptOut   *bool    `json:"account_matching_opt_out,omitempty"`
-	AccountNumber           string   `json:"account_number,omitempty"`
-	BankID                  string   `json:"bank_id,omitempty"`
-	BankIDCode              string   `json:"bank_id_code,omitempty"`
-	BaseCurrency            string   `json:"base_currency,omitempty"`
-	Bic                     string   `json:"bic,omitempty"`
-	Country                 string   `json:"country,omitempty"`
-	Iban                    string   `json:"iban,omitempty"`
-	JointAccount            *bool    `json:"joint_account,omitempty"`
-	SecondaryIdentification string   `json:"secondary_identification,omitempty"`
-	Status                  *string  `json:"status,omitempty"`
-	Switched                *bool    `json:"switched,omitempty"`
-	CustomerID              *string  `json:"customer_id,omitempty"`
-	Name                    []string `json:"name,omitempty"`
+	AccountClassification      *Classification             `json:"account_classification,omitempty"`
+	AccountMatchingOptOut      *bool                       `json:"account_matching_opt_out,omitempty"`
+	AccountNumber              string                      `json:"account_number,omitempty"`
+	BankID                     string                      `json:"bank_id,omitempty"`
+	BankIDCode                 BankIDCode                  `json:"bank_id_code,omitempty"`
+	BaseCurrency               string                      `json:"base_currency,omitempty"`
+	Bic                        string                      `json:"bic,omitempty"`
+	Country                    string                      `json:"country,omitempty"`
+	Iban                       string                      `json:"iban,omitempty"`
+	JointAccount               *bool                       `json:"joint_account,omitempty"`
+	SecondaryIdentification    string                      `json:"secondary_identification,omitempty"`
+	Status                     *Status                     `json:"status,omitempty"`
+	Switched                   *bool                       `json:"switched,omitempty"`
+	CustomerID                 *string                     `json:"customer_id,omitempty"`
+	Name                       []string                    `json:"name,omitempty"`
+	AlternativeNames           []string                    `json:"alternative_names,omitempty"`
+	NameMatchingStatus         string                      `json:"name_matching_status,omitempty"`
+	ProcessingService          string                      `json:"processing_service,omitempty"`
+	UserDefinedInformation     string                      `json:"user_defined_information,omitempty"`
+	ValidationType             string                      `json:"validation_type,omitempty"`
+	ReferenceMask              string                      `json:"reference_mask,omitempty"`
+	AcceptanceQualifier        string                      `json:"acceptance_qualifier,omitempty"`
+	OrganisationIdentification *OrganisationIdentification `json:"organisation_identification,omitempty"`
+	PrivateIdentification      *PrivateIdentification      `json:"private_identification,omitempty"`
+
+	// Extra holds attributes returned by the API that are not modelled.
+	//
+	// They are sent back to the API when the attributes are encoded.
+	Extra map[string]json.RawMessage `json:"-"`
+}
+
+// UnmarshalJSON implements json.Unmarshaler.
+//
+// Attributes that are not modelled are kept in Extra.
+func (a *Attributes) UnmarshalJSON(data []byte) error {
+	type attributes Attributes
+
+	var attrs attributes
+	extra, err := unmarshalWithExtra(data, &attrs)
+	if err != nil {
+		return err
+	}
+	attrs.Extra = extra
+
+	*a = Attributes(attrs)
+
+	return nil
+}
+
+// MarshalJSON implements json.Marshaler.
+//
+// Attributes held in Extra are included.
+func (a Attributes) MarshalJSON() ([]byte, error) {
+	type attributes Attributes
+	return marshalWithExtra(attributes(a), a.Extra)
+}
+
+// OrganisationIdentification identifies an account held by an organisation.
+type OrganisationIdentification struct {
+	Identification string   `json:"identification,omitempty"`
+	Actors         []Actor  `json:"actors,omitempty"`
+	Address        []string `json:"address,omitempty"`
+	City           string   `json:"city,omitempty"`
+	Country        string   `json:"country,omitempty"`
+	// Extra holds fields returned by the API that are not modelled.
+	Extra map[string]json.RawMessage `json:"-"`
+}
+
+// UnmarshalJSON implements json.Unmarshaler.
+//
+// Fields that are not modelled are kept in Extra.
+func (o *OrganisationIdentification) UnmarshalJSON(data []byte) error {
+	type identification OrganisationIdentification
+
+	var v identification
+	extra, err := unmarshalWithExtra(data, &v)
+	if err != nil {
+		return err
+	}
+	v.Extra = extra
+
+	*o = OrganisationIdentification(v)
+
+	return nil
+}
+
+// MarshalJSON implements json.Marshaler.
+//
+// Fields held in Extra are included.
+func (o OrganisationIdentification) MarshalJSON() ([]byte, error) {
+	type identification OrganisationIdentification
+	return marshalWithExtra(identification(o), o.Extra)
+}
+
+// Actor represents a person acting on behalf of an organisation.
+type Actor struct {
+	Name      []string `json:"name,omitempty"`
+	BirthDate string   `json:"birth_date,omitempty"`
+	Residency string   `json:"residency,omitempty"`
+	// Extra holds fields returned by the API that are not modelled.
+	Extra map[string]json.RawMessage `json:"-"`
+}
+
+// UnmarshalJSON implements json.Unmarshaler.
+//
+// Fields that are not modelled are kept in Extra.
+func (a *Actor) UnmarshalJSON(data []byte) error {
+	type actor Actor
+
+	var v actor
+	extra, err := unmarshalWithExtra(data, &v)
+	if err != nil {
+		return err
+	}
+	v.Extra = extra
+
+	*a = Actor(v)
+
+	return nil
+}
+
+// MarshalJSON implements json.Marshaler.
+//
+// Fields held in Extra are included.
+func (a Actor) MarshalJSON() ([]byte, error) {
+	type actor Actor
+	return marshalWithExtra(actor(a), a.Extra)
+}
+
+// PrivateIdentification identifies an account held by a private individual.
+type PrivateIdentification struct {
+	BirthDate      string   `json:"birth_date,omitempty"`
+	BirthCountry   string   `json:"birth_country,omitempty"`
+	Identification string   `json:"identification,omitempty"`
+	Address        []string `json:"address,omitempty"`
+	City           string   `json:"city,omitempty"`
+	Country        string   `json:"country,omitempty"`
+	// Extra holds fields returned by the API that are not modelled.
+	Extra map[string]json.RawMessage `json:"-"`
+}
+
+// UnmarshalJSON implements json.Unmarshaler.
+//
+// Fields that are not modelled are kept in Extra.
+func (p *PrivateIdentification) UnmarshalJSON(data []byte) error {
+	type identification PrivateIdentification
+
+	var v identification
+	extra, err := unmarshalWithExtra(data, &v)
+	if err != nil {
+		return err
+	}
+	v.Extra = extra
+
+	*p = PrivateIdentification(v)
+
+	return nil
+}
+
+// MarshalJSON implements json.Marshaler.
+//
+// Fields held in Extra are included.
+func (p PrivateIdentification) MarshalJSON() ([]byte, error) {
+	type identification PrivateIdentification
+	return marshalWithExtra(identification(p), p.Extra)
 }
 
 // NewAttributes returns an account attribute builder.
@@ -31,7 +188,17 @@ func NewAttributes(baseCurrency string, country string) *Attributes {
 
 // WithAccountClassification sets the account classification.
 func (a *Attributes) WithAccountClassification(class *string) *Attributes {
-	a.AccountClassification = class
+	if class == nil {
+		a.AccountClassification = nil
+		return a
+	}
+
+	return a.WithAccountClassificationValue(Classification(*class))
+}
+
+// WithAccountClassificationValue sets the account classification from a typed value.
+func (a *Attributes) WithAccountClassificationValue(class Classification) *Attributes {
+	a.AccountClassification = &class
 	return a
 }
 
@@ -55,6 +222,11 @@ func (a *Attributes) WithBankID(id string) *Attributes {
 
 // WithBankIDCode sets the bank id code for the bank.
 func (a *Attributes) WithBankIDCode(code string) *Attributes {
+	return a.WithBankIDCodeValue(BankIDCode(code))
+}
+
+// WithBankIDCodeValue sets the bank id code for the bank from a typed value.
+func (a *Attributes) WithBankIDCodeValue(code BankIDCode) *Attributes {
 	a.BankIDCode = code
 	return a
 }
@@ -97,7 +269,17 @@ func (a *Attributes) WithSecondaryIdentification(sec string) *Attributes {
 
 // WithStatus sets the account status.
 func (a *Attributes) WithStatus(status *string) *Attributes {
-	a.Status = status
+	if status == nil {
+		a.Status = nil
+		return a
+	}
+
+	return a.WithStatusValue(Status(*status))
+}
+
+// WithStatusValue sets the account status from a typed value.
+func (a *Attributes) WithStatusValue(status Status) *Attributes {
+	a.Status = &status
 	return a
 }
 
@@ -118,3 +300,123 @@ func (a *Attributes) WithName(name string) *Attributes {
 	a.Name = append(a.Name, name)
 	return a
 }
+
+// WithAlternativeName adds an alternative name for the account.
+func (a *Attributes) WithAlternativeName(name string) *Attributes {
+	a.AlternativeNames = append(a.AlternativeNames, name)
+	return a
+}
+
+// WithNameMatchingStatus sets the name matching status.
+func (a *Attributes) WithNameMatchingStatus(status string) *Attributes {
+	a.NameMatchingStatus = status
+	return a
+}
+
+// WithProcessingService sets the processing service for the account.
+func (a *Attributes) WithProcessingService(service string) *Attributes {
+	a.ProcessingService = service
+	return a
+}
+
+// WithUserDefinedInformation sets free text information about the account.
+func (a *Attributes) WithUserDefinedInformation(info string) *Attributes {
+	a.UserDefinedInformation = info
+	return a
+}
+
+// WithValidationType sets the validation type for the account.
+func (a *Attributes) WithValidationType(typ string) *Attributes {
+	a.ValidationType = typ
+	return a
+}
+
+// WithReferenceMask sets the reference mask for the account.
+func (a *Attributes) WithReferenceMask(mask string) *Attributes {
+	a.ReferenceMask = mask
+	return a
+}
+
+// WithAcceptanceQualifier sets the acceptance qualifier for the account.
+func (a *Attributes) WithAcceptanceQualifier(qualifier string) *Attributes {
+	a.AcceptanceQualifier = qualifier
+	return a
+}
+
+// WithOrganisationIdentification sets the identification of the
+// organisation holding the account.
+func (a *Attributes) WithOrganisationIdentification(id *OrganisationIdentification) *Attributes {
+	a.OrganisationIdentification = id
+	return a
+}
+
+// WithPrivateIdentification sets the identification of the
+// individual holding the account.
+func (a *Attributes) WithPrivateIdentification(id *PrivateIdentification) *Attributes {
+	a.PrivateIdentification = id
+	return a
+}
+
+// Validate returns an *UnknownEnumError if the attributes hold an enum
+// value that is not known.
+func (a *Attributes) Validate() error {
+	if e := a.unknownEnum(); e != nil {
+		return e
+	}
+
+	return nil
+}
+
+// unknownFields returns the JSON paths of the fields held in Extra by the
+// attributes and the identifications they hold, prefixed with path.
+func (a *Attributes) unknownFields(path string) []string {
+	fields := extraKeys(path, a.Extra)
+
+	if o := a.OrganisationIdentification; o != nil {
+		p := joinPath(path, "organisation_identification")
+		fields = append(fields, extraKeys(p, o.Extra)...)
+
+		for i, actor := range o.Actors {
+			fields = append(fields, extraKeys(fmt.Sprintf("%s.actors[%d]", p, i), actor.Extra)...)
+		}
+	}
+
+	if p := a.PrivateIdentification; p != nil {
+		fields = append(fields, extraKeys(joinPath(path, "private_identification"), p.Extra)...)
+	}
+
+	return fields
+}
+
+// unknownEnum returns the first enum value of the attributes that is not
+// known, or nil.
+func (a *Attributes) unknownEnum() *UnknownEnumError {
+	if a.AccountClassification != nil {
+		if e := checkEnum("account_classification", *a.AccountClassification); e != nil {
+			return e
+		}
+	}
+
+	if e := checkEnum("bank_id_code", a.BankIDCode); e != nil {
+		return e
+	}
+
+	if a.Status != nil {
+		return checkEnum("status", *a.Status)
+	}
+
+	return nil
+}
+
+// NormaliseIBAN removes white space, such as tabs and non-breaking spaces,
+// from an IBAN and upper cases it, so that IBANs match however they are
+// written.
+func NormaliseIBAN(iban string) string {
+	return strings.ToUpper(strings.Map(func(r rune) rune {
+		if unicode.IsSpace(r) {
+			return -1
+		}
+
+		return r
+	}, iban))
+}
@@ -0,0 +1,164 @@
+package account_test
+
+import (
+	"encoding/json"
+
+	"github.com/google/uuid"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"github.com/vivangkumar/form3-http-go/pkg/account"
+	"github.com/vivangkumar/form3-http-go/pkg/internal/fixtures"
+)
+
+var _ = Describe("Account attributes", func() {
+	var (
+		orgID     string
+		accountID string
+	)
+
+	BeforeEach(func() {
+		orgID = uuid.NewString()
+		accountID = uuid.NewString()
+	})
+
+	Describe("Round tripping responses", func() {
+		DescribeTable("should not lose any fields on decode and encode",
+			func(body func() string) {
+				var resp account.Response
+				Expect(json.Unmarshal([]byte(body()), &resp)).To(Succeed())
+				Expect(resp.Data.ID).To(Equal(accountID))
+				Expect(resp.Data.OrganisationID).To(Equal(orgID))
+
+				encoded, err := json.Marshal(resp)
+				Expect(err).To(BeNil())
+				Expect(encoded).To(MatchJSON(body()))
+			},
+			Entry("with all attributes", func() string {
+				return fixtures.AccountsResponseAllAttributes(accountID, orgID, "GB", "GBP")
+			}),
+			Entry("with all fields", func() string {
+				return fixtures.AccountsResponseAllFields(orgID, accountID, "FR", "EUR")
+			}),
+			Entry("with the minimum fields", func() string {
+				return fixtures.AccountsResponseMinFields(orgID, accountID, "FR", "EUR")
+			}),
+		)
+
+		It("should decode every attribute", func() {
+			body := fixtures.AccountsResponseAllAttributes(accountID, orgID, "GB", "GBP")
+
+			var resp account.Response
+			Expect(json.Unmarshal([]byte(body), &resp)).To(Succeed())
+
+			attrs := resp.Data.Attributes
+			Expect(attrs.AlternativeNames).To(Equal([]string{"Sam", "Sammy"}))
+			Expect(attrs.NameMatchingStatus).To(Equal("supported"))
+			Expect(attrs.ProcessingService).To(Equal("ABC Bank"))
+			Expect(attrs.UserDefinedInformation).To(Equal("Some important info"))
+			Expect(attrs.ValidationType).To(Equal("card"))
+			Expect(attrs.ReferenceMask).To(Equal("############"))
+			Expect(attrs.AcceptanceQualifier).To(Equal("same_day"))
+
+			Expect(attrs.OrganisationIdentification.Identification).To(Equal("123654"))
+			Expect(attrs.OrganisationIdentification.Actors).To(HaveLen(1))
+			Expect(attrs.OrganisationIdentification.Actors[0].Name).To(Equal([]string{"Jeff Page"}))
+			Expect(attrs.PrivateIdentification.BirthCountry).To(Equal("GB"))
+			Expect(attrs.PrivateIdentification.Address).To(Equal([]string{"10 Avenue des Champs"}))
+
+			rels := resp.Data.Relationships
+			Expect(rels.MasterAccount.Data).To(Equal([]account.ResourceIdentifier{
+				{Type: "accounts", ID: "a52d13a4-f435-4c00-cfad-f5e7ac5972df"},
+			}))
+			Expect(rels.AccountEvents.Data).To(HaveLen(1))
+			Expect(rels.AccountEvents.Data[0].Type).To(Equal("account_events"))
+		})
+	})
+
+	Describe("Building attributes", func() {
+		It("should set the new attributes", func() {
+			orgIdentification := &account.OrganisationIdentification{
+				Identification: "123654",
+				Actors:         []account.Actor{{Name: []string{"Jeff Page"}}},
+			}
+			privateIdentification := &account.PrivateIdentification{
+				Identification: "13YH458762",
+			}
+
+			attrs := account.NewAttributes("GBP", "GB").
+				WithAlternativeName("Sam").
+				WithAlternativeName("Sammy").
+				WithNameMatchingStatus("supported").
+				WithProcessingService("ABC Bank").
+				WithUserDefinedInformation("info").
+				WithValidationType("card").
+				WithReferenceMask("####").
+				WithAcceptanceQualifier("same_day").
+				WithOrganisationIdentification(orgIdentification).
+				WithPrivateIdentification(privateIdentification)
+
+			Expect(attrs.AlternativeNames).To(Equal([]string{"Sam", "Sammy"}))
+			Expect(attrs.NameMatchingStatus).To(Equal("supported"))
+			Expect(attrs.ProcessingService).To(Equal("ABC Bank"))
+			Expect(attrs.UserDefinedInformation).To(Equal("info"))
+			Expect(attrs.ValidationType).To(Equal("card"))
+			Expect(attrs.ReferenceMask).To(Equal("####"))
+			Expect(attrs.AcceptanceQualifier).To(Equal("same_day"))
+			Expect(attrs.OrganisationIdentification).To(Equal(orgIdentification))
+			Expect(attrs.PrivateIdentification).To(Equal(privateIdentification))
+		})
+
+		It("should relate an account to its master account", func() {
+			masterID := uuid.NewString()
+			acc := account.New(orgID).WithMasterAccount(masterID)
+
+			b, err := json.Marshal(acc)
+			Expect(err).To(BeNil())
+			Expect(b).To(MatchJSON(`{
+				"organisation_id": "` + orgID + `",
+				"type": "accounts",
+				"relationships": {
+					"master_account": {"data": [{"type": "accounts", "id": "` + masterID + `"}]}
+				}
+			}`))
+		})
+	})
+
+	Describe("Decoding documents", func() {
+		It("should decode meta and resolve included accounts", func() {
+			var resp account.Response
+			err := json.Unmarshal([]byte(`{
+				"data": {
+					"id": "1",
+					"type": "accounts",
+					"relationships": {
+						"master_account": {"data": {"type": "accounts", "id": "0"}}
+					}
+				},
+				"included": [
+					{"type": "accounts", "id": "0", "attributes": {"country": "GB"}}
+				],
+				"meta": {"total": 1}
+			}`), &resp)
+			Expect(err).To(BeNil())
+			Expect(resp.UnknownFields()).To(BeEmpty())
+
+			var total int
+			ok, err := resp.Meta.Lookup("total", &total)
+			Expect(err).To(BeNil())
+			Expect(ok).To(BeTrue())
+			Expect(total).To(Equal(1))
+
+			master, err := resp.MasterAccount()
+			Expect(err).To(BeNil())
+			Expect(master.ID).To(Equal("0"))
+			Expect(master.Attributes.Country).To(Equal("GB"))
+		})
+	})
+})
+
+var _ = Describe("NormaliseIBAN", func() {
+	It("removes white space and upper cases the IBAN", func() {
+		Expect(account.NormaliseIBAN("gb11 nwbk\t4003\u00a00041 4268 19\n")).To(Equal("GB11NWBK40030041426819"))
+	})
+})
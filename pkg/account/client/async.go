@@ -0,0 +1,57 @@
+package client
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+
+	"github.com/vivangkumar/form3-http-go/pkg/account"
+	"github.com/vivangkumar/form3-http-go/pkg/account/outbox"
+)
+
+// ErrNoOutbox is returned by CreateAsync when no outbox is open.
+var ErrNoOutbox = errors.New("no outbox is open")
+
+// asyncState holds the outbox of a Client.
+type asyncState struct {
+	outbox atomic.Pointer[outbox.Outbox]
+}
+
+// OpenOutbox opens the outbox in dir and starts creating the accounts
+// queued in it with this client, including those left by a previous run.
+//
+// Accounts passed to CreateAsync are queued in it from then on, until
+// another outbox is opened. Close the outbox to stop it.
+func (c *Client) OpenOutbox(dir string, opts ...outbox.Opt) (*outbox.Outbox, error) {
+	ob, err := outbox.Open(dir, c, opts...)
+	if err != nil {
+		return nil, err
+	}
+
+	c.async.outbox.Store(ob)
+
+	return ob, nil
+}
+
+// CreateAsync queues acc in the outbox opened with OpenOutbox, to be
+// created in the background, and returns a ticket once it is stored on disk.
+//
+// Use the Wait or Subscribe methods of the outbox to learn the outcome.
+// If the outbox is closed, ErrNoOutbox is returned.
+func (c *Client) CreateAsync(
+	ctx context.Context,
+	acc *account.Account,
+) (outbox.Ticket, error) {
+	ob := c.async.outbox.Load()
+	if ob == nil {
+		return "", ErrNoOutbox
+	}
+
+	t, err := ob.Enqueue(ctx, acc)
+	if errors.Is(err, outbox.ErrClosed) {
+		c.async.outbox.CompareAndSwap(ob, nil)
+		return "", ErrNoOutbox
+	}
+
+	return t, err
+}
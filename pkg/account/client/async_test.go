@@ -0,0 +1,86 @@
+package client_test
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"time"
+
+	"github.com/google/uuid"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"github.com/vivangkumar/form3-http-go/pkg/account"
+	"github.com/vivangkumar/form3-http-go/pkg/account/client"
+	"github.com/vivangkumar/form3-http-go/pkg/account/internal/fakes"
+)
+
+var _ = Describe("Asynchronous creates", func() {
+	var (
+		ctx            context.Context
+		fakeBaseClient *fakes.FakeBaseClient
+		cl             *client.Client
+	)
+
+	BeforeEach(func() {
+		ctx = context.Background()
+
+		fakeBaseClient = new(fakes.FakeBaseClient)
+		fakeBaseClient.PostStub = func(
+			ctx context.Context,
+			path string,
+			body any,
+			target any,
+		) (*http.Response, error) {
+			b, err := json.Marshal(body)
+			if err != nil {
+				return nil, err
+			}
+
+			err = json.Unmarshal(b, target)
+			if err != nil {
+				return nil, err
+			}
+
+			return &http.Response{StatusCode: http.StatusCreated}, nil
+		}
+
+		cl = client.New(fakeBaseClient)
+	})
+
+	It("should require an outbox", func() {
+		_, err := cl.CreateAsync(ctx, account.New(uuid.NewString()))
+		Expect(err).To(MatchError(client.ErrNoOutbox))
+	})
+
+	It("should create accounts through the outbox", func() {
+		ob, err := cl.OpenOutbox(GinkgoT().TempDir())
+		Expect(err).NotTo(HaveOccurred())
+		defer ob.Close()
+
+		acc := account.New(uuid.NewString()).WithID(uuid.NewString())
+
+		t, err := cl.CreateAsync(ctx, acc)
+		Expect(err).NotTo(HaveOccurred())
+
+		wctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+		defer cancel()
+
+		res, err := ob.Wait(wctx, t)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(res.Account.ID).To(Equal(acc.ID))
+
+		Expect(fakeBaseClient.PostCallCount()).To(Equal(1))
+		_, path, _, _ := fakeBaseClient.PostArgsForCall(0)
+		Expect(path).To(Equal("/v1/organisation/accounts/"))
+	})
+
+	It("should stop queueing once the outbox is closed", func() {
+		ob, err := cl.OpenOutbox(GinkgoT().TempDir())
+		Expect(err).NotTo(HaveOccurred())
+		Expect(ob.Close()).To(Succeed())
+
+		_, err = cl.CreateAsync(ctx, account.New(uuid.NewString()))
+		Expect(err).To(MatchError(client.ErrNoOutbox))
+	})
+})
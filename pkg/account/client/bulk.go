@@ -0,0 +1,341 @@
+package client
+
+import (
+	"context"
+	"errors"
+	"sync"
+
+	"github.com/vivangkumar/form3-http-go/pkg/account"
+	baseclient "github.com/vivangkumar/form3-http-go/pkg/client"
+)
+
+const defaultBulkConcurrency = 4
+
+// ErrSkipped is set on results for items that were not attempted because the
+// bulk operation was stopped early.
+var ErrSkipped = errors.New("skipped")
+
+// BulkResult is the outcome of a single item of a bulk operation.
+type BulkResult struct {
+	// Index is the position of the item in the input.
+	Index int
+
+	// Account is the account returned by the API, if any.
+	//
+	// It is not set for deletes.
+	Account *account.Account
+
+	// Err is the error the item failed with, if any.
+	Err error
+
+	// Attempts is the number of HTTP attempts made for the item.
+	Attempts int
+}
+
+// BulkProgress reports the progress of a bulk operation.
+type BulkProgress struct {
+	// Completed is the number of items that have finished, including failures.
+	Completed int
+
+	// Failed is the number of items that have failed.
+	Failed int
+
+	// Total is the number of items in the input.
+	//
+	// It is 0 when items are read from a channel.
+	Total int
+}
+
+// BulkOpt represents an option that can be passed to bulk operations.
+type BulkOpt func(o *bulkOpts)
+
+type bulkOpts struct {
+	concurrency int
+	failFast    bool
+	onProgress  func(BulkProgress)
+	onResult    func(BulkResult)
+}
+
+// Concurrency sets the maximum number of items processed at once.
+//
+// If not used, 4 items are processed at once.
+func Concurrency(n int) BulkOpt {
+	return func(o *bulkOpts) {
+		if n > 0 {
+			o.concurrency = n
+		}
+	}
+}
+
+// FailFast stops a bulk operation on the first failed item.
+//
+// Items in flight are cancelled and remaining items are skipped.
+func FailFast() BulkOpt {
+	return func(o *bulkOpts) {
+		o.failFast = true
+	}
+}
+
+// OnProgress calls fn each time an item finishes.
+//
+// Calls are serialised, so fn does not need to be safe for concurrent use.
+func OnProgress(fn func(BulkProgress)) BulkOpt {
+	return func(o *bulkOpts) {
+		o.onProgress = fn
+	}
+}
+
+// OnResult calls fn with the result of each item as it finishes.
+//
+// Calls are serialised, so fn does not need to be safe for concurrent use.
+// Skipped items are not reported.
+func OnResult(fn func(BulkResult)) BulkOpt {
+	return func(o *bulkOpts) {
+		o.onResult = fn
+	}
+}
+
+// CreateMany creates accounts concurrently.
+//
+// A result is returned for every account, in input order. The returned error
+// is the first failure when FailFast is used, or the context error if ctx is
+// done before all accounts are attempted. Accounts that were not attempted
+// have their error set to ErrSkipped.
+func (c *Client) CreateMany(
+	ctx context.Context,
+	accs []*account.Account,
+	opts ...BulkOpt,
+) ([]BulkResult, error) {
+	return runBulk(ctx, sliceChan(accs), len(accs), opts, c.createItem)
+}
+
+// CreateManyFrom creates accounts read from accs concurrently.
+//
+// It returns once accs is closed and all accounts read from it have been
+// processed. A result is returned for every account read, in the order
+// they were read.
+//
+// If the operation stops early, because ctx is done or FailFast is used,
+// accounts sent afterwards are read and discarded until accs is closed, so
+// senders are not blocked. Senders should still stop on ctx.Done and close
+// accs.
+func (c *Client) CreateManyFrom(
+	ctx context.Context,
+	accs <-chan *account.Account,
+	opts ...BulkOpt,
+) ([]BulkResult, error) {
+	return runBulk(ctx, accs, 0, opts, c.createItem)
+}
+
+// DeleteMany deletes accounts concurrently.
+//
+// Results are returned as with CreateMany.
+func (c *Client) DeleteMany(
+	ctx context.Context,
+	params []account.DeleteAccountParams,
+	opts ...BulkOpt,
+) ([]BulkResult, error) {
+	return runBulk(ctx, sliceChan(params), len(params), opts, c.deleteItem)
+}
+
+// DeleteManyFrom deletes accounts read from params concurrently.
+//
+// Results are returned as with CreateManyFrom, and params is drained in the
+// same way if the operation stops early.
+func (c *Client) DeleteManyFrom(
+	ctx context.Context,
+	params <-chan account.DeleteAccountParams,
+	opts ...BulkOpt,
+) ([]BulkResult, error) {
+	return runBulk(ctx, params, 0, opts, c.deleteItem)
+}
+
+func (c *Client) createItem(
+	ctx context.Context,
+	acc *account.Account,
+) (*account.Account, error) {
+	resp, err := c.Create(ctx, acc)
+	if err != nil {
+		return nil, err
+	}
+
+	return resp.Data, nil
+}
+
+func (c *Client) deleteItem(
+	ctx context.Context,
+	params account.DeleteAccountParams,
+) (*account.Account, error) {
+	_, err := c.Delete(ctx, params)
+	return nil, err
+}
+
+// sliceChan returns a closed channel holding the items of s.
+func sliceChan[T any](s []T) <-chan T {
+	ch := make(chan T, len(s))
+	for _, item := range s {
+		ch <- item
+	}
+	close(ch)
+
+	return ch
+}
+
+type bulkJob[T any] struct {
+	index int
+	item  T
+}
+
+// runBulk processes items read from in with a bounded number of workers.
+//
+// It returns once all workers have exited.
+func runBulk[T any](
+	ctx context.Context,
+	in <-chan T,
+	total int,
+	opts []BulkOpt,
+	fn func(ctx context.Context, item T) (*account.Account, error),
+) ([]BulkResult, error) {
+	o := bulkOpts{concurrency: defaultBulkConcurrency}
+	for _, opt := range opts {
+		opt(&o)
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	var (
+		mu       sync.Mutex
+		results  = make([]BulkResult, 0, total)
+		progress = BulkProgress{Total: total}
+		firstErr error
+	)
+
+	jobs := make(chan bulkJob[T])
+
+	var wg sync.WaitGroup
+	for i := 0; i < o.concurrency; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			for j := range jobs {
+				itemCtx, counter := baseclient.ContextWithAttemptCounter(ctx)
+				acc, err := fn(itemCtx, j.item)
+
+				mu.Lock()
+				results[j.index] = BulkResult{
+					Index:    j.index,
+					Account:  acc,
+					Err:      err,
+					Attempts: counter.Attempts(),
+				}
+
+				if o.onResult != nil {
+					o.onResult(results[j.index])
+				}
+
+				progress.Completed++
+				if err != nil {
+					progress.Failed++
+
+					if o.failFast && firstErr == nil {
+						firstErr = err
+						cancel()
+					}
+				}
+
+				if o.onProgress != nil {
+					o.onProgress(progress)
+				}
+				mu.Unlock()
+			}
+		}()
+	}
+
+	// Dispatch items until the input is exhausted or the operation is stopped.
+	exhausted := dispatch(ctx, in, jobs, func(index int) {
+		mu.Lock()
+		results = append(results, BulkResult{Index: index, Err: ErrSkipped})
+		mu.Unlock()
+	})
+
+	close(jobs)
+	wg.Wait()
+
+	// Items sent after the operation stopped are discarded, so that the
+	// sender does not block.
+	if ctx.Err() != nil {
+		go drain(in)
+	}
+
+	// Items of a slice that were never read are skipped.
+	for index := len(results); index < total; index++ {
+		results = append(results, BulkResult{Index: index, Err: ErrSkipped})
+	}
+
+	if firstErr != nil {
+		return results, firstErr
+	}
+
+	// The context error is only returned if the operation stopped before
+	// every item was attempted. Items of a channel that was not closed yet
+	// may be discarded too.
+	if skipped(results) || (total == 0 && !exhausted) {
+		return results, ctx.Err()
+	}
+
+	return results, nil
+}
+
+// skipped reports whether any of results was skipped.
+func skipped(results []BulkResult) bool {
+	for _, r := range results {
+		if errors.Is(r.Err, ErrSkipped) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// drain reads and discards items until in is closed.
+func drain[T any](in <-chan T) {
+	for range in {
+	}
+}
+
+// dispatch sends items read from in to jobs until in is closed or ctx is
+// done. It reports whether in was closed.
+//
+// add is called with the index of each item read, before it is dispatched.
+func dispatch[T any](
+	ctx context.Context,
+	in <-chan T,
+	jobs chan<- bulkJob[T],
+	add func(index int),
+) bool {
+	for index := 0; ; index++ {
+		// Check for cancellation first, since select picks randomly
+		// between ready cases.
+		if ctx.Err() != nil {
+			return false
+		}
+
+		select {
+		case <-ctx.Done():
+			return false
+		case item, ok := <-in:
+			if !ok {
+				return true
+			}
+			add(index)
+
+			select {
+			case <-ctx.Done():
+				return false
+			case jobs <- bulkJob[T]{index: index, item: item}:
+			}
+		}
+	}
+}
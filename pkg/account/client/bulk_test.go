@@ -0,0 +1,332 @@
+package client_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/google/uuid"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"github.com/vivangkumar/form3-http-go/pkg/account"
+	"github.com/vivangkumar/form3-http-go/pkg/account/client"
+	"github.com/vivangkumar/form3-http-go/pkg/account/internal/fakes"
+	baseclient "github.com/vivangkumar/form3-http-go/pkg/client"
+)
+
+var _ = Describe("Bulk account operations", func() {
+	var (
+		ctx context.Context
+
+		orgID string
+		accs  []*account.Account
+
+		fakeBaseClient *fakes.FakeBaseClient
+		cl             *client.Client
+
+		inFlight    atomic.Int32
+		maxInFlight atomic.Int32
+		failIDs     map[string]bool
+	)
+
+	BeforeEach(func() {
+		ctx = context.Background()
+		orgID = uuid.NewString()
+
+		accs = nil
+		for i := 0; i < 20; i++ {
+			accs = append(accs, account.New(orgID).WithID(uuid.NewString()))
+		}
+
+		inFlight.Store(0)
+		maxInFlight.Store(0)
+		failIDs = make(map[string]bool)
+
+		fakeBaseClient = new(fakes.FakeBaseClient)
+		cl = client.New(fakeBaseClient)
+
+		fakeBaseClient.PostStub = func(
+			ctx context.Context,
+			path string,
+			body any,
+			target any,
+		) (*http.Response, error) {
+			n := inFlight.Add(1)
+			defer inFlight.Add(-1)
+
+			for {
+				m := maxInFlight.Load()
+				if n <= m || maxInFlight.CompareAndSwap(m, n) {
+					break
+				}
+			}
+
+			b, _ := json.Marshal(body)
+			var req struct {
+				Data *account.Account `json:"data"`
+			}
+			_ = json.Unmarshal(b, &req)
+
+			select {
+			case <-ctx.Done():
+				return nil, ctx.Err()
+			case <-time.After(time.Millisecond):
+			}
+
+			if failIDs[req.Data.ID] {
+				return nil, apiError{
+					httpResponse: &http.Response{StatusCode: http.StatusBadRequest},
+					underlying:   errors.New("BAD_REQUEST"),
+				}
+			}
+
+			resp := fmt.Sprintf(`{"data": {"id": %q}}`, req.Data.ID)
+			return &http.Response{StatusCode: http.StatusCreated}, json.Unmarshal([]byte(resp), target)
+		}
+	})
+
+	Describe("Creating many accounts", func() {
+		It("should return results in input order", func() {
+			results, err := cl.CreateMany(ctx, accs, client.Concurrency(3))
+			Expect(err).To(BeNil())
+			Expect(results).To(HaveLen(len(accs)))
+
+			for i, r := range results {
+				Expect(r.Index).To(Equal(i))
+				Expect(r.Err).To(BeNil())
+				Expect(r.Account.ID).To(Equal(accs[i].ID))
+			}
+
+			By("bounding concurrency", func() {
+				Expect(maxInFlight.Load()).To(BeNumerically("<=", 3))
+			})
+		})
+
+		It("should report progress", func() {
+			failIDs[accs[3].ID] = true
+
+			var progress []client.BulkProgress
+			_, err := cl.CreateMany(ctx, accs, client.OnProgress(func(p client.BulkProgress) {
+				progress = append(progress, p)
+			}))
+			Expect(err).To(BeNil())
+
+			Expect(progress).To(HaveLen(len(accs)))
+			Expect(progress[len(progress)-1]).To(Equal(client.BulkProgress{
+				Completed: len(accs),
+				Failed:    1,
+				Total:     len(accs),
+			}))
+		})
+
+		It("should report each result as it finishes", func() {
+			failIDs[accs[3].ID] = true
+
+			seen := make(map[int]client.BulkResult)
+			results, err := cl.CreateMany(ctx, accs, client.OnResult(func(r client.BulkResult) {
+				seen[r.Index] = r
+			}))
+			Expect(err).To(BeNil())
+
+			Expect(seen).To(HaveLen(len(accs)))
+			for _, r := range results {
+				Expect(seen[r.Index]).To(Equal(r))
+			}
+			Expect(seen[3].Err).To(Not(BeNil()))
+		})
+
+		When("an item fails", func() {
+			BeforeEach(func() {
+				failIDs[accs[5].ID] = true
+			})
+
+			It("should continue with the other items", func() {
+				results, err := cl.CreateMany(ctx, accs)
+				Expect(err).To(BeNil())
+
+				for i, r := range results {
+					if i == 5 {
+						assertErrorResponse(r.Err, http.StatusBadRequest, "BAD_REQUEST")
+						continue
+					}
+					Expect(r.Err).To(BeNil())
+				}
+			})
+
+			It("should stop early when failing fast", func() {
+				results, err := cl.CreateMany(ctx, accs, client.Concurrency(1), client.FailFast())
+				assertErrorResponse(err, http.StatusBadRequest, "BAD_REQUEST")
+				Expect(results).To(HaveLen(len(accs)))
+
+				for i, r := range results[:5] {
+					Expect(r.Err).To(BeNil(), fmt.Sprint(i))
+				}
+				Expect(results[5].Err).To(Equal(err))
+				for _, r := range results[6:] {
+					Expect(r.Err).To(MatchError(client.ErrSkipped))
+				}
+			})
+		})
+
+		When("the context is cancelled", func() {
+			It("should skip remaining items and return the context error", func() {
+				ctx, cancel := context.WithCancel(ctx)
+
+				results, err := cl.CreateMany(ctx, accs, client.Concurrency(1), client.OnProgress(func(p client.BulkProgress) {
+					if p.Completed == 2 {
+						cancel()
+					}
+				}))
+				Expect(err).To(MatchError(context.Canceled))
+				Expect(results).To(HaveLen(len(accs)))
+				Expect(results[0].Err).To(BeNil())
+				Expect(results[len(accs)-1].Err).To(MatchError(client.ErrSkipped))
+			})
+
+			It("should not return the context error once every item was attempted", func() {
+				ctx, cancel := context.WithCancel(ctx)
+
+				results, err := cl.CreateMany(ctx, accs, client.OnProgress(func(p client.BulkProgress) {
+					if p.Completed == p.Total {
+						cancel()
+					}
+				}))
+				Expect(err).To(BeNil())
+				Expect(results).To(HaveLen(len(accs)))
+				for i, r := range results {
+					Expect(r.Err).To(BeNil(), fmt.Sprint(i))
+				}
+			})
+		})
+
+		Context("reading from a channel", func() {
+			It("should process every item until the channel is closed", func() {
+				ch := make(chan *account.Account)
+				go func() {
+					defer close(ch)
+					for _, acc := range accs {
+						ch <- acc
+					}
+				}()
+
+				results, err := cl.CreateManyFrom(ctx, ch, client.Concurrency(5))
+				Expect(err).To(BeNil())
+				Expect(results).To(HaveLen(len(accs)))
+
+				for i, r := range results {
+					Expect(r.Account.ID).To(Equal(accs[i].ID))
+				}
+			})
+
+			It("should return when the context is done", func() {
+				ch := make(chan *account.Account)
+				ctx, cancel := context.WithTimeout(ctx, 10*time.Millisecond)
+				defer cancel()
+
+				results, err := cl.CreateManyFrom(ctx, ch)
+				Expect(err).To(MatchError(context.DeadlineExceeded))
+				Expect(results).To(BeEmpty())
+			})
+
+			It("should not block senders once the operation stops", func() {
+				failIDs[accs[0].ID] = true
+
+				ch := make(chan *account.Account)
+				sent := make(chan struct{})
+				go func() {
+					defer close(sent)
+					defer close(ch)
+					for _, acc := range accs {
+						ch <- acc
+					}
+				}()
+
+				_, err := cl.CreateManyFrom(ctx, ch, client.Concurrency(1), client.FailFast())
+				Expect(err).NotTo(BeNil())
+
+				Eventually(sent).Should(BeClosed())
+			})
+		})
+	})
+
+	Describe("Deleting many accounts", func() {
+		BeforeEach(func() {
+			fakeBaseClient.DeleteStub = func(
+				ctx context.Context,
+				path string,
+				query map[string]string,
+			) (*http.Response, error) {
+				if strings.HasSuffix(path, accs[1].ID) {
+					return nil, apiError{
+						httpResponse: &http.Response{StatusCode: http.StatusConflict},
+						underlying:   errors.New("CONFLICT"),
+					}
+				}
+
+				return &http.Response{StatusCode: http.StatusNoContent}, nil
+			}
+		})
+
+		It("should return a result per item", func() {
+			var params []account.DeleteAccountParams
+			for _, acc := range accs {
+				params = append(params, account.DeleteAccountParams{ID: acc.ID})
+			}
+
+			results, err := cl.DeleteMany(ctx, params)
+			Expect(err).To(BeNil())
+			Expect(results).To(HaveLen(len(accs)))
+
+			Expect(results[0].Err).To(BeNil())
+			assertErrorResponse(results[1].Err, http.StatusConflict, "CONFLICT")
+			Expect(fakeBaseClient.DeleteCallCount()).To(Equal(len(accs)))
+		})
+	})
+
+	Describe("Counting attempts", func() {
+		It("should report HTTP attempts including retries", func() {
+			var (
+				mu    sync.Mutex
+				calls = make(map[string]int)
+			)
+
+			base, err := baseclient.New(
+				baseclient.WithRetry(2, 0),
+				baseclient.WithHTTPClient(doerFunc(func(req *http.Request) (*http.Response, error) {
+					mu.Lock()
+					calls[req.URL.Path]++
+					n := calls[req.URL.Path]
+					mu.Unlock()
+
+					if n == 1 && strings.HasSuffix(req.URL.Path, accs[0].ID) {
+						return &http.Response{StatusCode: http.StatusServiceUnavailable, Body: http.NoBody}, nil
+					}
+
+					return &http.Response{StatusCode: http.StatusNoContent, Body: io.NopCloser(strings.NewReader(""))}, nil
+				})),
+			)
+			Expect(err).To(BeNil())
+
+			params := []account.DeleteAccountParams{{ID: accs[0].ID}, {ID: accs[1].ID}}
+			results, err := client.New(base).DeleteMany(ctx, params)
+			Expect(err).To(BeNil())
+
+			Expect(results[0].Attempts).To(Equal(2))
+			Expect(results[1].Attempts).To(Equal(1))
+		})
+	})
+})
+
+type doerFunc func(req *http.Request) (*http.Response, error)
+
+func (f doerFunc) Do(req *http.Request) (*http.Response, error) {
+	return f(req)
+}
@@ -10,13 +10,19 @@ package client
 
 import (
 	"context"
-	"fmt"
 	"net/http"
 
 	"github.com/vivangkumar/form3-http-go/pkg/account"
+	baseclient "github.com/vivangkumar/form3-http-go/pkg/client"
+	"github.com/vivangkumar/form3-http-go/pkg/resource"
 )
 
-const accountsBasePath = "/v1/organisation/accounts/"
+const (
+	accountsBasePath = "/v1/organisation/accounts/"
+	accountsType     = "accounts"
+
+	attrAccountID = "form3.account_id"
+)
 
 //go:generate go run github.com/maxbrunsfeld/counterfeiter/v6 -generate
 
@@ -34,6 +40,12 @@ type baseClient interface {
 		body any,
 		target any,
 	) (*http.Response, error)
+	Patch(
+		ctx context.Context,
+		path string,
+		body any,
+		target any,
+	) (*http.Response, error)
 	Delete(
 		ctx context.Context,
 		path string,
@@ -41,40 +53,78 @@ type baseClient interface {
 	) (*http.Response, error)
 }
 
-type accountCreationRequest struct {
-	Data *account.Account `json:"data,omitempty"`
-}
-
 // Client represents an account client.
+//
+// It is a typed wrapper over a resource.Client for accounts.
 type Client struct {
-	baseClient baseClient
+	accounts *resource.Client[account.Account]
+
+	// tracer opens a span for each account operation.
+	tracer baseclient.Tracer
+
+	async asyncState
+}
+
+// Opt represents an option that can be passed
+// during creation of a Client to configure it.
+type Opt func(c *Client)
+
+// WithTracer sets the tracer used to open a span per account operation.
+//
+// Spans opened by the base client for each HTTP attempt are children of
+// the operation span when both use the same tracer.
+func WithTracer(t baseclient.Tracer) Opt {
+	return func(c *Client) {
+		if t != nil {
+			c.tracer = t
+		}
+	}
 }
 
 // New creates a new account client.
 //
 // It requires an underlying client that satisfies the baseClient interface.
-func New(baseClient baseClient) *Client {
-	return &Client{baseClient: baseClient}
+func New(baseClient baseClient, opts ...Opt) *Client {
+	c := &Client{
+		tracer: baseclient.NoopTracer(),
+	}
+
+	for _, opt := range opts {
+		opt(c)
+	}
+
+	c.accounts = resource.New(baseClient, resource.Config[account.Account]{
+		Type:        accountsType,
+		BasePath:    accountsBasePath,
+		ID:          func(acc *account.Account) string { return acc.ID },
+		Name:        "account",
+		IDAttribute: attrAccountID,
+		Match:       account.Mismatches,
+		Tracer:      c.tracer,
+	})
+
+	return c
 }
 
 // Create creates a new bank account.
+//
+// Create requests carry an idempotency key so that they can be retried
+// safely. A key set with client.ContextWithIdempotencyKey is used if present.
+// Otherwise, one is derived from the account ID, if set.
+//
+// If a retried request is rejected as a duplicate, the existing account is
+// fetched. The create is treated as successful if the existing account
+// matches acc on every field that acc sets.
 func (c *Client) Create(
 	ctx context.Context,
 	acc *account.Account,
 ) (*account.Response, error) {
-	if acc == nil {
-		return nil, fmt.Errorf("account entity is nil")
-	}
-
-	target := new(account.Response)
-	req := accountCreationRequest{Data: acc}
-
-	_, err := c.baseClient.Post(ctx, accountsBasePath, &req, target)
+	doc, err := c.accounts.Create(ctx, acc)
 	if err != nil {
-		return nil, fmt.Errorf("create account: %w", err)
+		return nil, err
 	}
 
-	return target, nil
+	return (*account.Response)(doc), nil
 }
 
 // Fetch retrieves an account from the API given an ID.
@@ -82,14 +132,52 @@ func (c *Client) Fetch(
 	ctx context.Context,
 	params account.FetchAccountParams,
 ) (*account.Response, error) {
-	target := new(account.Response)
+	doc, err := c.accounts.Fetch(ctx, params.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	return (*account.Response)(doc), nil
+}
+
+// List retrieves a page of accounts.
+//
+// The links of the response can be used to page through the accounts.
+func (c *Client) List(
+	ctx context.Context,
+	params account.ListAccountParams,
+) (*account.ListResponse, error) {
+	doc, err := c.accounts.List(ctx, resource.ListParams{
+		PageNumber: params.PageNumber,
+		PageSize:   params.PageSize,
+		Filter:     params.Filter,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return (*account.ListResponse)(doc), nil
+}
 
-	_, err := c.baseClient.Get(ctx, accountsBasePath+params.ID, nil, target)
+// Pages returns an iterator over every page of accounts, starting with the
+// page of params.
+func (c *Client) Pages(params account.ListAccountParams) *account.Pages {
+	return account.NewPages(c, params)
+}
+
+// Update patches the account with the ID set on acc.
+//
+// The version set on acc must match the current version of the account.
+func (c *Client) Update(
+	ctx context.Context,
+	acc *account.Account,
+) (*account.Response, error) {
+	doc, err := c.accounts.Update(ctx, acc)
 	if err != nil {
-		return nil, fmt.Errorf("fetch account: %w", err)
+		return nil, err
 	}
 
-	return target, nil
+	return (*account.Response)(doc), nil
 }
 
 // Delete deletes the account with the given ID and version.
@@ -97,13 +185,9 @@ func (c *Client) Delete(
 	ctx context.Context,
 	params account.DeleteAccountParams,
 ) (*account.DeleteResponse, error) {
-	query := map[string]string{
-		"version": fmt.Sprintf("%d", params.Version),
-	}
-
-	_, err := c.baseClient.Delete(ctx, accountsBasePath+params.ID, query)
+	err := c.accounts.Delete(ctx, params.ID, params.Version)
 	if err != nil {
-		return nil, fmt.Errorf("delete account: %w", err)
+		return nil, err
 	}
 
 	return &account.DeleteResponse{}, nil
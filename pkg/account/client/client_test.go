@@ -16,6 +16,8 @@ import (
 	"github.com/vivangkumar/form3-http-go/pkg/account"
 	"github.com/vivangkumar/form3-http-go/pkg/account/client"
 	"github.com/vivangkumar/form3-http-go/pkg/account/internal/fakes"
+	baseclient "github.com/vivangkumar/form3-http-go/pkg/client"
+	"github.com/vivangkumar/form3-http-go/pkg/client/tracetest"
 	"github.com/vivangkumar/form3-http-go/pkg/internal/fixtures"
 )
 
@@ -234,6 +236,125 @@ var _ = Describe("Account client", func() {
 		})
 	})
 
+	Describe("List accounts", func() {
+		Context("with success response", func() {
+			BeforeEach(func() {
+				respBody = fmt.Sprintf(`{
+					"data": [{"id": %q, "organisation_id": %q}],
+					"links": {"self": "/v1/organisation/accounts", "next": "/v1/organisation/accounts?page[number]=1"}
+				}`, accountID, orgID)
+
+				fakeBaseClient.GetStub = func(
+					ctx context.Context,
+					path string,
+					query map[string]string,
+					target any,
+				) (*http.Response, error) {
+					return &http.Response{StatusCode: http.StatusOK}, json.Unmarshal([]byte(respBody), target)
+				}
+			})
+
+			It("should return the page of accounts", func() {
+				resp, err := cl.List(ctx, account.ListAccountParams{
+					PageSize: 1,
+					Filter:   map[string]string{"country": "FR"},
+				})
+				Expect(err).To(BeNil())
+				Expect(resp.Data).To(HaveLen(1))
+				Expect(resp.Data[0].ID).To(Equal(accountID))
+				Expect(*resp.Links.Next).To(HaveSuffix("page[number]=1"))
+
+				_, path, query, _ := fakeBaseClient.GetArgsForCall(0)
+				Expect(path).To(Equal("/v1/organisation/accounts/"))
+				Expect(query).To(Equal(map[string]string{
+					"page[size]":      "1",
+					"filter[country]": "FR",
+				}))
+			})
+		})
+
+		Context("with HTTP error response", func() {
+			BeforeEach(func() {
+				fakeBaseClient.GetReturns(nil, apiError{
+					httpResponse: &http.Response{StatusCode: http.StatusForbidden},
+					underlying:   fmt.Errorf("FORBIDDEN"),
+				})
+			})
+
+			It("should return an http response enriched error", func() {
+				resp, err := cl.List(ctx, account.ListAccountParams{})
+				Expect(err).To(Not(BeNil()))
+				Expect(resp).To(BeNil())
+
+				assertErrorResponse(err, http.StatusForbidden, "FORBIDDEN")
+			})
+		})
+	})
+
+	Describe("Update account", func() {
+		BeforeEach(func() {
+			acc.Version = new(int64)
+		})
+
+		Context("with success response", func() {
+			BeforeEach(func() {
+				fakeBaseClient.PatchStub = func(
+					ctx context.Context,
+					path string,
+					body any,
+					target any,
+				) (*http.Response, error) {
+					err := json.Unmarshal([]byte(respBody), &target)
+					if err != nil {
+						return nil, err
+					}
+
+					return &http.Response{StatusCode: http.StatusOK}, nil
+				}
+
+				respBody = fixtures.AccountsResponseAllFields(orgID, accountID, "FR", "EUR")
+			})
+
+			It("should patch the account", func() {
+				resp, err := cl.Update(ctx, acc)
+				Expect(err).To(BeNil())
+				assertAllAccountFields(resp.Data, orgID, accountID)
+
+				_, path, _, _ := fakeBaseClient.PatchArgsForCall(0)
+				Expect(path).To(Equal("/v1/organisation/accounts/" + accountID))
+			})
+		})
+
+		Context("with an account without an ID", func() {
+			It("should return an error", func() {
+				resp, err := cl.Update(ctx, account.New(orgID))
+				Expect(err).To(Not(BeNil()))
+				Expect(resp).To(BeNil())
+				Expect(fakeBaseClient.PatchCallCount()).To(Equal(0))
+			})
+		})
+
+		Context("with HTTP error response", func() {
+			errMsg := "CONFLICT"
+			BeforeEach(func() {
+				fakeBaseClient.PatchReturns(nil, apiError{
+					httpResponse: &http.Response{
+						StatusCode: http.StatusConflict,
+					},
+					underlying: fmt.Errorf(errMsg),
+				})
+			})
+
+			It("should return an http response enriched error", func() {
+				resp, err := cl.Update(ctx, acc)
+				Expect(err).To(Not(BeNil()))
+				Expect(resp).To(BeNil())
+
+				assertErrorResponse(err, http.StatusConflict, errMsg)
+			})
+		})
+	})
+
 	Describe("Delete account", func() {
 		Context("with success response", func() {
 			BeforeEach(func() {
@@ -292,15 +413,223 @@ var _ = Describe("Account client", func() {
 	})
 })
 
+var _ = Describe("Account client tracing", func() {
+	var (
+		ctx context.Context
+
+		fakeBaseClient *fakes.FakeBaseClient
+		tracer         *tracetest.Tracer
+		cl             *client.Client
+
+		accountID string
+	)
+
+	BeforeEach(func() {
+		ctx = context.Background()
+		accountID = uuid.NewString()
+
+		fakeBaseClient = new(fakes.FakeBaseClient)
+		tracer = tracetest.New()
+		cl = client.New(fakeBaseClient, client.WithTracer(tracer))
+	})
+
+	Context("with success response", func() {
+		BeforeEach(func() {
+			fakeBaseClient.GetReturns(&http.Response{
+				StatusCode: http.StatusOK,
+				Header:     http.Header{"X-Request-Id": []string{"req-1"}},
+			}, nil)
+		})
+
+		It("should record a span for the operation", func() {
+			_, err := cl.Fetch(ctx, account.FetchAccountParams{ID: accountID})
+			Expect(err).To(BeNil())
+
+			spans := tracer.SpansNamed("accounts.fetch")
+			Expect(spans).To(HaveLen(1))
+			Expect(spans[0].Ended).To(BeTrue())
+			Expect(spans[0].Err).To(BeNil())
+			Expect(spans[0].Attributes).To(HaveKeyWithValue("form3.account_id", accountID))
+			Expect(spans[0].Attributes).To(HaveKeyWithValue(baseclient.AttrHTTPStatusCode, http.StatusOK))
+			Expect(spans[0].Attributes).To(HaveKeyWithValue(baseclient.AttrRequestID, "req-1"))
+
+			By("passing the span context to the base client", func() {
+				reqCtx, _, _, _ := fakeBaseClient.GetArgsForCall(0)
+				tc, ok := baseclient.TraceContextFromContext(reqCtx)
+				Expect(ok).To(BeTrue())
+				Expect(tc).To(Equal(spans[0].TraceContext))
+			})
+		})
+	})
+
+	Context("with HTTP error response", func() {
+		BeforeEach(func() {
+			fakeBaseClient.DeleteReturns(nil, apiError{
+				httpResponse: &http.Response{StatusCode: http.StatusConflict},
+				underlying:   fmt.Errorf("CONFLICT"),
+			})
+		})
+
+		It("should record the error on the span", func() {
+			_, err := cl.Delete(ctx, account.DeleteAccountParams{ID: accountID, Version: 1})
+			Expect(err).To(Not(BeNil()))
+
+			spans := tracer.SpansNamed("accounts.delete")
+			Expect(spans).To(HaveLen(1))
+			Expect(spans[0].Err).To(Equal(err))
+			Expect(spans[0].Attributes).To(HaveKeyWithValue(baseclient.AttrHTTPStatusCode, http.StatusConflict))
+		})
+	})
+})
+
+var _ = Describe("Account creation retries", func() {
+	var (
+		ctx context.Context
+
+		orgID     string
+		accountID string
+
+		fakeBaseClient *fakes.FakeBaseClient
+		cl             *client.Client
+
+		acc *account.Account
+	)
+
+	BeforeEach(func() {
+		ctx = context.Background()
+
+		orgID = uuid.NewString()
+		accountID = uuid.NewString()
+
+		fakeBaseClient = new(fakes.FakeBaseClient)
+		cl = client.New(fakeBaseClient)
+
+		attrs := account.NewAttributes("EUR", "FR").WithBankIDCode("FR")
+		acc = account.New(orgID).WithID(accountID).WithAttributes(attrs)
+	})
+
+	Describe("Idempotency keys", func() {
+		BeforeEach(func() {
+			fakeBaseClient.PostReturns(&http.Response{StatusCode: http.StatusCreated}, nil)
+		})
+
+		It("should derive a key from the account ID", func() {
+			_, err := cl.Create(ctx, acc)
+			Expect(err).To(BeNil())
+
+			reqCtx, _, _, _ := fakeBaseClient.PostArgsForCall(0)
+			key, ok := baseclient.IdempotencyKeyFromContext(reqCtx)
+			Expect(ok).To(BeTrue())
+			Expect(key).To(Equal(baseclient.IdempotencyKeyFor("accounts", accountID)))
+		})
+
+		It("should use a key set by the caller", func() {
+			ctx = baseclient.ContextWithIdempotencyKey(ctx, "my-key")
+
+			_, err := cl.Create(ctx, acc)
+			Expect(err).To(BeNil())
+
+			reqCtx, _, _, _ := fakeBaseClient.PostArgsForCall(0)
+			key, _ := baseclient.IdempotencyKeyFromContext(reqCtx)
+			Expect(key).To(Equal("my-key"))
+		})
+
+		It("should not set a key without an account ID", func() {
+			_, err := cl.Create(ctx, account.New(orgID))
+			Expect(err).To(BeNil())
+
+			reqCtx, _, _, _ := fakeBaseClient.PostArgsForCall(0)
+			_, ok := baseclient.IdempotencyKeyFromContext(reqCtx)
+			Expect(ok).To(BeFalse())
+		})
+	})
+
+	Describe("Duplicate ID conflicts", func() {
+		var (
+			attempts int
+			respBody string
+		)
+
+		BeforeEach(func() {
+			attempts = 2
+			respBody = fixtures.AccountsResponseAllFields(orgID, accountID, "FR", "EUR")
+
+			fakeBaseClient.GetStub = func(
+				ctx context.Context,
+				path string,
+				query map[string]string,
+				target any,
+			) (*http.Response, error) {
+				err := json.Unmarshal([]byte(respBody), &target)
+				if err != nil {
+					return nil, err
+				}
+
+				return &http.Response{StatusCode: http.StatusOK}, nil
+			}
+		})
+
+		JustBeforeEach(func() {
+			fakeBaseClient.PostReturns(nil, apiError{
+				httpResponse: &http.Response{StatusCode: http.StatusConflict},
+				underlying:   fmt.Errorf("CONFLICT"),
+				attempts:     attempts,
+			})
+		})
+
+		When("the conflict is returned on a retry", func() {
+			It("should return the existing account when it matches", func() {
+				resp, err := cl.Create(ctx, acc)
+				Expect(err).To(BeNil())
+				Expect(resp.Data.ID).To(Equal(accountID))
+
+				Expect(fakeBaseClient.GetCallCount()).To(Equal(1))
+				_, path, _, _ := fakeBaseClient.GetArgsForCall(0)
+				Expect(path).To(HaveSuffix(accountID))
+			})
+
+			It("should return the conflict when the existing account differs", func() {
+				acc.Attributes.WithBic("OTHERBIC")
+
+				resp, err := cl.Create(ctx, acc)
+				Expect(err).To(Not(BeNil()))
+				Expect(resp).To(BeNil())
+				Expect(err.Error()).To(ContainSubstring("attributes.bic"))
+
+				assertErrorResponse(err, http.StatusConflict, "CONFLICT")
+			})
+		})
+
+		When("the conflict is returned on the first attempt", func() {
+			BeforeEach(func() {
+				attempts = 1
+			})
+
+			It("should return the conflict", func() {
+				resp, err := cl.Create(ctx, acc)
+				Expect(err).To(Not(BeNil()))
+				Expect(resp).To(BeNil())
+
+				Expect(fakeBaseClient.GetCallCount()).To(Equal(0))
+			})
+		})
+	})
+})
+
 type apiError struct {
 	httpResponse *http.Response
 	underlying   error
+	attempts     int
 }
 
 func (a apiError) HTTPResponse() *http.Response {
 	return a.httpResponse
 }
 
+func (a apiError) Attempts() int {
+	return a.attempts
+}
+
 func (a apiError) Error() string {
 	return fmt.Sprintf(a.underlying.Error())
 }
@@ -0,0 +1,99 @@
+package client
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+
+	"github.com/vivangkumar/form3-http-go/pkg/account"
+	baseclient "github.com/vivangkumar/form3-http-go/pkg/client"
+)
+
+const defaultDeleteAttempts = 3
+
+// DeleteLatestOpt represents an option that can be passed to DeleteLatest.
+type DeleteLatestOpt func(o *deleteLatestOpts)
+
+type deleteLatestOpts struct {
+	maxAttempts    int
+	ignoreNotFound bool
+}
+
+// MaxDeleteAttempts sets the number of times DeleteLatest fetches the
+// latest version and attempts to delete it.
+//
+// If not used, 3 attempts are made.
+func MaxDeleteAttempts(n int) DeleteLatestOpt {
+	return func(o *deleteLatestOpts) {
+		if n > 0 {
+			o.maxAttempts = n
+		}
+	}
+}
+
+// IgnoreNotFound treats an account that does not exist as deleted.
+func IgnoreNotFound() DeleteLatestOpt {
+	return func(o *deleteLatestOpts) {
+		o.ignoreNotFound = true
+	}
+}
+
+// DeleteLatest deletes the latest version of the account with the given ID.
+//
+// The current version is fetched before deleting. If the account is modified
+// between the fetch and the delete, the API returns a conflict and the latest
+// version is fetched again, up to a bounded number of attempts.
+func (c *Client) DeleteLatest(
+	ctx context.Context,
+	id string,
+	opts ...DeleteLatestOpt,
+) (_ *account.DeleteResponse, err error) {
+	o := deleteLatestOpts{maxAttempts: defaultDeleteAttempts}
+	for _, opt := range opts {
+		opt(&o)
+	}
+
+	ctx, span := c.tracer.Start(ctx, "accounts.delete_latest")
+	defer func() { span.End(err) }()
+	span.SetAttribute(attrAccountID, id)
+
+	for attempt := 1; ; attempt++ {
+		resp, err := c.deleteLatest(ctx, id)
+		if err == nil {
+			return resp, nil
+		}
+
+		switch baseclient.StatusCode(err) {
+		case http.StatusNotFound:
+			if o.ignoreNotFound {
+				return &account.DeleteResponse{}, nil
+			}
+		case http.StatusConflict:
+			if attempt < o.maxAttempts {
+				continue
+			}
+		}
+
+		return nil, fmt.Errorf("delete latest account (attempt %d): %w", attempt, err)
+	}
+}
+
+// deleteLatest fetches the current version of an account and deletes it.
+func (c *Client) deleteLatest(
+	ctx context.Context,
+	id string,
+) (*account.DeleteResponse, error) {
+	resp, err := c.Fetch(ctx, account.FetchAccountParams{ID: id})
+	if err != nil {
+		return nil, err
+	}
+
+	if resp.Data == nil || resp.Data.Version == nil {
+		return nil, fmt.Errorf("account %s has no version", id)
+	}
+
+	return c.Delete(ctx, account.DeleteAccountParams{
+		ID:      id,
+		Version: *resp.Data.Version,
+	})
+}
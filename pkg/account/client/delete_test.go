@@ -0,0 +1,137 @@
+package client_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/google/uuid"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"github.com/vivangkumar/form3-http-go/pkg/account/client"
+	"github.com/vivangkumar/form3-http-go/pkg/account/internal/fakes"
+)
+
+var _ = Describe("Delete latest account", func() {
+	var (
+		ctx context.Context
+
+		accountID string
+		versions  []int64
+
+		fakeBaseClient *fakes.FakeBaseClient
+		cl             *client.Client
+	)
+
+	errResponse := func(sc int) error {
+		return apiError{
+			httpResponse: &http.Response{StatusCode: sc},
+			underlying:   errors.New(http.StatusText(sc)),
+		}
+	}
+
+	BeforeEach(func() {
+		ctx = context.Background()
+		accountID = uuid.NewString()
+		versions = []int64{2, 3, 4}
+
+		fakeBaseClient = new(fakes.FakeBaseClient)
+		cl = client.New(fakeBaseClient)
+
+		fakeBaseClient.GetStub = func(
+			ctx context.Context,
+			path string,
+			query map[string]string,
+			target any,
+		) (*http.Response, error) {
+			n := fakeBaseClient.GetCallCount() - 1
+			body := fmt.Sprintf(`{"data": {"id": %q, "version": %d}}`, accountID, versions[n])
+
+			return &http.Response{StatusCode: http.StatusOK}, json.Unmarshal([]byte(body), target)
+		}
+	})
+
+	Context("the delete succeeds", func() {
+		BeforeEach(func() {
+			fakeBaseClient.DeleteReturns(&http.Response{StatusCode: http.StatusNoContent}, nil)
+		})
+
+		It("should delete the fetched version", func() {
+			resp, err := cl.DeleteLatest(ctx, accountID)
+			Expect(err).To(BeNil())
+			Expect(resp).To(Not(BeNil()))
+
+			_, path, query := fakeBaseClient.DeleteArgsForCall(0)
+			Expect(path).To(HaveSuffix(accountID))
+			Expect(query).To(HaveKeyWithValue("version", "2"))
+		})
+	})
+
+	Context("the account is modified before it is deleted", func() {
+		BeforeEach(func() {
+			fakeBaseClient.DeleteReturnsOnCall(0, nil, errResponse(http.StatusConflict))
+			fakeBaseClient.DeleteReturnsOnCall(1, &http.Response{StatusCode: http.StatusNoContent}, nil)
+		})
+
+		It("should fetch the latest version again and retry", func() {
+			_, err := cl.DeleteLatest(ctx, accountID)
+			Expect(err).To(BeNil())
+
+			Expect(fakeBaseClient.GetCallCount()).To(Equal(2))
+			Expect(fakeBaseClient.DeleteCallCount()).To(Equal(2))
+
+			_, _, query := fakeBaseClient.DeleteArgsForCall(1)
+			Expect(query).To(HaveKeyWithValue("version", "3"))
+		})
+	})
+
+	Context("conflicts persist", func() {
+		BeforeEach(func() {
+			fakeBaseClient.DeleteReturns(nil, errResponse(http.StatusConflict))
+		})
+
+		It("should give up after the maximum number of attempts", func() {
+			_, err := cl.DeleteLatest(ctx, accountID, client.MaxDeleteAttempts(2))
+			Expect(err).To(Not(BeNil()))
+			assertErrorResponse(err, http.StatusConflict, "Conflict")
+
+			Expect(fakeBaseClient.DeleteCallCount()).To(Equal(2))
+		})
+	})
+
+	Context("the account does not exist", func() {
+		BeforeEach(func() {
+			fakeBaseClient.GetStub = nil
+			fakeBaseClient.GetReturns(nil, errResponse(http.StatusNotFound))
+		})
+
+		It("should return a not found error", func() {
+			_, err := cl.DeleteLatest(ctx, accountID)
+			Expect(err).To(Not(BeNil()))
+			assertErrorResponse(err, http.StatusNotFound, "Not Found")
+
+			Expect(fakeBaseClient.DeleteCallCount()).To(Equal(0))
+		})
+
+		It("should succeed when not found is ignored", func() {
+			resp, err := cl.DeleteLatest(ctx, accountID, client.IgnoreNotFound())
+			Expect(err).To(BeNil())
+			Expect(resp).To(Not(BeNil()))
+		})
+	})
+
+	Context("the account is deleted between the fetch and the delete", func() {
+		BeforeEach(func() {
+			fakeBaseClient.DeleteReturns(nil, errResponse(http.StatusNotFound))
+		})
+
+		It("should succeed when not found is ignored", func() {
+			_, err := cl.DeleteLatest(ctx, accountID, client.IgnoreNotFound())
+			Expect(err).To(BeNil())
+			Expect(fakeBaseClient.DeleteCallCount()).To(Equal(1))
+		})
+	})
+})
@@ -0,0 +1,126 @@
+package client
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/vivangkumar/form3-http-go/pkg/account"
+	baseclient "github.com/vivangkumar/form3-http-go/pkg/client"
+	"github.com/vivangkumar/form3-http-go/pkg/resource"
+)
+
+// DriftError is returned by Ensure when an account with the requested ID
+// exists, but differs from the requested account.
+type DriftError struct {
+	// ID is the ID of the account.
+	ID string
+
+	// Fields are the JSON names of the fields that differ.
+	Fields []string
+
+	// Existing is the account as it exists in the API.
+	Existing *account.Account
+}
+
+// Error implements the error interface.
+func (e *DriftError) Error() string {
+	return fmt.Sprintf(
+		"account %s differs in %s",
+		e.ID,
+		strings.Join(e.Fields, ", "),
+	)
+}
+
+// EnsureOpt represents an option that can be passed to Ensure.
+type EnsureOpt func(o *ensureOpts)
+
+type ensureOpts struct {
+	patchOnDrift bool
+}
+
+// PatchOnDrift patches an existing account that differs from the requested
+// account, instead of returning a DriftError.
+func PatchOnDrift() EnsureOpt {
+	return func(o *ensureOpts) {
+		o.patchOnDrift = true
+	}
+}
+
+// Ensure makes sure that acc exists.
+//
+// If no account with the ID exists, it is created. If one exists and matches
+// acc on every field that acc sets, the existing account is returned.
+// Otherwise, a *DriftError is returned, unless PatchOnDrift is passed.
+//
+// acc must have an ID set.
+func (c *Client) Ensure(
+	ctx context.Context,
+	acc *account.Account,
+	opts ...EnsureOpt,
+) (_ *account.Response, err error) {
+	if acc == nil {
+		return nil, fmt.Errorf("account entity is nil")
+	}
+
+	if acc.ID == "" {
+		return nil, fmt.Errorf("account ID is empty")
+	}
+
+	var o ensureOpts
+	for _, opt := range opts {
+		opt(&o)
+	}
+
+	ctx, span := c.tracer.Start(ctx, "accounts.ensure")
+	defer func() { span.End(err) }()
+	span.SetAttribute(attrAccountID, acc.ID)
+
+	created, err := c.Create(ctx, acc)
+	if err == nil {
+		return created, nil
+	}
+
+	// A conflict means that an account with the ID already exists.
+	if baseclient.StatusCode(err) != http.StatusConflict {
+		return nil, fmt.Errorf("ensure account: %w", err)
+	}
+
+	// A retried create has already fetched and compared the existing account.
+	var (
+		existing *account.Response
+		fields   []string
+	)
+
+	var mismatch *resource.MismatchError[account.Account]
+	if errors.As(err, &mismatch) {
+		existing, fields = (*account.Response)(mismatch.Existing), mismatch.Fields
+	} else {
+		existing, err = c.Fetch(ctx, account.FetchAccountParams{ID: acc.ID})
+		if err != nil {
+			return nil, fmt.Errorf("ensure account: %w", err)
+		}
+
+		fields = account.Mismatches(acc, existing.Data)
+		if len(fields) == 0 {
+			return existing, nil
+		}
+	}
+
+	drift := &DriftError{ID: acc.ID, Fields: fields, Existing: existing.Data}
+	if !o.patchOnDrift {
+		return nil, fmt.Errorf("ensure account: %w", drift)
+	}
+
+	patch := *acc
+	patch.Version = existing.Data.Version
+
+	updated, err := c.Update(ctx, &patch)
+	if err != nil {
+		return nil, fmt.Errorf("ensure account: %s: %w", drift.Error(), err)
+	}
+
+	return updated, nil
+}
@@ -0,0 +1,206 @@
+package client_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/google/uuid"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"github.com/vivangkumar/form3-http-go/pkg/account"
+	"github.com/vivangkumar/form3-http-go/pkg/account/client"
+	"github.com/vivangkumar/form3-http-go/pkg/account/internal/fakes"
+	"github.com/vivangkumar/form3-http-go/pkg/internal/fixtures"
+)
+
+var _ = Describe("Ensure account", func() {
+	var (
+		ctx context.Context
+
+		orgID     string
+		accountID string
+
+		fakeBaseClient *fakes.FakeBaseClient
+		cl             *client.Client
+
+		acc *account.Account
+	)
+
+	BeforeEach(func() {
+		ctx = context.Background()
+
+		orgID = uuid.NewString()
+		accountID = uuid.NewString()
+
+		fakeBaseClient = new(fakes.FakeBaseClient)
+		cl = client.New(fakeBaseClient)
+
+		attrs := account.NewAttributes("EUR", "FR").WithBic("NWBKFR42")
+		acc = account.New(orgID).WithID(accountID).WithAttributes(attrs)
+	})
+
+	Context("the account does not exist", func() {
+		BeforeEach(func() {
+			fakeBaseClient.PostStub = func(
+				ctx context.Context,
+				path string,
+				body any,
+				target any,
+			) (*http.Response, error) {
+				respBody := fixtures.AccountsResponseAllFields(orgID, accountID, "FR", "EUR")
+				return &http.Response{StatusCode: http.StatusCreated}, json.Unmarshal([]byte(respBody), target)
+			}
+		})
+
+		It("should create the account", func() {
+			resp, err := cl.Ensure(ctx, acc)
+			Expect(err).To(BeNil())
+			Expect(resp.Data.ID).To(Equal(accountID))
+
+			Expect(fakeBaseClient.PostCallCount()).To(Equal(1))
+			Expect(fakeBaseClient.GetCallCount()).To(Equal(0))
+		})
+	})
+
+	Context("the account exists", func() {
+		BeforeEach(func() {
+			fakeBaseClient.PostReturns(nil, apiError{
+				httpResponse: &http.Response{StatusCode: http.StatusConflict},
+				underlying:   fmt.Errorf("CONFLICT"),
+				attempts:     1,
+			})
+
+			fakeBaseClient.GetStub = func(
+				ctx context.Context,
+				path string,
+				query map[string]string,
+				target any,
+			) (*http.Response, error) {
+				respBody := fixtures.AccountsResponseAllFields(orgID, accountID, "FR", "EUR")
+				return &http.Response{StatusCode: http.StatusOK}, json.Unmarshal([]byte(respBody), target)
+			}
+		})
+
+		When("it matches the requested account", func() {
+			It("should return the existing account", func() {
+				resp, err := cl.Ensure(ctx, acc)
+				Expect(err).To(BeNil())
+				Expect(resp.Data.ID).To(Equal(accountID))
+
+				Expect(fakeBaseClient.PatchCallCount()).To(Equal(0))
+			})
+		})
+
+		When("it differs from the requested account", func() {
+			BeforeEach(func() {
+				acc.Attributes.WithBic("OTHERBIC").WithIban("GB33BUKB20201555555555")
+			})
+
+			It("should return a drift error", func() {
+				resp, err := cl.Ensure(ctx, acc)
+				Expect(err).To(Not(BeNil()))
+				Expect(resp).To(BeNil())
+
+				var drift *client.DriftError
+				Expect(errors.As(err, &drift)).To(BeTrue())
+				Expect(drift.ID).To(Equal(accountID))
+				Expect(drift.Fields).To(ConsistOf("attributes.bic", "attributes.iban"))
+				Expect(drift.Existing).To(Not(BeNil()))
+
+				Expect(fakeBaseClient.PatchCallCount()).To(Equal(0))
+			})
+
+			Context("and patching is enabled", func() {
+				BeforeEach(func() {
+					fakeBaseClient.PatchReturns(&http.Response{StatusCode: http.StatusOK}, nil)
+				})
+
+				It("should patch the account with the existing version", func() {
+					_, err := cl.Ensure(ctx, acc, client.PatchOnDrift())
+					Expect(err).To(BeNil())
+
+					Expect(fakeBaseClient.PatchCallCount()).To(Equal(1))
+					_, path, body, _ := fakeBaseClient.PatchArgsForCall(0)
+					Expect(path).To(HaveSuffix(accountID))
+
+					b, err := json.Marshal(body)
+					Expect(err).To(BeNil())
+
+					var req struct {
+						Data *account.Account `json:"data"`
+					}
+					Expect(json.Unmarshal(b, &req)).To(Succeed())
+					Expect(req.Data.Version).To(Not(BeNil()))
+					Expect(*req.Data.Version).To(Equal(int64(0)))
+					Expect(req.Data.Attributes.Bic).To(Equal("OTHERBIC"))
+
+					By("not modifying the requested account", func() {
+						Expect(acc.Version).To(BeNil())
+					})
+				})
+			})
+		})
+	})
+
+	Context("a retried create finds the account exists", func() {
+		BeforeEach(func() {
+			fakeBaseClient.PostReturns(nil, apiError{
+				httpResponse: &http.Response{StatusCode: http.StatusConflict},
+				underlying:   fmt.Errorf("CONFLICT"),
+				attempts:     2,
+			})
+
+			fakeBaseClient.GetStub = func(
+				ctx context.Context,
+				path string,
+				query map[string]string,
+				target any,
+			) (*http.Response, error) {
+				respBody := fixtures.AccountsResponseAllFields(orgID, accountID, "FR", "EUR")
+				return &http.Response{StatusCode: http.StatusOK}, json.Unmarshal([]byte(respBody), target)
+			}
+
+			acc.Attributes.WithBic("OTHERBIC")
+		})
+
+		It("should use the account fetched by the create", func() {
+			_, err := cl.Ensure(ctx, acc)
+
+			var drift *client.DriftError
+			Expect(errors.As(err, &drift)).To(BeTrue(), fmt.Sprint(err))
+			Expect(drift.Fields).To(ConsistOf("attributes.bic"))
+			Expect(drift.Existing.ID).To(Equal(accountID))
+
+			Expect(fakeBaseClient.GetCallCount()).To(Equal(1))
+		})
+	})
+
+	Context("creation fails with another error", func() {
+		BeforeEach(func() {
+			fakeBaseClient.PostReturns(nil, apiError{
+				httpResponse: &http.Response{StatusCode: http.StatusBadRequest},
+				underlying:   fmt.Errorf("BAD_REQUEST"),
+			})
+		})
+
+		It("should return the error", func() {
+			_, err := cl.Ensure(ctx, acc)
+			Expect(err).To(Not(BeNil()))
+			assertErrorResponse(err, http.StatusBadRequest, "BAD_REQUEST")
+
+			Expect(fakeBaseClient.GetCallCount()).To(Equal(0))
+		})
+	})
+
+	Context("the account has no ID", func() {
+		It("should return an error", func() {
+			_, err := cl.Ensure(ctx, account.New(orgID))
+			Expect(err).To(Not(BeNil()))
+			Expect(fakeBaseClient.PostCallCount()).To(Equal(0))
+		})
+	})
+})
@@ -0,0 +1,636 @@
+// Package csv encodes accounts as CSV rows, one account per row.
+//
+// Columns are named after the JSON names of the account fields, such as
+// id, version and bank_id. Multi-valued attributes such as name are written
+// to indexed columns, name[0] to name[3], so that each line of a name is a
+// cell of its own. Structured attributes such as private_identification are
+// written as JSON. The relationship to a master account is written to the
+// master_account_id column.
+//
+// Fields that are not modelled by account.Account, and held in its Extra
+// fields, are not written.
+package csv
+
+import (
+	gocsv "encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"reflect"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/vivangkumar/form3-http-go/pkg/account"
+)
+
+// IgnoreColumn may be used as the target of a mapping to skip a column.
+const IgnoreColumn = "-"
+
+// listWidths are the number of columns written by default for
+// multi-valued attributes, matching the limits of the API.
+var listWidths = map[string]int{
+	"name":              4,
+	"alternative_names": 3,
+}
+
+// column reads and writes one field of an account.
+type column struct {
+	name string
+
+	// list reports whether the field holds several values, written to
+	// indexed columns.
+	list bool
+
+	// get returns the values of the field, or nil if it is not set.
+	get func(acc *account.Account) ([]string, error)
+
+	// set sets the field from its non-empty values.
+	set func(acc *account.Account, values []string) error
+}
+
+// columns are the known columns, in the order they are written.
+var columns = buildColumns()
+
+// columnsByName indexes columns by name.
+var columnsByName = func() map[string]*column {
+	m := make(map[string]*column, len(columns))
+	for _, c := range columns {
+		m[c.name] = c
+	}
+
+	return m
+}()
+
+func buildColumns() []*column {
+	cols := []*column{
+		{
+			name: "id",
+			get:  func(acc *account.Account) ([]string, error) { return optional(acc.ID), nil },
+			set: func(acc *account.Account, values []string) error {
+				acc.ID = values[0]
+				return nil
+			},
+		},
+		{
+			name: "organisation_id",
+			get:  func(acc *account.Account) ([]string, error) { return optional(acc.OrganisationID), nil },
+			set: func(acc *account.Account, values []string) error {
+				acc.OrganisationID = values[0]
+				return nil
+			},
+		},
+		{
+			name: "version",
+			get: func(acc *account.Account) ([]string, error) {
+				if acc.Version == nil {
+					return nil, nil
+				}
+
+				return []string{strconv.FormatInt(*acc.Version, 10)}, nil
+			},
+			set: func(acc *account.Account, values []string) error {
+				v, err := strconv.ParseInt(values[0], 10, 64)
+				if err != nil {
+					return fmt.Errorf("%q is not a whole number", values[0])
+				}
+				acc.Version = &v
+
+				return nil
+			},
+		},
+		{
+			name: "master_account_id",
+			get: func(acc *account.Account) ([]string, error) {
+				if acc.Relationships == nil {
+					return nil, nil
+				}
+
+				id, ok := acc.Relationships.MasterAccount.One()
+				if !ok {
+					return nil, nil
+				}
+
+				return []string{id.ID}, nil
+			},
+			set: func(acc *account.Account, values []string) error {
+				acc.WithMasterAccount(values[0])
+				return nil
+			},
+		},
+	}
+
+	t := reflect.TypeOf(account.Attributes{})
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+
+		name, _, _ := strings.Cut(f.Tag.Get("json"), ",")
+		if name == "" || name == "-" {
+			continue
+		}
+
+		cols = append(cols, attributeColumn(name, f))
+	}
+
+	return cols
+}
+
+// attributeColumn returns the column of an attribute field.
+func attributeColumn(name string, f reflect.StructField) *column {
+	field := func(acc *account.Account) reflect.Value {
+		return reflect.ValueOf(acc.Attributes).Elem().FieldByIndex(f.Index)
+	}
+
+	c := &column{
+		name: name,
+		list: f.Type.Kind() == reflect.Slice,
+		get: func(acc *account.Account) ([]string, error) {
+			if acc.Attributes == nil {
+				return nil, nil
+			}
+
+			return formatValue(field(acc))
+		},
+		set: func(acc *account.Account, values []string) error {
+			if acc.Attributes == nil {
+				acc.Attributes = &account.Attributes{}
+			}
+
+			return parseValue(field(acc), values)
+		},
+	}
+
+	return c
+}
+
+// formatValue returns the cells of an attribute value.
+func formatValue(v reflect.Value) ([]string, error) {
+	switch v.Kind() {
+	case reflect.String:
+		return optional(v.String()), nil
+	case reflect.Slice:
+		if v.Len() == 0 {
+			return nil, nil
+		}
+
+		values := make([]string, v.Len())
+		for i := range values {
+			values[i] = v.Index(i).String()
+		}
+
+		return values, nil
+	case reflect.Pointer:
+		if v.IsNil() {
+			return nil, nil
+		}
+
+		elem := v.Elem()
+		switch elem.Kind() {
+		case reflect.String:
+			return []string{elem.String()}, nil
+		case reflect.Bool:
+			return []string{strconv.FormatBool(elem.Bool())}, nil
+		default:
+			b, err := json.Marshal(v.Interface())
+			if err != nil {
+				return nil, err
+			}
+
+			return []string{string(b)}, nil
+		}
+	}
+
+	return nil, fmt.Errorf("unsupported type %s", v.Type())
+}
+
+// parseValue sets an attribute value from its cells.
+func parseValue(v reflect.Value, values []string) error {
+	switch v.Kind() {
+	case reflect.String:
+		v.SetString(values[0])
+		return nil
+	case reflect.Slice:
+		s := reflect.MakeSlice(v.Type(), len(values), len(values))
+		for i, value := range values {
+			s.Index(i).SetString(value)
+		}
+		v.Set(s)
+
+		return nil
+	case reflect.Pointer:
+		p := reflect.New(v.Type().Elem())
+
+		switch p.Elem().Kind() {
+		case reflect.String:
+			p.Elem().SetString(values[0])
+		case reflect.Bool:
+			b, err := strconv.ParseBool(values[0])
+			if err != nil {
+				return fmt.Errorf("%q is not true or false", values[0])
+			}
+			p.Elem().SetBool(b)
+		default:
+			err := json.Unmarshal([]byte(values[0]), p.Interface())
+			if err != nil {
+				return fmt.Errorf("invalid JSON: %w", err)
+			}
+		}
+		v.Set(p)
+
+		return nil
+	}
+
+	return fmt.Errorf("unsupported type %s", v.Type())
+}
+
+func optional(s string) []string {
+	if s == "" {
+		return nil
+	}
+
+	return []string{s}
+}
+
+// DefaultColumns returns the columns written by default: every known
+// column, with four name columns and three alternative_names columns.
+func DefaultColumns() []string {
+	var names []string
+	for _, c := range columns {
+		if !c.list {
+			names = append(names, c.name)
+			continue
+		}
+
+		for i := 0; i < listWidths[c.name]; i++ {
+			names = append(names, indexedName(c.name, i))
+		}
+	}
+
+	return names
+}
+
+// indexedColumn matches the indexed columns of multi-valued fields.
+var indexedColumn = regexp.MustCompile(`^(.+)\[(\d+)\]$`)
+
+func indexedName(name string, i int) string {
+	return name + "[" + strconv.Itoa(i) + "]"
+}
+
+// cell is a column of a header, resolved to a known column.
+type cell struct {
+	col *column
+
+	// index is the position of the value of a multi-valued field.
+	index int
+}
+
+// resolve resolves a column name, such as bank_id or name[1].
+//
+// A multi-valued field may also be named without an index, in which case
+// the column holds its first value.
+func resolve(name string) (cell, error) {
+	if m := indexedColumn.FindStringSubmatch(name); m != nil {
+		c, ok := columnsByName[m[1]]
+		if !ok || !c.list {
+			return cell{}, fmt.Errorf("unknown column %q", name)
+		}
+
+		i, err := strconv.Atoi(m[2])
+		if err != nil {
+			return cell{}, fmt.Errorf("unknown column %q", name)
+		}
+
+		return cell{col: c, index: i}, nil
+	}
+
+	c, ok := columnsByName[name]
+	if !ok {
+		return cell{}, fmt.Errorf("unknown column %q", name)
+	}
+
+	return cell{col: c}, nil
+}
+
+// RowError is returned for a row that cannot be decoded.
+//
+// Reading may continue with the next row.
+type RowError struct {
+	// Line is the line of the row in the input.
+	Line int
+
+	// Column is the header of the invalid cell, if any.
+	Column string
+
+	Err error
+}
+
+func (e *RowError) Error() string {
+	if e.Column == "" {
+		return fmt.Sprintf("line %d: %s", e.Line, e.Err)
+	}
+
+	return fmt.Sprintf("line %d: column %s: %s", e.Line, e.Column, e.Err)
+}
+
+func (e *RowError) Unwrap() error {
+	return e.Err
+}
+
+// ReaderOpt represents an option that can be passed to NewReader.
+type ReaderOpt func(r *Reader)
+
+// WithMapping maps the headers of the input to column names, for example
+// {"Sort Code": "bank_id"}. Map a header to IgnoreColumn to skip it.
+//
+// Headers that are not mapped must be column names.
+func WithMapping(m map[string]string) ReaderOpt {
+	return func(r *Reader) {
+		r.mapping = m
+	}
+}
+
+// Reader reads accounts from CSV rows.
+//
+// The first row is the header.
+type Reader struct {
+	r       *gocsv.Reader
+	mapping map[string]string
+
+	header []string
+	cells  []*cell
+
+	record []string
+	line   int
+}
+
+// NewReader returns a Reader reading from r.
+func NewReader(r io.Reader, opts ...ReaderOpt) *Reader {
+	cr := gocsv.NewReader(r)
+	cr.FieldsPerRecord = -1
+
+	reader := &Reader{r: cr}
+	for _, opt := range opts {
+		opt(reader)
+	}
+
+	return reader
+}
+
+// Header returns the header of the input, reading it if required.
+func (r *Reader) Header() ([]string, error) {
+	if r.header != nil {
+		return r.header, nil
+	}
+
+	header, err := r.r.Read()
+	if errors.Is(err, io.EOF) {
+		return nil, fmt.Errorf("read header: input is empty")
+	}
+	if err != nil {
+		return nil, fmt.Errorf("read header: %w", err)
+	}
+
+	cells := make([]*cell, len(header))
+	for i, h := range header {
+		name := strings.TrimSpace(h)
+		if mapped, ok := r.mapping[name]; ok {
+			name = mapped
+		}
+
+		if name == IgnoreColumn {
+			continue
+		}
+
+		c, err := resolve(name)
+		if err != nil {
+			return nil, fmt.Errorf("read header: %w", err)
+		}
+		cells[i] = &c
+	}
+
+	r.header = header
+	r.cells = cells
+
+	return header, nil
+}
+
+// Read returns the account of the next row.
+//
+// It returns io.EOF once there are no rows left, and a *RowError for rows
+// that cannot be decoded.
+func (r *Reader) Read() (*account.Account, error) {
+	if _, err := r.Header(); err != nil {
+		return nil, err
+	}
+
+	record, err := r.r.Read()
+	if err != nil {
+		if errors.Is(err, io.EOF) {
+			return nil, io.EOF
+		}
+
+		var pe *gocsv.ParseError
+		if errors.As(err, &pe) {
+			r.record = nil
+			r.line = pe.StartLine
+			return nil, &RowError{Line: pe.StartLine, Err: pe.Err}
+		}
+
+		return nil, err
+	}
+
+	r.record = record
+	r.line, _ = r.r.FieldPos(0)
+
+	if len(record) != len(r.header) {
+		return nil, &RowError{
+			Line: r.line,
+			Err:  fmt.Errorf("has %d cells, the header has %d", len(record), len(r.header)),
+		}
+	}
+
+	acc := account.New("")
+
+	// Values of multi-valued fields are collected before being set.
+	lists := make(map[*column]map[int]string)
+	listHeaders := make(map[*column]string)
+
+	for i, value := range record {
+		c := r.cells[i]
+		if c == nil || value == "" {
+			continue
+		}
+
+		if c.col.list {
+			if lists[c.col] == nil {
+				lists[c.col] = make(map[int]string)
+			}
+			lists[c.col][c.index] = value
+			listHeaders[c.col] = r.header[i]
+
+			continue
+		}
+
+		err := c.col.set(acc, []string{value})
+		if err != nil {
+			return nil, &RowError{Line: r.line, Column: r.header[i], Err: err}
+		}
+	}
+
+	for col, byIndex := range lists {
+		indexes := make([]int, 0, len(byIndex))
+		for i := range byIndex {
+			indexes = append(indexes, i)
+		}
+		sort.Ints(indexes)
+
+		values := make([]string, len(indexes))
+		for i, index := range indexes {
+			values[i] = byIndex[index]
+		}
+
+		err := col.set(acc, values)
+		if err != nil {
+			return nil, &RowError{Line: r.line, Column: listHeaders[col], Err: err}
+		}
+	}
+
+	return acc, nil
+}
+
+// Record returns the cells of the row last read, as they were in the input.
+//
+// It is nil if the row could not be parsed as CSV.
+func (r *Reader) Record() []string {
+	return r.record
+}
+
+// Line returns the line of the row last read.
+func (r *Reader) Line() int {
+	return r.line
+}
+
+// WriterOpt represents an option that can be passed to NewWriter.
+type WriterOpt func(w *Writer)
+
+// WithColumns sets the columns to write, in order.
+//
+// If not used, DefaultColumns are written.
+func WithColumns(names ...string) WriterOpt {
+	return func(w *Writer) {
+		w.names = names
+	}
+}
+
+// Writer writes accounts as CSV rows.
+//
+// The header is written before the first row.
+type Writer struct {
+	w     *gocsv.Writer
+	names []string
+
+	cells []cell
+
+	// widths are the number of columns of each multi-valued field.
+	widths map[*column]int
+
+	wroteHeader bool
+}
+
+// NewWriter returns a Writer writing to w.
+func NewWriter(w io.Writer, opts ...WriterOpt) *Writer {
+	writer := &Writer{w: gocsv.NewWriter(w)}
+	for _, opt := range opts {
+		opt(writer)
+	}
+
+	if writer.names == nil {
+		writer.names = DefaultColumns()
+	}
+
+	return writer
+}
+
+// writeHeader resolves the columns and writes the header.
+func (w *Writer) writeHeader() error {
+	if w.wroteHeader {
+		return nil
+	}
+
+	w.cells = make([]cell, len(w.names))
+	w.widths = make(map[*column]int)
+
+	for i, name := range w.names {
+		c, err := resolve(name)
+		if err != nil {
+			return fmt.Errorf("write header: %w", err)
+		}
+		w.cells[i] = c
+
+		if c.col.list && c.index+1 > w.widths[c.col] {
+			w.widths[c.col] = c.index + 1
+		}
+	}
+
+	err := w.w.Write(w.names)
+	if err != nil {
+		return fmt.Errorf("write header: %w", err)
+	}
+	w.wroteHeader = true
+
+	return nil
+}
+
+// Write writes acc as a row.
+//
+// It returns an error if a multi-valued field has more values than columns.
+func (w *Writer) Write(acc *account.Account) error {
+	err := w.writeHeader()
+	if err != nil {
+		return err
+	}
+
+	values := make(map[*column][]string)
+	for _, c := range w.cells {
+		if _, ok := values[c.col]; ok {
+			continue
+		}
+
+		v, err := c.col.get(acc)
+		if err != nil {
+			return fmt.Errorf("write %s: %w", c.col.name, err)
+		}
+		values[c.col] = v
+
+		if c.col.list && len(v) > w.widths[c.col] {
+			return fmt.Errorf(
+				"write %s: %d values do not fit in %d columns",
+				c.col.name, len(v), w.widths[c.col],
+			)
+		}
+	}
+
+	record := make([]string, len(w.cells))
+	for i, c := range w.cells {
+		v := values[c.col]
+		if c.index < len(v) {
+			record[i] = v[c.index]
+		}
+	}
+
+	return w.w.Write(record)
+}
+
+// Flush writes buffered rows, and the header if no rows were written.
+func (w *Writer) Flush() error {
+	err := w.writeHeader()
+	if err != nil {
+		return err
+	}
+
+	w.w.Flush()
+
+	return w.w.Error()
+}
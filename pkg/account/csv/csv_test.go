@@ -0,0 +1,194 @@
+package csv_test
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"strings"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"github.com/vivangkumar/form3-http-go/pkg/account"
+	"github.com/vivangkumar/form3-http-go/pkg/account/csv"
+)
+
+var _ = Describe("Account CSV", func() {
+	fullAccount := func() *account.Account {
+		version := int64(2)
+		customerID := "customer-1"
+		joint := false
+
+		attrs := account.NewAttributes("GBP", "GB").
+			WithBankID("400300").
+			WithBankIDCodeValue(account.BankIDCodeGBDSC).
+			WithBic("NWBKGB22").
+			WithAccountNumber("41426819").
+			WithIban("GB11NWBK40030041426819").
+			WithAccountClassificationValue(account.ClassificationPersonal).
+			WithStatusValue(account.StatusConfirmed).
+			WithCustomerID(&customerID).
+			WithJointAccount(&joint).
+			WithName("Samantha Holder").
+			WithName("c/o Jo Holder, \"The Lodge\"").
+			WithAlternativeName("Sam Holder").
+			WithPrivateIdentification(&account.PrivateIdentification{
+				BirthDate: "2017-07-23",
+				Address:   []string{"10 Avenue des Champs"},
+			})
+
+		acc := account.New("eb0bd6f5-c3f5-44b2-b677-acd23cdde73c").
+			WithID("ad27e265-9605-4b4b-a0e5-3003ea9cc4dc").
+			WithAttributes(attrs).
+			WithMasterAccount("a52d13a4-f435-4c00-cfad-f5e7ac5972df")
+		acc.Version = &version
+
+		return acc
+	}
+
+	Describe("Round trips", func() {
+		It("should read back the accounts it writes", func() {
+			accs := []*account.Account{
+				fullAccount(),
+				account.New("eb0bd6f5-c3f5-44b2-b677-acd23cdde73c").
+					WithAttributes(account.NewAttributes("EUR", "FR")),
+			}
+
+			var buf bytes.Buffer
+			w := csv.NewWriter(&buf)
+			for _, acc := range accs {
+				Expect(w.Write(acc)).To(Succeed())
+			}
+			Expect(w.Flush()).To(Succeed())
+
+			r := csv.NewReader(&buf)
+			for _, want := range accs {
+				got, err := r.Read()
+				Expect(err).To(BeNil())
+				Expect(got).To(Equal(want))
+			}
+
+			_, err := r.Read()
+			Expect(err).To(Equal(io.EOF))
+		})
+
+		It("should write multi-valued names to one cell per line", func() {
+			var buf bytes.Buffer
+			w := csv.NewWriter(&buf, csv.WithColumns("id", "name[0]", "name[1]"))
+			Expect(w.Write(fullAccount())).To(Succeed())
+			Expect(w.Flush()).To(Succeed())
+
+			Expect(buf.String()).To(Equal(
+				"id,name[0],name[1]\n" +
+					`ad27e265-9605-4b4b-a0e5-3003ea9cc4dc,Samantha Holder,"c/o Jo Holder, ""The Lodge"""` + "\n",
+			))
+		})
+
+		It("should reject more values than columns", func() {
+			w := csv.NewWriter(io.Discard, csv.WithColumns("id", "name[0]"))
+			Expect(w.Write(fullAccount())).To(MatchError(ContainSubstring("2 values do not fit in 1 columns")))
+		})
+
+		It("should write the header without rows", func() {
+			var buf bytes.Buffer
+			w := csv.NewWriter(&buf, csv.WithColumns("id", "country"))
+			Expect(w.Flush()).To(Succeed())
+			Expect(buf.String()).To(Equal("id,country\n"))
+		})
+
+		It("should reject unknown columns", func() {
+			w := csv.NewWriter(io.Discard, csv.WithColumns("id", "sort_code"))
+			Expect(w.Flush()).To(MatchError(ContainSubstring(`unknown column "sort_code"`)))
+		})
+	})
+
+	Describe("Reading spreadsheets", func() {
+		It("should map headers to columns", func() {
+			in := "Sort Code,Account Number,Holder,Notes\n400300,41426819,Samantha Holder,VIP\n"
+
+			r := csv.NewReader(strings.NewReader(in), csv.WithMapping(map[string]string{
+				"Sort Code":      "bank_id",
+				"Account Number": "account_number",
+				"Holder":         "name",
+				"Notes":          csv.IgnoreColumn,
+			}))
+
+			acc, err := r.Read()
+			Expect(err).To(BeNil())
+			Expect(acc.Attributes.BankID).To(Equal("400300"))
+			Expect(acc.Attributes.AccountNumber).To(Equal("41426819"))
+			Expect(acc.Attributes.Name).To(Equal([]string{"Samantha Holder"}))
+		})
+
+		It("should reject unmapped unknown headers", func() {
+			r := csv.NewReader(strings.NewReader("Sort Code\n400300\n"))
+
+			_, err := r.Read()
+			Expect(err).To(MatchError(ContainSubstring(`unknown column "Sort Code"`)))
+		})
+
+		It("should skip empty cells of multi-valued fields", func() {
+			r := csv.NewReader(strings.NewReader("name[0],name[1],name[2]\nSamantha,,Holder\n"))
+
+			acc, err := r.Read()
+			Expect(err).To(BeNil())
+			Expect(acc.Attributes.Name).To(Equal([]string{"Samantha", "Holder"}))
+		})
+
+		It("should report invalid rows and continue", func() {
+			in := "id,joint_account,version\n" +
+				"1,true,1\n" +
+				"2,maybe,1\n" +
+				"3,false\n" +
+				"4,false,one\n" +
+				"5,false,5\n"
+
+			r := csv.NewReader(strings.NewReader(in))
+
+			var (
+				ids  []string
+				errs []*csv.RowError
+			)
+			for {
+				acc, err := r.Read()
+				if errors.Is(err, io.EOF) {
+					break
+				}
+
+				var re *csv.RowError
+				if errors.As(err, &re) {
+					errs = append(errs, re)
+					continue
+				}
+				Expect(err).To(BeNil())
+
+				ids = append(ids, acc.ID)
+			}
+
+			Expect(ids).To(Equal([]string{"1", "5"}))
+			Expect(errs).To(HaveLen(3))
+			Expect(errs[0].Error()).To(Equal(`line 3: column joint_account: "maybe" is not true or false`))
+			Expect(errs[1].Error()).To(Equal("line 4: has 2 cells, the header has 3"))
+			Expect(errs[2].Line).To(Equal(5))
+			Expect(errs[2].Column).To(Equal("version"))
+		})
+
+		It("should expose the raw cells of the last row", func() {
+			r := csv.NewReader(strings.NewReader("id,country\n1,GB\n"))
+
+			_, err := r.Read()
+			Expect(err).To(BeNil())
+			Expect(r.Record()).To(Equal([]string{"1", "GB"}))
+			Expect(r.Line()).To(Equal(2))
+
+			header, err := r.Header()
+			Expect(err).To(BeNil())
+			Expect(header).To(Equal([]string{"id", "country"}))
+		})
+
+		It("should reject empty input", func() {
+			_, err := csv.NewReader(strings.NewReader("")).Read()
+			Expect(err).To(MatchError(ContainSubstring("input is empty")))
+		})
+	})
+})
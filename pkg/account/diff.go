@@ -0,0 +1,428 @@
+package account
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+)
+
+// attributesPath is the path of the fields of Attributes.
+const attributesPath = "attributes"
+
+// FieldChange is a field whose value differs between two accounts.
+type FieldChange struct {
+	// Field is the JSON path of the field, for example "attributes.iban".
+	Field string `json:"field"`
+
+	// From and To are the values of the field in the two accounts, nil if
+	// it is not set. Pointer fields hold the value they point to, and
+	// attributes held in Extra hold their JSON.
+	From any `json:"from"`
+	To   any `json:"to"`
+}
+
+// Diff returns the fields that differ from a to b: the organisation, the
+// type, the attributes in the order they are declared, and then the
+// attributes held in Extra by name.
+//
+// The ID, version and relationships are not compared. Fields set to their
+// zero value are unset, and a nil account has no fields set.
+func Diff(a *Account, b *Account) []FieldChange {
+	a, b = orEmpty(a), orEmpty(b)
+
+	var changes []FieldChange
+	add := func(field string, from any, to any) {
+		if !equal(from, to) {
+			changes = append(changes, FieldChange{Field: field, From: from, To: to})
+		}
+	}
+
+	add("organisation_id", fieldValue(reflect.ValueOf(a.OrganisationID)), fieldValue(reflect.ValueOf(b.OrganisationID)))
+	add("type", fieldValue(reflect.ValueOf(a.Type)), fieldValue(reflect.ValueOf(b.Type)))
+
+	aa, ba := a.Attributes, b.Attributes
+	if aa == nil {
+		aa = &Attributes{}
+	}
+	if ba == nil {
+		ba = &Attributes{}
+	}
+
+	av, bv := reflect.ValueOf(aa).Elem(), reflect.ValueOf(ba).Elem()
+	for i := 0; i < av.NumField(); i++ {
+		f := av.Type().Field(i)
+		if !f.IsExported() || f.Tag.Get("json") == "-" {
+			continue
+		}
+
+		add(joinPath(attributesPath, jsonFieldName(f)), fieldValue(av.Field(i)), fieldValue(bv.Field(i)))
+	}
+
+	for _, k := range unionKeys(aa.Extra, ba.Extra) {
+		add(joinPath(attributesPath, k), rawValue(aa.Extra, k), rawValue(ba.Extra, k))
+	}
+
+	return changes
+}
+
+// Mismatches compares want against got field by field, and returns the JSON
+// paths of the fields that differ.
+//
+// Only the fields set on want are compared, since the API fills in defaults
+// for fields that were not sent. The organisation is compared only if it is
+// set too. Nested objects, such as attributes.private_identification, are
+// compared field by field in the same way. If got is nil, the path data is
+// returned.
+func Mismatches(want *Account, got *Account) []string {
+	if got == nil {
+		return []string{"data"}
+	}
+	want = orEmpty(want)
+
+	var fields []string
+	fields = append(fields, mismatches("organisation_id", reflect.ValueOf(want.OrganisationID), reflect.ValueOf(got.OrganisationID))...)
+	fields = append(fields, mismatches("type", reflect.ValueOf(want.Type), reflect.ValueOf(got.Type))...)
+	fields = append(fields, mismatches(attributesPath, reflect.ValueOf(want.Attributes), reflect.ValueOf(got.Attributes))...)
+
+	return fields
+}
+
+// mismatches returns the paths of the fields below path that are set on
+// want and differ in got. Structs are compared field by field, in the order
+// they are declared and then by the names held in Extra, and other values
+// as a whole.
+func mismatches(path string, want reflect.Value, got reflect.Value) []string {
+	w := fieldValue(want)
+	if w == nil {
+		return nil
+	}
+	g := fieldValue(got)
+
+	wv := reflect.ValueOf(w)
+	if wv.Kind() != reflect.Struct {
+		if equal(w, g) {
+			return nil
+		}
+		return []string{path}
+	}
+
+	gv := reflect.Zero(wv.Type())
+	if g != nil {
+		gv = reflect.ValueOf(g)
+	}
+
+	var fields []string
+	for i := 0; i < wv.NumField(); i++ {
+		f := wv.Type().Field(i)
+		if !f.IsExported() || f.Tag.Get("json") == "-" {
+			continue
+		}
+
+		fields = append(fields, mismatches(joinPath(path, jsonFieldName(f)), wv.Field(i), gv.Field(i))...)
+	}
+
+	we, ge := extraOf(wv), extraOf(gv)
+	for _, k := range unionKeys(we, nil) {
+		if !equal(rawValue(we, k), rawValue(ge, k)) {
+			fields = append(fields, joinPath(path, k))
+		}
+	}
+
+	return fields
+}
+
+// extraOf returns the Extra field of the struct v, or nil if it has none.
+func extraOf(v reflect.Value) map[string]json.RawMessage {
+	f := v.FieldByName("Extra")
+	if !f.IsValid() {
+		return nil
+	}
+
+	extra, _ := f.Interface().(map[string]json.RawMessage)
+
+	return extra
+}
+
+// Patch returns the account to update current with, to make the given
+// changes to it, such as those returned by Diff(current, want).
+//
+// It holds the ID, organisation, type and version of current and only the
+// changed fields, so that other fields are left alone by the update.
+// Attributes changed to nil are sent as null, to clear them.
+func Patch(current *Account, changes []FieldChange) (*Account, error) {
+	current = orEmpty(current)
+
+	patch := &Account{
+		ID:             current.ID,
+		OrganisationID: current.OrganisationID,
+		Type:           current.Type,
+		Version:        current.Version,
+	}
+
+	for _, c := range changes {
+		err := patch.setField(c.Field, c.To, true)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return patch, nil
+}
+
+// Conflict is a field changed to different values by both sides of a
+// merge.
+type Conflict struct {
+	Field string `json:"field"`
+
+	Base   any `json:"base"`
+	Ours   any `json:"ours"`
+	Theirs any `json:"theirs"`
+}
+
+// Merge applies the changes made from base to ours onto theirs, the
+// account as changed from base by another writer. It is for updates that
+// fail with 409 Conflict because the account changed after it was fetched:
+// base is the account fetched, ours the account sent and theirs the
+// account fetched again.
+//
+// Fields changed to different values on both sides conflict, and keep the
+// value of theirs. The merged account has the ID and version of theirs, so
+// once the conflicts are resolved it can be sent with:
+//
+//	patch, err := account.Patch(theirs, account.Diff(theirs, merged))
+func Merge(base *Account, ours *Account, theirs *Account) (*Account, []Conflict, error) {
+	merged, err := copyAccount(orEmpty(theirs))
+	if err != nil {
+		return nil, nil, err
+	}
+
+	changed := make(map[string]FieldChange)
+	for _, c := range Diff(base, theirs) {
+		changed[c.Field] = c
+	}
+
+	var conflicts []Conflict
+	for _, c := range Diff(base, ours) {
+		t, ok := changed[c.Field]
+		if !ok {
+			err := merged.setField(c.Field, c.To, false)
+			if err != nil {
+				return nil, nil, err
+			}
+			continue
+		}
+
+		if !equal(c.To, t.To) {
+			conflicts = append(conflicts, Conflict{Field: c.Field, Base: c.From, Ours: c.To, Theirs: t.To})
+		}
+	}
+
+	return merged, conflicts, nil
+}
+
+// setField sets the field at the JSON path field to v. Attributes set to
+// nil are unset, or sent as null if null is true.
+func (a *Account) setField(field string, v any, null bool) error {
+	name, ok := cutPrefix(field, attributesPath+".")
+	if !ok {
+		switch field {
+		case "organisation_id":
+			return setValue(reflect.ValueOf(&a.OrganisationID).Elem(), field, v)
+		case "type":
+			return setValue(reflect.ValueOf(&a.Type).Elem(), field, v)
+		}
+
+		return fmt.Errorf("unknown field %q", field)
+	}
+
+	if a.Attributes == nil {
+		a.Attributes = &Attributes{}
+	}
+	attrs := a.Attributes
+
+	av := reflect.ValueOf(attrs).Elem()
+	for i := 0; i < av.NumField(); i++ {
+		f := av.Type().Field(i)
+		if !f.IsExported() || f.Tag.Get("json") == "-" || jsonFieldName(f) != name {
+			continue
+		}
+
+		err := setValue(av.Field(i), field, v)
+		if err != nil {
+			return err
+		}
+
+		// Unset fields are omitted when encoded, so the null is sent instead.
+		if v == nil && null {
+			attrs.setExtra(name, json.RawMessage("null"))
+		}
+
+		return nil
+	}
+
+	if v == nil {
+		if null {
+			attrs.setExtra(name, json.RawMessage("null"))
+		} else {
+			delete(attrs.Extra, name)
+		}
+
+		return nil
+	}
+
+	raw, err := json.Marshal(v)
+	if err != nil {
+		return fmt.Errorf("set %s: %w", field, err)
+	}
+	attrs.setExtra(name, raw)
+
+	return nil
+}
+
+func (a *Attributes) setExtra(name string, raw json.RawMessage) {
+	if a.Extra == nil {
+		a.Extra = make(map[string]json.RawMessage)
+	}
+	a.Extra[name] = raw
+}
+
+// setValue sets fv to v, or the value v points to for pointer fields. Values
+// of other types are converted through JSON.
+func setValue(fv reflect.Value, field string, v any) error {
+	if v == nil {
+		fv.Set(reflect.Zero(fv.Type()))
+		return nil
+	}
+
+	rv := reflect.ValueOf(v)
+	ft := fv.Type()
+
+	switch {
+	case rv.Type().AssignableTo(ft):
+		fv.Set(rv)
+		return nil
+	case ft.Kind() == reflect.Pointer && rv.Type().AssignableTo(ft.Elem()):
+		p := reflect.New(ft.Elem())
+		p.Elem().Set(rv)
+		fv.Set(p)
+		return nil
+	}
+
+	raw, err := json.Marshal(v)
+	if err != nil {
+		return fmt.Errorf("set %s: %w", field, err)
+	}
+
+	p := reflect.New(ft)
+	err = json.Unmarshal(raw, p.Interface())
+	if err != nil {
+		return fmt.Errorf("set %s: %w", field, err)
+	}
+	fv.Set(p.Elem())
+
+	return nil
+}
+
+// fieldValue returns the value of a field, the value it points to, or nil
+// if it is not set. Empty slices are not set.
+func fieldValue(v reflect.Value) any {
+	if v.IsZero() || (v.Kind() == reflect.Slice && v.Len() == 0) {
+		return nil
+	}
+
+	if v.Kind() == reflect.Pointer {
+		v = v.Elem()
+	}
+
+	return v.Interface()
+}
+
+// rawValue returns the JSON of key in extra, or nil if it is not set.
+func rawValue(extra map[string]json.RawMessage, key string) any {
+	raw, ok := extra[key]
+	if !ok {
+		return nil
+	}
+
+	return raw
+}
+
+// equal reports whether a and b are the same value, comparing values of
+// different types, such as JSON and the value it encodes, by their JSON.
+func equal(a any, b any) bool {
+	if reflect.DeepEqual(a, b) {
+		return true
+	}
+	if a == nil || b == nil {
+		return false
+	}
+
+	var av, bv any
+	if json.Unmarshal(jsonOf(a), &av) != nil || json.Unmarshal(jsonOf(b), &bv) != nil {
+		return false
+	}
+
+	return reflect.DeepEqual(av, bv)
+}
+
+func jsonOf(v any) []byte {
+	if raw, ok := v.(json.RawMessage); ok {
+		return raw
+	}
+
+	b, _ := json.Marshal(v)
+
+	return b
+}
+
+// copyAccount returns a deep copy of acc.
+func copyAccount(acc *Account) (*Account, error) {
+	b, err := json.Marshal(acc)
+	if err != nil {
+		return nil, fmt.Errorf("copy account: %w", err)
+	}
+
+	c := &Account{}
+	err = json.Unmarshal(b, c)
+	if err != nil {
+		return nil, fmt.Errorf("copy account: %w", err)
+	}
+
+	return c, nil
+}
+
+func orEmpty(acc *Account) *Account {
+	if acc == nil {
+		return &Account{}
+	}
+
+	return acc
+}
+
+// unionKeys returns the keys of a and b, sorted.
+func unionKeys(a map[string]json.RawMessage, b map[string]json.RawMessage) []string {
+	seen := make(map[string]bool, len(a)+len(b))
+	var keys []string
+	for _, m := range []map[string]json.RawMessage{a, b} {
+		for k := range m {
+			if !seen[k] {
+				seen[k] = true
+				keys = append(keys, k)
+			}
+		}
+	}
+	sort.Strings(keys)
+
+	return keys
+}
+
+// cutPrefix returns s without prefix, and whether s began with it.
+func cutPrefix(s string, prefix string) (string, bool) {
+	if !strings.HasPrefix(s, prefix) {
+		return s, false
+	}
+
+	return s[len(prefix):], true
+}
@@ -0,0 +1,245 @@
+package account_test
+
+import (
+	"encoding/json"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"github.com/vivangkumar/form3-http-go/pkg/account"
+)
+
+var _ = Describe("Diff", func() {
+	var a, b *account.Account
+
+	BeforeEach(func() {
+		matching := true
+		customerID := "customer-1"
+
+		a = account.New("org").WithID("1").WithAttributes(&account.Attributes{
+			Country:               "GB",
+			Iban:                  "GB11NWBK40030041426819",
+			AccountMatchingOptOut: &matching,
+			CustomerID:            &customerID,
+			Name:                  []string{"Samantha Holder"},
+		})
+
+		v := int64(3)
+		b = account.New("org").WithID("1").WithAttributes(&account.Attributes{
+			Country: "GB",
+			Iban:    "GB11NWBK40030041426819",
+			Name:    []string{"Samantha", "Holder"},
+		})
+		b.Version = &v
+	})
+
+	It("returns the fields that differ, in order", func() {
+		not := false
+		b.Attributes.AccountMatchingOptOut = &not
+		b.Attributes.Bic = "NWBKGB22"
+
+		Expect(account.Diff(a, b)).To(Equal([]account.FieldChange{
+			{Field: "attributes.account_matching_opt_out", From: true, To: false},
+			{Field: "attributes.bic", From: nil, To: "NWBKGB22"},
+			{Field: "attributes.customer_id", From: "customer-1", To: nil},
+			{Field: "attributes.name", From: []string{"Samantha Holder"}, To: []string{"Samantha", "Holder"}},
+		}))
+	})
+
+	It("ignores the ID and version", func() {
+		b.ID = "2"
+		b.Attributes = a.Attributes
+
+		Expect(account.Diff(a, b)).To(BeEmpty())
+	})
+
+	It("treats empty and unset values alike", func() {
+		a.Attributes = &account.Attributes{Name: []string{}}
+
+		Expect(account.Diff(a, &account.Account{OrganisationID: "org", Type: a.Type})).To(BeEmpty())
+		Expect(account.Diff(nil, &account.Account{Type: "accounts"})).To(Equal([]account.FieldChange{
+			{Field: "type", From: nil, To: "accounts"},
+		}))
+	})
+
+	It("compares attributes that are not modelled by their JSON", func() {
+		a.Attributes.Extra = map[string]json.RawMessage{"colour": json.RawMessage(`"red"`), "tags": json.RawMessage(`["a"]`)}
+		attrs := *a.Attributes
+		b.Attributes = &attrs
+		b.Attributes.Extra = map[string]json.RawMessage{"colour": json.RawMessage(` "red" `), "size": json.RawMessage(`1`)}
+
+		changes := account.Diff(a, b)
+		Expect(changes).To(HaveLen(2))
+		Expect(changes[0].Field).To(Equal("attributes.size"))
+		Expect(changes[1].Field).To(Equal("attributes.tags"))
+	})
+})
+
+var _ = Describe("Mismatches", func() {
+	It("compares the organisation and the fields set on want", func() {
+		want := account.New("org").WithAttributes(&account.Attributes{Country: "GB", Bic: "NWBKGB22"})
+		got := account.New("other").WithAttributes(&account.Attributes{
+			Country:      "GB",
+			Bic:          "NWBKGB21",
+			BaseCurrency: "GBP",
+		})
+
+		Expect(account.Mismatches(want, got)).To(Equal([]string{"organisation_id", "attributes.bic"}))
+		Expect(account.Mismatches(want, nil)).To(Equal([]string{"data"}))
+	})
+
+	It("ignores the organisation when want has none", func() {
+		want := account.New("").WithAttributes(&account.Attributes{Country: "GB"})
+		got := account.New("org").WithAttributes(&account.Attributes{Country: "GB"})
+
+		Expect(account.Mismatches(want, got)).To(BeEmpty())
+	})
+
+	It("compares only the nested fields set on want", func() {
+		want := account.New("org").WithAttributes(&account.Attributes{
+			PrivateIdentification: &account.PrivateIdentification{Identification: "13YH458762"},
+		})
+		got := account.New("org").WithAttributes(&account.Attributes{
+			PrivateIdentification: &account.PrivateIdentification{
+				Identification: "13YH458762",
+				City:           "London",
+			},
+		})
+
+		Expect(account.Mismatches(want, got)).To(BeEmpty())
+
+		got.Attributes.PrivateIdentification.Identification = "other"
+		Expect(account.Mismatches(want, got)).To(Equal([]string{"attributes.private_identification.identification"}))
+
+		got.Attributes.PrivateIdentification = nil
+		Expect(account.Mismatches(want, got)).To(Equal([]string{"attributes.private_identification.identification"}))
+	})
+})
+
+var _ = Describe("Patch", func() {
+	It("holds only the changed fields", func() {
+		v := int64(2)
+		current := account.New("org").WithID("1").WithAttributes(&account.Attributes{
+			Country: "GB",
+			Bic:     "NWBKGB22",
+			Name:    []string{"Samantha Holder"},
+		})
+		current.Version = &v
+
+		status := account.StatusConfirmed
+		want := account.New("org").WithID("1").WithAttributes(&account.Attributes{
+			Country: "GB",
+			Name:    []string{"Sam Holder"},
+			Status:  &status,
+		})
+
+		patch, err := account.Patch(current, account.Diff(current, want))
+		Expect(err).NotTo(HaveOccurred())
+
+		b, err := json.Marshal(patch)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(b).To(MatchJSON(`{
+			"id": "1",
+			"organisation_id": "org",
+			"type": "accounts",
+			"version": 2,
+			"attributes": {
+				"bic": null,
+				"name": ["Sam Holder"],
+				"status": "confirmed"
+			}
+		}`))
+	})
+
+	It("converts values through JSON", func() {
+		patch, err := account.Patch(nil, []account.FieldChange{
+			{Field: "attributes.status", To: "pending"},
+			{Field: "attributes.name", To: []any{"Jane Doe"}},
+			{Field: "attributes.colour", To: "red"},
+		})
+		Expect(err).NotTo(HaveOccurred())
+
+		Expect(*patch.Attributes.Status).To(Equal(account.StatusPending))
+		Expect(patch.Attributes.Name).To(Equal([]string{"Jane Doe"}))
+		Expect(patch.Attributes.Extra).To(HaveKeyWithValue("colour", json.RawMessage(`"red"`)))
+	})
+
+	It("rejects unknown fields and values", func() {
+		_, err := account.Patch(nil, []account.FieldChange{{Field: "version", To: 1}})
+		Expect(err).To(MatchError(`unknown field "version"`))
+
+		_, err = account.Patch(nil, []account.FieldChange{{Field: "attributes.name", To: 1}})
+		Expect(err).To(MatchError(ContainSubstring("set attributes.name: ")))
+	})
+})
+
+var _ = Describe("Merge", func() {
+	var base, ours, theirs *account.Account
+
+	BeforeEach(func() {
+		base = account.New("org").WithID("1").WithAttributes(&account.Attributes{
+			Country:       "GB",
+			AccountNumber: "41426819",
+			Bic:           "NWBKGB22",
+			Name:          []string{"Samantha Holder"},
+		})
+
+		ours = account.New("org").WithID("1").WithAttributes(&account.Attributes{
+			Country:       "GB",
+			AccountNumber: "41426819",
+			Bic:           "NWBKGB21",
+			Name:          []string{"Samantha Holder"},
+		})
+
+		v := int64(1)
+		theirs = account.New("org").WithID("1").WithAttributes(&account.Attributes{
+			Country:       "GB",
+			AccountNumber: "41426819",
+			Bic:           "NWBKGB22",
+			Name:          []string{"Sam Holder"},
+		})
+		theirs.Version = &v
+	})
+
+	It("applies our changes onto theirs", func() {
+		merged, conflicts, err := account.Merge(base, ours, theirs)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(conflicts).To(BeEmpty())
+
+		Expect(*merged.Version).To(Equal(int64(1)))
+		Expect(merged.Attributes.Bic).To(Equal("NWBKGB21"))
+		Expect(merged.Attributes.Name).To(Equal([]string{"Sam Holder"}))
+
+		Expect(theirs.Attributes.Bic).To(Equal("NWBKGB22"), "theirs is not modified")
+
+		patch, err := account.Patch(theirs, account.Diff(theirs, merged))
+		Expect(err).NotTo(HaveOccurred())
+		Expect(*patch.Version).To(Equal(int64(1)))
+		Expect(patch.Attributes).To(Equal(&account.Attributes{Bic: "NWBKGB21"}))
+	})
+
+	It("reports fields changed differently on both sides", func() {
+		ours.Attributes.Name = []string{"S. Holder"}
+		ours.Attributes.AccountNumber = ""
+
+		merged, conflicts, err := account.Merge(base, ours, theirs)
+		Expect(err).NotTo(HaveOccurred())
+
+		Expect(conflicts).To(Equal([]account.Conflict{{
+			Field:  "attributes.name",
+			Base:   []string{"Samantha Holder"},
+			Ours:   []string{"S. Holder"},
+			Theirs: []string{"Sam Holder"},
+		}}))
+		Expect(merged.Attributes.Name).To(Equal([]string{"Sam Holder"}))
+		Expect(merged.Attributes.AccountNumber).To(BeEmpty())
+	})
+
+	It("does not conflict on the same change", func() {
+		ours.Attributes.Name = theirs.Attributes.Name
+
+		_, conflicts, err := account.Merge(base, ours, theirs)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(conflicts).To(BeEmpty())
+	})
+})
@@ -0,0 +1,185 @@
+package account
+
+import (
+	"encoding/json"
+	"fmt"
+)
+
+// Classification represents the account classification.
+type Classification string
+
+// Known account classifications.
+const (
+	ClassificationPersonal Classification = "Personal"
+	ClassificationBusiness Classification = "Business"
+)
+
+// Known reports whether c is a classification known to this library.
+func (c Classification) Known() bool {
+	switch c {
+	case ClassificationPersonal, ClassificationBusiness:
+		return true
+	}
+
+	return false
+}
+
+// Status represents the status of an account.
+type Status string
+
+// Known account statuses.
+const (
+	StatusPending   Status = "pending"
+	StatusConfirmed Status = "confirmed"
+	StatusFailed    Status = "failed"
+)
+
+// Known reports whether s is a status known to this library.
+func (s Status) Known() bool {
+	switch s {
+	case StatusPending, StatusConfirmed, StatusFailed:
+		return true
+	}
+
+	return false
+}
+
+// BankIDCode identifies the type of bank ID used by an account.
+type BankIDCode string
+
+// Known bank ID codes.
+//
+// https://www.api-docs.form3.tech/api/schemes/bacs/accounts/accounts/create-an-account
+const (
+	BankIDCodeAUBSB BankIDCode = "AUBSB"
+	BankIDCodeBE    BankIDCode = "BE"
+	BankIDCodeCACPA BankIDCode = "CACPA"
+	BankIDCodeCHBCC BankIDCode = "CHBCC"
+	BankIDCodeDEBLZ BankIDCode = "DEBLZ"
+	BankIDCodeESNCC BankIDCode = "ESNCC"
+	BankIDCodeFR    BankIDCode = "FR"
+	BankIDCodeGBDSC BankIDCode = "GBDSC"
+	BankIDCodeGRBIC BankIDCode = "GRBIC"
+	BankIDCodeHKNCC BankIDCode = "HKNCC"
+	BankIDCodeITNCC BankIDCode = "ITNCC"
+	BankIDCodeLU    BankIDCode = "LU"
+	BankIDCodePLKNR BankIDCode = "PLKNR"
+	BankIDCodePTNCC BankIDCode = "PTNCC"
+	BankIDCodeUSABA BankIDCode = "USABA"
+)
+
+var knownBankIDCodes = map[BankIDCode]bool{
+	BankIDCodeAUBSB: true,
+	BankIDCodeBE:    true,
+	BankIDCodeCACPA: true,
+	BankIDCodeCHBCC: true,
+	BankIDCodeDEBLZ: true,
+	BankIDCodeESNCC: true,
+	BankIDCodeFR:    true,
+	BankIDCodeGBDSC: true,
+	BankIDCodeGRBIC: true,
+	BankIDCodeHKNCC: true,
+	BankIDCodeITNCC: true,
+	BankIDCodeLU:    true,
+	BankIDCodePLKNR: true,
+	BankIDCodePTNCC: true,
+	BankIDCodeUSABA: true,
+}
+
+// Known reports whether c is a bank ID code known to this library.
+func (c BankIDCode) Known() bool {
+	return knownBankIDCodes[c]
+}
+
+// EnumMode controls how enum values unknown to this library are handled.
+type EnumMode int
+
+const (
+	// EnumLenient keeps unknown values as they are.
+	EnumLenient EnumMode = iota
+
+	// EnumStrict rejects unknown values.
+	EnumStrict
+)
+
+// UnknownEnumError is returned in strict mode for an enum value that is not
+// known to this library.
+type UnknownEnumError struct {
+	// Field is the JSON path of the field holding the value.
+	Field string
+
+	// Value is the unknown value.
+	Value string
+}
+
+// Error implements the error interface.
+func (e *UnknownEnumError) Error() string {
+	return fmt.Sprintf("unknown value %q for %s", e.Value, e.Field)
+}
+
+// in returns a copy of e with the field nested in parent, or nil if e is nil.
+func (e *UnknownEnumError) in(parent string) *UnknownEnumError {
+	if e == nil {
+		return nil
+	}
+
+	return &UnknownEnumError{Field: parent + "." + e.Field, Value: e.Value}
+}
+
+// enum is implemented by the enum types in this package.
+type enum interface {
+	~string
+	Known() bool
+}
+
+// checkEnum returns an *UnknownEnumError if v is set and not known.
+func checkEnum[T enum](field string, v T) *UnknownEnumError {
+	if v == "" || v.Known() {
+		return nil
+	}
+
+	return &UnknownEnumError{Field: field, Value: string(v)}
+}
+
+// Marshal JSON encodes v.
+//
+// In strict mode, v is validated first if it has a Validate method, such
+// as Account and Attributes do. An *UnknownEnumError is returned if it holds
+// an enum value that is not known.
+func Marshal(v any, mode EnumMode) ([]byte, error) {
+	if mode == EnumStrict {
+		err := validate(v)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return json.Marshal(v)
+}
+
+// Unmarshal JSON decodes data into v.
+//
+// In strict mode, v is validated after decoding if it has a Validate
+// method, such as Account and Response do. An *UnknownEnumError is returned
+// if data holds an enum value that is not known. In lenient mode, unknown
+// values are kept.
+func Unmarshal(data []byte, v any, mode EnumMode) error {
+	err := json.Unmarshal(data, v)
+	if err != nil {
+		return err
+	}
+
+	if mode == EnumStrict {
+		return validate(v)
+	}
+
+	return nil
+}
+
+func validate(v any) error {
+	if val, ok := v.(interface{ Validate() error }); ok {
+		return val.Validate()
+	}
+
+	return nil
+}
@@ -0,0 +1,114 @@
+package account_test
+
+import (
+	"encoding/json"
+	"errors"
+
+	"github.com/google/uuid"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"github.com/vivangkumar/form3-http-go/pkg/account"
+	"github.com/vivangkumar/form3-http-go/pkg/internal/fixtures"
+)
+
+var _ = Describe("Account enums", func() {
+	Describe("Building attributes with typed values", func() {
+		It("should set the typed values", func() {
+			attrs := account.NewAttributes("GBP", "GB").
+				WithAccountClassificationValue(account.ClassificationBusiness).
+				WithStatusValue(account.StatusPending).
+				WithBankIDCodeValue(account.BankIDCodeGBDSC)
+
+			Expect(*attrs.AccountClassification).To(Equal(account.ClassificationBusiness))
+			Expect(*attrs.Status).To(Equal(account.StatusPending))
+			Expect(attrs.BankIDCode).To(Equal(account.BankIDCodeGBDSC))
+			Expect(attrs.Validate()).To(Succeed())
+		})
+
+		It("should keep supporting untyped values", func() {
+			class := "Personal"
+			status := "confirmed"
+
+			attrs := account.NewAttributes("GBP", "GB").
+				WithAccountClassification(&class).
+				WithStatus(&status).
+				WithBankIDCode("DEBLZ")
+
+			Expect(*attrs.AccountClassification).To(Equal(account.ClassificationPersonal))
+			Expect(*attrs.Status).To(Equal(account.StatusConfirmed))
+			Expect(attrs.BankIDCode).To(Equal(account.BankIDCodeDEBLZ))
+
+			attrs.WithAccountClassification(nil).WithStatus(nil)
+			Expect(attrs.AccountClassification).To(BeNil())
+			Expect(attrs.Status).To(BeNil())
+		})
+	})
+
+	Describe("Decoding", func() {
+		var body []byte
+
+		BeforeEach(func() {
+			var resp map[string]any
+			raw := fixtures.AccountsResponseMinFields(uuid.NewString(), uuid.NewString(), "FR", "EUR")
+			Expect(json.Unmarshal([]byte(raw), &resp)).To(Succeed())
+
+			attrs := resp["data"].(map[string]any)["attributes"].(map[string]any)
+			attrs["account_classification"] = "personal"
+
+			var err error
+			body, err = json.Marshal(resp)
+			Expect(err).To(BeNil())
+		})
+
+		It("should keep unknown values in lenient mode", func() {
+			var resp account.Response
+			Expect(account.Unmarshal(body, &resp, account.EnumLenient)).To(Succeed())
+			Expect(*resp.Data.Attributes.AccountClassification).To(Equal(account.Classification("personal")))
+			Expect(resp.Data.Attributes.AccountClassification.Known()).To(BeFalse())
+		})
+
+		It("should reject unknown values in strict mode", func() {
+			var resp account.Response
+			err := account.Unmarshal(body, &resp, account.EnumStrict)
+
+			var e *account.UnknownEnumError
+			Expect(errors.As(err, &e)).To(BeTrue())
+			Expect(e.Field).To(Equal("attributes.account_classification"))
+			Expect(e.Value).To(Equal("personal"))
+			Expect(err).To(MatchError(`data: unknown value "personal" for attributes.account_classification`))
+
+			Expect(resp.Validate()).To(MatchError(err.Error()))
+		})
+
+		It("should accept known values in strict mode", func() {
+			raw := fixtures.AccountsResponseAllAttributes(uuid.NewString(), uuid.NewString(), "GB", "GBP")
+
+			var resp account.Response
+			Expect(account.Unmarshal([]byte(raw), &resp, account.EnumStrict)).To(Succeed())
+		})
+	})
+
+	Describe("Encoding", func() {
+		var acc *account.Account
+
+		BeforeEach(func() {
+			attrs := account.NewAttributes("EUR", "FR").WithBankIDCode("XX")
+			acc = account.New(uuid.NewString()).WithAttributes(attrs)
+		})
+
+		It("should keep unknown values in lenient mode", func() {
+			b, err := account.Marshal(acc, account.EnumLenient)
+			Expect(err).To(BeNil())
+			Expect(string(b)).To(ContainSubstring(`"bank_id_code":"XX"`))
+		})
+
+		It("should reject unknown values in strict mode", func() {
+			_, err := account.Marshal(acc, account.EnumStrict)
+			Expect(err).To(MatchError(&account.UnknownEnumError{
+				Field: "attributes.bank_id_code",
+				Value: "XX",
+			}))
+		})
+	})
+})
@@ -0,0 +1,114 @@
+package account
+
+import (
+	"encoding/json"
+	"reflect"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// knownFieldsCache caches the JSON field names of struct types.
+var knownFieldsCache sync.Map
+
+// knownFields returns the JSON names of the fields of struct type t.
+func knownFields(t reflect.Type) map[string]bool {
+	if fields, ok := knownFieldsCache.Load(t); ok {
+		return fields.(map[string]bool)
+	}
+
+	fields := make(map[string]bool)
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		if !f.IsExported() || f.Tag.Get("json") == "-" {
+			continue
+		}
+
+		fields[jsonFieldName(f)] = true
+	}
+	knownFieldsCache.Store(t, fields)
+
+	return fields
+}
+
+// unmarshalWithExtra decodes data into v, which must point to a struct.
+//
+// Fields in data that v does not model are returned.
+func unmarshalWithExtra(data []byte, v any) (map[string]json.RawMessage, error) {
+	err := json.Unmarshal(data, v)
+	if err != nil {
+		return nil, err
+	}
+
+	var raw map[string]json.RawMessage
+	err = json.Unmarshal(data, &raw)
+	if err != nil {
+		return nil, err
+	}
+
+	known := knownFields(reflect.TypeOf(v).Elem())
+	for k := range raw {
+		if known[k] {
+			delete(raw, k)
+		}
+	}
+
+	if len(raw) == 0 {
+		return nil, nil
+	}
+
+	return raw, nil
+}
+
+// marshalWithExtra encodes v, adding extra fields that v does not model.
+//
+// Modelled fields take precedence over extra fields with the same name.
+func marshalWithExtra(v any, extra map[string]json.RawMessage) ([]byte, error) {
+	b, err := json.Marshal(v)
+	if err != nil || len(extra) == 0 {
+		return b, err
+	}
+
+	var fields map[string]json.RawMessage
+	err = json.Unmarshal(b, &fields)
+	if err != nil {
+		return nil, err
+	}
+
+	for k, val := range extra {
+		if _, ok := fields[k]; !ok {
+			fields[k] = val
+		}
+	}
+
+	return json.Marshal(fields)
+}
+
+// extraKeys returns the sorted keys of extra, prefixed with path.
+func extraKeys(path string, extra map[string]json.RawMessage) []string {
+	keys := make([]string, 0, len(extra))
+	for k := range extra {
+		keys = append(keys, joinPath(path, k))
+	}
+	sort.Strings(keys)
+
+	return keys
+}
+
+func joinPath(path string, name string) string {
+	if path == "" {
+		return name
+	}
+
+	return path + "." + name
+}
+
+// jsonFieldName returns the JSON name of a struct field.
+func jsonFieldName(f reflect.StructField) string {
+	name, _, _ := strings.Cut(f.Tag.Get("json"), ",")
+	if name == "" {
+		return f.Name
+	}
+
+	return name
+}
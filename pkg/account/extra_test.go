@@ -0,0 +1,114 @@
+package account_test
+
+import (
+	"encoding/json"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"github.com/vivangkumar/form3-http-go/pkg/account"
+)
+
+var _ = Describe("Unmodelled fields", func() {
+	var body string
+
+	BeforeEach(func() {
+		body = `{
+			"data": {
+				"id": "1",
+				"type": "accounts",
+				"version": 1,
+				"created_on": "2023-01-01T00:00:00Z",
+				"attributes": {
+					"country": "GB",
+					"colour": "red",
+					"tags": ["a", "b"]
+				}
+			}
+		}`
+	})
+
+	It("should keep unmodelled fields on decode", func() {
+		var resp account.Response
+		Expect(json.Unmarshal([]byte(body), &resp)).To(Succeed())
+
+		Expect(resp.Data.Extra).To(HaveKeyWithValue("created_on", json.RawMessage(`"2023-01-01T00:00:00Z"`)))
+		Expect(resp.Data.Attributes.Extra).To(HaveLen(2))
+		Expect(resp.Data.Attributes.Country).To(Equal("GB"))
+
+		Expect(resp.UnknownFields()).To(Equal([]string{
+			"data.created_on",
+			"data.attributes.colour",
+			"data.attributes.tags",
+		}))
+	})
+
+	It("should send unmodelled fields back on encode", func() {
+		var resp account.Response
+		Expect(json.Unmarshal([]byte(body), &resp)).To(Succeed())
+
+		// Modify the account as an update would.
+		resp.Data.Attributes.WithBic("NWBKGB22")
+
+		b, err := json.Marshal(resp.Data)
+		Expect(err).To(BeNil())
+		Expect(b).To(MatchJSON(`{
+			"id": "1",
+			"type": "accounts",
+			"version": 1,
+			"created_on": "2023-01-01T00:00:00Z",
+			"attributes": {
+				"country": "GB",
+				"bic": "NWBKGB22",
+				"colour": "red",
+				"tags": ["a", "b"]
+			}
+		}`))
+	})
+
+	It("should prefer modelled fields over extra fields", func() {
+		acc := account.New("org").WithID("1")
+		acc.Extra = map[string]json.RawMessage{"id": json.RawMessage(`"2"`)}
+
+		b, err := json.Marshal(acc)
+		Expect(err).To(BeNil())
+		Expect(b).To(MatchJSON(`{"id": "1", "organisation_id": "org", "type": "accounts"}`))
+	})
+
+	It("should keep unmodelled nested fields", func() {
+		var resp account.Response
+		Expect(json.Unmarshal([]byte(`{
+			"data": {
+				"id": "1",
+				"attributes": {
+					"organisation_identification": {
+						"identification": "123654",
+						"registration_number": "42",
+						"actors": [{"name": ["Jeff Page"], "role": "director"}]
+					},
+					"private_identification": {"identification": "13YH458762", "title": "Dr"}
+				},
+				"relationships": {"owner": {"data": {"type": "users", "id": "2"}}}
+			}
+		}`), &resp)).To(Succeed())
+
+		Expect(resp.UnknownFields()).To(Equal([]string{
+			"data.attributes.organisation_identification.registration_number",
+			"data.attributes.organisation_identification.actors[0].role",
+			"data.attributes.private_identification.title",
+			"data.relationships.owner",
+		}))
+
+		b, err := json.Marshal(resp.Data.Attributes.PrivateIdentification)
+		Expect(err).To(BeNil())
+		Expect(b).To(MatchJSON(`{"identification": "13YH458762", "title": "Dr"}`))
+	})
+
+	It("should not report unknown fields for modelled responses", func() {
+		var resp account.Response
+		Expect(json.Unmarshal([]byte(`{"data": {"id": "1", "attributes": {"country": "GB"}}}`), &resp)).To(Succeed())
+
+		Expect(resp.Data.Extra).To(BeNil())
+		Expect(resp.UnknownFields()).To(BeEmpty())
+	})
+})
@@ -39,6 +39,22 @@ type FakeBaseClient struct {
 		result1 *http.Response
 		result2 error
 	}
+	PatchStub        func(context.Context, string, any, any) (*http.Response, error)
+	patchMutex       sync.RWMutex
+	patchArgsForCall []struct {
+		arg1 context.Context
+		arg2 string
+		arg3 any
+		arg4 any
+	}
+	patchReturns struct {
+		result1 *http.Response
+		result2 error
+	}
+	patchReturnsOnCall map[int]struct {
+		result1 *http.Response
+		result2 error
+	}
 	PostStub        func(context.Context, string, any, any) (*http.Response, error)
 	postMutex       sync.RWMutex
 	postArgsForCall []struct {
@@ -192,6 +208,73 @@ func (fake *FakeBaseClient) GetReturnsOnCall(i int, result1 *http.Response, resu
 	}{result1, result2}
 }
 
+func (fake *FakeBaseClient) Patch(arg1 context.Context, arg2 string, arg3 any, arg4 any) (*http.Response, error) {
+	fake.patchMutex.Lock()
+	ret, specificReturn := fake.patchReturnsOnCall[len(fake.patchArgsForCall)]
+	fake.patchArgsForCall = append(fake.patchArgsForCall, struct {
+		arg1 context.Context
+		arg2 string
+		arg3 any
+		arg4 any
+	}{arg1, arg2, arg3, arg4})
+	stub := fake.PatchStub
+	fakeReturns := fake.patchReturns
+	fake.recordInvocation("Patch", []interface{}{arg1, arg2, arg3, arg4})
+	fake.patchMutex.Unlock()
+	if stub != nil {
+		return stub(arg1, arg2, arg3, arg4)
+	}
+	if specificReturn {
+		return ret.result1, ret.result2
+	}
+	return fakeReturns.result1, fakeReturns.result2
+}
+
+func (fake *FakeBaseClient) PatchCallCount() int {
+	fake.patchMutex.RLock()
+	defer fake.patchMutex.RUnlock()
+	return len(fake.patchArgsForCall)
+}
+
+func (fake *FakeBaseClient) PatchCalls(stub func(context.Context, string, any, any) (*http.Response, error)) {
+	fake.patchMutex.Lock()
+	defer fake.patchMutex.Unlock()
+	fake.PatchStub = stub
+}
+
+func (fake *FakeBaseClient) PatchArgsForCall(i int) (context.Context, string, any, any) {
+	fake.patchMutex.RLock()
+	defer fake.patchMutex.RUnlock()
+	argsForCall := fake.patchArgsForCall[i]
+	return argsForCall.arg1, argsForCall.arg2, argsForCall.arg3, argsForCall.arg4
+}
+
+func (fake *FakeBaseClient) PatchReturns(result1 *http.Response, result2 error) {
+	fake.patchMutex.Lock()
+	defer fake.patchMutex.Unlock()
+	fake.PatchStub = nil
+	fake.patchReturns = struct {
+		result1 *http.Response
+		result2 error
+	}{result1, result2}
+}
+
+func (fake *FakeBaseClient) PatchReturnsOnCall(i int, result1 *http.Response, result2 error) {
+	fake.patchMutex.Lock()
+	defer fake.patchMutex.Unlock()
+	fake.PatchStub = nil
+	if fake.patchReturnsOnCall == nil {
+		fake.patchReturnsOnCall = make(map[int]struct {
+			result1 *http.Response
+			result2 error
+		})
+	}
+	fake.patchReturnsOnCall[i] = struct {
+		result1 *http.Response
+		result2 error
+	}{result1, result2}
+}
+
 func (fake *FakeBaseClient) Post(arg1 context.Context, arg2 string, arg3 any, arg4 any) (*http.Response, error) {
 	fake.postMutex.Lock()
 	ret, specificReturn := fake.postReturnsOnCall[len(fake.postArgsForCall)]
@@ -266,6 +349,8 @@ func (fake *FakeBaseClient) Invocations() map[string][][]interface{} {
 	defer fake.deleteMutex.RUnlock()
 	fake.getMutex.RLock()
 	defer fake.getMutex.RUnlock()
+	fake.patchMutex.RLock()
+	defer fake.patchMutex.RUnlock()
 	fake.postMutex.RLock()
 	defer fake.postMutex.RUnlock()
 	copiedInvocations := map[string][][]interface{}{}
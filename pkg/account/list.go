@@ -0,0 +1,62 @@
+package account
+
+import (
+	"context"
+	"fmt"
+	"io"
+)
+
+// DefaultPageSize is the number of accounts listed per request by Pages if
+// no page size is set.
+const DefaultPageSize = 100
+
+// Lister lists accounts. The account client satisfies it.
+type Lister interface {
+	List(ctx context.Context, params ListAccountParams) (*ListResponse, error)
+}
+
+// Pages lists every account, page by page.
+type Pages struct {
+	lister Lister
+	params ListAccountParams
+	done   bool
+}
+
+// NewPages returns an iterator over the pages of accounts listed with l,
+// starting with the page of params. If params has no page size,
+// DefaultPageSize accounts are listed per request.
+func NewPages(l Lister, params ListAccountParams) *Pages {
+	if params.PageSize <= 0 {
+		params.PageSize = DefaultPageSize
+	}
+
+	return &Pages{lister: l, params: params}
+}
+
+// Next lists the next page of accounts.
+//
+// It returns io.EOF once every page has been listed: after a page without
+// a next link, or, if the API returns no links, after a page that is
+// shorter than the page size. The API may return fewer accounts than the
+// page size on pages that are not the last. Pages may overlap if accounts
+// are created during the listing, and may skip accounts if they are
+// deleted.
+func (p *Pages) Next(ctx context.Context) (*ListResponse, error) {
+	if p.done {
+		return nil, io.EOF
+	}
+
+	resp, err := p.lister.List(ctx, p.params)
+	if err != nil {
+		return nil, fmt.Errorf("list page %d: %w", p.params.PageNumber, err)
+	}
+
+	p.params.PageNumber++
+	if resp.Links != nil {
+		p.done = resp.Links.Next == nil || *resp.Links.Next == ""
+	} else {
+		p.done = len(resp.Data) < p.params.PageSize
+	}
+
+	return resp, nil
+}
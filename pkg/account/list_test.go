@@ -0,0 +1,82 @@
+package account_test
+
+import (
+	"context"
+	"errors"
+	"io"
+	"strconv"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"github.com/vivangkumar/form3-http-go/pkg/account"
+	"github.com/vivangkumar/form3-http-go/pkg/internal/fakes"
+)
+
+var _ = Describe("Pages", func() {
+	var (
+		ctx    context.Context
+		lister *fakes.Lister
+	)
+
+	BeforeEach(func() {
+		ctx = context.Background()
+
+		accs := make([]account.Account, 5)
+		for i := range accs {
+			accs[i] = *account.New("org").WithID(strconv.Itoa(i))
+		}
+		lister = fakes.NewLister(accs...)
+	})
+
+	// ids returns the IDs of every account listed by pages.
+	ids := func(pages *account.Pages) []string {
+		var ids []string
+		for {
+			resp, err := pages.Next(ctx)
+			if errors.Is(err, io.EOF) {
+				return ids
+			}
+			Expect(err).NotTo(HaveOccurred())
+
+			for _, acc := range resp.Data {
+				ids = append(ids, acc.ID)
+			}
+		}
+	}
+
+	It("lists every page until one is short", func() {
+		Expect(ids(account.NewPages(lister, account.ListAccountParams{PageSize: 2}))).To(Equal([]string{"0", "1", "2", "3", "4"}))
+		Expect(lister.Calls()).To(Equal(3))
+	})
+
+	It("follows next links on pages shorter than the page size", func() {
+		lister.SetMaxPageSize(2)
+
+		Expect(ids(account.NewPages(lister, account.ListAccountParams{PageSize: 10}))).To(Equal([]string{"0", "1", "2", "3", "4"}))
+		Expect(lister.Calls()).To(Equal(3))
+	})
+
+	It("stops at a full page without a next link", func() {
+		lister.SetMaxPageSize(5)
+
+		Expect(ids(account.NewPages(lister, account.ListAccountParams{PageSize: 5}))).To(HaveLen(5))
+		Expect(lister.Calls()).To(Equal(1))
+	})
+
+	It("starts with the page of the params", func() {
+		Expect(ids(account.NewPages(lister, account.ListAccountParams{PageNumber: 1, PageSize: 2}))).To(Equal([]string{"2", "3", "4"}))
+	})
+
+	It("lists the default page size without one", func() {
+		Expect(ids(account.NewPages(lister, account.ListAccountParams{}))).To(HaveLen(5))
+		Expect(lister.Calls()).To(Equal(1))
+	})
+
+	It("names the page that failed", func() {
+		lister.SetErr(errors.New("unavailable"))
+
+		_, err := account.NewPages(lister, account.ListAccountParams{PageNumber: 3}).Next(ctx)
+		Expect(err).To(MatchError("list page 3: unavailable"))
+	})
+})
@@ -0,0 +1,164 @@
+package mirror
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/vivangkumar/form3-http-go/pkg/account"
+)
+
+// maxEventSize is the largest event body accepted by Handler.
+const maxEventSize = 1 << 20
+
+// accountsType is the resource type of accounts.
+const accountsType = "accounts"
+
+// SignatureHeader is the header that carries the signature of events
+// POSTed to Handler.
+const SignatureHeader = "X-Signature"
+
+// EventType is the kind of change an event reports.
+type EventType string
+
+// Event types.
+const (
+	EventCreated EventType = "created"
+	EventUpdated EventType = "updated"
+	EventDeleted EventType = "deleted"
+)
+
+// Event reports a change to an account, such as a webhook notification.
+type Event struct {
+	Type EventType `json:"event_type"`
+
+	// ResourceType is the type of the changed resource. Events for
+	// resources other than accounts are ignored. It may be empty.
+	ResourceType string `json:"resource_type,omitempty"`
+
+	// Time is when the change happened, if known. It is used to report the
+	// lag of events.
+	Time time.Time `json:"time"`
+
+	// Account is the account after the change, or the deleted account.
+	// Deletes only need its ID and version.
+	Account *account.Account `json:"data"`
+}
+
+// Apply updates the mirror with ev.
+//
+// Created and updated accounts replace older versions. Deleted accounts are
+// removed, and are not brought back by listings started before the delete.
+func (m *Mirror) Apply(ev Event) error {
+	if ev.ResourceType != "" && ev.ResourceType != accountsType {
+		return nil
+	}
+
+	if ev.Account == nil || ev.Account.ID == "" {
+		return errors.New("event has no account ID")
+	}
+
+	switch ev.Type {
+	case EventCreated, EventUpdated, EventDeleted:
+	default:
+		return fmt.Errorf("unknown event type %q", ev.Type)
+	}
+
+	// Deletes need not carry the organisation.
+	if ev.Type != EventDeleted && !m.owns(ev.Account) {
+		return nil
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	now := m.now()
+
+	if ev.Type == EventDeleted {
+		m.store.remove(ev.Account.ID, version(ev.Account), now)
+	} else {
+		m.store.put(ev.Account, now)
+	}
+
+	m.status.LastEvent = now
+	m.status.EventLag = 0
+	if !ev.Time.IsZero() {
+		m.status.EventLag = now.Sub(ev.Time)
+	}
+
+	return nil
+}
+
+// Handler returns an HTTP handler that applies events POSTed to it as JSON,
+// for example:
+//
+//	{"event_type": "updated", "resource_type": "accounts", "data": {"id": "...", "version": 1, ...}}
+//
+// Events must be signed with secret, a secret shared with the sender: the
+// SignatureHeader of each request must hold the signature of its body, as
+// returned by Sign. Events without a valid signature are rejected with 401
+// Unauthorized, as are all events if secret is empty.
+//
+// It responds 204 No Content once an event is applied, and 400 Bad Request
+// for invalid events.
+func (m *Mirror) Handler(secret []byte) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			w.Header().Set("Allow", http.MethodPost)
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+
+		b, err := io.ReadAll(http.MaxBytesReader(w, r.Body, maxEventSize))
+		if err != nil {
+			http.Error(w, fmt.Sprintf("read event: %s", err), http.StatusBadRequest)
+			return
+		}
+
+		if !verify(secret, b, r.Header.Get(SignatureHeader)) {
+			http.Error(w, "invalid signature", http.StatusUnauthorized)
+			return
+		}
+
+		var ev Event
+		err = json.Unmarshal(b, &ev)
+		if err != nil {
+			http.Error(w, fmt.Sprintf("decode event: %s", err), http.StatusBadRequest)
+			return
+		}
+
+		err = m.Apply(ev)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		w.WriteHeader(http.StatusNoContent)
+	})
+}
+
+// Sign returns the signature of an event body, to be sent in the
+// SignatureHeader. It is "sha256=" followed by the hex encoded HMAC-SHA256
+// of body, keyed with secret.
+func Sign(secret []byte, body []byte) string {
+	h := hmac.New(sha256.New, secret)
+	h.Write(body)
+
+	return "sha256=" + hex.EncodeToString(h.Sum(nil))
+}
+
+// verify reports whether sig is the signature of body. Nothing verifies
+// with an empty secret.
+func verify(secret []byte, body []byte, sig string) bool {
+	if len(secret) == 0 {
+		return false
+	}
+
+	return hmac.Equal([]byte(sig), []byte(Sign(secret, body)))
+}
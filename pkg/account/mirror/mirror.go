@@ -0,0 +1,318 @@
+// Package mirror keeps a local copy of an organisation's accounts, for
+// workloads that read accounts far more often than they change them.
+//
+// A Mirror lists every account once, then keeps in sync by listing them
+// again periodically and by applying webhook events as they arrive. An
+// account is only replaced by a newer version, so listings and events may
+// arrive in any order. Queries are answered from memory.
+package mirror
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"sync"
+	"time"
+
+	"github.com/vivangkumar/form3-http-go/pkg/account"
+)
+
+const (
+	defaultInterval = 5 * time.Minute
+
+	// retryInterval is the longest wait before a failed listing is retried.
+	retryInterval = 10 * time.Second
+)
+
+// Opt represents an option that can be passed to New.
+type Opt func(m *Mirror)
+
+// WithPageSize sets the number of accounts listed per request, instead of
+// account.DefaultPageSize.
+func WithPageSize(n int) Opt {
+	return func(m *Mirror) {
+		if n > 0 {
+			m.pageSize = n
+		}
+	}
+}
+
+// WithInterval sets how often Run lists every account again.
+//
+// If not used, accounts are listed every 5 minutes.
+func WithInterval(d time.Duration) Opt {
+	return func(m *Mirror) {
+		if d > 0 {
+			m.interval = d
+		}
+	}
+}
+
+// WithFilter lists only the accounts matching filter, for example
+// {"country": "GB"}. Accounts added by events that do not match are removed
+// by the next listing.
+func WithFilter(filter map[string]string) Opt {
+	return func(m *Mirror) {
+		m.filter = filter
+	}
+}
+
+// WithErrorHandler calls fn with the errors of listings made by Run.
+func WithErrorHandler(fn func(error)) Opt {
+	return func(m *Mirror) {
+		m.onError = fn
+	}
+}
+
+// SyncResult summarises a listing of every account.
+type SyncResult struct {
+	// Listed is the number of accounts listed.
+	Listed int
+
+	// Added, Updated and Removed count the accounts the listing added,
+	// replaced with a newer version and found to be deleted. Accounts are
+	// only found to be deleted once two listings in a row miss them.
+	Added   int
+	Updated int
+	Removed int
+
+	// Duration is how long the listing took.
+	Duration time.Duration
+}
+
+// Status reports how up to date a mirror is.
+type Status struct {
+	// Accounts is the number of accounts mirrored.
+	Accounts int
+
+	// Synced reports whether every account has been listed at least once.
+	Synced bool
+
+	// LastSync is when the last complete listing started. Every change made
+	// before then is mirrored, except deletes without an event, which are
+	// mirrored by the listing after.
+	LastSync time.Time
+
+	// SyncDuration is how long the last complete listing took.
+	SyncDuration time.Duration
+
+	// Staleness is the time since LastSync. Changes made since then are only
+	// mirrored if their events were applied.
+	Staleness time.Duration
+
+	// LastEvent is when an event was last applied.
+	LastEvent time.Time
+
+	// EventLag is the delay between the last event applied happening and
+	// being applied, if the event carried its time.
+	EventLag time.Duration
+
+	// LastError is the error of the last listing, if it failed.
+	LastError error
+}
+
+// Mirror is a local copy of the accounts of an organisation.
+//
+// It is safe for concurrent use. Accounts returned by its queries are
+// shared, and must not be modified.
+type Mirror struct {
+	lister   account.Lister
+	orgID    string
+	pageSize int
+	interval time.Duration
+	filter   map[string]string
+	onError  func(error)
+	now      func() time.Time
+
+	// syncMu allows one listing at a time.
+	syncMu sync.Mutex
+
+	mu     sync.RWMutex
+	store  *store
+	status Status
+
+	synced     chan struct{}
+	syncedOnce sync.Once
+}
+
+// New returns an empty mirror of the accounts of the organisation orgID,
+// listed with l. If orgID is empty, accounts of every organisation are
+// mirrored.
+//
+// Call Run, or Sync, to fill it.
+func New(l account.Lister, orgID string, opts ...Opt) *Mirror {
+	m := &Mirror{
+		lister:   l,
+		orgID:    orgID,
+		interval: defaultInterval,
+		now:      time.Now,
+		store:    newStore(),
+		synced:   make(chan struct{}),
+	}
+
+	for _, opt := range opts {
+		opt(m)
+	}
+
+	return m
+}
+
+// Run lists every account, and then lists them again at the interval set
+// with WithInterval, until ctx is done. Failed listings are retried sooner.
+//
+// It returns the context error.
+func (m *Mirror) Run(ctx context.Context) error {
+	for {
+		wait := m.interval
+
+		_, err := m.Sync(ctx)
+		if err != nil && ctx.Err() == nil {
+			if m.onError != nil {
+				m.onError(err)
+			}
+			if wait > retryInterval {
+				wait = retryInterval
+			}
+		}
+
+		t := time.NewTimer(wait)
+		select {
+		case <-ctx.Done():
+			t.Stop()
+			return ctx.Err()
+		case <-t.C:
+		}
+	}
+}
+
+// Sync lists every account, page by page, and updates the mirror with
+// them. Accounts are only removed once the listing is complete, and the
+// listing before it did not return them either.
+func (m *Mirror) Sync(ctx context.Context) (SyncResult, error) {
+	m.syncMu.Lock()
+	defer m.syncMu.Unlock()
+
+	var res SyncResult
+	start := m.now()
+
+	pages := account.NewPages(m.lister, account.ListAccountParams{
+		PageSize: m.pageSize,
+		Filter:   m.filter,
+	})
+	for {
+		resp, err := pages.Next(ctx)
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			err = fmt.Errorf("sync: %w", err)
+
+			m.mu.Lock()
+			m.status.LastError = err
+			m.mu.Unlock()
+
+			return res, err
+		}
+
+		m.mu.Lock()
+		now := m.now()
+		for i := range resp.Data {
+			acc := &resp.Data[i]
+			if !m.owns(acc) {
+				continue
+			}
+
+			res.Listed++
+			switch m.store.put(acc, now) {
+			case added:
+				res.Added++
+			case updated:
+				res.Updated++
+			}
+		}
+		m.mu.Unlock()
+	}
+
+	m.mu.Lock()
+	res.Removed = m.store.prune(start)
+	res.Duration = m.now().Sub(start)
+
+	m.status.Synced = true
+	m.status.LastSync = start
+	m.status.SyncDuration = res.Duration
+	m.status.LastError = nil
+	m.mu.Unlock()
+
+	m.syncedOnce.Do(func() { close(m.synced) })
+
+	return res, nil
+}
+
+// Synced returns a channel that is closed once every account has been
+// listed for the first time.
+func (m *Mirror) Synced() <-chan struct{} {
+	return m.synced
+}
+
+// Status reports how up to date the mirror is.
+func (m *Mirror) Status() Status {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	s := m.status
+	s.Accounts = len(m.store.byID)
+	if s.Synced {
+		s.Staleness = m.now().Sub(s.LastSync)
+	}
+
+	return s
+}
+
+// Len returns the number of accounts mirrored.
+func (m *Mirror) Len() int {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	return len(m.store.byID)
+}
+
+// Get returns the account with the given ID.
+func (m *Mirror) Get(id string) (*account.Account, bool) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	return m.store.get(id)
+}
+
+// ByIBAN returns the accounts with the given IBAN, ordered by ID. White
+// space and case are ignored.
+func (m *Mirror) ByIBAN(iban string) []*account.Account {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	return m.store.lookup(m.store.byIBAN, account.NormaliseIBAN(iban))
+}
+
+// ByAccountNumber returns the accounts with the given account number,
+// ordered by ID.
+func (m *Mirror) ByAccountNumber(number string) []*account.Account {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	return m.store.lookup(m.store.byAccountNumber, number)
+}
+
+// ByCustomerID returns the accounts with the given customer ID, ordered
+// by ID.
+func (m *Mirror) ByCustomerID(customerID string) []*account.Account {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	return m.store.lookup(m.store.byCustomerID, customerID)
+}
+
+// owns reports whether acc belongs to the mirrored organisation.
+func (m *Mirror) owns(acc *account.Account) bool {
+	return acc.ID != "" && (m.orgID == "" || acc.OrganisationID == m.orgID)
+}
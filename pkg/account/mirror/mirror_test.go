@@ -0,0 +1,397 @@
+package mirror_test
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"github.com/vivangkumar/form3-http-go/pkg/account"
+	"github.com/vivangkumar/form3-http-go/pkg/account/mirror"
+	"github.com/vivangkumar/form3-http-go/pkg/internal/fakes"
+)
+
+func newAccount(orgID string, v int64, attrs *account.Attributes) account.Account {
+	acc := account.New(orgID).WithID(uuid.NewString()).WithAttributes(attrs)
+	acc.Version = &v
+
+	return *acc
+}
+
+func withVersion(acc account.Account, v int64) account.Account {
+	acc.Version = &v
+	return acc
+}
+
+var _ = Describe("Mirror", func() {
+	var (
+		ctx    context.Context
+		orgID  string
+		lister *fakes.Lister
+		m      *mirror.Mirror
+
+		first, second, third account.Account
+	)
+
+	BeforeEach(func() {
+		ctx = context.Background()
+		orgID = uuid.NewString()
+
+		customerID := "customer-1"
+		first = newAccount(orgID, 0, &account.Attributes{Iban: "GB11NWBK40030041426819", AccountNumber: "41426819", CustomerID: &customerID})
+		second = newAccount(orgID, 0, &account.Attributes{Iban: "GB29NWBK60161331926819", AccountNumber: "31926819", CustomerID: &customerID})
+		third = newAccount(orgID, 2, &account.Attributes{AccountNumber: "41426819"})
+
+		lister = fakes.NewLister(first, second, third)
+
+		m = mirror.New(lister, orgID, mirror.WithPageSize(2))
+	})
+
+	It("lists every account, page by page", func() {
+		res, err := m.Sync(ctx)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(res.Listed).To(Equal(3))
+		Expect(res.Added).To(Equal(3))
+		Expect(lister.Calls()).To(Equal(2))
+
+		Expect(m.Len()).To(Equal(3))
+		Expect(m.Synced()).To(BeClosed())
+	})
+
+	Describe("queries", func() {
+		BeforeEach(func() {
+			_, err := m.Sync(ctx)
+			Expect(err).NotTo(HaveOccurred())
+		})
+
+		It("finds accounts by ID", func() {
+			acc, ok := m.Get(first.ID)
+			Expect(ok).To(BeTrue())
+			Expect(acc.Attributes.Iban).To(Equal(first.Attributes.Iban))
+
+			_, ok = m.Get(uuid.NewString())
+			Expect(ok).To(BeFalse())
+		})
+
+		It("finds accounts by IBAN, ignoring spaces and case", func() {
+			accs := m.ByIBAN("gb11 nwbk 4003 0041 4268 19")
+			Expect(accs).To(HaveLen(1))
+			Expect(accs[0].ID).To(Equal(first.ID))
+		})
+
+		It("finds accounts by account number", func() {
+			accs := m.ByAccountNumber("41426819")
+			Expect(accs).To(HaveLen(2))
+			Expect([]string{accs[0].ID, accs[1].ID}).To(ConsistOf(first.ID, third.ID))
+		})
+
+		It("finds accounts by customer ID", func() {
+			Expect(m.ByCustomerID("customer-1")).To(HaveLen(2))
+			Expect(m.ByCustomerID("customer-2")).To(BeEmpty())
+		})
+	})
+
+	It("updates accounts whose version changed on later listings", func() {
+		_, err := m.Sync(ctx)
+		Expect(err).NotTo(HaveOccurred())
+
+		moved := withVersion(first, 1)
+		moved.Attributes = &account.Attributes{Iban: "GB33BUKB20201555555555"}
+		lister.Set(moved, withVersion(second, 0))
+
+		res, err := m.Sync(ctx)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(res.Updated).To(Equal(1))
+
+		Expect(m.ByIBAN(first.Attributes.Iban)).To(BeEmpty())
+		Expect(m.ByIBAN("GB33BUKB20201555555555")).To(HaveLen(1))
+	})
+
+	It("removes accounts missed by two listings in a row", func() {
+		_, err := m.Sync(ctx)
+		Expect(err).NotTo(HaveOccurred())
+
+		lister.Set(first, second)
+
+		res, err := m.Sync(ctx)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(res.Removed).To(BeZero())
+		_, ok := m.Get(third.ID)
+		Expect(ok).To(BeTrue())
+
+		res, err = m.Sync(ctx)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(res.Removed).To(Equal(1))
+		_, ok = m.Get(third.ID)
+		Expect(ok).To(BeFalse())
+	})
+
+	It("keeps accounts shifted out of a listing by a delete", func() {
+		_, err := m.Sync(ctx)
+		Expect(err).NotTo(HaveOccurred())
+
+		// first is deleted once page 0 is listed, so third moves onto it.
+		lister.OnList(func(page int) {
+			if page == 0 {
+				lister.Set(second, third)
+			}
+		})
+
+		res, err := m.Sync(ctx)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(res.Removed).To(BeZero())
+		_, ok := m.Get(third.ID)
+		Expect(ok).To(BeTrue())
+
+		lister.OnList(nil)
+
+		for i := 0; i < 2; i++ {
+			_, err = m.Sync(ctx)
+			Expect(err).NotTo(HaveOccurred())
+		}
+		_, ok = m.Get(first.ID)
+		Expect(ok).To(BeFalse())
+		Expect(m.Len()).To(Equal(2))
+	})
+
+	It("ignores accounts of other organisations", func() {
+		lister.Set(first, newAccount(uuid.NewString(), 0, nil))
+
+		res, err := m.Sync(ctx)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(res.Listed).To(Equal(1))
+		Expect(m.Len()).To(Equal(1))
+	})
+
+	It("keeps accounts when a listing fails", func() {
+		_, err := m.Sync(ctx)
+		Expect(err).NotTo(HaveOccurred())
+
+		lister.SetErr(errors.New("unavailable"))
+
+		_, err = m.Sync(ctx)
+		Expect(err).To(MatchError(ContainSubstring("sync: list page 0: unavailable")))
+		Expect(m.Len()).To(Equal(3))
+		Expect(m.Status().LastError).To(MatchError(err))
+	})
+
+	Describe("events", func() {
+		BeforeEach(func() {
+			_, err := m.Sync(ctx)
+			Expect(err).NotTo(HaveOccurred())
+		})
+
+		It("applies created accounts", func() {
+			acc := newAccount(orgID, 0, &account.Attributes{CustomerID: strPtr("customer-2")})
+
+			Expect(m.Apply(mirror.Event{Type: mirror.EventCreated, Account: &acc})).To(Succeed())
+			Expect(m.ByCustomerID("customer-2")).To(HaveLen(1))
+		})
+
+		It("applies newer versions only", func() {
+			newer := withVersion(first, 3)
+			newer.Attributes = &account.Attributes{AccountNumber: "00000003"}
+			older := withVersion(first, 2)
+			older.Attributes = &account.Attributes{AccountNumber: "00000002"}
+
+			Expect(m.Apply(mirror.Event{Type: mirror.EventUpdated, Account: &newer})).To(Succeed())
+			Expect(m.Apply(mirror.Event{Type: mirror.EventUpdated, Account: &older})).To(Succeed())
+
+			acc, _ := m.Get(first.ID)
+			Expect(acc.Attributes.AccountNumber).To(Equal("00000003"))
+			Expect(m.ByAccountNumber("41426819")).To(HaveLen(1))
+		})
+
+		It("applies deletes", func() {
+			Expect(m.Apply(mirror.Event{Type: mirror.EventDeleted, Account: &account.Account{ID: first.ID, Version: first.Version}})).To(Succeed())
+
+			_, ok := m.Get(first.ID)
+			Expect(ok).To(BeFalse())
+			Expect(m.ByIBAN(first.Attributes.Iban)).To(BeEmpty())
+		})
+
+		It("does not bring back accounts deleted during a listing", func() {
+			lister.OnList(func(page int) {
+				if page == 0 {
+					Expect(m.Apply(mirror.Event{Type: mirror.EventDeleted, Account: &account.Account{ID: first.ID, Version: first.Version}})).To(Succeed())
+				}
+			})
+
+			_, err := m.Sync(ctx)
+			Expect(err).NotTo(HaveOccurred())
+
+			_, ok := m.Get(first.ID)
+			Expect(ok).To(BeFalse())
+		})
+
+		It("keeps accounts created during a listing", func() {
+			acc := newAccount(orgID, 0, nil)
+			lister.OnList(func(page int) {
+				if page == 1 {
+					Expect(m.Apply(mirror.Event{Type: mirror.EventCreated, Account: &acc})).To(Succeed())
+				}
+			})
+
+			_, err := m.Sync(ctx)
+			Expect(err).NotTo(HaveOccurred())
+
+			_, ok := m.Get(acc.ID)
+			Expect(ok).To(BeTrue())
+		})
+
+		It("ignores other resources", func() {
+			Expect(m.Apply(mirror.Event{Type: mirror.EventCreated, ResourceType: "payments", Account: &account.Account{ID: "1"}})).To(Succeed())
+			Expect(m.Len()).To(Equal(3))
+		})
+
+		It("rejects invalid events", func() {
+			Expect(m.Apply(mirror.Event{Type: mirror.EventCreated})).To(MatchError("event has no account ID"))
+			Expect(m.Apply(mirror.Event{Type: "moved", Account: &first})).To(MatchError(`unknown event type "moved"`))
+		})
+
+		It("reports the lag of events", func() {
+			acc := newAccount(orgID, 0, nil)
+			ev := mirror.Event{Type: mirror.EventCreated, Time: time.Now().Add(-time.Minute), Account: &acc}
+			Expect(m.Apply(ev)).To(Succeed())
+
+			s := m.Status()
+			Expect(s.EventLag).To(BeNumerically("~", time.Minute, time.Second))
+			Expect(s.LastEvent).To(BeTemporally("~", time.Now(), time.Second))
+		})
+	})
+
+	Describe("Handler", func() {
+		var (
+			srv    *httptest.Server
+			secret []byte
+		)
+
+		BeforeEach(func() {
+			secret = []byte("webhook-secret")
+			srv = httptest.NewServer(m.Handler(secret))
+			DeferCleanup(srv.Close)
+		})
+
+		postSigned := func(body string, sig string) int {
+			req, err := http.NewRequest(http.MethodPost, srv.URL, strings.NewReader(body))
+			Expect(err).NotTo(HaveOccurred())
+			req.Header.Set("Content-Type", "application/json")
+			if sig != "" {
+				req.Header.Set(mirror.SignatureHeader, sig)
+			}
+
+			resp, err := http.DefaultClient.Do(req)
+			Expect(err).NotTo(HaveOccurred())
+			resp.Body.Close()
+
+			return resp.StatusCode
+		}
+
+		post := func(body string) int {
+			return postSigned(body, mirror.Sign(secret, []byte(body)))
+		}
+
+		It("applies posted events", func() {
+			body := `{"event_type": "created", "resource_type": "accounts", "data": {"id": "` + first.ID + `", "organisation_id": "` + orgID + `", "version": 0}}`
+			Expect(post(body)).To(Equal(http.StatusNoContent))
+
+			_, ok := m.Get(first.ID)
+			Expect(ok).To(BeTrue())
+		})
+
+		It("rejects invalid events", func() {
+			Expect(post(`{"event_type": "created"`)).To(Equal(http.StatusBadRequest))
+			Expect(post(`{"event_type": "created", "data": {}}`)).To(Equal(http.StatusBadRequest))
+		})
+
+		It("rejects events without a valid signature", func() {
+			_, err := m.Sync(ctx)
+			Expect(err).NotTo(HaveOccurred())
+
+			body := `{"event_type": "deleted", "data": {"id": "` + first.ID + `", "version": 0}}`
+
+			Expect(postSigned(body, "")).To(Equal(http.StatusUnauthorized))
+			Expect(postSigned(body, mirror.Sign([]byte("other"), []byte(body)))).To(Equal(http.StatusUnauthorized))
+			Expect(postSigned(body+" ", mirror.Sign(secret, []byte(body)))).To(Equal(http.StatusUnauthorized))
+
+			_, ok := m.Get(first.ID)
+			Expect(ok).To(BeTrue())
+		})
+
+		It("rejects every event without a secret", func() {
+			srv := httptest.NewServer(m.Handler(nil))
+			defer srv.Close()
+
+			body := `{"event_type": "deleted", "data": {"id": "` + first.ID + `", "version": 0}}`
+			resp, err := http.Post(srv.URL, "application/json", strings.NewReader(body))
+			Expect(err).NotTo(HaveOccurred())
+			resp.Body.Close()
+			Expect(resp.StatusCode).To(Equal(http.StatusUnauthorized))
+		})
+
+		It("only accepts POST", func() {
+			resp, err := http.Get(srv.URL)
+			Expect(err).NotTo(HaveOccurred())
+			resp.Body.Close()
+			Expect(resp.StatusCode).To(Equal(http.StatusMethodNotAllowed))
+		})
+	})
+
+	Describe("Run", func() {
+		It("lists accounts at the interval until stopped", func() {
+			m = mirror.New(lister, orgID, mirror.WithInterval(10*time.Millisecond))
+
+			ctx, cancel := context.WithCancel(ctx)
+			done := make(chan error)
+			go func() { done <- m.Run(ctx) }()
+
+			Eventually(m.Synced()).Should(BeClosed())
+			Expect(m.Len()).To(Equal(3))
+
+			lister.Set(first)
+			Eventually(m.Len).Should(Equal(1))
+
+			s := m.Status()
+			Expect(s.Synced).To(BeTrue())
+			Expect(s.Accounts).To(Equal(1))
+			Expect(s.Staleness).To(BeNumerically("<", time.Second))
+
+			cancel()
+			Eventually(done).Should(Receive(MatchError(context.Canceled)))
+		})
+
+		It("reports failed listings", func() {
+			lister.SetErr(errors.New("unavailable"))
+
+			var mu sync.Mutex
+			var errs []error
+			m = mirror.New(lister, orgID, mirror.WithErrorHandler(func(err error) {
+				mu.Lock()
+				defer mu.Unlock()
+				errs = append(errs, err)
+			}))
+
+			ctx, cancel := context.WithCancel(ctx)
+			defer cancel()
+			go m.Run(ctx)
+
+			Eventually(func() int {
+				mu.Lock()
+				defer mu.Unlock()
+				return len(errs)
+			}).Should(Equal(1))
+			Expect(m.Status().Synced).To(BeFalse())
+		})
+	})
+})
+
+func strPtr(s string) *string {
+	return &s
+}
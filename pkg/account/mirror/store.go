@@ -0,0 +1,223 @@
+package mirror
+
+import (
+	"sort"
+	"time"
+
+	"github.com/vivangkumar/form3-http-go/pkg/account"
+)
+
+// record is a mirrored account.
+type record struct {
+	acc *account.Account
+
+	// updated is when the record was last written.
+	updated time.Time
+
+	// missed is the number of complete listings in a row that did not
+	// return the account.
+	missed int
+}
+
+// store holds mirrored accounts, indexed by the fields they are queried by.
+// It is not safe for concurrent use.
+type store struct {
+	byID            map[string]*record
+	byIBAN          index
+	byAccountNumber index
+	byCustomerID    index
+
+	// tombstones are the versions of accounts deleted by events, so that a
+	// listing started before the delete does not bring them back.
+	tombstones map[string]tombstone
+}
+
+type tombstone struct {
+	version int64
+	deleted time.Time
+}
+
+func newStore() *store {
+	return &store{
+		byID:            make(map[string]*record),
+		byIBAN:          make(index),
+		byAccountNumber: make(index),
+		byCustomerID:    make(index),
+		tombstones:      make(map[string]tombstone),
+	}
+}
+
+// change is how a put changed the store.
+type change int
+
+const (
+	unchanged change = iota
+	added
+	updated
+)
+
+// put stores acc, unless a newer version of it is stored or was deleted.
+func (s *store) put(acc *account.Account, now time.Time) change {
+	v := version(acc)
+
+	if ts, ok := s.tombstones[acc.ID]; ok {
+		if v <= ts.version {
+			return unchanged
+		}
+		delete(s.tombstones, acc.ID)
+	}
+
+	c := added
+
+	old, ok := s.byID[acc.ID]
+	if ok {
+		if version(old.acc) >= v {
+			old.updated = now
+			old.missed = 0
+			return unchanged
+		}
+		s.unindex(old.acc)
+		c = updated
+	}
+
+	s.byID[acc.ID] = &record{acc: acc, updated: now}
+	s.index(acc)
+
+	return c
+}
+
+// remove deletes the account with id, unless a newer version is stored.
+// It reports whether the store changed.
+func (s *store) remove(id string, v int64, now time.Time) bool {
+	if ts, ok := s.tombstones[id]; !ok || ts.version < v {
+		s.tombstones[id] = tombstone{version: v, deleted: now}
+	}
+
+	old, ok := s.byID[id]
+	if !ok || version(old.acc) > v {
+		return false
+	}
+
+	s.unindex(old.acc)
+	delete(s.byID, id)
+
+	return true
+}
+
+// prune removes the accounts not written since before by the second
+// complete listing in a row, and the tombstones older than before, which the
+// listing has made redundant. It returns the number of accounts removed.
+//
+// Listings are paged by offset, so an account deleted from an earlier page
+// during a listing shifts a live account out of the next one. Such an
+// account is listed again by the next listing, so it is kept until then.
+func (s *store) prune(before time.Time) int {
+	n := 0
+	for id, r := range s.byID {
+		if !r.updated.Before(before) {
+			continue
+		}
+
+		r.missed++
+		if r.missed < 2 {
+			continue
+		}
+
+		s.unindex(r.acc)
+		delete(s.byID, id)
+		n++
+	}
+
+	for id, ts := range s.tombstones {
+		if ts.deleted.Before(before) {
+			delete(s.tombstones, id)
+		}
+	}
+
+	return n
+}
+
+func (s *store) get(id string) (*account.Account, bool) {
+	r, ok := s.byID[id]
+	if !ok {
+		return nil, false
+	}
+
+	return r.acc, true
+}
+
+func (s *store) lookup(idx index, key string) []*account.Account {
+	ids := idx[key]
+	if len(ids) == 0 {
+		return nil
+	}
+
+	accs := make([]*account.Account, 0, len(ids))
+	for id := range ids {
+		accs = append(accs, s.byID[id].acc)
+	}
+	sort.Slice(accs, func(i, j int) bool { return accs[i].ID < accs[j].ID })
+
+	return accs
+}
+
+func (s *store) index(acc *account.Account) {
+	iban, number, customerID := keys(acc)
+	s.byIBAN.add(iban, acc.ID)
+	s.byAccountNumber.add(number, acc.ID)
+	s.byCustomerID.add(customerID, acc.ID)
+}
+
+func (s *store) unindex(acc *account.Account) {
+	iban, number, customerID := keys(acc)
+	s.byIBAN.remove(iban, acc.ID)
+	s.byAccountNumber.remove(number, acc.ID)
+	s.byCustomerID.remove(customerID, acc.ID)
+}
+
+// keys returns the index keys of acc.
+func keys(acc *account.Account) (iban string, number string, customerID string) {
+	attrs := acc.Attributes
+	if attrs == nil {
+		return "", "", ""
+	}
+
+	if attrs.CustomerID != nil {
+		customerID = *attrs.CustomerID
+	}
+
+	return account.NormaliseIBAN(attrs.Iban), attrs.AccountNumber, customerID
+}
+
+// version returns the version of acc, 0 if it has none.
+func version(acc *account.Account) int64 {
+	if acc.Version == nil {
+		return 0
+	}
+
+	return *acc.Version
+}
+
+// index maps a key to the IDs of the accounts with it.
+type index map[string]map[string]bool
+
+func (idx index) add(key string, id string) {
+	if key == "" {
+		return
+	}
+
+	ids, ok := idx[key]
+	if !ok {
+		ids = make(map[string]bool)
+		idx[key] = ids
+	}
+	ids[id] = true
+}
+
+func (idx index) remove(key string, id string) {
+	ids := idx[key]
+	delete(ids, id)
+	if len(ids) == 0 {
+		delete(idx, key)
+	}
+}
@@ -0,0 +1,245 @@
+package outbox
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/vivangkumar/form3-http-go/pkg/account"
+)
+
+// journalFile is the name of the journal in the outbox directory.
+const journalFile = "outbox.log"
+
+// lockFile is the name of the file locked by the process that has the
+// outbox directory open.
+const lockFile = "outbox.lock"
+
+// Operations recorded in the journal.
+const (
+	// opEnqueue queues an account.
+	opEnqueue = "enqueue"
+
+	// opAttempt records a failed attempt that will be retried.
+	opAttempt = "attempt"
+
+	// opDone records the outcome of a ticket.
+	opDone = "done"
+)
+
+// entry is a line of the journal.
+type entry struct {
+	Op     string    `json:"op"`
+	Ticket Ticket    `json:"ticket"`
+	Time   time.Time `json:"time"`
+
+	// Account is the queued account, or the created one when done.
+	Account *account.Account `json:"account,omitempty"`
+
+	// IdempotencyKey is the key the account is created with, if it was
+	// set by the caller.
+	IdempotencyKey string `json:"idempotency_key,omitempty"`
+
+	// Attempt is the number of attempts made so far.
+	Attempt int `json:"attempt,omitempty"`
+
+	// Next is when the next attempt is due.
+	Next *time.Time `json:"next,omitempty"`
+
+	// Enqueued is when the ticket was queued, for done entries.
+	Enqueued *time.Time `json:"enqueued,omitempty"`
+
+	Status int    `json:"status,omitempty"`
+	Error  string `json:"error,omitempty"`
+}
+
+// journal is an append-only file of entries. Each append is synced to
+// disk before it returns.
+type journal struct {
+	path string
+	f    *os.File
+
+	// entries is the number of entries in the file.
+	entries int
+
+	// size is the length of the file up to the last complete entry.
+	size int64
+
+	// err is set once a failed append could not be undone. The file
+	// may end in part of an entry, so nothing more is appended.
+	err error
+}
+
+// openJournal opens the journal at path, creating it if needed, and
+// returns its entries.
+//
+// A last line without a newline is the remains of an append interrupted
+// by a crash. It was never acknowledged, so it is dropped.
+func openJournal(path string) (*journal, []entry, error) {
+	b, err := os.ReadFile(path)
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return nil, nil, fmt.Errorf("read outbox: %w", err)
+	}
+
+	complete := bytes.LastIndexByte(b, '\n') + 1
+
+	var entries []entry
+	for i, line := range bytes.SplitAfter(b[:complete], []byte("\n")) {
+		if len(line) == 0 {
+			continue
+		}
+
+		var e entry
+		err := json.Unmarshal(line, &e)
+		if err != nil {
+			return nil, nil, fmt.Errorf("read outbox: line %d: %w", i+1, err)
+		}
+		entries = append(entries, e)
+	}
+
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o600)
+	if err != nil {
+		return nil, nil, fmt.Errorf("open outbox: %w", err)
+	}
+
+	if complete < len(b) {
+		err = f.Truncate(int64(complete))
+		if err != nil {
+			f.Close()
+			return nil, nil, fmt.Errorf("truncate outbox: %w", err)
+		}
+	}
+
+	return &journal{
+		path:    path,
+		f:       f,
+		entries: len(entries),
+		size:    int64(complete),
+	}, entries, nil
+}
+
+// append writes entries to the journal.
+//
+// A failed write can leave part of the entries in the file, so the file
+// is truncated back to its previous size. Otherwise later appends would
+// follow a torn line, and the journal could not be read again.
+func (j *journal) append(entries ...entry) error {
+	if j.err != nil {
+		return j.err
+	}
+
+	b, err := encodeEntries(entries)
+	if err != nil {
+		return err
+	}
+
+	_, err = j.f.Write(b)
+	if err != nil {
+		err = fmt.Errorf("write outbox: %w", err)
+		j.undo(err)
+		return err
+	}
+
+	err = j.f.Sync()
+	if err != nil {
+		err = fmt.Errorf("sync outbox: %w", err)
+		j.undo(err)
+		return err
+	}
+
+	j.entries += len(entries)
+	j.size += int64(len(b))
+
+	return nil
+}
+
+// undo truncates the journal back to its last complete entry after a
+// failed append. If that fails too, the journal is left broken and err is
+// returned by every later append.
+func (j *journal) undo(err error) {
+	terr := j.f.Truncate(j.size)
+	if terr != nil {
+		j.err = fmt.Errorf("%v; truncate outbox: %w", err, terr)
+	}
+}
+
+// rewrite replaces the journal with entries. The replacement is written
+// to a temporary file and renamed over the journal, so that a crash
+// leaves either the old or the new journal.
+func (j *journal) rewrite(entries []entry) error {
+	b, err := encodeEntries(entries)
+	if err != nil {
+		return err
+	}
+
+	tmp := j.path + ".tmp"
+
+	f, err := os.OpenFile(tmp, os.O_CREATE|os.O_TRUNC|os.O_WRONLY, 0o600)
+	if err != nil {
+		return fmt.Errorf("compact outbox: %w", err)
+	}
+
+	_, err = f.Write(b)
+	if err == nil {
+		err = f.Sync()
+	}
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	if err == nil {
+		err = os.Rename(tmp, j.path)
+	}
+	if err != nil {
+		os.Remove(tmp)
+		return fmt.Errorf("compact outbox: %w", err)
+	}
+
+	syncDir(filepath.Dir(j.path))
+
+	f, err = os.OpenFile(j.path, os.O_WRONLY|os.O_APPEND, 0o600)
+	if err != nil {
+		return fmt.Errorf("compact outbox: %w", err)
+	}
+
+	j.f.Close()
+	j.f = f
+	j.entries = len(entries)
+	j.size = int64(len(b))
+	j.err = nil
+
+	return nil
+}
+
+func (j *journal) close() error {
+	return j.f.Close()
+}
+
+func encodeEntries(entries []entry) ([]byte, error) {
+	var buf bytes.Buffer
+	for _, e := range entries {
+		b, err := json.Marshal(e)
+		if err != nil {
+			return nil, fmt.Errorf("encode outbox entry: %w", err)
+		}
+		buf.Write(b)
+		buf.WriteByte('\n')
+	}
+
+	return buf.Bytes(), nil
+}
+
+// syncDir syncs a directory, so that a rename in it is durable. Not every
+// platform supports it, so errors are ignored.
+func syncDir(dir string) {
+	d, err := os.Open(dir)
+	if err != nil {
+		return
+	}
+	defer d.Close()
+
+	d.Sync()
+}
@@ -0,0 +1,40 @@
+//go:build !linux && !darwin
+
+package outbox
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+// lockDir takes an exclusive lock on the outbox directory dir, held until
+// unlockDir is called.
+//
+// On this platform the lock is the existence of the lock file, so it is
+// not released if the process exits without closing the outbox. The file
+// must then be removed by hand.
+func lockDir(dir string) (*os.File, error) {
+	path := filepath.Join(dir, lockFile)
+
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_EXCL|os.O_RDWR, 0o600)
+	if errors.Is(err, os.ErrExist) {
+		return nil, fmt.Errorf("%w: remove %s if no process has it open", ErrLocked, path)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("lock outbox: %w", err)
+	}
+
+	return f, nil
+}
+
+// unlockDir releases a lock taken by lockDir.
+func unlockDir(f *os.File) error {
+	err := f.Close()
+	if rerr := os.Remove(f.Name()); err == nil {
+		err = rerr
+	}
+
+	return err
+}
@@ -0,0 +1,37 @@
+//go:build linux || darwin
+
+package outbox
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"golang.org/x/sys/unix"
+)
+
+// lockDir takes an exclusive lock on the outbox directory dir. It is held
+// until unlockDir is called, or the process exits.
+func lockDir(dir string) (*os.File, error) {
+	f, err := os.OpenFile(filepath.Join(dir, lockFile), os.O_CREATE|os.O_RDWR, 0o600)
+	if err != nil {
+		return nil, fmt.Errorf("lock outbox: %w", err)
+	}
+
+	err = unix.Flock(int(f.Fd()), unix.LOCK_EX|unix.LOCK_NB)
+	if err != nil {
+		f.Close()
+		if errors.Is(err, unix.EWOULDBLOCK) {
+			return nil, ErrLocked
+		}
+		return nil, fmt.Errorf("lock outbox: %w", err)
+	}
+
+	return f, nil
+}
+
+// unlockDir releases a lock taken by lockDir.
+func unlockDir(f *os.File) error {
+	return f.Close()
+}
@@ -0,0 +1,788 @@
+// Package outbox queues account creates on disk, so that they are sent once
+// the API can be reached.
+//
+// An Outbox keeps a journal file in a directory. Enqueue appends an account
+// to the journal and returns a Ticket. A dispatcher running in the background
+// creates queued accounts, retrying failures with backoff, and appends each
+// outcome to the journal. Accounts still queued when the process stops are
+// sent once the outbox is opened again, and outcomes can be awaited by ticket
+// across restarts, until they expire.
+//
+// Only one process may open an outbox directory at a time. Open locks the
+// directory, and fails with ErrLocked while another Outbox has it open.
+package outbox
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/vivangkumar/form3-http-go/pkg/account"
+	baseclient "github.com/vivangkumar/form3-http-go/pkg/client"
+)
+
+const (
+	accountsType = "accounts"
+
+	defaultMinBackoff = time.Second
+	defaultMaxBackoff = 5 * time.Minute
+	defaultRetention  = 24 * time.Hour
+
+	// compactAfter is the number of entries the journal may grow by, beyond
+	// twice the live entries, before it is compacted.
+	compactAfter = 1000
+)
+
+var (
+	// ErrUnknownTicket is returned for tickets that were never issued by the
+	// outbox, or whose outcome has expired.
+	ErrUnknownTicket = errors.New("unknown ticket")
+
+	// ErrClosed is returned once the outbox is closed.
+	ErrClosed = errors.New("outbox is closed")
+
+	// ErrLocked is returned by Open when the directory is open in another
+	// Outbox, in this or another process.
+	ErrLocked = errors.New("outbox directory is already open")
+)
+
+// Ticket identifies a queued account.
+type Ticket string
+
+// Creator creates accounts, and fetches them to check creates that may
+// already have succeeded. The account client satisfies it.
+type Creator interface {
+	Create(ctx context.Context, acc *account.Account) (*account.Response, error)
+	Fetch(ctx context.Context, params account.FetchAccountParams) (*account.Response, error)
+}
+
+// Result is the outcome of a ticket.
+type Result struct {
+	Ticket Ticket
+
+	// Account is the account returned by the API, if it was created.
+	Account *account.Account
+
+	// Err is the error the create failed with, if any.
+	//
+	// After a restart, it only holds the message of the original error.
+	Err error
+
+	// Status is the status of the last response from the API, if any.
+	Status int
+
+	// Attempts is the number of attempts made.
+	Attempts int
+
+	Enqueued  time.Time
+	Completed time.Time
+}
+
+// Opt represents an option that can be passed to Open.
+type Opt func(o *Outbox)
+
+// WithConcurrency sets the maximum number of accounts created at once.
+//
+// If not used, accounts are created one at a time.
+func WithConcurrency(n int) Opt {
+	return func(o *Outbox) {
+		if n > 0 {
+			o.concurrency = n
+		}
+	}
+}
+
+// WithBackoff sets the wait before the second attempt of an account, which
+// doubles on each further attempt up to max.
+//
+// If not used, the wait starts at 1 second and is at most 5 minutes.
+func WithBackoff(min time.Duration, max time.Duration) Opt {
+	return func(o *Outbox) {
+		if min > 0 {
+			o.minBackoff = min
+		}
+		if max >= o.minBackoff {
+			o.maxBackoff = max
+		}
+	}
+}
+
+// WithMaxAttempts fails accounts that could not be created in n attempts.
+//
+// If not used, accounts are retried until they are created or rejected.
+func WithMaxAttempts(n int) Opt {
+	return func(o *Outbox) {
+		if n > 0 {
+			o.maxAttempts = n
+		}
+	}
+}
+
+// WithRetention sets how long outcomes are kept after they complete.
+//
+// If not used, they are kept for 24 hours.
+func WithRetention(d time.Duration) Opt {
+	return func(o *Outbox) {
+		if d > 0 {
+			o.retention = d
+		}
+	}
+}
+
+// WithErrorHandler calls fn with errors the dispatcher cannot return, such
+// as failures writing to the journal.
+func WithErrorHandler(fn func(error)) Opt {
+	return func(o *Outbox) {
+		o.onError = fn
+	}
+}
+
+// item is the state of a ticket.
+type item struct {
+	ticket   Ticket
+	acc      *account.Account
+	key      string
+	enqueued time.Time
+
+	// attempts is the number of attempts made so far.
+	attempts int
+
+	// replayed is set for tickets restored from the journal, which may
+	// have been sent before the outbox was closed.
+	replayed bool
+
+	// next is when the next attempt is due.
+	next     time.Time
+	inFlight bool
+
+	// result is set once the ticket is done.
+	result *Result
+	subs   []chan Result
+}
+
+// Outbox is a durable queue of accounts to create.
+type Outbox struct {
+	creator     Creator
+	concurrency int
+	minBackoff  time.Duration
+	maxBackoff  time.Duration
+	maxAttempts int
+	retention   time.Duration
+	onError     func(error)
+	now         func() time.Time
+
+	lock *os.File
+
+	mu       sync.Mutex
+	journal  *journal
+	items    map[Ticket]*item
+	pending  []*item
+	inFlight int
+	closed   bool
+
+	wake   chan struct{}
+	cancel context.CancelFunc
+	done   chan struct{}
+}
+
+// Open opens the outbox in dir, creating the directory if needed, and
+// starts dispatching queued accounts to c.
+//
+// Close must be called to stop the dispatcher.
+func Open(dir string, c Creator, opts ...Opt) (*Outbox, error) {
+	o := &Outbox{
+		creator:     c,
+		concurrency: 1,
+		minBackoff:  defaultMinBackoff,
+		maxBackoff:  defaultMaxBackoff,
+		retention:   defaultRetention,
+		now:         time.Now,
+		items:       make(map[Ticket]*item),
+		wake:        make(chan struct{}, 1),
+		done:        make(chan struct{}),
+	}
+
+	for _, opt := range opts {
+		opt(o)
+	}
+
+	err := os.MkdirAll(dir, 0o700)
+	if err != nil {
+		return nil, fmt.Errorf("create outbox directory: %w", err)
+	}
+
+	o.lock, err = lockDir(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	j, entries, err := openJournal(filepath.Join(dir, journalFile))
+	if err != nil {
+		unlockDir(o.lock)
+		return nil, err
+	}
+	o.journal = j
+
+	o.replay(entries)
+
+	// Start from a compact journal, without expired outcomes.
+	err = o.compact()
+	if err != nil {
+		j.close()
+		unlockDir(o.lock)
+		return nil, err
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	o.cancel = cancel
+
+	go o.dispatch(ctx)
+
+	return o, nil
+}
+
+// replay restores the state recorded by the journal.
+func (o *Outbox) replay(entries []entry) {
+	for _, e := range entries {
+		it := o.items[e.Ticket]
+
+		switch e.Op {
+		case opEnqueue:
+			it = &item{
+				ticket:   e.Ticket,
+				acc:      e.Account,
+				key:      e.IdempotencyKey,
+				enqueued: e.Time,
+				next:     e.Time,
+				replayed: true,
+			}
+			o.items[e.Ticket] = it
+		case opAttempt:
+			if it == nil || it.result != nil {
+				continue
+			}
+
+			it.attempts = e.Attempt
+			if e.Next != nil {
+				it.next = *e.Next
+			}
+		case opDone:
+			if it == nil {
+				// Compacted journals only keep the outcome.
+				it = &item{ticket: e.Ticket}
+				o.items[e.Ticket] = it
+			}
+
+			res := &Result{
+				Ticket:    e.Ticket,
+				Status:    e.Status,
+				Attempts:  e.Attempt,
+				Completed: e.Time,
+			}
+			if e.Enqueued != nil {
+				res.Enqueued = *e.Enqueued
+			}
+			if e.Error != "" {
+				res.Err = errors.New(e.Error)
+			} else {
+				res.Account = e.Account
+			}
+
+			it.result = res
+			it.acc = nil
+		}
+	}
+
+	for _, e := range entries {
+		it := o.items[e.Ticket]
+		if e.Op == opEnqueue && it.result == nil {
+			o.pending = append(o.pending, it)
+		}
+	}
+}
+
+// compact rewrites the journal with the pending tickets and the outcomes
+// that have not expired. o.mu must be held, or o not yet shared.
+func (o *Outbox) compact() error {
+	var entries []entry
+
+	for _, it := range o.pending {
+		entries = append(entries, entry{
+			Op:             opEnqueue,
+			Ticket:         it.ticket,
+			Time:           it.enqueued,
+			Account:        it.acc,
+			IdempotencyKey: it.key,
+		})
+
+		if it.attempts > 0 {
+			next := it.next
+			entries = append(entries, entry{
+				Op:      opAttempt,
+				Ticket:  it.ticket,
+				Time:    it.enqueued,
+				Attempt: it.attempts,
+				Next:    &next,
+			})
+		}
+	}
+
+	cutoff := o.now().Add(-o.retention)
+
+	var done []*Result
+	for t, it := range o.items {
+		if it.result == nil {
+			continue
+		}
+		if it.result.Completed.Before(cutoff) {
+			delete(o.items, t)
+			continue
+		}
+		done = append(done, it.result)
+	}
+
+	sort.Slice(done, func(i, j int) bool { return done[i].Completed.Before(done[j].Completed) })
+
+	for _, res := range done {
+		entries = append(entries, doneEntry(res))
+	}
+
+	return o.journal.rewrite(entries)
+}
+
+// doneEntry returns the journal entry recording res.
+func doneEntry(res *Result) entry {
+	enqueued := res.Enqueued
+
+	e := entry{
+		Op:       opDone,
+		Ticket:   res.Ticket,
+		Time:     res.Completed,
+		Account:  res.Account,
+		Attempt:  res.Attempts,
+		Enqueued: &enqueued,
+		Status:   res.Status,
+	}
+	if res.Err != nil {
+		e.Error = res.Err.Error()
+	}
+
+	return e
+}
+
+// Enqueue queues acc to be created, and returns once it is stored on disk.
+//
+// acc is copied, so later changes to it are not sent. If acc has no ID,
+// one is generated, so that retries cannot create the account twice. An
+// idempotency key set on ctx with client.ContextWithIdempotencyKey is kept
+// and sent with every attempt. Otherwise a key derived from the account ID
+// is sent.
+func (o *Outbox) Enqueue(ctx context.Context, acc *account.Account) (Ticket, error) {
+	if acc == nil {
+		return "", errors.New("account entity is nil")
+	}
+
+	err := ctx.Err()
+	if err != nil {
+		return "", err
+	}
+
+	b, err := json.Marshal(acc)
+	if err != nil {
+		return "", fmt.Errorf("encode account: %w", err)
+	}
+
+	cp := &account.Account{}
+	err = json.Unmarshal(b, cp)
+	if err != nil {
+		return "", fmt.Errorf("encode account: %w", err)
+	}
+
+	if cp.ID == "" {
+		cp.ID = uuid.NewString()
+	}
+
+	key, _ := baseclient.IdempotencyKeyFromContext(ctx)
+
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	if o.closed {
+		return "", ErrClosed
+	}
+
+	now := o.now()
+	it := &item{
+		ticket:   Ticket(uuid.NewString()),
+		acc:      cp,
+		key:      key,
+		enqueued: now,
+		next:     now,
+	}
+
+	err = o.journal.append(entry{
+		Op:             opEnqueue,
+		Ticket:         it.ticket,
+		Time:           now,
+		Account:        cp,
+		IdempotencyKey: key,
+	})
+	if err != nil {
+		return "", fmt.Errorf("enqueue account: %w", err)
+	}
+
+	o.items[it.ticket] = it
+	o.pending = append(o.pending, it)
+	o.signal()
+
+	return it.ticket, nil
+}
+
+// Subscribe returns a channel that receives the outcome of t once it is
+// done, straight away if it already is.
+//
+// The channel is closed after the outcome is sent, or without sending it if
+// the outbox is closed first.
+func (o *Outbox) Subscribe(t Ticket) (<-chan Result, error) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	it, ok := o.items[t]
+	if !ok {
+		return nil, fmt.Errorf("ticket %s: %w", t, ErrUnknownTicket)
+	}
+
+	ch := make(chan Result, 1)
+
+	switch {
+	case it.result != nil:
+		ch <- *it.result
+		close(ch)
+	case o.closed:
+		close(ch)
+	default:
+		it.subs = append(it.subs, ch)
+	}
+
+	return ch, nil
+}
+
+// Wait waits for the outcome of t. The returned error is that of the
+// outcome, if the create failed, or the reason there is no outcome.
+func (o *Outbox) Wait(ctx context.Context, t Ticket) (Result, error) {
+	ch, err := o.Subscribe(t)
+	if err != nil {
+		return Result{}, err
+	}
+
+	select {
+	case res, ok := <-ch:
+		if !ok {
+			return Result{}, ErrClosed
+		}
+
+		return res, res.Err
+	case <-ctx.Done():
+		return Result{}, ctx.Err()
+	}
+}
+
+// Pending returns the number of accounts waiting to be created.
+func (o *Outbox) Pending() int {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	return len(o.pending)
+}
+
+// Close stops the dispatcher, cancelling attempts in flight, and closes
+// the journal. Cancelled attempts are not counted, and their accounts are
+// sent again once the outbox is reopened.
+func (o *Outbox) Close() error {
+	o.mu.Lock()
+	if o.closed {
+		o.mu.Unlock()
+		return nil
+	}
+	o.closed = true
+	o.mu.Unlock()
+
+	o.cancel()
+	<-o.done
+
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	for _, it := range o.pending {
+		for _, ch := range it.subs {
+			close(ch)
+		}
+		it.subs = nil
+	}
+
+	err := o.journal.close()
+	if uerr := unlockDir(o.lock); err == nil {
+		err = uerr
+	}
+
+	return err
+}
+
+// signal wakes the dispatcher.
+func (o *Outbox) signal() {
+	select {
+	case o.wake <- struct{}{}:
+	default:
+	}
+}
+
+// dispatch creates queued accounts until ctx is done.
+func (o *Outbox) dispatch(ctx context.Context) {
+	defer close(o.done)
+
+	var wg sync.WaitGroup
+	defer wg.Wait()
+
+	for {
+		due, wait := o.due()
+
+		for _, it := range due {
+			wg.Add(1)
+			go func(it *item) {
+				defer wg.Done()
+
+				o.attempt(ctx, it)
+				o.signal()
+			}(it)
+		}
+
+		var (
+			timer   *time.Timer
+			timeout <-chan time.Time
+		)
+		if wait >= 0 {
+			timer = time.NewTimer(wait)
+			timeout = timer.C
+		}
+
+		select {
+		case <-ctx.Done():
+		case <-o.wake:
+		case <-timeout:
+		}
+
+		if timer != nil {
+			timer.Stop()
+		}
+
+		if ctx.Err() != nil {
+			return
+		}
+	}
+}
+
+// due marks the pending tickets that are due as in flight, up to the
+// concurrency, and returns them. It also returns the wait until the next
+// ticket is due, or -1 if none is waiting.
+func (o *Outbox) due() ([]*item, time.Duration) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	now := o.now()
+	wait := time.Duration(-1)
+
+	var due []*item
+	for _, it := range o.pending {
+		if it.inFlight {
+			continue
+		}
+
+		if d := it.next.Sub(now); d > 0 {
+			if wait < 0 || d < wait {
+				wait = d
+			}
+			continue
+		}
+
+		if o.inFlight < o.concurrency {
+			it.inFlight = true
+			o.inFlight++
+			due = append(due, it)
+		}
+	}
+
+	return due, wait
+}
+
+// attempt creates the account of it, and records the outcome.
+//
+// Every attempt carries the same idempotency key. If an attempt conflicts
+// with an account that an earlier attempt may have created, the account is
+// fetched, and the create succeeds if it matches.
+func (o *Outbox) attempt(ctx context.Context, it *item) {
+	o.mu.Lock()
+	key := it.key
+	maybeSent := it.attempts > 0 || it.replayed
+	o.mu.Unlock()
+
+	if key == "" {
+		key = baseclient.IdempotencyKeyFor(accountsType, it.acc.ID)
+	}
+	actx := baseclient.ContextWithIdempotencyKey(ctx, key)
+
+	resp, err := o.creator.Create(actx, it.acc)
+	if maybeSent && baseclient.StatusCode(err) == http.StatusConflict {
+		resp, err = o.resolveConflict(actx, it.acc, err)
+	}
+
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	it.inFlight = false
+	o.inFlight--
+
+	// The outbox is closing. The attempt is made again once reopened.
+	if err != nil && ctx.Err() != nil {
+		return
+	}
+
+	it.attempts++
+	now := o.now()
+	status := baseclient.StatusCode(err)
+
+	if err != nil && retryable(err) && (o.maxAttempts == 0 || it.attempts < o.maxAttempts) {
+		it.next = now.Add(o.backoff(it.attempts))
+
+		next := it.next
+		jerr := o.journal.append(entry{
+			Op:      opAttempt,
+			Ticket:  it.ticket,
+			Time:    now,
+			Attempt: it.attempts,
+			Next:    &next,
+			Status:  status,
+			Error:   err.Error(),
+		})
+		if jerr != nil {
+			o.handleError(jerr)
+		}
+
+		return
+	}
+
+	res := &Result{
+		Ticket:    it.ticket,
+		Err:       err,
+		Status:    status,
+		Attempts:  it.attempts,
+		Enqueued:  it.enqueued,
+		Completed: now,
+	}
+	if err == nil {
+		res.Status = http.StatusCreated
+		if resp != nil {
+			res.Account = resp.Data
+		}
+	}
+
+	// If the outcome is not recorded, the account is created again once
+	// reopened, which its ID makes safe.
+	jerr := o.journal.append(doneEntry(res))
+	if jerr != nil {
+		o.handleError(jerr)
+	}
+
+	it.result = res
+	it.acc = nil
+	o.remove(it)
+
+	for _, ch := range it.subs {
+		ch <- *res
+		close(ch)
+	}
+	it.subs = nil
+
+	if o.journal.entries > 2*len(o.items)+compactAfter {
+		err := o.compact()
+		if err != nil {
+			o.handleError(err)
+		}
+	}
+}
+
+// remove removes it from the pending tickets.
+func (o *Outbox) remove(it *item) {
+	for i, p := range o.pending {
+		if p == it {
+			o.pending = append(o.pending[:i], o.pending[i+1:]...)
+			return
+		}
+	}
+}
+
+// backoff returns the wait after the given number of attempts.
+func (o *Outbox) backoff(attempts int) time.Duration {
+	d := o.minBackoff
+	for i := 1; i < attempts && d < o.maxBackoff; i++ {
+		d *= 2
+	}
+
+	if d > o.maxBackoff {
+		return o.maxBackoff
+	}
+
+	return d
+}
+
+func (o *Outbox) handleError(err error) {
+	if o.onError != nil {
+		o.onError(err)
+	}
+}
+
+// resolveConflict fetches the account that a create of acc conflicted with.
+// It returns the account if it matches acc, so the create succeeded on an
+// earlier attempt whose response was lost. Otherwise it returns the
+// conflict, or the error fetching the account.
+func (o *Outbox) resolveConflict(
+	ctx context.Context,
+	acc *account.Account,
+	conflict error,
+) (*account.Response, error) {
+	existing, err := o.creator.Fetch(ctx, account.FetchAccountParams{ID: acc.ID})
+	if err != nil {
+		return nil, fmt.Errorf("fetch account after conflict: %w", err)
+	}
+
+	if fields := account.Mismatches(acc, existing.Data); len(fields) > 0 {
+		return nil, fmt.Errorf("%w: existing account differs in %s", conflict, strings.Join(fields, ", "))
+	}
+
+	return existing, nil
+}
+
+// retryable reports whether a create that failed with err may succeed
+// later: the API could not be reached, timed out, was overloaded or
+// failed.
+func retryable(err error) bool {
+	switch sc := baseclient.StatusCode(err); {
+	case sc == http.StatusRequestTimeout || sc == http.StatusTooManyRequests || sc >= 500:
+		return true
+	case sc != 0:
+		return false
+	}
+
+	var ne net.Error
+	return errors.As(err, &ne) || errors.Is(err, context.DeadlineExceeded)
+}
@@ -0,0 +1,533 @@
+package outbox_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"github.com/vivangkumar/form3-http-go/pkg/account"
+	accountclient "github.com/vivangkumar/form3-http-go/pkg/account/client"
+	"github.com/vivangkumar/form3-http-go/pkg/account/outbox"
+	baseclient "github.com/vivangkumar/form3-http-go/pkg/client"
+)
+
+// created is a create received by the fake API.
+type created struct {
+	id             string
+	idempotencyKey string
+}
+
+var _ = Describe("Outbox", func() {
+	var (
+		dir      string
+		mu       sync.Mutex
+		received []created
+		status   func(n int) int
+		lost     func(n int) bool
+		stored   map[string]*account.Account
+		fetches  int
+		server   *httptest.Server
+		accounts *accountclient.Client
+		opts     []outbox.Opt
+		ob       *outbox.Outbox
+	)
+
+	BeforeEach(func() {
+		dir = GinkgoT().TempDir()
+		received = nil
+		status = func(int) int { return http.StatusCreated }
+		lost = func(int) bool { return false }
+		stored = make(map[string]*account.Account)
+		fetches = 0
+		opts = []outbox.Opt{outbox.WithBackoff(time.Millisecond, 10*time.Millisecond)}
+
+		server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("Content-Type", "application/vnd.api+json")
+
+			if r.Method == http.MethodGet {
+				mu.Lock()
+				fetches++
+				acc := stored[path.Base(r.URL.Path)]
+				mu.Unlock()
+
+				if acc == nil {
+					w.WriteHeader(http.StatusNotFound)
+					w.Write([]byte(`{"error_message": "not found"}`))
+					return
+				}
+
+				json.NewEncoder(w).Encode(map[string]any{"data": acc})
+				return
+			}
+
+			b, _ := io.ReadAll(r.Body)
+
+			var req struct {
+				Data *account.Account `json:"data"`
+			}
+			Expect(json.Unmarshal(b, &req)).To(Succeed())
+
+			version := int64(0)
+			req.Data.Version = &version
+
+			mu.Lock()
+			received = append(received, created{req.Data.ID, r.Header.Get("Idempotency-Key")})
+			n := len(received)
+			mu.Unlock()
+
+			sc := status(n)
+
+			mu.Lock()
+			exists := stored[req.Data.ID] != nil
+			switch {
+			case lost(n):
+				// The account is created, but the response does not arrive.
+				stored[req.Data.ID] = req.Data
+				sc = http.StatusServiceUnavailable
+			case sc == http.StatusCreated && exists:
+				sc = http.StatusConflict
+			case sc == http.StatusCreated:
+				stored[req.Data.ID] = req.Data
+			}
+			mu.Unlock()
+
+			w.WriteHeader(sc)
+
+			if sc != http.StatusCreated {
+				w.Write([]byte(`{"error_message": "failed"}`))
+				return
+			}
+
+			json.NewEncoder(w).Encode(map[string]any{"data": req.Data})
+		}))
+		DeferCleanup(server.Close)
+
+		c, err := baseclient.New(baseclient.WithBaseURL(server.URL))
+		Expect(err).NotTo(HaveOccurred())
+		accounts = accountclient.New(c)
+	})
+
+	open := func() *outbox.Outbox {
+		o, err := outbox.Open(dir, accounts, opts...)
+		Expect(err).NotTo(HaveOccurred())
+		DeferCleanup(o.Close)
+
+		return o
+	}
+
+	JustBeforeEach(func() {
+		ob = open()
+	})
+
+	creates := func() []created {
+		mu.Lock()
+		defer mu.Unlock()
+
+		return append([]created(nil), received...)
+	}
+
+	fetchCount := func() int {
+		mu.Lock()
+		defer mu.Unlock()
+
+		return fetches
+	}
+
+	wait := func(t outbox.Ticket) (outbox.Result, error) {
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+
+		return ob.Wait(ctx, t)
+	}
+
+	It("creates queued accounts in the background", func() {
+		acc := account.New(uuid.NewString())
+
+		t, err := ob.Enqueue(context.Background(), acc)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(t).NotTo(BeEmpty())
+
+		res, err := wait(t)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(res.Ticket).To(Equal(t))
+		Expect(res.Account.ID).NotTo(BeEmpty())
+		Expect(res.Account.Version).To(HaveValue(BeEquivalentTo(0)))
+		Expect(res.Status).To(Equal(http.StatusCreated))
+		Expect(res.Attempts).To(Equal(1))
+		Expect(res.Completed).NotTo(BeTemporally("<", res.Enqueued))
+
+		// The caller's account is left as it is.
+		Expect(acc.ID).To(BeEmpty())
+		Expect(ob.Pending()).To(BeZero())
+	})
+
+	It("sends the idempotency key of the context", func() {
+		ctx := baseclient.ContextWithIdempotencyKey(context.Background(), "key-1")
+
+		t, err := ob.Enqueue(ctx, account.New(uuid.NewString()))
+		Expect(err).NotTo(HaveOccurred())
+
+		_, err = wait(t)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(creates()[0].idempotencyKey).To(Equal("key-1"))
+	})
+
+	It("sends an idempotency key derived from the account ID", func() {
+		acc := account.New(uuid.NewString()).WithID(uuid.NewString())
+
+		t, err := ob.Enqueue(context.Background(), acc)
+		Expect(err).NotTo(HaveOccurred())
+
+		_, err = wait(t)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(creates()[0].idempotencyKey).To(Equal(baseclient.IdempotencyKeyFor("accounts", acc.ID)))
+	})
+
+	Describe("conflicts", func() {
+		var acc *account.Account
+
+		BeforeEach(func() {
+			acc = account.New(uuid.NewString()).
+				WithID(uuid.NewString()).
+				WithAttributes(&account.Attributes{Country: "GB"})
+		})
+
+		It("completes a create whose response was lost", func() {
+			lost = func(n int) bool { return n == 1 }
+
+			t, err := ob.Enqueue(context.Background(), acc)
+			Expect(err).NotTo(HaveOccurred())
+
+			res, err := wait(t)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(res.Attempts).To(Equal(2))
+			Expect(res.Account.ID).To(Equal(acc.ID))
+			Expect(fetchCount()).To(Equal(1))
+
+			got := creates()
+			Expect(got).To(HaveLen(2))
+			Expect(got[1].idempotencyKey).To(Equal(got[0].idempotencyKey))
+		})
+
+		It("completes a create sent before a restart", func() {
+			lost = func(n int) bool { return n == 1 }
+
+			// The account is not retried before the restart.
+			Expect(ob.Close()).To(Succeed())
+			opts = []outbox.Opt{outbox.WithBackoff(time.Hour, time.Hour)}
+			ob = open()
+
+			_, err := ob.Enqueue(context.Background(), acc)
+			Expect(err).NotTo(HaveOccurred())
+
+			Eventually(creates).Should(HaveLen(1))
+			Expect(ob.Close()).To(Succeed())
+
+			// The attempt is not recorded, as if the process stopped
+			// before the response arrived.
+			journal := filepath.Join(dir, "outbox.log")
+			b, err := os.ReadFile(journal)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(os.WriteFile(journal, b[:bytes.IndexByte(b, '\n')+1], 0o600)).To(Succeed())
+
+			ob = open()
+			Expect(ob.Pending()).To(Equal(1))
+			Eventually(ob.Pending).Should(BeZero())
+			Expect(fetchCount()).To(Equal(1))
+		})
+
+		It("fails when the existing account differs", func() {
+			other := *acc
+			other.Attributes = &account.Attributes{Country: "FR"}
+			status = func(n int) int {
+				if n == 1 {
+					mu.Lock()
+					stored[acc.ID] = &other
+					mu.Unlock()
+					return http.StatusServiceUnavailable
+				}
+				return http.StatusCreated
+			}
+
+			t, err := ob.Enqueue(context.Background(), acc)
+			Expect(err).NotTo(HaveOccurred())
+
+			res, err := wait(t)
+			Expect(baseclient.StatusCode(err)).To(Equal(http.StatusConflict))
+			Expect(err).To(MatchError(ContainSubstring("existing account differs in attributes.country")))
+			Expect(res.Attempts).To(Equal(2))
+		})
+
+		It("fails a conflict on the first attempt without fetching", func() {
+			stored[acc.ID] = acc
+
+			t, err := ob.Enqueue(context.Background(), acc)
+			Expect(err).NotTo(HaveOccurred())
+
+			_, err = wait(t)
+			Expect(baseclient.StatusCode(err)).To(Equal(http.StatusConflict))
+			Expect(fetchCount()).To(BeZero())
+		})
+	})
+
+	It("retries while the API is unavailable, with the same account ID", func() {
+		status = func(n int) int {
+			if n < 3 {
+				return http.StatusServiceUnavailable
+			}
+			return http.StatusCreated
+		}
+
+		t, err := ob.Enqueue(context.Background(), account.New(uuid.NewString()))
+		Expect(err).NotTo(HaveOccurred())
+
+		res, err := wait(t)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(res.Attempts).To(Equal(3))
+
+		got := creates()
+		Expect(got).To(HaveLen(3))
+		Expect(got[1].id).To(Equal(got[0].id))
+		Expect(got[2].id).To(Equal(got[0].id))
+		Expect(got[2].idempotencyKey).To(Equal(got[0].idempotencyKey))
+	})
+
+	It("fails accounts the API rejects without retrying", func() {
+		status = func(int) int { return http.StatusBadRequest }
+
+		t, err := ob.Enqueue(context.Background(), account.New(uuid.NewString()))
+		Expect(err).NotTo(HaveOccurred())
+
+		res, err := wait(t)
+		Expect(baseclient.StatusCode(err)).To(Equal(http.StatusBadRequest))
+		Expect(res.Err).To(Equal(err))
+		Expect(res.Status).To(Equal(http.StatusBadRequest))
+		Expect(res.Attempts).To(Equal(1))
+		Expect(creates()).To(HaveLen(1))
+	})
+
+	Context("with a maximum number of attempts", func() {
+		BeforeEach(func() {
+			status = func(int) int { return http.StatusServiceUnavailable }
+			opts = append(opts, outbox.WithMaxAttempts(2))
+		})
+
+		It("gives up", func() {
+			t, err := ob.Enqueue(context.Background(), account.New(uuid.NewString()))
+			Expect(err).NotTo(HaveOccurred())
+
+			res, err := wait(t)
+			Expect(err).To(HaveOccurred())
+			Expect(res.Status).To(Equal(http.StatusServiceUnavailable))
+			Expect(res.Attempts).To(Equal(2))
+		})
+	})
+
+	It("delivers outcomes to subscribers", func() {
+		t, err := ob.Enqueue(context.Background(), account.New(uuid.NewString()))
+		Expect(err).NotTo(HaveOccurred())
+
+		ch, err := ob.Subscribe(t)
+		Expect(err).NotTo(HaveOccurred())
+
+		var res outbox.Result
+		Eventually(ch).Should(Receive(&res))
+		Expect(res.Err).NotTo(HaveOccurred())
+		Eventually(ch).Should(BeClosed())
+
+		// Subscribing later gets the outcome straight away.
+		ch, err = ob.Subscribe(t)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(ch).To(Receive(Equal(res)))
+	})
+
+	It("rejects unknown tickets", func() {
+		_, err := ob.Subscribe("nope")
+		Expect(err).To(MatchError(outbox.ErrUnknownTicket))
+	})
+
+	It("creates accounts in the order they were queued", func() {
+		var tickets []outbox.Ticket
+		for i := 0; i < 10; i++ {
+			t, err := ob.Enqueue(context.Background(), account.New(uuid.NewString()))
+			Expect(err).NotTo(HaveOccurred())
+			tickets = append(tickets, t)
+		}
+
+		for i, t := range tickets {
+			res, err := wait(t)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(creates()[i].id).To(Equal(res.Account.ID))
+		}
+	})
+
+	Context("with concurrency", func() {
+		BeforeEach(func() {
+			opts = append(opts, outbox.WithConcurrency(4))
+		})
+
+		It("creates every account", func() {
+			var tickets []outbox.Ticket
+			for i := 0; i < 20; i++ {
+				t, err := ob.Enqueue(context.Background(), account.New(uuid.NewString()))
+				Expect(err).NotTo(HaveOccurred())
+				tickets = append(tickets, t)
+			}
+
+			for _, t := range tickets {
+				_, err := wait(t)
+				Expect(err).NotTo(HaveOccurred())
+			}
+			Expect(creates()).To(HaveLen(20))
+		})
+	})
+
+	It("cannot be opened twice at once", func() {
+		_, err := outbox.Open(dir, accounts, opts...)
+		Expect(err).To(MatchError(outbox.ErrLocked))
+
+		Expect(ob.Close()).To(Succeed())
+		ob = open()
+	})
+
+	Describe("restarts", func() {
+		It("sends accounts queued before a restart", func() {
+			down := true
+			status = func(int) int {
+				mu.Lock()
+				defer mu.Unlock()
+
+				if down {
+					return http.StatusServiceUnavailable
+				}
+				return http.StatusCreated
+			}
+
+			t, err := ob.Enqueue(context.Background(), account.New(uuid.NewString()))
+			Expect(err).NotTo(HaveOccurred())
+
+			Eventually(creates).ShouldNot(BeEmpty())
+			Expect(ob.Close()).To(Succeed())
+
+			_, err = ob.Enqueue(context.Background(), account.New(uuid.NewString()))
+			Expect(err).To(MatchError(outbox.ErrClosed))
+
+			mu.Lock()
+			down = false
+			mu.Unlock()
+
+			ob = open()
+			Expect(ob.Pending()).To(Equal(1))
+
+			res, err := wait(t)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(res.Attempts).To(BeNumerically(">=", 2))
+
+			got := creates()
+			Expect(got[len(got)-1].id).To(Equal(got[0].id))
+		})
+
+		It("keeps outcomes across restarts", func() {
+			t, err := ob.Enqueue(context.Background(), account.New(uuid.NewString()))
+			Expect(err).NotTo(HaveOccurred())
+
+			want, err := wait(t)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(ob.Close()).To(Succeed())
+
+			ob = open()
+
+			res, err := wait(t)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(res.Account.ID).To(Equal(want.Account.ID))
+			Expect(res.Completed).To(BeTemporally("==", want.Completed))
+			Expect(creates()).To(HaveLen(1))
+		})
+
+		It("keeps failures across restarts", func() {
+			status = func(int) int { return http.StatusBadRequest }
+
+			t, err := ob.Enqueue(context.Background(), account.New(uuid.NewString()))
+			Expect(err).NotTo(HaveOccurred())
+
+			_, err = wait(t)
+			Expect(err).To(HaveOccurred())
+			Expect(ob.Close()).To(Succeed())
+
+			ob = open()
+
+			res, err := wait(t)
+			Expect(err).To(MatchError(ContainSubstring("returned status 400")))
+			Expect(res.Status).To(Equal(http.StatusBadRequest))
+		})
+
+		It("drops an entry whose write was interrupted", func() {
+			Expect(ob.Close()).To(Succeed())
+
+			f, err := os.OpenFile(filepath.Join(dir, "outbox.log"), os.O_APPEND|os.O_WRONLY, 0o600)
+			Expect(err).NotTo(HaveOccurred())
+			_, err = f.WriteString(`{"op":"enqueue","tick`)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(f.Close()).To(Succeed())
+
+			ob = open()
+			Expect(ob.Pending()).To(BeZero())
+
+			t, err := ob.Enqueue(context.Background(), account.New(uuid.NewString()))
+			Expect(err).NotTo(HaveOccurred())
+
+			_, err = wait(t)
+			Expect(err).NotTo(HaveOccurred())
+		})
+
+		Context("with a short retention", func() {
+			BeforeEach(func() {
+				opts = append(opts, outbox.WithRetention(time.Millisecond))
+			})
+
+			It("forgets expired outcomes", func() {
+				t, err := ob.Enqueue(context.Background(), account.New(uuid.NewString()))
+				Expect(err).NotTo(HaveOccurred())
+
+				_, err = wait(t)
+				Expect(err).NotTo(HaveOccurred())
+				Expect(ob.Close()).To(Succeed())
+
+				time.Sleep(5 * time.Millisecond)
+				ob = open()
+
+				_, err = ob.Subscribe(t)
+				Expect(errors.Is(err, outbox.ErrUnknownTicket)).To(BeTrue())
+			})
+		})
+	})
+
+	It("stops waiting when the outbox is closed", func() {
+		status = func(int) int { return http.StatusServiceUnavailable }
+
+		t, err := ob.Enqueue(context.Background(), account.New(uuid.NewString()))
+		Expect(err).NotTo(HaveOccurred())
+
+		ch, err := ob.Subscribe(t)
+		Expect(err).NotTo(HaveOccurred())
+
+		Expect(ob.Close()).To(Succeed())
+		Eventually(ch).Should(BeClosed())
+
+		_, err = wait(t)
+		Expect(err).To(MatchError(outbox.ErrClosed))
+	})
+})
@@ -14,3 +14,16 @@ type DeleteAccountParams struct {
 	// Version represents the account version that should be deleted.
 	Version int64
 }
+
+// ListAccountParams represents parameters to pass when listing accounts.
+type ListAccountParams struct {
+	// PageNumber represents the page to return, starting at 0.
+	PageNumber int
+
+	// PageSize represents the number of accounts per page.
+	PageSize int
+
+	// Filter represents attributes to filter accounts by,
+	// for example {"bank_id": "400300"}.
+	Filter map[string]string
+}
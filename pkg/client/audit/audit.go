@@ -0,0 +1,255 @@
+// Package audit records the mutating requests made by a client.Client.
+//
+// Middleware writes one Record per POST, PATCH and DELETE request sent,
+// retries included, to a Sink. FileSink chains the records it writes with
+// keyed HMAC-SHA256 hashes, so that changes to the file can be detected with
+// Verify.
+package audit
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/vivangkumar/form3-http-go/pkg/client"
+)
+
+// requestIDHeader carries the ID the API gives each request.
+const requestIDHeader = "X-Request-Id"
+
+// Record is the audit record of a request.
+type Record struct {
+	Time time.Time `json:"time"`
+
+	// Caller identifies who made the request, see ContextWithCaller.
+	Caller string `json:"caller,omitempty"`
+
+	Method string `json:"method"`
+	Path   string `json:"path"`
+
+	// ResourceType, ResourceID and Version identify the resource, read
+	// from the response, the request body or the path and query.
+	ResourceType string `json:"resource_type,omitempty"`
+	ResourceID   string `json:"resource_id,omitempty"`
+	Version      *int64 `json:"version,omitempty"`
+
+	// Status is the status returned by the API, if it responded.
+	Status int `json:"status,omitempty"`
+
+	// RequestID is the X-Request-Id of the response, or of the request.
+	RequestID string `json:"request_id,omitempty"`
+
+	IdempotencyKey string `json:"idempotency_key,omitempty"`
+
+	// Error is the error the request failed with, if no response was
+	// received in full.
+	Error string `json:"error,omitempty"`
+}
+
+type callerKey struct{}
+
+// ContextWithCaller returns a context whose requests are recorded as made
+// by caller, such as the name of a service or user.
+func ContextWithCaller(ctx context.Context, caller string) context.Context {
+	return context.WithValue(ctx, callerKey{}, caller)
+}
+
+// CallerFromContext returns the caller carried by ctx, if any.
+func CallerFromContext(ctx context.Context) (string, bool) {
+	caller, ok := ctx.Value(callerKey{}).(string)
+	return caller, ok
+}
+
+// Opt represents an option that can be passed to Middleware.
+type Opt func(o *options)
+
+type options struct {
+	caller  string
+	onError func(error)
+	now     func() time.Time
+}
+
+// WithDefaultCaller records requests whose context has no caller as made
+// by caller.
+func WithDefaultCaller(caller string) Opt {
+	return func(o *options) {
+		o.caller = caller
+	}
+}
+
+// WithErrorHandler calls fn with errors writing records, instead of
+// failing the request.
+func WithErrorHandler(fn func(error)) Opt {
+	return func(o *options) {
+		o.onError = fn
+	}
+}
+
+// Middleware returns client middleware that writes a record to sink for
+// every POST, PATCH and DELETE request.
+//
+// By default, a request whose record cannot be written fails with the
+// error, even though it was sent, so that unaudited changes do not go
+// unnoticed. Use WithErrorHandler to handle these errors instead.
+//
+// Add it with client.WithMiddleware.
+func Middleware(sink Sink, opts ...Opt) client.Middleware {
+	o := &options{now: time.Now}
+	for _, opt := range opts {
+		opt(o)
+	}
+
+	return func(next client.Doer) client.Doer {
+		return client.DoerFunc(func(req *http.Request) (*http.Response, error) {
+			switch req.Method {
+			case http.MethodPost, http.MethodPatch, http.MethodDelete:
+			default:
+				return next.Do(req)
+			}
+
+			rec := o.record(req)
+
+			resp, err := next.Do(req)
+			if err == nil {
+				err = readResponse(&rec, resp)
+				if err != nil {
+					resp = nil
+				}
+			}
+			if err != nil {
+				rec.Error = err.Error()
+			}
+
+			werr := sink.Write(req.Context(), rec)
+			if werr == nil {
+				return resp, err
+			}
+
+			werr = fmt.Errorf("audit %s %s: %w", req.Method, req.URL.Path, werr)
+			if o.onError != nil {
+				o.onError(werr)
+				return resp, err
+			}
+
+			if resp != nil {
+				resp.Body.Close()
+			}
+
+			return nil, werr
+		})
+	}
+}
+
+// record starts the record of req.
+func (o *options) record(req *http.Request) Record {
+	rec := Record{
+		Time:           o.now().UTC(),
+		Caller:         o.caller,
+		Method:         req.Method,
+		Path:           req.URL.Path,
+		RequestID:      req.Header.Get(requestIDHeader),
+		IdempotencyKey: req.Header.Get("Idempotency-Key"),
+	}
+
+	if caller, ok := CallerFromContext(req.Context()); ok {
+		rec.Caller = caller
+	}
+
+	// The path names the resource, or its collection for creates.
+	segments := strings.Split(strings.Trim(req.URL.Path, "/"), "/")
+	if n := len(segments); req.Method == http.MethodPost {
+		rec.ResourceType = segments[n-1]
+	} else if n >= 2 {
+		rec.ResourceType, rec.ResourceID = segments[n-2], segments[n-1]
+	}
+
+	if v, err := strconv.ParseInt(req.URL.Query().Get("version"), 10, 64); err == nil {
+		rec.Version = &v
+	}
+
+	if body, err := requestBody(req); err == nil {
+		readDocument(&rec, body)
+	}
+
+	return rec
+}
+
+// readResponse completes rec from resp, whose body is read and replaced.
+// If the body cannot be read, it is closed.
+func readResponse(rec *Record, resp *http.Response) error {
+	rec.Status = resp.StatusCode
+
+	if id := resp.Header.Get(requestIDHeader); id != "" {
+		rec.RequestID = id
+	}
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 || resp.Body == nil {
+		return nil
+	}
+
+	body, err := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if err != nil {
+		return fmt.Errorf("read response body: %w", err)
+	}
+	resp.Body = io.NopCloser(bytes.NewReader(body))
+
+	readDocument(rec, body)
+
+	return nil
+}
+
+// readDocument sets the resource of rec from a JSON:API document, if body
+// is one.
+func readDocument(rec *Record, body []byte) {
+	var doc struct {
+		Data *struct {
+			Type    string `json:"type"`
+			ID      string `json:"id"`
+			Version *int64 `json:"version"`
+		} `json:"data"`
+	}
+
+	if json.Unmarshal(body, &doc) != nil || doc.Data == nil {
+		return
+	}
+
+	if doc.Data.Type != "" {
+		rec.ResourceType = doc.Data.Type
+	}
+	if doc.Data.ID != "" {
+		rec.ResourceID = doc.Data.ID
+	}
+	if doc.Data.Version != nil {
+		rec.Version = doc.Data.Version
+	}
+}
+
+// requestBody returns the body of req, leaving it readable.
+func requestBody(req *http.Request) ([]byte, error) {
+	if req.Body == nil || req.Body == http.NoBody {
+		return nil, nil
+	}
+
+	if req.GetBody != nil {
+		rc, err := req.GetBody()
+		if err != nil {
+			return nil, err
+		}
+		defer rc.Close()
+
+		return io.ReadAll(rc)
+	}
+
+	body, err := io.ReadAll(req.Body)
+	req.Body.Close()
+	req.Body = io.NopCloser(bytes.NewReader(body))
+
+	return body, err
+}
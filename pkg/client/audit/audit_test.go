@@ -0,0 +1,333 @@
+package audit_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing/iotest"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"github.com/vivangkumar/form3-http-go/pkg/client"
+	"github.com/vivangkumar/form3-http-go/pkg/client/audit"
+)
+
+// recordSink collects the records written to it.
+type recordSink struct {
+	records []audit.Record
+	err     error
+}
+
+func (s *recordSink) Write(_ context.Context, rec audit.Record) error {
+	s.records = append(s.records, rec)
+	return s.err
+}
+
+var _ = Describe("Middleware", func() {
+	var (
+		server *httptest.Server
+		status int
+		sink   *recordSink
+		opts   []audit.Opt
+		c      *client.Client
+	)
+
+	BeforeEach(func() {
+		status = http.StatusCreated
+		sink = &recordSink{}
+		opts = nil
+
+		server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("X-Request-Id", "req-1")
+			w.WriteHeader(status)
+			switch {
+			case status >= 300:
+				w.Write([]byte(`{"error_message": "version conflict"}`))
+			case r.Method != http.MethodDelete:
+				w.Write([]byte(`{"data": {"type": "accounts", "id": "ad27e265", "version": 0}}`))
+			}
+		}))
+	})
+
+	AfterEach(func() {
+		server.Close()
+	})
+
+	JustBeforeEach(func() {
+		var err error
+		c, err = client.New(
+			client.WithBaseURL(server.URL),
+			client.WithMiddleware(audit.Middleware(sink, opts...)),
+		)
+		Expect(err).NotTo(HaveOccurred())
+	})
+
+	It("records creates with the resource from the response", func() {
+		ctx := client.ContextWithIdempotencyKey(context.Background(), "key-1")
+		ctx = audit.ContextWithCaller(ctx, "payments")
+
+		var target json.RawMessage
+		_, err := c.Post(ctx, "/v1/organisation/accounts", map[string]any{"data": map[string]any{"type": "accounts"}}, &target)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(target).To(ContainSubstring("ad27e265"))
+
+		Expect(sink.records).To(HaveLen(1))
+		rec := sink.records[0]
+		Expect(rec.Time).NotTo(BeZero())
+		Expect(rec.Caller).To(Equal("payments"))
+		Expect(rec.Method).To(Equal(http.MethodPost))
+		Expect(rec.Path).To(Equal("/v1/organisation/accounts"))
+		Expect(rec.ResourceType).To(Equal("accounts"))
+		Expect(rec.ResourceID).To(Equal("ad27e265"))
+		Expect(rec.Version).To(HaveValue(BeEquivalentTo(0)))
+		Expect(rec.Status).To(Equal(http.StatusCreated))
+		Expect(rec.RequestID).To(Equal("req-1"))
+		Expect(rec.IdempotencyKey).To(Equal("key-1"))
+		Expect(rec.Error).To(BeEmpty())
+	})
+
+	It("records deletes with the resource from the path and query", func() {
+		status = http.StatusNoContent
+
+		_, err := c.Delete(context.Background(), "/v1/organisation/accounts/ad27e265", map[string]string{"version": "3"})
+		Expect(err).NotTo(HaveOccurred())
+
+		Expect(sink.records).To(HaveLen(1))
+		rec := sink.records[0]
+		Expect(rec.Method).To(Equal(http.MethodDelete))
+		Expect(rec.ResourceType).To(Equal("accounts"))
+		Expect(rec.ResourceID).To(Equal("ad27e265"))
+		Expect(rec.Version).To(HaveValue(BeEquivalentTo(3)))
+		Expect(rec.Status).To(Equal(http.StatusNoContent))
+	})
+
+	It("records failed requests with their status", func() {
+		status = http.StatusConflict
+
+		_, err := c.Patch(context.Background(), "/v1/organisation/accounts/ad27e265", map[string]any{}, nil)
+		Expect(client.StatusCode(err)).To(Equal(http.StatusConflict))
+
+		Expect(sink.records).To(HaveLen(1))
+		Expect(sink.records[0].Method).To(Equal(http.MethodPatch))
+		Expect(sink.records[0].Status).To(Equal(http.StatusConflict))
+	})
+
+	It("does not record reads", func() {
+		_, err := c.Get(context.Background(), "/v1/organisation/accounts/ad27e265", nil, nil)
+		Expect(err).NotTo(HaveOccurred())
+
+		Expect(sink.records).To(BeEmpty())
+	})
+
+	Context("with a default caller", func() {
+		BeforeEach(func() {
+			opts = append(opts, audit.WithDefaultCaller("batch"))
+		})
+
+		It("records it when the context has no caller", func() {
+			_, err := c.Delete(context.Background(), "/v1/organisation/accounts/ad27e265", nil)
+			Expect(err).NotTo(HaveOccurred())
+
+			Expect(sink.records[0].Caller).To(Equal("batch"))
+		})
+	})
+
+	Context("when the sink fails", func() {
+		BeforeEach(func() {
+			sink.err = errors.New("disk full")
+		})
+
+		It("fails the request", func() {
+			_, err := c.Delete(context.Background(), "/v1/organisation/accounts/ad27e265", nil)
+			Expect(err).To(MatchError(ContainSubstring("disk full")))
+		})
+
+		Context("with an error handler", func() {
+			var handled []error
+
+			BeforeEach(func() {
+				handled = nil
+				opts = append(opts, audit.WithErrorHandler(func(err error) {
+					handled = append(handled, err)
+				}))
+			})
+
+			It("passes it the error instead", func() {
+				_, err := c.Delete(context.Background(), "/v1/organisation/accounts/ad27e265", nil)
+				Expect(err).NotTo(HaveOccurred())
+
+				Expect(handled).To(HaveLen(1))
+				Expect(handled[0]).To(MatchError(ContainSubstring("disk full")))
+			})
+		})
+	})
+})
+
+var _ = Describe("Sinks", func() {
+	rec := audit.Record{Method: http.MethodPost, Path: "/v1/organisation/accounts"}
+
+	It("writes JSON lines to a writer", func() {
+		var buf bytes.Buffer
+		sink := audit.NewWriterSink(&buf)
+
+		Expect(sink.Write(context.Background(), rec)).To(Succeed())
+		Expect(sink.Write(context.Background(), rec)).To(Succeed())
+
+		lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+		Expect(lines).To(HaveLen(2))
+		Expect(lines[0]).To(MatchJSON(`{"time": "0001-01-01T00:00:00Z", "method": "POST", "path": "/v1/organisation/accounts"}`))
+	})
+
+	It("sends records to a channel until the context is done", func() {
+		ch := make(chan audit.Record, 1)
+		sink := audit.ChanSink(ch)
+
+		Expect(sink.Write(context.Background(), rec)).To(Succeed())
+		Expect(<-ch).To(Equal(rec))
+
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+
+		ch <- rec
+		Expect(sink.Write(ctx, rec)).To(MatchError(context.Canceled))
+	})
+})
+
+var _ = Describe("FileSink", func() {
+	var file string
+
+	key := []byte("audit-key")
+
+	BeforeEach(func() {
+		file = filepath.Join(GinkgoT().TempDir(), "audit.log")
+	})
+
+	write := func(paths ...string) {
+		sink, err := audit.OpenFile(file, key)
+		Expect(err).NotTo(HaveOccurred())
+		defer sink.Close()
+
+		for _, p := range paths {
+			Expect(sink.Write(context.Background(), audit.Record{Method: http.MethodDelete, Path: p})).To(Succeed())
+		}
+	}
+
+	lines := func() []string {
+		b, err := os.ReadFile(file)
+		Expect(err).NotTo(HaveOccurred())
+
+		l := strings.SplitAfter(string(b), "\n")
+		return l[:len(l)-1]
+	}
+
+	rewrite := func(lines []string) {
+		Expect(os.WriteFile(file, []byte(strings.Join(lines, "")), 0o600)).To(Succeed())
+	}
+
+	It("chains records across reopens", func() {
+		write("/a", "/b")
+		write("/c")
+
+		v, err := audit.VerifyFile(file, key)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(v.Records).To(Equal(3))
+		Expect(v.LastHash).To(HaveLen(64))
+
+		var e struct {
+			Seq      int             `json:"seq"`
+			PrevHash string          `json:"prev_hash"`
+			Record   json.RawMessage `json:"record"`
+			Hash     string          `json:"hash"`
+		}
+		Expect(json.Unmarshal([]byte(lines()[2]), &e)).To(Succeed())
+		Expect(e.Seq).To(Equal(3))
+		Expect(e.Hash).To(Equal(v.LastHash))
+		Expect(e.Record).To(ContainSubstring(`"path":"/c"`))
+	})
+
+	It("verifies an empty log", func() {
+		v, err := audit.Verify(strings.NewReader(""), key)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(v.Records).To(BeZero())
+	})
+
+	DescribeTable("detects tampering",
+		func(tamper func(lines []string) []string, line int) {
+			write("/a", "/b", "/c")
+			rewrite(tamper(lines()))
+
+			_, err := audit.VerifyFile(file, key)
+
+			var chainErr *audit.ChainError
+			Expect(errors.As(err, &chainErr)).To(BeTrue())
+			Expect(chainErr.Line).To(Equal(line))
+
+			_, err = audit.OpenFile(file, key)
+			Expect(errors.As(err, &chainErr)).To(BeTrue())
+		},
+		Entry("of a record", func(l []string) []string {
+			l[1] = strings.Replace(l[1], `"/b"`, `"/x"`, 1)
+			return l
+		}, 2),
+		Entry("by removing a record", func(l []string) []string {
+			return append(l[:1], l[2:]...)
+		}, 2),
+		Entry("by reordering records", func(l []string) []string {
+			l[1], l[2] = l[2], l[1]
+			return l
+		}, 2),
+	)
+
+	It("detects records hashed with another key", func() {
+		write("/a")
+
+		_, err := audit.VerifyFile(file, []byte("other-key"))
+
+		var chainErr *audit.ChainError
+		Expect(errors.As(err, &chainErr)).To(BeTrue())
+		Expect(chainErr.Line).To(Equal(1))
+		Expect(chainErr.Reason).To(Equal("hash does not match the record"))
+	})
+
+	It("requires a key", func() {
+		_, err := audit.OpenFile(file, nil)
+		Expect(err).To(MatchError(ContainSubstring("key is empty")))
+	})
+
+	It("removes a record left incomplete by an interrupted write", func() {
+		write("/a", "/b", "/c")
+		l := lines()
+		torn := l[2][:10]
+		rewrite(append(l[:2], torn))
+
+		_, err := audit.VerifyFile(file, key)
+		var chainErr *audit.ChainError
+		Expect(errors.As(err, &chainErr)).To(BeTrue())
+		Expect(chainErr.Line).To(Equal(3))
+		Expect(chainErr.Reason).To(Equal("record is incomplete"))
+
+		sink, err := audit.OpenFile(file, key)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(string(sink.Truncated())).To(Equal(torn))
+		Expect(sink.Write(context.Background(), audit.Record{Method: http.MethodDelete, Path: "/d"})).To(Succeed())
+		Expect(sink.Close()).To(Succeed())
+
+		v, err := audit.VerifyFile(file, key)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(v.Records).To(Equal(3))
+		Expect(lines()[2]).To(ContainSubstring(`"path":"/d"`))
+	})
+
+	It("reports errors reading the file", func() {
+		_, err := audit.Verify(io.MultiReader(strings.NewReader("{"), iotest.ErrReader(errors.New("device error"))), key)
+		Expect(err).To(MatchError(ContainSubstring("read records")))
+	})
+})
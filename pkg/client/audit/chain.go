@@ -0,0 +1,255 @@
+package audit
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"sync"
+)
+
+// genesisHash is the previous hash of the first record of a file.
+var genesisHash = strings.Repeat("0", sha256.Size*2)
+
+// entry is a line of a hash-chained file.
+type entry struct {
+	Seq      uint64          `json:"seq"`
+	PrevHash string          `json:"prev_hash"`
+	Record   json.RawMessage `json:"record"`
+	Hash     string          `json:"hash"`
+}
+
+// chainHash returns the HMAC-SHA256 of a record with key, chained to the
+// previous one.
+func chainHash(key []byte, seq uint64, prevHash string, record []byte) string {
+	h := hmac.New(sha256.New, key)
+	fmt.Fprintf(h, "%d\n%s\n", seq, prevHash)
+	h.Write(record)
+
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// ChainError reports where a hash-chained file fails verification.
+type ChainError struct {
+	// Line is the line of the file, starting at 1.
+	Line int
+
+	Reason string
+}
+
+// Error implements the error interface.
+func (e *ChainError) Error() string {
+	return fmt.Sprintf("line %d: %s", e.Line, e.Reason)
+}
+
+// Verified summarises a hash-chained file that passed verification.
+type Verified struct {
+	// Records is the number of records.
+	Records int
+
+	// LastHash is the hash of the last record. Keeping a copy elsewhere
+	// lets truncation of the file be detected too.
+	LastHash string
+}
+
+// Verify checks the hash chain of the records read from r, using the key
+// they were written with. It returns a *ChainError for the first record
+// that was changed, removed, reordered or inserted.
+func Verify(r io.Reader, key []byte) (Verified, error) {
+	v := Verified{LastHash: genesisHash}
+
+	br := bufio.NewReader(r)
+	for line := 1; ; line++ {
+		b, err := br.ReadBytes('\n')
+		if errors.Is(err, io.EOF) {
+			if len(b) == 0 {
+				return v, nil
+			}
+
+			return Verified{}, &ChainError{Line: line, Reason: "record is incomplete"}
+		}
+		if err != nil {
+			return Verified{}, fmt.Errorf("read records: %w", err)
+		}
+
+		var e entry
+		err = json.Unmarshal(b, &e)
+		if err != nil {
+			return Verified{}, &ChainError{Line: line, Reason: fmt.Sprintf("not a record: %s", err)}
+		}
+
+		switch {
+		case e.Seq != uint64(v.Records+1):
+			return Verified{}, &ChainError{Line: line, Reason: fmt.Sprintf("sequence is %d, want %d", e.Seq, v.Records+1)}
+		case e.PrevHash != v.LastHash:
+			return Verified{}, &ChainError{Line: line, Reason: "previous hash does not match the previous record"}
+		case !hmac.Equal([]byte(e.Hash), []byte(chainHash(key, e.Seq, e.PrevHash, e.Record))):
+			return Verified{}, &ChainError{Line: line, Reason: "hash does not match the record"}
+		}
+
+		v.Records++
+		v.LastHash = e.Hash
+	}
+}
+
+// VerifyFile checks the hash chain of the file at path, see Verify.
+func VerifyFile(path string, key []byte) (Verified, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return Verified{}, fmt.Errorf("open audit file: %w", err)
+	}
+	defer f.Close()
+
+	return Verify(f, key)
+}
+
+// FileSink appends records to a file, chaining each to the previous one
+// with an HMAC-SHA256 keyed by a secret key, so that records cannot be
+// rewritten and hashed again without it. Each record is synced to disk
+// before Write returns.
+//
+// Only one FileSink may write to a file at once.
+type FileSink struct {
+	mu       sync.Mutex
+	f        *os.File
+	key      []byte
+	seq      uint64
+	lastHash string
+
+	// size is the length of the file up to the last complete record.
+	size int64
+
+	// err is set once a failed write could not be undone, and is returned
+	// by every later write.
+	err error
+
+	truncated []byte
+}
+
+// OpenFile opens the file at path for appending, creating it if needed.
+// Records are chained with key, which should be kept apart from the file.
+//
+// The existing records are verified first, so that a broken chain is not
+// extended. An incomplete last line, left by a write that was interrupted,
+// is removed and reported by Truncated.
+func OpenFile(path string, key []byte) (*FileSink, error) {
+	if len(key) == 0 {
+		return nil, fmt.Errorf("open audit file: key is empty")
+	}
+
+	b, err := os.ReadFile(path)
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return nil, fmt.Errorf("read audit file: %w", err)
+	}
+
+	complete := bytes.LastIndexByte(b, '\n') + 1
+
+	v, err := Verify(bytes.NewReader(b[:complete]), key)
+	if err != nil {
+		return nil, fmt.Errorf("verify %s: %w", path, err)
+	}
+
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0o600)
+	if err != nil {
+		return nil, fmt.Errorf("open audit file: %w", err)
+	}
+
+	s := &FileSink{
+		f:        f,
+		key:      key,
+		seq:      uint64(v.Records),
+		lastHash: v.LastHash,
+		size:     int64(complete),
+	}
+
+	if complete < len(b) {
+		err = f.Truncate(int64(complete))
+		if err != nil {
+			f.Close()
+			return nil, fmt.Errorf("truncate audit file: %w", err)
+		}
+		s.truncated = b[complete:]
+	}
+
+	return s, nil
+}
+
+// Truncated returns the incomplete last line removed from the file when it
+// was opened, or nil if there was none.
+func (s *FileSink) Truncated() []byte {
+	return s.truncated
+}
+
+// Write implements Sink.
+//
+// If the record cannot be written, the file is truncated back to the last
+// complete record, so that the chain can still be extended and verified.
+func (s *FileSink) Write(_ context.Context, rec Record) error {
+	record, err := json.Marshal(rec)
+	if err != nil {
+		return fmt.Errorf("encode record: %w", err)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.err != nil {
+		return s.err
+	}
+
+	e := entry{Seq: s.seq + 1, PrevHash: s.lastHash, Record: record}
+	e.Hash = chainHash(s.key, e.Seq, e.PrevHash, e.Record)
+
+	b, err := json.Marshal(e)
+	if err != nil {
+		return fmt.Errorf("encode record: %w", err)
+	}
+
+	var buf bytes.Buffer
+	buf.Write(b)
+	buf.WriteByte('\n')
+
+	_, err = s.f.Write(buf.Bytes())
+	if err != nil {
+		err = fmt.Errorf("write record: %w", err)
+		s.undo(err)
+		return err
+	}
+
+	err = s.f.Sync()
+	if err != nil {
+		err = fmt.Errorf("sync audit file: %w", err)
+		s.undo(err)
+		return err
+	}
+
+	s.seq, s.lastHash = e.Seq, e.Hash
+	s.size += int64(buf.Len())
+
+	return nil
+}
+
+// undo truncates the file back to the last complete record after a failed
+// write. If that fails too, the sink is broken and err is kept.
+func (s *FileSink) undo(err error) {
+	terr := s.f.Truncate(s.size)
+	if terr != nil {
+		s.err = fmt.Errorf("%v; truncate audit file: %w", err, terr)
+	}
+}
+
+// Close closes the file.
+func (s *FileSink) Close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.f.Close()
+}
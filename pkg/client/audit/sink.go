@@ -0,0 +1,67 @@
+package audit
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"sync"
+)
+
+// Sink stores audit records.
+//
+// Sinks must be safe for concurrent use.
+type Sink interface {
+	Write(ctx context.Context, rec Record) error
+}
+
+// SinkFunc adapts a function to a Sink.
+type SinkFunc func(ctx context.Context, rec Record) error
+
+// Write calls f(ctx, rec).
+func (f SinkFunc) Write(ctx context.Context, rec Record) error {
+	return f(ctx, rec)
+}
+
+// WriterSink writes records to an io.Writer as JSON, one per line.
+type WriterSink struct {
+	mu sync.Mutex
+	w  io.Writer
+}
+
+// NewWriterSink returns a sink writing to w.
+func NewWriterSink(w io.Writer) *WriterSink {
+	return &WriterSink{w: w}
+}
+
+// Write implements Sink.
+func (s *WriterSink) Write(_ context.Context, rec Record) error {
+	b, err := json.Marshal(rec)
+	if err != nil {
+		return fmt.Errorf("encode record: %w", err)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	_, err = fmt.Fprintf(s.w, "%s\n", b)
+	if err != nil {
+		return fmt.Errorf("write record: %w", err)
+	}
+
+	return nil
+}
+
+// ChanSink sends records to a channel.
+type ChanSink chan<- Record
+
+// Write implements Sink. It blocks until the record is received or ctx is
+// done.
+func (s ChanSink) Write(ctx context.Context, rec Record) error {
+	select {
+	case s <- rec:
+		return nil
+	case <-ctx.Done():
+		return fmt.Errorf("send record: %w", ctx.Err())
+	}
+}
@@ -0,0 +1,394 @@
+package client
+
+import (
+	"bytes"
+	"container/list"
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+const defaultCacheEntries = 1000
+
+// CachedResponse is a response held by a CacheStore.
+type CachedResponse struct {
+	StatusCode int
+	Header     http.Header
+	Body       []byte
+
+	// StoredAt is when the response was stored or last revalidated.
+	StoredAt time.Time
+}
+
+// etag returns the ETag validator of the response, if any.
+func (r *CachedResponse) etag() string {
+	return r.Header.Get("ETag")
+}
+
+// lastModified returns the Last-Modified validator of the response, if any.
+func (r *CachedResponse) lastModified() string {
+	return r.Header.Get("Last-Modified")
+}
+
+// hasValidators reports whether the response can be revalidated.
+func (r *CachedResponse) hasValidators() bool {
+	return r.etag() != "" || r.lastModified() != ""
+}
+
+// response returns a new HTTP response for req holding the cached response.
+func (r *CachedResponse) response(req *http.Request) *http.Response {
+	return &http.Response{
+		Status:        fmt.Sprintf("%d %s", r.StatusCode, http.StatusText(r.StatusCode)),
+		StatusCode:    r.StatusCode,
+		Proto:         "HTTP/1.1",
+		ProtoMajor:    1,
+		ProtoMinor:    1,
+		Header:        r.Header.Clone(),
+		Body:          io.NopCloser(bytes.NewReader(r.Body)),
+		ContentLength: int64(len(r.Body)),
+		Request:       req,
+	}
+}
+
+// CacheStore stores cached responses by key.
+//
+// Implement it to keep responses in an external store. Implementations must
+// be safe for concurrent use.
+type CacheStore interface {
+	// Get returns the response stored under key.
+	//
+	// It reports false if there is none.
+	Get(ctx context.Context, key string) (*CachedResponse, bool, error)
+
+	// Set stores resp under key.
+	Set(ctx context.Context, key string, resp *CachedResponse) error
+
+	// Delete removes the response stored under key, if any.
+	Delete(ctx context.Context, key string) error
+}
+
+// CacheStats reports the activity of a Cache.
+type CacheStats struct {
+	// Hits is the number of responses served from the cache,
+	// including those revalidated with the API.
+	Hits int64
+
+	// Misses is the number of responses that had to be fetched in full.
+	Misses int64
+
+	// Revalidations is the number of conditional requests sent.
+	Revalidations int64
+
+	// Invalidations is the number of successful writes that invalidated
+	// the entry of their URL, whether or not it was cached.
+	Invalidations int64
+
+	// StoreErrors is the number of errors returned by the store.
+	StoreErrors int64
+}
+
+// CacheOpt represents an option that can be passed to NewCache.
+type CacheOpt func(c *Cache)
+
+// CacheTTL sets how long responses without an ETag or Last-Modified
+// validator are served from the cache.
+//
+// If not used, such responses are not cached.
+func CacheTTL(ttl time.Duration) CacheOpt {
+	return func(c *Cache) {
+		c.ttl = ttl
+	}
+}
+
+// CacheStorage sets the store responses are kept in.
+//
+// If not used, responses are kept in memory, in an LRU store holding up
+// to 1000 entries.
+func CacheStorage(s CacheStore) CacheOpt {
+	return func(c *Cache) {
+		if s != nil {
+			c.store = s
+		}
+	}
+}
+
+// Cache caches successful GET responses, keyed by method, URL and the
+// credentials the request is sent with: its Authorization and Cookie
+// headers, or the key ID of its signature.
+//
+// Responses carrying an ETag or Last-Modified header are revalidated with a
+// conditional request each time they are used, and served from the cache if
+// the API returns 304 Not Modified. Other responses are served from the cache
+// for the configured TTL.
+//
+// A successful non-GET request through the same client invalidates the
+// entry of its URL for its credentials, so updating or deleting an account
+// drops the cached fetch of that account. Cached lists with query parameters are not
+// invalidated.
+type Cache struct {
+	store CacheStore
+	ttl   time.Duration
+
+	now func() time.Time
+
+	hits          atomic.Int64
+	misses        atomic.Int64
+	revalidations atomic.Int64
+	invalidations atomic.Int64
+	storeErrors   atomic.Int64
+}
+
+// NewCache creates a response cache.
+//
+// Pass it to a client with WithCache.
+func NewCache(opts ...CacheOpt) *Cache {
+	c := &Cache{
+		store: NewLRUStore(defaultCacheEntries),
+		now:   time.Now,
+	}
+
+	for _, opt := range opts {
+		opt(c)
+	}
+
+	return c
+}
+
+// WithCache caches responses to GET requests in cache.
+//
+// The same cache may be shared by several clients. Responses are only
+// served to requests sent with the same credentials.
+func WithCache(cache *Cache) Opt {
+	return func(c *Client) error {
+		if cache == nil {
+			return fmt.Errorf("cache opt: cache is nil")
+		}
+		c.middleware = append(c.middleware, cache.Middleware)
+
+		return nil
+	}
+}
+
+// Stats returns a snapshot of the cache activity.
+func (c *Cache) Stats() CacheStats {
+	return CacheStats{
+		Hits:          c.hits.Load(),
+		Misses:        c.misses.Load(),
+		Revalidations: c.revalidations.Load(),
+		Invalidations: c.invalidations.Load(),
+		StoreErrors:   c.storeErrors.Load(),
+	}
+}
+
+// Middleware returns next wrapped with the cache.
+func (c *Cache) Middleware(next Doer) Doer {
+	return DoerFunc(func(req *http.Request) (*http.Response, error) {
+		if req.Method != http.MethodGet {
+			return c.write(next, req)
+		}
+
+		return c.read(next, req)
+	})
+}
+
+// read serves a GET request from the cache, revalidating if required.
+func (c *Cache) read(next Doer, req *http.Request) (*http.Response, error) {
+	ctx := req.Context()
+	key := cacheKey(req.Method, req.URL.String(), credentialKey(req))
+
+	entry, ok, err := c.store.Get(ctx, key)
+	if err != nil {
+		c.storeErrors.Add(1)
+		ok = false
+	}
+
+	if ok && !entry.hasValidators() && c.now().Sub(entry.StoredAt) < c.ttl {
+		c.hits.Add(1)
+		return entry.response(req), nil
+	}
+
+	revalidate := ok && entry.hasValidators()
+	if revalidate {
+		req = req.Clone(ctx)
+		if etag := entry.etag(); etag != "" {
+			req.Header.Set("If-None-Match", etag)
+		}
+		if lm := entry.lastModified(); lm != "" {
+			req.Header.Set("If-Modified-Since", lm)
+		}
+
+		c.revalidations.Add(1)
+	}
+
+	resp, err := next.Do(req)
+	if err != nil {
+		return nil, err
+	}
+
+	if revalidate && resp.StatusCode == http.StatusNotModified {
+		resp.Body.Close()
+
+		entry.StoredAt = c.now()
+		c.set(ctx, key, entry)
+		c.hits.Add(1)
+
+		return entry.response(req), nil
+	}
+
+	c.misses.Add(1)
+
+	if !c.cacheable(resp) {
+		return resp, nil
+	}
+
+	body, err := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if err != nil {
+		return nil, fmt.Errorf("read response body: %w", err)
+	}
+	resp.Body = io.NopCloser(bytes.NewReader(body))
+
+	c.set(ctx, key, &CachedResponse{
+		StatusCode: resp.StatusCode,
+		Header:     resp.Header.Clone(),
+		Body:       body,
+		StoredAt:   c.now(),
+	})
+
+	return resp, nil
+}
+
+// write sends a non-GET request and invalidates the entry of its URL if
+// the request succeeds.
+func (c *Cache) write(next Doer, req *http.Request) (*http.Response, error) {
+	resp, err := next.Do(req)
+	if err != nil {
+		return nil, err
+	}
+
+	if req.Method != http.MethodHead && resp.StatusCode >= 200 && resp.StatusCode < 300 {
+		u := *req.URL
+		u.RawQuery = ""
+
+		err := c.store.Delete(req.Context(), cacheKey(http.MethodGet, u.String(), credentialKey(req)))
+		if err != nil {
+			c.storeErrors.Add(1)
+		} else {
+			c.invalidations.Add(1)
+		}
+	}
+
+	return resp, nil
+}
+
+// cacheable reports whether resp may be stored.
+func (c *Cache) cacheable(resp *http.Response) bool {
+	if resp.StatusCode != http.StatusOK {
+		return false
+	}
+
+	if strings.Contains(resp.Header.Get("Cache-Control"), "no-store") {
+		return false
+	}
+
+	return resp.Header.Get("ETag") != "" ||
+		resp.Header.Get("Last-Modified") != "" ||
+		c.ttl > 0
+}
+
+func (c *Cache) set(ctx context.Context, key string, resp *CachedResponse) {
+	err := c.store.Set(ctx, key, resp)
+	if err != nil {
+		c.storeErrors.Add(1)
+	}
+}
+
+// cacheKey identifies the entry of a request. It includes the credentials
+// the request is sent with, so that a cache shared by clients with
+// different credentials never serves one client's response to another.
+func cacheKey(method string, url string, credentials string) string {
+	return method + " " + url + "\n" + credentials
+}
+
+// lruStore is an in-memory CacheStore that evicts the least recently used
+// entry once full.
+type lruStore struct {
+	mu         sync.Mutex
+	maxEntries int
+	order      *list.List
+	entries    map[string]*list.Element
+}
+
+type lruEntry struct {
+	key  string
+	resp *CachedResponse
+}
+
+// NewLRUStore returns an in-memory CacheStore holding up to maxEntries
+// responses.
+func NewLRUStore(maxEntries int) CacheStore {
+	if maxEntries <= 0 {
+		maxEntries = defaultCacheEntries
+	}
+
+	return &lruStore{
+		maxEntries: maxEntries,
+		order:      list.New(),
+		entries:    make(map[string]*list.Element),
+	}
+}
+
+func (s *lruStore) Get(_ context.Context, key string) (*CachedResponse, bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	el, ok := s.entries[key]
+	if !ok {
+		return nil, false, nil
+	}
+	s.order.MoveToFront(el)
+
+	// Callers may update the response, so a copy is returned.
+	resp := *el.Value.(*lruEntry).resp
+
+	return &resp, true, nil
+}
+
+func (s *lruStore) Set(_ context.Context, key string, resp *CachedResponse) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if el, ok := s.entries[key]; ok {
+		el.Value.(*lruEntry).resp = resp
+		s.order.MoveToFront(el)
+
+		return nil
+	}
+
+	s.entries[key] = s.order.PushFront(&lruEntry{key: key, resp: resp})
+
+	if s.order.Len() > s.maxEntries {
+		oldest := s.order.Back()
+		s.order.Remove(oldest)
+		delete(s.entries, oldest.Value.(*lruEntry).key)
+	}
+
+	return nil
+}
+
+func (s *lruStore) Delete(_ context.Context, key string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if el, ok := s.entries[key]; ok {
+		s.order.Remove(el)
+		delete(s.entries, key)
+	}
+
+	return nil
+}
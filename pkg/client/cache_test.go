@@ -0,0 +1,275 @@
+package client_test
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/pem"
+	"errors"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"github.com/vivangkumar/form3-http-go/pkg/account"
+	"github.com/vivangkumar/form3-http-go/pkg/client"
+	"github.com/vivangkumar/form3-http-go/pkg/client/internal/fakes"
+)
+
+var _ = Describe("Caching responses", func() {
+	var (
+		cl             *client.Client
+		fakeHTTPClient *fakes.FakeHttpClient
+		cache          *client.Cache
+
+		ctx  context.Context
+		path string
+
+		header http.Header
+	)
+
+	fetch := func() *account.Response {
+		var r account.Response
+		_, err := cl.Get(ctx, path, nil, &r)
+		Expect(err).To(BeNil())
+
+		return &r
+	}
+
+	BeforeEach(func() {
+		ctx = context.Background()
+		path = "/v1/organisation/accounts/1"
+		header = http.Header{}
+
+		fakeHTTPClient = new(fakes.FakeHttpClient)
+		fakeHTTPClient.DoStub = func(req *http.Request) (*http.Response, error) {
+			if req.Method == http.MethodDelete {
+				return &http.Response{StatusCode: http.StatusNoContent, Body: http.NoBody}, nil
+			}
+
+			if etag := req.Header.Get("If-None-Match"); etag != "" && etag == header.Get("ETag") {
+				return &http.Response{StatusCode: http.StatusNotModified, Body: http.NoBody}, nil
+			}
+
+			return &http.Response{
+				StatusCode: http.StatusOK,
+				Header:     header.Clone(),
+				Body:       io.NopCloser(strings.NewReader(`{"data": {"id": "1", "version": 1}}`)),
+			}, nil
+		}
+	})
+
+	JustBeforeEach(func() {
+		c, err := client.New(client.WithCache(cache), client.WithHTTPClient(fakeHTTPClient))
+		Expect(err).To(BeNil())
+		cl = c
+	})
+
+	When("responses have no validators", func() {
+		BeforeEach(func() {
+			cache = client.NewCache(client.CacheTTL(50 * time.Millisecond))
+		})
+
+		It("should serve responses from the cache until the TTL expires", func() {
+			Expect(fetch().Data.ID).To(Equal("1"))
+			Expect(fetch().Data.ID).To(Equal("1"))
+			Expect(fakeHTTPClient.DoCallCount()).To(Equal(1))
+
+			time.Sleep(60 * time.Millisecond)
+
+			fetch()
+			Expect(fakeHTTPClient.DoCallCount()).To(Equal(2))
+			Expect(cache.Stats()).To(Equal(client.CacheStats{Hits: 1, Misses: 2}))
+		})
+
+		It("should invalidate the entry after a delete through the same client", func() {
+			fetch()
+
+			_, err := cl.Delete(ctx, path, map[string]string{"version": "1"})
+			Expect(err).To(BeNil())
+
+			fetch()
+			Expect(fakeHTTPClient.DoCallCount()).To(Equal(3))
+			Expect(cache.Stats().Invalidations).To(Equal(int64(1)))
+		})
+
+		It("should not serve responses to requests with other credentials", func() {
+			fetch()
+
+			other, err := client.New(
+				client.WithCache(cache),
+				client.WithHTTPClient(fakeHTTPClient),
+				client.WithHTTPRequestHeaders(map[string]string{"Authorization": "Bearer other"}),
+			)
+			Expect(err).To(BeNil())
+
+			_, err = other.Get(ctx, path, nil, new(account.Response))
+			Expect(err).To(BeNil())
+			Expect(fakeHTTPClient.DoCallCount()).To(Equal(2))
+		})
+
+		It("should serve signed requests by their key ID", func() {
+			key, err := rsa.GenerateKey(rand.Reader, 2048)
+			Expect(err).To(BeNil())
+			keyPEM := pem.EncodeToMemory(&pem.Block{
+				Type:  "RSA PRIVATE KEY",
+				Bytes: x509.MarshalPKCS1PrivateKey(key),
+			})
+
+			signed := func(keyID string) *client.Client {
+				c, err := client.New(
+					client.WithRequestSigning(keyID, keyPEM),
+					client.WithCache(cache),
+					client.WithHTTPClient(fakeHTTPClient),
+				)
+				Expect(err).To(BeNil())
+
+				return c
+			}
+
+			for _, c := range []*client.Client{signed("key-1"), signed("key-1"), signed("key-2")} {
+				_, err := c.Get(ctx, path, nil, new(account.Response))
+				Expect(err).To(BeNil())
+			}
+			Expect(fakeHTTPClient.DoCallCount()).To(Equal(2))
+		})
+
+		It("should not cache responses marked no-store", func() {
+			header.Set("Cache-Control", "no-store")
+
+			fetch()
+			fetch()
+			Expect(fakeHTTPClient.DoCallCount()).To(Equal(2))
+		})
+	})
+
+	When("no TTL is configured", func() {
+		BeforeEach(func() {
+			cache = client.NewCache()
+		})
+
+		It("should not cache responses without validators", func() {
+			fetch()
+			fetch()
+			Expect(fakeHTTPClient.DoCallCount()).To(Equal(2))
+			Expect(cache.Stats().Hits).To(BeZero())
+		})
+	})
+
+	When("responses carry an ETag", func() {
+		BeforeEach(func() {
+			cache = client.NewCache()
+			header.Set("ETag", `"v1"`)
+		})
+
+		It("should revalidate and serve the cached response when not modified", func() {
+			fetch()
+
+			r := fetch()
+			Expect(r.Data.ID).To(Equal("1"))
+
+			Expect(fakeHTTPClient.DoCallCount()).To(Equal(2))
+			req := fakeHTTPClient.DoArgsForCall(1)
+			Expect(req.Header.Get("If-None-Match")).To(Equal(`"v1"`))
+
+			Expect(cache.Stats()).To(Equal(client.CacheStats{Hits: 1, Misses: 1, Revalidations: 1}))
+		})
+
+		It("should replace the entry when modified", func() {
+			fetch()
+			header.Set("ETag", `"v2"`)
+
+			fetch()
+			fetch()
+
+			req := fakeHTTPClient.DoArgsForCall(2)
+			Expect(req.Header.Get("If-None-Match")).To(Equal(`"v2"`))
+			Expect(cache.Stats().Misses).To(Equal(int64(2)))
+		})
+	})
+
+	When("the store fails", func() {
+		BeforeEach(func() {
+			cache = client.NewCache(client.CacheTTL(time.Minute), client.CacheStorage(failingStore{}))
+		})
+
+		It("should send requests as if nothing was cached", func() {
+			fetch()
+			fetch()
+			Expect(fakeHTTPClient.DoCallCount()).To(Equal(2))
+			Expect(cache.Stats().StoreErrors).To(Equal(int64(4)))
+		})
+	})
+})
+
+var _ = Describe("LRU cache store", func() {
+	It("should evict the least recently used entry", func() {
+		ctx := context.Background()
+		store := client.NewLRUStore(2)
+
+		Expect(store.Set(ctx, "a", &client.CachedResponse{StatusCode: 200})).To(Succeed())
+		Expect(store.Set(ctx, "b", &client.CachedResponse{StatusCode: 200})).To(Succeed())
+
+		_, ok, _ := store.Get(ctx, "a")
+		Expect(ok).To(BeTrue())
+
+		Expect(store.Set(ctx, "c", &client.CachedResponse{StatusCode: 200})).To(Succeed())
+
+		_, ok, _ = store.Get(ctx, "b")
+		Expect(ok).To(BeFalse())
+		_, ok, _ = store.Get(ctx, "a")
+		Expect(ok).To(BeTrue())
+	})
+})
+
+var _ = Describe("Middleware", func() {
+	It("should wrap each attempt in the order given", func() {
+		var calls []string
+		mw := func(name string) client.Middleware {
+			return func(next client.Doer) client.Doer {
+				return client.DoerFunc(func(req *http.Request) (*http.Response, error) {
+					calls = append(calls, name)
+					return next.Do(req)
+				})
+			}
+		}
+
+		fakeHTTPClient := new(fakes.FakeHttpClient)
+		fakeHTTPClient.DoReturns(&http.Response{
+			StatusCode: http.StatusNoContent,
+			Body:       http.NoBody,
+			Request:    &http.Request{Method: http.MethodDelete, URL: &url.URL{}},
+		}, nil)
+
+		c, err := client.New(
+			client.WithMiddleware(mw("outer"), mw("inner")),
+			client.WithHTTPClient(fakeHTTPClient),
+		)
+		Expect(err).To(BeNil())
+
+		_, err = c.Delete(context.Background(), "/v1/organisation/accounts/1", nil)
+		Expect(err).To(BeNil())
+
+		Expect(calls).To(Equal([]string{"outer", "inner"}))
+		Expect(fakeHTTPClient.DoCallCount()).To(Equal(1))
+	})
+})
+
+type failingStore struct{}
+
+func (failingStore) Get(context.Context, string) (*client.CachedResponse, bool, error) {
+	return nil, false, errors.New("unavailable")
+}
+
+func (failingStore) Set(context.Context, string, *client.CachedResponse) error {
+	return errors.New("unavailable")
+}
+
+func (failingStore) Delete(context.Context, string) error {
+	return errors.New("unavailable")
+}
@@ -13,12 +13,15 @@ import (
 	"bytes"
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"net/http"
 	"net/url"
 	"strings"
 	"time"
+
+	"github.com/vivangkumar/form3-http-go/pkg/client/jsonapi"
 )
 
 const (
@@ -50,6 +53,25 @@ type Client struct {
 
 	// headers represent optional headers to set on each request.
 	headers map[string]string
+
+	// tracer opens spans for each HTTP attempt.
+	tracer Tracer
+
+	// retry configures how failed requests are retried.
+	retry retryPolicy
+
+	// strictDecoding rejects response fields that the target does not model.
+	strictDecoding bool
+
+	// middleware wraps httpClient once all options are applied.
+	middleware []Middleware
+
+	// signer signs each attempt just before it is sent, inside middleware.
+	signer *signer
+
+	// transport configures the HTTP transport built when no HTTP client
+	// is set with WithHTTPClient.
+	transport transportConfig
 }
 
 // New constructs a form3 http client.
@@ -61,12 +83,12 @@ func New(opts ...Opt) (*Client, error) {
 	baseURL, _ := url.Parse(defaultBaseURL)
 
 	c := &Client{
-		httpClient: &http.Client{
-			Timeout:   defaultTimeout,
-			Transport: &http.Transport{},
-		},
 		baseURL: baseURL,
 		headers: make(map[string]string),
+		tracer:  noopTracer{},
+		retry:   retryPolicy{backoff: defaultRetryBackoff},
+
+		transport: transportConfig{timeout: defaultTimeout},
 	}
 
 	for _, opt := range opts {
@@ -76,6 +98,23 @@ func New(opts ...Opt) (*Client, error) {
 		}
 	}
 
+	if c.httpClient == nil {
+		c.httpClient = &http.Client{
+			Timeout:   c.transport.timeout,
+			Transport: c.transport.build(),
+		}
+	} else if len(c.transport.opts) > 0 {
+		return nil, fmt.Errorf(
+			"apply options: %s cannot be combined with WithHTTPClient",
+			strings.Join(c.transport.opts, ", "),
+		)
+	}
+
+	if c.signer != nil {
+		c.httpClient = c.signer.middleware(c.httpClient)
+	}
+	c.httpClient = wrapMiddleware(c.httpClient, c.middleware)
+
 	return c, nil
 }
 
@@ -114,6 +153,24 @@ func (c *Client) Post(
 	return c.Do(req, target)
 }
 
+// Patch is a convenience method to create and execute a PATCH request against the API.
+//
+// body represents the request body, while target is the value to which an API
+// response will be decoded into.
+func (c *Client) Patch(
+	ctx context.Context,
+	path string,
+	body any,
+	target any,
+) (*http.Response, error) {
+	req, err := c.NewRequest(ctx, http.MethodPatch, path, nil, body)
+	if err != nil {
+		return nil, fmt.Errorf("create request: %w", err)
+	}
+
+	return c.Do(req, target)
+}
+
 // Delete is a convenience method to create and execute a DELETE request against the API.
 func (c *Client) Delete(
 	ctx context.Context,
@@ -189,6 +246,12 @@ func (c *Client) NewRequest(
 		}
 	}
 
+	// Send the idempotency key, if any.
+	injectIdempotencyKey(ctx, method, req.Header)
+
+	// Propagate trace context, if any.
+	injectTraceContext(ctx, req.Header)
+
 	return req, nil
 }
 
@@ -198,26 +261,127 @@ func (c *Client) NewRequest(
 //
 // If an API error has occurred i.e where the status code is not 2xx, then an
 // ErrorResponse is returned.
+//
+// When retries are configured, requests that fail with a transport error or a
+// retryable status code are retried. Only idempotent requests, or requests
+// carrying an idempotency key, are retried.
 func (c *Client) Do(req *http.Request, target any) (*http.Response, error) {
-	resp, err := c.httpClient.Do(req)
+	retry := canRetry(req)
+
+	for attempt := 1; ; attempt++ {
+		resp, retryable, err := c.attempt(req, target, attempt)
+		if err == nil {
+			return resp, nil
+		}
+
+		var e *errorResponse
+		if errors.As(err, &e) {
+			e.attempts = attempt
+		}
+
+		if !retry || !retryable || attempt > c.retry.maxRetries {
+			return nil, err
+		}
+
+		err = sleep(req.Context(), c.retry.wait(attempt+1))
+		if err != nil {
+			return nil, fmt.Errorf("wait to retry: %w", err)
+		}
+
+		err = rewindBody(req)
+		if err != nil {
+			return nil, fmt.Errorf("rewind request body: %w", err)
+		}
+	}
+}
+
+// attempt makes a single HTTP request.
+//
+// It reports whether the request may be retried if it fails.
+func (c *Client) attempt(
+	req *http.Request,
+	target any,
+	attempt int,
+) (resp *http.Response, retryable bool, err error) {
+	ctx, span := c.tracer.Start(req.Context(), "http.request")
+	defer func() {
+		RecordResponse(span, resp, err)
+		span.End(err)
+	}()
+
+	span.SetAttribute(AttrHTTPMethod, req.Method)
+	span.SetAttribute(AttrHTTPURL, req.URL.String())
+	span.SetAttribute(AttrAttempt, attempt)
+
+	if counter := attemptCounterFromContext(ctx); counter != nil {
+		counter.inc()
+	}
+
+	// Middleware runs before signing, so it learns the signing identity
+	// from the context.
+	if c.signer != nil {
+		ctx = contextWithSigningKeyID(ctx, c.signer.keyID)
+	}
+
+	// The attempt span is the parent of the request on the server.
+	req = req.WithContext(ctx)
+	injectTraceContext(ctx, req.Header)
+
+	resp, err = c.httpClient.Do(req)
 	if err != nil {
-		return nil, fmt.Errorf("do request: %w", err)
+		// Errors caused by the caller giving up are not retried.
+		return nil, ctx.Err() == nil, fmt.Errorf("do request: %w", err)
 	}
 	defer resp.Body.Close()
 
 	err = c.maybeDecodeAPIError(resp)
 	if err != nil {
-		return nil, err
+		return nil, retryableStatus(resp.StatusCode), err
 	}
 
 	if target != nil && resp.StatusCode != http.StatusNoContent {
-		err := json.NewDecoder(resp.Body).Decode(target)
+		err := c.decode(resp.Body, target)
 		if err != nil {
-			return nil, fmt.Errorf("decode response body: %w", err)
+			return nil, false, fmt.Errorf("decode response body: %w", err)
 		}
 	}
 
-	return resp, nil
+	return resp, false, nil
+}
+
+// decode JSON decodes r into target.
+//
+// With strict decoding, fields that target does not model are rejected.
+// Targets that keep unmodelled fields themselves report them through an
+// UnknownFields method, and targets with a Validate method are validated.
+func (c *Client) decode(r io.Reader, target any) error {
+	dec := json.NewDecoder(r)
+	if c.strictDecoding {
+		dec.DisallowUnknownFields()
+	}
+
+	err := dec.Decode(target)
+	if err != nil || !c.strictDecoding {
+		return err
+	}
+
+	if u, ok := target.(interface{ UnknownFields() []string }); ok {
+		fields := u.UnknownFields()
+		if len(fields) > 0 {
+			return &UnknownFieldsError{Fields: fields}
+		}
+	}
+
+	if v, ok := target.(interface{ Validate() error }); ok {
+		return v.Validate()
+	}
+
+	return nil
+}
+
+// Tracer returns the tracer configured on the client.
+func (c *Client) Tracer() Tracer {
+	return c.tracer
 }
 
 // maybeDecodeAPIError checks for any errors returned as part of the response body.
@@ -235,7 +399,10 @@ func (c *Client) maybeDecodeAPIError(resp *http.Response) error {
 	// https://www.api-docs.form3.tech/api/schemes/sepa-instant-credit-transfer/introduction/errors-status-codes
 	if sc == http.StatusBadRequest || sc == http.StatusConflict ||
 		sc == http.StatusForbidden {
-		var e apiError
+		var e struct {
+			apiError
+			Errors jsonapi.Errors `json:"errors,omitempty"`
+		}
 		err := json.NewDecoder(resp.Body).Decode(&e)
 		if err != nil {
 			return fmt.Errorf(
@@ -245,7 +412,11 @@ func (c *Client) maybeDecodeAPIError(resp *http.Response) error {
 			)
 		}
 
-		r.underlying = &e
+		// Some endpoints return a JSON:API errors array instead.
+		r.underlying = &e.apiError
+		if len(e.Errors) > 0 && e.ErrorMessage == nil {
+			r.underlying = e.Errors
+		}
 	}
 
 	return &r
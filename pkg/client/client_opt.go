@@ -3,6 +3,7 @@ package client
 import (
 	"fmt"
 	"net/url"
+	"time"
 )
 
 // Opt represents an option that can be passed
@@ -14,10 +15,15 @@ type Opt func(c *Client) error
 // It must conform to the httpClient interface.
 //
 // Use this for advanced configuration of the HTTP
-// client.
+// client. It cannot be combined with the options that configure the
+// transport, such as WithProxy.
 func WithHTTPClient(hc httpClient) Opt {
 	return func(c *Client) error {
+		if hc == nil {
+			return fmt.Errorf("http client opt: client is nil")
+		}
 		c.httpClient = hc
+
 		return nil
 	}
 }
@@ -47,3 +53,54 @@ func WithHTTPRequestHeaders(headers map[string]string) Opt {
 		return nil
 	}
 }
+
+// WithTracer sets the tracer used to open spans around requests.
+//
+// If not used, spans are not recorded.
+func WithTracer(t Tracer) Opt {
+	return func(c *Client) error {
+		if t == nil {
+			return fmt.Errorf("tracer opt: tracer is nil")
+		}
+		c.tracer = t
+
+		return nil
+	}
+}
+
+// WithRetry retries failed requests up to maxRetries times.
+//
+// Requests are retried on transport errors and on 429 and 5xx responses.
+// The wait before each retry starts at backoff and doubles on every retry,
+// up to a minute.
+//
+// Only idempotent requests and requests carrying an idempotency key
+// are retried. See ContextWithIdempotencyKey.
+func WithRetry(maxRetries int, backoff time.Duration) Opt {
+	return func(c *Client) error {
+		if maxRetries < 0 {
+			return fmt.Errorf("retry opt: max retries must not be negative")
+		}
+
+		if backoff < 0 {
+			return fmt.Errorf("retry opt: backoff must not be negative")
+		}
+
+		c.retry = retryPolicy{maxRetries: maxRetries, backoff: backoff}
+
+		return nil
+	}
+}
+
+// WithStrictDecoding rejects API responses containing fields that the
+// response type does not model.
+//
+// It is intended for tests and CI, so that fields added to the API are
+// noticed. Responses that fail validation, such as those holding unknown
+// enum values, are also rejected.
+func WithStrictDecoding() Opt {
+	return func(c *Client) error {
+		c.strictDecoding = true
+		return nil
+	}
+}
@@ -2,14 +2,20 @@ package client_test
 
 import (
 	"context"
+	"encoding/json"
+	"errors"
 	"io"
 	"net/http"
+	"strings"
 
+	"github.com/google/uuid"
 	. "github.com/onsi/ginkgo/v2"
 	. "github.com/onsi/gomega"
 
+	"github.com/vivangkumar/form3-http-go/pkg/account"
 	"github.com/vivangkumar/form3-http-go/pkg/client"
 	"github.com/vivangkumar/form3-http-go/pkg/client/internal/fakes"
+	"github.com/vivangkumar/form3-http-go/pkg/internal/fixtures"
 )
 
 var _ = Describe("Setting client options", func() {
@@ -96,4 +102,117 @@ var _ = Describe("Setting client options", func() {
 			})
 		})
 	})
+
+	When("strict decoding is configured", func() {
+		var body string
+
+		BeforeEach(func() {
+			c, err := client.New(
+				client.WithHTTPClient(fakeHTTPClient),
+				client.WithStrictDecoding(),
+			)
+			Expect(err).To(BeNil())
+			cl = c
+		})
+
+		JustBeforeEach(func() {
+			fakeHTTPClient.DoReturns(&http.Response{
+				StatusCode: http.StatusOK,
+				Body:       io.NopCloser(strings.NewReader(body)),
+			}, nil)
+		})
+
+		Context("and the response has only known fields", func() {
+			BeforeEach(func() {
+				body = fixtures.AccountsResponseAllAttributes(uuid.NewString(), uuid.NewString(), "GB", "GBP")
+			})
+
+			It("should decode the response", func() {
+				var r account.Response
+				_, err := cl.Get(ctx, "/accounts", nil, &r)
+				Expect(err).To(BeNil())
+				Expect(r.Data).To(Not(BeNil()))
+			})
+		})
+
+		Context("and the response has an unknown top level field", func() {
+			BeforeEach(func() {
+				body = `{"data": {"id": "1"}, "unknown": true}`
+			})
+
+			It("should return an error", func() {
+				var r account.Response
+				_, err := cl.Get(ctx, "/accounts", nil, &r)
+				Expect(err).To(MatchError(ContainSubstring("unknown")))
+			})
+		})
+
+		Context("and the response has an unknown account field", func() {
+			BeforeEach(func() {
+				body = `{"data": {"id": "1", "created_on": "2023-01-01", "attributes": {"colour": "red"}}}`
+			})
+
+			It("should return the unknown fields", func() {
+				var r account.Response
+				_, err := cl.Get(ctx, "/accounts", nil, &r)
+
+				var e *client.UnknownFieldsError
+				Expect(errors.As(err, &e)).To(BeTrue())
+				Expect(e.Fields).To(Equal([]string{"data.created_on", "data.attributes.colour"}))
+			})
+		})
+
+		Context("and the response has an unknown nested field", func() {
+			BeforeEach(func() {
+				body = `{"data": {"id": "1", "attributes": {"private_identification": {"title": "Dr"}}}}`
+			})
+
+			It("should return the unknown fields", func() {
+				var r account.Response
+				_, err := cl.Get(ctx, "/accounts", nil, &r)
+
+				var e *client.UnknownFieldsError
+				Expect(errors.As(err, &e)).To(BeTrue())
+				Expect(e.Fields).To(Equal([]string{"data.attributes.private_identification.title"}))
+			})
+		})
+
+		Context("and the response has an unknown enum value", func() {
+			BeforeEach(func() {
+				body = `{"data": {"id": "1", "attributes": {"status": "closed"}}}`
+			})
+
+			It("should return an error", func() {
+				var r account.Response
+				_, err := cl.Get(ctx, "/accounts", nil, &r)
+
+				var e *account.UnknownEnumError
+				Expect(errors.As(err, &e)).To(BeTrue())
+			})
+		})
+	})
+
+	When("strict decoding is not configured", func() {
+		BeforeEach(func() {
+			c, err := client.New(client.WithHTTPClient(fakeHTTPClient))
+			Expect(err).To(BeNil())
+			cl = c
+
+			fakeHTTPClient.DoReturns(&http.Response{
+				StatusCode: http.StatusOK,
+				Body: io.NopCloser(strings.NewReader(
+					`{"data": {"id": "1", "created_on": "2023-01-01", "attributes": {"status": "closed"}}}`,
+				)),
+			}, nil)
+		})
+
+		It("should keep unknown fields", func() {
+			var r account.Response
+			_, err := cl.Get(ctx, "/accounts", nil, &r)
+			Expect(err).To(BeNil())
+
+			Expect(r.Data.Extra).To(HaveKeyWithValue("created_on", json.RawMessage(`"2023-01-01"`)))
+			Expect(*r.Data.Attributes.Status).To(Equal(account.Status("closed")))
+		})
+	})
 })
@@ -17,6 +17,7 @@ import (
 	"github.com/vivangkumar/form3-http-go/pkg/account"
 	"github.com/vivangkumar/form3-http-go/pkg/client"
 	"github.com/vivangkumar/form3-http-go/pkg/client/internal/fakes"
+	"github.com/vivangkumar/form3-http-go/pkg/client/jsonapi"
 	"github.com/vivangkumar/form3-http-go/pkg/internal/fixtures"
 )
 
@@ -276,6 +277,36 @@ var _ = Describe("Client", func() {
 			})
 		})
 
+		Context("JSON:API errors array", func() {
+			BeforeEach(func() {
+				fakeHTTPClient.DoReturns(&http.Response{
+					StatusCode: http.StatusBadRequest,
+					Body: io.NopCloser(bytes.NewBufferString(`{"errors": [
+						{"status": "400", "code": "invalid", "title": "Invalid field", "source": {"pointer": "/data/attributes/bic"}},
+						{"status": "400", "title": "Missing field", "detail": "country is required"}
+					]}`)),
+					Request: &http.Request{
+						Method: http.MethodGet,
+						URL:    &url.URL{},
+					},
+				}, nil)
+			})
+
+			It("should decode every error", func() {
+				var b []byte
+				_, err := cl.Get(ctx, path, nil, &b)
+				assertErrorResponse(err, http.StatusBadRequest)
+
+				var errs jsonapi.Errors
+				Expect(errors.As(err, &errs)).To(BeTrue())
+				Expect(errs).To(HaveLen(2))
+				Expect(errs[0].Source.Pointer).To(Equal("/data/attributes/bic"))
+				Expect(err.Error()).To(HaveSuffix(
+					"Invalid field: code: invalid: source: /data/attributes/bic; Missing field: country is required",
+				))
+			})
+		})
+
 		Context("other errors", func() {
 			BeforeEach(func() {
 				fakeHTTPClient.DoReturns(&http.Response{
@@ -388,6 +419,49 @@ var _ = Describe("Client", func() {
 		})
 	})
 
+	Describe("Executing PATCH requests", func() {
+		BeforeEach(func() {
+			path = path + "/accounts/" + accountID
+		})
+
+		Context("the request is successful", func() {
+			BeforeEach(func() {
+				respBody := fixtures.AccountsResponseAllFields(orgID, accountID, "FR", "EUR")
+				fakeHTTPClient.DoReturns(&http.Response{
+					StatusCode: http.StatusOK,
+					Body:       io.NopCloser(bytes.NewBuffer([]byte(respBody))),
+				}, nil)
+			})
+
+			It("should return the HTTP success response", func() {
+				acc := account.New(orgID).WithID(accountID)
+
+				var r response
+				resp, err := cl.Patch(ctx, path, acc, &r)
+				Expect(err).To(BeNil())
+
+				Expect(resp.StatusCode).To(Equal(http.StatusOK))
+				expectAccountsResponse(r, orgID, accountID)
+
+				req := fakeHTTPClient.DoArgsForCall(0)
+				Expect(req.Method).To(Equal(http.MethodPatch))
+				Expect(req.Header.Get("Content-Type")).To(Equal("application/vnd.api+json"))
+			})
+		})
+
+		Context("the request fails", func() {
+			BeforeEach(func() {
+				fakeHTTPClient.DoReturns(nil, fmt.Errorf("request error"))
+			})
+
+			It("should return the error", func() {
+				resp, err := cl.Patch(ctx, path, nil, nil)
+				Expect(err).To(Not(BeNil()))
+				Expect(resp).To(BeNil())
+			})
+		})
+	})
+
 	Describe("Executing DELETE requests", func() {
 		BeforeEach(func() {
 			path = path + "/accounts/" + accountID
@@ -0,0 +1,216 @@
+package client
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+)
+
+// WithRequestCoalescing shares one HTTP request between concurrent identical
+// GET requests.
+//
+// GET requests with the same URL and authorisation headers that are made
+// while one is in flight wait for its response instead of sending their own.
+// Each caller decodes its own copy of the response.
+//
+// The shared request is only cancelled once every caller waiting for it
+// has given up.
+func WithRequestCoalescing() Opt {
+	return func(c *Client) error {
+		c.middleware = append(c.middleware, coalesce)
+		return nil
+	}
+}
+
+// coalesce returns next wrapped with request coalescing.
+func coalesce(next Doer) Doer {
+	return &coalescer{
+		next:  next,
+		calls: make(map[string]*sharedCall),
+	}
+}
+
+type coalescer struct {
+	next Doer
+
+	mu    sync.Mutex
+	calls map[string]*sharedCall
+}
+
+// sharedCall is an in-flight request shared by several callers.
+type sharedCall struct {
+	done chan struct{}
+
+	// cancel cancels the shared request.
+	cancel context.CancelFunc
+
+	// waiters is the number of callers waiting for the request.
+	waiters int
+
+	resp *http.Response
+	body []byte
+	err  error
+}
+
+// Do sends req, or waits for an identical request in flight.
+func (c *coalescer) Do(req *http.Request) (*http.Response, error) {
+	if req.Method != http.MethodGet {
+		return c.next.Do(req)
+	}
+
+	key := coalesceKey(req)
+
+	c.mu.Lock()
+	call, ok := c.calls[key]
+	if !ok {
+		ctx, cancel := context.WithCancel(detach(req.Context()))
+		call = &sharedCall{done: make(chan struct{}), cancel: cancel}
+		c.calls[key] = call
+
+		go c.run(key, call, req.Clone(ctx))
+	}
+	call.waiters++
+	c.mu.Unlock()
+
+	select {
+	case <-call.done:
+		return call.response(req)
+	case <-req.Context().Done():
+		c.leave(key, call)
+		return nil, req.Context().Err()
+	}
+}
+
+// run sends the shared request and records its outcome.
+func (c *coalescer) run(key string, call *sharedCall, req *http.Request) {
+	defer call.cancel()
+
+	resp, err := c.next.Do(req)
+	if err == nil {
+		call.body, err = io.ReadAll(resp.Body)
+		resp.Body.Close()
+		if err != nil {
+			err = fmt.Errorf("read response body: %w", err)
+		}
+	}
+	call.resp, call.err = resp, err
+
+	c.mu.Lock()
+	if c.calls[key] == call {
+		delete(c.calls, key)
+	}
+	c.mu.Unlock()
+
+	close(call.done)
+}
+
+// leave removes a caller that gave up waiting.
+//
+// The shared request is cancelled once no callers are left.
+func (c *coalescer) leave(key string, call *sharedCall) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	call.waiters--
+	if call.waiters > 0 {
+		return
+	}
+
+	call.cancel()
+	if c.calls[key] == call {
+		delete(c.calls, key)
+	}
+}
+
+// response returns a copy of the shared response for req.
+func (call *sharedCall) response(req *http.Request) (*http.Response, error) {
+	if call.err != nil {
+		return nil, call.err
+	}
+
+	resp := *call.resp
+	resp.Header = call.resp.Header.Clone()
+	resp.Body = io.NopCloser(bytes.NewReader(call.body))
+	resp.Request = req
+
+	return &resp, nil
+}
+
+// coalesceKey identifies requests that may share a response.
+func coalesceKey(req *http.Request) string {
+	return req.URL.String() + "\n" + credentialKey(req)
+}
+
+// credentialHeaders are the request headers that affect authorisation.
+//
+// Responses to requests that differ in these headers are never shared.
+var credentialHeaders = []string{"Authorization", "Cookie"}
+
+// credentialKey identifies the credentials req is sent with.
+//
+// A request signature is identified by its key ID alone, since the
+// signature itself changes with the Date header of every request. Requests
+// that a client signs after its middleware runs are identified by the key
+// ID it will sign them with.
+func credentialKey(req *http.Request) string {
+	values := make([]string, len(credentialHeaders))
+	for i, h := range credentialHeaders {
+		values[i] = strings.Join(req.Header.Values(h), ",")
+	}
+
+	if keyID, ok := signingKeyIDFromContext(req.Context()); ok {
+		values[0] = "Signature " + keyID
+	} else if keyID, ok := signatureKeyID(values[0]); ok {
+		values[0] = "Signature " + keyID
+	}
+
+	return strings.Join(values, "\n")
+}
+
+// signatureKeyID returns the key ID of a Signature authorization header.
+func signatureKeyID(auth string) (string, bool) {
+	if !strings.HasPrefix(auth, "Signature ") {
+		return "", false
+	}
+
+	_, rest, ok := strings.Cut(auth, `keyId="`)
+	if !ok {
+		return "", false
+	}
+
+	keyID, _, ok := strings.Cut(rest, `"`)
+
+	return keyID, ok
+}
+
+// detachedContext carries the values of its parent, but is never done.
+type detachedContext struct {
+	parent context.Context
+}
+
+// detach returns a context holding the values of ctx that is not cancelled
+// when ctx is.
+func detach(ctx context.Context) context.Context {
+	return detachedContext{parent: ctx}
+}
+
+func (detachedContext) Deadline() (time.Time, bool) {
+	return time.Time{}, false
+}
+
+func (detachedContext) Done() <-chan struct{} {
+	return nil
+}
+
+func (detachedContext) Err() error {
+	return nil
+}
+
+func (d detachedContext) Value(key any) any {
+	return d.parent.Value(key)
+}
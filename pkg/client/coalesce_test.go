@@ -0,0 +1,206 @@
+package client_test
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"github.com/vivangkumar/form3-http-go/pkg/account"
+	"github.com/vivangkumar/form3-http-go/pkg/client"
+	"github.com/vivangkumar/form3-http-go/pkg/client/internal/fakes"
+)
+
+var _ = Describe("Coalescing requests", func() {
+	var (
+		cl             *client.Client
+		fakeHTTPClient *fakes.FakeHttpClient
+
+		ctx     context.Context
+		path    string
+		release chan struct{}
+
+		// entered counts requests that reached the coalescing layer.
+		entered atomic.Int32
+
+		sharedCtx chan context.Context
+	)
+
+	BeforeEach(func() {
+		ctx = context.Background()
+		path = "/v1/organisation/accounts/1"
+		release = make(chan struct{})
+		entered.Store(0)
+		sharedCtx = make(chan context.Context, 10)
+
+		fakeHTTPClient = new(fakes.FakeHttpClient)
+		fakeHTTPClient.DoStub = func(req *http.Request) (*http.Response, error) {
+			sharedCtx <- req.Context()
+
+			select {
+			case <-release:
+			case <-req.Context().Done():
+				return nil, req.Context().Err()
+			}
+
+			return &http.Response{
+				StatusCode: http.StatusOK,
+				Body:       io.NopCloser(strings.NewReader(`{"data": {"id": "1", "attributes": {"country": "GB"}}}`)),
+			}, nil
+		}
+
+		counter := func(next client.Doer) client.Doer {
+			return client.DoerFunc(func(req *http.Request) (*http.Response, error) {
+				entered.Add(1)
+				return next.Do(req)
+			})
+		}
+
+		c, err := client.New(
+			client.WithHTTPClient(fakeHTTPClient),
+			client.WithMiddleware(counter),
+			client.WithRequestCoalescing(),
+		)
+		Expect(err).To(BeNil())
+		cl = c
+	})
+
+	// waitForCallers waits until n requests are waiting for a response.
+	waitForCallers := func(n int) {
+		Eventually(entered.Load).Should(BeNumerically("==", n))
+
+		// Give the last caller time to join the shared request.
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	It("should share one request between concurrent identical GETs", func() {
+		const n = 10
+
+		var (
+			wg      sync.WaitGroup
+			results = make([]*account.Response, n)
+			errs    = make([]error, n)
+		)
+
+		for i := 0; i < n; i++ {
+			wg.Add(1)
+			go func(i int) {
+				defer GinkgoRecover()
+				defer wg.Done()
+
+				results[i] = new(account.Response)
+				_, errs[i] = cl.Get(ctx, path, nil, results[i])
+			}(i)
+		}
+
+		waitForCallers(n)
+		close(release)
+		wg.Wait()
+
+		Expect(fakeHTTPClient.DoCallCount()).To(Equal(1))
+		for i := 0; i < n; i++ {
+			Expect(errs[i]).To(BeNil())
+			Expect(results[i].Data.ID).To(Equal("1"))
+		}
+
+		By("decoding a copy for each caller", func() {
+			results[0].Data.Attributes.Country = "FR"
+			Expect(results[1].Data.Attributes.Country).To(Equal("GB"))
+		})
+	})
+
+	It("should not share requests with different authorisation", func() {
+		other, err := client.New(
+			client.WithHTTPClient(fakeHTTPClient),
+			client.WithHTTPRequestHeaders(map[string]string{"Authorization": "Bearer other"}),
+			client.WithRequestCoalescing(),
+		)
+		Expect(err).To(BeNil())
+
+		close(release)
+
+		var wg sync.WaitGroup
+		for _, c := range []*client.Client{cl, other} {
+			wg.Add(1)
+			go func(c *client.Client) {
+				defer GinkgoRecover()
+				defer wg.Done()
+
+				_, err := c.Get(ctx, path, nil, new(account.Response))
+				Expect(err).To(BeNil())
+			}(c)
+		}
+		wg.Wait()
+
+		Expect(fakeHTTPClient.DoCallCount()).To(Equal(2))
+	})
+
+	When("a caller gives up", func() {
+		It("should not cancel the shared request for the other callers", func() {
+			cancelled, cancel := context.WithCancel(ctx)
+
+			first := make(chan error, 1)
+			go func() {
+				_, err := cl.Get(cancelled, path, nil, new(account.Response))
+				first <- err
+			}()
+
+			second := make(chan error, 1)
+			go func() {
+				_, err := cl.Get(ctx, path, nil, new(account.Response))
+				second <- err
+			}()
+
+			waitForCallers(2)
+			cancel()
+			Eventually(first).Should(Receive(MatchError(context.Canceled)))
+
+			close(release)
+			Eventually(second).Should(Receive(BeNil()))
+			Expect(fakeHTTPClient.DoCallCount()).To(Equal(1))
+		})
+
+		It("should cancel the shared request once every caller has given up", func() {
+			cancelled, cancel := context.WithCancel(ctx)
+
+			done := make(chan error, 1)
+			go func() {
+				_, err := cl.Get(cancelled, path, nil, new(account.Response))
+				done <- err
+			}()
+
+			var reqCtx context.Context
+			Eventually(sharedCtx).Should(Receive(&reqCtx))
+
+			cancel()
+			Eventually(done).Should(Receive(MatchError(context.Canceled)))
+			Eventually(reqCtx.Done()).Should(BeClosed())
+		})
+	})
+
+	It("should not share other methods", func() {
+		fakeHTTPClient.DoStub = nil
+		fakeHTTPClient.DoReturns(&http.Response{StatusCode: http.StatusNoContent, Body: http.NoBody}, nil)
+
+		var wg sync.WaitGroup
+		for i := 0; i < 3; i++ {
+			wg.Add(1)
+			go func() {
+				defer GinkgoRecover()
+				defer wg.Done()
+
+				_, err := cl.Delete(ctx, path, nil)
+				Expect(err).To(BeNil())
+			}()
+		}
+		wg.Wait()
+
+		Expect(fakeHTTPClient.DoCallCount()).To(Equal(3))
+	})
+})
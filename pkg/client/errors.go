@@ -1,8 +1,10 @@
 package client
 
 import (
+	"errors"
 	"fmt"
 	"net/http"
+	"strings"
 )
 
 // apiError contains all fields that might be returned in an error response.
@@ -45,12 +47,32 @@ func (e apiError) Error() string {
 type errorResponse struct {
 	httpResponse *http.Response
 	underlying   error
+
+	// attempts is the number of HTTP attempts made before the error.
+	attempts int
 }
 
 func (e errorResponse) HTTPResponse() *http.Response {
 	return e.httpResponse
 }
 
+// Attempts returns the number of HTTP attempts made, including retries.
+func (e errorResponse) Attempts() int {
+	return e.attempts
+}
+
+// StatusCode returns the HTTP status code returned by the API.
+func (e errorResponse) StatusCode() int {
+	return e.httpResponse.StatusCode
+}
+
+// Unwrap returns the error decoded from the response body, if any.
+//
+// For endpoints returning a JSON:API errors array, this is a jsonapi.Errors.
+func (e errorResponse) Unwrap() error {
+	return e.underlying
+}
+
 func (e errorResponse) Error() string {
 	msg := fmt.Sprintf(
 		"%s %s returned status %d",
@@ -65,3 +87,37 @@ func (e errorResponse) Error() string {
 
 	return msg
 }
+
+// UnknownFieldsError is returned with strict decoding when a response
+// contains fields that are not modelled.
+type UnknownFieldsError struct {
+	// Fields are the JSON paths of the unknown fields.
+	Fields []string
+}
+
+// Error implements the error interface.
+func (e *UnknownFieldsError) Error() string {
+	return fmt.Sprintf("unknown fields: %s", strings.Join(e.Fields, ", "))
+}
+
+// responseFromError returns the HTTP response carried by err, if any.
+func responseFromError(err error) *http.Response {
+	var e interface{ HTTPResponse() *http.Response }
+	if errors.As(err, &e) {
+		return e.HTTPResponse()
+	}
+
+	return nil
+}
+
+// StatusCode returns the HTTP status code carried by err.
+//
+// It returns 0 if err does not carry an HTTP response.
+func StatusCode(err error) int {
+	resp := responseFromError(err)
+	if resp == nil {
+		return 0
+	}
+
+	return resp.StatusCode
+}
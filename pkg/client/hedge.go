@@ -0,0 +1,313 @@
+package client
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+const (
+	defaultHedgeDelay    = 50 * time.Millisecond
+	defaultHedgeMaxRatio = 0.1
+
+	// hedgeWindow is the number of latencies kept to compute percentiles.
+	hedgeWindow = 1000
+
+	// hedgeMinSamples is the number of latencies required before the
+	// percentile is used instead of the fixed delay.
+	hedgeMinSamples = 20
+)
+
+// HedgeStats reports the activity of a Hedger.
+type HedgeStats struct {
+	// Requests is the number of GET requests seen.
+	Requests int64
+
+	// Hedges is the number of hedged requests sent.
+	Hedges int64
+
+	// HedgeWins is the number of hedged requests that finished first.
+	HedgeWins int64
+
+	// Capped is the number of hedges not sent because of the cap.
+	Capped int64
+}
+
+// HedgeOpt represents an option that can be passed to NewHedger.
+type HedgeOpt func(h *Hedger)
+
+// HedgeDelay sets how long to wait for a response before sending a hedge.
+//
+// If not used, the delay is 50ms.
+func HedgeDelay(d time.Duration) HedgeOpt {
+	return func(h *Hedger) {
+		if d > 0 {
+			h.delay = d
+		}
+	}
+}
+
+// HedgePercentile waits for the given percentile of recent response
+// latencies before sending a hedge, for example 0.95.
+//
+// The fixed delay is used until enough responses have been seen.
+func HedgePercentile(p float64) HedgeOpt {
+	return func(h *Hedger) {
+		if p > 0 && p < 1 {
+			h.percentile = p
+		}
+	}
+}
+
+// HedgeMaxRatio caps hedges to the given share of requests, for example 0.1.
+//
+// If not used, at most 10% of requests are hedged.
+func HedgeMaxRatio(r float64) HedgeOpt {
+	return func(h *Hedger) {
+		if r >= 0 && r <= 1 {
+			h.maxRatio = r
+		}
+	}
+}
+
+// Hedger sends a second, hedged, attempt for GET requests that have not
+// received a response within a delay.
+//
+// Whichever attempt finishes first with a response that would not be
+// retried is used, and the other is cancelled. If the first attempt to
+// finish fails, or returns a status that would be retried, the other
+// attempt is waited for.
+type Hedger struct {
+	delay      time.Duration
+	percentile float64
+	maxRatio   float64
+
+	mu        sync.Mutex
+	latencies []time.Duration
+	next      int
+
+	requests  atomic.Int64
+	hedges    atomic.Int64
+	hedgeWins atomic.Int64
+	capped    atomic.Int64
+}
+
+// NewHedger creates a hedging policy.
+//
+// Pass it to a client with WithHedging.
+func NewHedger(opts ...HedgeOpt) *Hedger {
+	h := &Hedger{
+		delay:    defaultHedgeDelay,
+		maxRatio: defaultHedgeMaxRatio,
+	}
+
+	for _, opt := range opts {
+		opt(h)
+	}
+
+	return h
+}
+
+// WithHedging hedges GET requests as configured by h.
+func WithHedging(h *Hedger) Opt {
+	return func(c *Client) error {
+		if h == nil {
+			return fmt.Errorf("hedging opt: hedger is nil")
+		}
+		c.middleware = append(c.middleware, h.Middleware)
+
+		return nil
+	}
+}
+
+// Stats returns a snapshot of the hedging activity.
+func (h *Hedger) Stats() HedgeStats {
+	return HedgeStats{
+		Requests:  h.requests.Load(),
+		Hedges:    h.hedges.Load(),
+		HedgeWins: h.hedgeWins.Load(),
+		Capped:    h.capped.Load(),
+	}
+}
+
+type hedgeResult struct {
+	resp   *http.Response
+	err    error
+	hedge  bool
+	cancel context.CancelFunc
+}
+
+// Middleware returns next wrapped with hedging.
+func (h *Hedger) Middleware(next Doer) Doer {
+	return DoerFunc(func(req *http.Request) (*http.Response, error) {
+		if req.Method != http.MethodGet {
+			return next.Do(req)
+		}
+
+		return h.do(next, req)
+	})
+}
+
+func (h *Hedger) do(next Doer, req *http.Request) (*http.Response, error) {
+	requests := h.requests.Add(1)
+	start := time.Now()
+
+	results := make(chan hedgeResult, 2)
+	send := func(hedge bool) context.CancelFunc {
+		ctx, cancel := context.WithCancel(req.Context())
+		go func() {
+			resp, err := next.Do(req.Clone(ctx))
+			results <- hedgeResult{resp: resp, err: err, hedge: hedge, cancel: cancel}
+		}()
+
+		return cancel
+	}
+
+	// cancels holds the cancel func of each attempt, keyed by whether it
+	// is the hedge.
+	cancels := map[bool]context.CancelFunc{false: send(false)}
+	inFlight := 1
+
+	timer := time.NewTimer(h.hedgeDelay())
+	defer timer.Stop()
+
+	for {
+		select {
+		case <-timer.C:
+			if !h.reserveHedge(requests) {
+				h.capped.Add(1)
+				continue
+			}
+
+			cancels[true] = send(true)
+			inFlight++
+		case r := <-results:
+			inFlight--
+
+			// A failed attempt, or one that would be retried, is only
+			// returned if there is nothing else to wait for.
+			if !r.final() && inFlight > 0 {
+				r.release()
+				continue
+			}
+
+			// Stop the other attempt, if any, and release its response.
+			for hedge, cancel := range cancels {
+				if hedge != r.hedge {
+					cancel()
+				}
+			}
+			go drain(results, inFlight)
+
+			if r.err != nil {
+				r.cancel()
+				return nil, r.err
+			}
+
+			if r.hedge {
+				h.hedgeWins.Add(1)
+			}
+			h.record(time.Since(start))
+
+			// The context of the winning attempt is kept until its body
+			// is closed.
+			r.resp.Body = &cancelOnClose{ReadCloser: r.resp.Body, cancel: r.cancel}
+
+			return r.resp, nil
+		}
+	}
+}
+
+// final reports whether r is a response that would not be retried.
+func (r hedgeResult) final() bool {
+	return r.err == nil && !retryableStatus(r.resp.StatusCode)
+}
+
+// release cancels the attempt of r and closes its response, if any.
+func (r hedgeResult) release() {
+	r.cancel()
+	if r.resp != nil {
+		r.resp.Body.Close()
+	}
+}
+
+// drain releases the responses of n attempts still in flight.
+func drain(results <-chan hedgeResult, n int) {
+	for i := 0; i < n; i++ {
+		r := <-results
+		r.release()
+	}
+}
+
+// reserveHedge counts a hedge if one may be sent without exceeding the
+// maximum share of hedged requests, and reports whether it was counted.
+//
+// Concurrent requests cannot both take the last hedge allowed.
+func (h *Hedger) reserveHedge(requests int64) bool {
+	for {
+		hedges := h.hedges.Load()
+		if float64(hedges+1) > h.maxRatio*float64(requests) {
+			return false
+		}
+
+		if h.hedges.CompareAndSwap(hedges, hedges+1) {
+			return true
+		}
+	}
+}
+
+// hedgeDelay returns how long to wait before sending a hedge.
+func (h *Hedger) hedgeDelay() time.Duration {
+	if h.percentile == 0 {
+		return h.delay
+	}
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if len(h.latencies) < hedgeMinSamples {
+		return h.delay
+	}
+
+	sorted := make([]time.Duration, len(h.latencies))
+	copy(sorted, h.latencies)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i] < sorted[j] })
+
+	return sorted[int(h.percentile*float64(len(sorted)-1))]
+}
+
+// record records the latency of a request, as seen by the caller.
+func (h *Hedger) record(d time.Duration) {
+	if h.percentile == 0 {
+		return
+	}
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if len(h.latencies) < hedgeWindow {
+		h.latencies = append(h.latencies, d)
+		return
+	}
+
+	h.latencies[h.next] = d
+	h.next = (h.next + 1) % hedgeWindow
+}
+
+// cancelOnClose cancels a context once the body it wraps is closed.
+type cancelOnClose struct {
+	io.ReadCloser
+	cancel context.CancelFunc
+}
+
+func (b *cancelOnClose) Close() error {
+	err := b.ReadCloser.Close()
+	b.cancel()
+
+	return err
+}
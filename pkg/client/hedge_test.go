@@ -0,0 +1,247 @@
+package client_test
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"github.com/vivangkumar/form3-http-go/pkg/account"
+	"github.com/vivangkumar/form3-http-go/pkg/client"
+	"github.com/vivangkumar/form3-http-go/pkg/client/internal/fakes"
+)
+
+var _ = Describe("Hedging requests", func() {
+	var (
+		cl             *client.Client
+		fakeHTTPClient *fakes.FakeHttpClient
+		hedger         *client.Hedger
+
+		ctx  context.Context
+		path string
+
+		// slow reports whether the nth call should be slow.
+		slow func(n int32) bool
+
+		calls     atomic.Int32
+		cancelled chan int32
+	)
+
+	okResponse := func(id string) *http.Response {
+		return &http.Response{
+			StatusCode: http.StatusOK,
+			Body:       io.NopCloser(strings.NewReader(`{"data": {"id": "` + id + `"}}`)),
+			Request:    &http.Request{Method: http.MethodGet, URL: &url.URL{}},
+		}
+	}
+
+	BeforeEach(func() {
+		ctx = context.Background()
+		path = "/v1/organisation/accounts/1"
+		calls.Store(0)
+		slow = func(int32) bool { return false }
+
+		// Attempts cancelled late may outlive the spec, so they report to
+		// the channel of their own spec.
+		ch := make(chan int32, 100)
+		cancelled = ch
+
+		fakeHTTPClient = new(fakes.FakeHttpClient)
+		fakeHTTPClient.DoStub = func(req *http.Request) (*http.Response, error) {
+			n := calls.Add(1)
+			if !slow(n) {
+				return okResponse("fast"), nil
+			}
+
+			select {
+			case <-time.After(time.Second):
+				return okResponse("slow"), nil
+			case <-req.Context().Done():
+				ch <- n
+				return nil, req.Context().Err()
+			}
+		}
+	})
+
+	JustBeforeEach(func() {
+		c, err := client.New(client.WithHTTPClient(fakeHTTPClient), client.WithHedging(hedger))
+		Expect(err).To(BeNil())
+		cl = c
+	})
+
+	fetch := func() string {
+		var r account.Response
+		_, err := cl.Get(ctx, path, nil, &r)
+		Expect(err).To(BeNil())
+
+		return r.Data.ID
+	}
+
+	When("the first attempt is slow", func() {
+		BeforeEach(func() {
+			hedger = client.NewHedger(client.HedgeDelay(10*time.Millisecond), client.HedgeMaxRatio(1))
+			slow = func(n int32) bool { return n == 1 }
+		})
+
+		It("should use the hedge and cancel the first attempt", func() {
+			Expect(fetch()).To(Equal("fast"))
+			Expect(fakeHTTPClient.DoCallCount()).To(Equal(2))
+			Eventually(cancelled).Should(Receive(Equal(int32(1))))
+
+			Expect(hedger.Stats()).To(Equal(client.HedgeStats{Requests: 1, Hedges: 1, HedgeWins: 1}))
+		})
+	})
+
+	When("the first attempt is fast", func() {
+		BeforeEach(func() {
+			hedger = client.NewHedger(client.HedgeDelay(time.Second), client.HedgeMaxRatio(1))
+		})
+
+		It("should not hedge", func() {
+			Expect(fetch()).To(Equal("fast"))
+			Expect(fakeHTTPClient.DoCallCount()).To(Equal(1))
+			Expect(hedger.Stats().Hedges).To(BeZero())
+		})
+	})
+
+	When("the first attempt fails after the hedge is sent", func() {
+		BeforeEach(func() {
+			hedger = client.NewHedger(client.HedgeDelay(10*time.Millisecond), client.HedgeMaxRatio(1))
+
+			fakeHTTPClient.DoStub = func(req *http.Request) (*http.Response, error) {
+				if calls.Add(1) == 1 {
+					time.Sleep(30 * time.Millisecond)
+					return nil, io.ErrUnexpectedEOF
+				}
+
+				time.Sleep(50 * time.Millisecond)
+				return okResponse("hedge"), nil
+			}
+		})
+
+		It("should wait for the hedge", func() {
+			Expect(fetch()).To(Equal("hedge"))
+		})
+	})
+
+	When("the first attempt is rejected with a retryable status after the hedge is sent", func() {
+		BeforeEach(func() {
+			hedger = client.NewHedger(client.HedgeDelay(10*time.Millisecond), client.HedgeMaxRatio(1))
+
+			fakeHTTPClient.DoStub = func(req *http.Request) (*http.Response, error) {
+				if calls.Add(1) == 1 {
+					time.Sleep(30 * time.Millisecond)
+					return &http.Response{
+						StatusCode: http.StatusServiceUnavailable,
+						Body:       http.NoBody,
+						Request:    &http.Request{Method: http.MethodGet, URL: &url.URL{}},
+					}, nil
+				}
+
+				time.Sleep(50 * time.Millisecond)
+				return okResponse("hedge"), nil
+			}
+		})
+
+		It("should wait for the hedge", func() {
+			Expect(fetch()).To(Equal("hedge"))
+			Expect(hedger.Stats().HedgeWins).To(Equal(int64(1)))
+		})
+	})
+
+	When("concurrent requests compete for the last hedge", func() {
+		BeforeEach(func() {
+			hedger = client.NewHedger(client.HedgeDelay(5*time.Millisecond), client.HedgeMaxRatio(0.1))
+
+			fakeHTTPClient.DoStub = func(req *http.Request) (*http.Response, error) {
+				time.Sleep(20 * time.Millisecond)
+				return okResponse("slow"), nil
+			}
+		})
+
+		It("should not exceed the given share of requests", func() {
+			var wg sync.WaitGroup
+			for i := 0; i < 20; i++ {
+				wg.Add(1)
+				go func() {
+					defer GinkgoRecover()
+					defer wg.Done()
+
+					fetch()
+				}()
+			}
+			wg.Wait()
+
+			stats := hedger.Stats()
+			Expect(stats.Requests).To(Equal(int64(20)))
+			Expect(stats.Hedges).To(BeNumerically("<=", 2))
+			Expect(stats.Hedges + stats.Capped).To(Equal(int64(20)))
+		})
+	})
+
+	When("hedges are capped", func() {
+		BeforeEach(func() {
+			hedger = client.NewHedger(client.HedgeDelay(5*time.Millisecond), client.HedgeMaxRatio(0.5))
+
+			fakeHTTPClient.DoStub = func(req *http.Request) (*http.Response, error) {
+				time.Sleep(20 * time.Millisecond)
+				return okResponse("slow"), nil
+			}
+		})
+
+		It("should not hedge more than the given share of requests", func() {
+			for i := 0; i < 4; i++ {
+				fetch()
+			}
+
+			stats := hedger.Stats()
+			Expect(stats.Requests).To(Equal(int64(4)))
+			Expect(stats.Hedges).To(Equal(int64(2)))
+			Expect(stats.Capped).To(Equal(int64(2)))
+		})
+	})
+
+	When("hedging after a percentile", func() {
+		BeforeEach(func() {
+			hedger = client.NewHedger(
+				client.HedgeDelay(time.Hour),
+				client.HedgePercentile(0.95),
+				client.HedgeMaxRatio(1),
+			)
+			slow = func(n int32) bool { return n == 21 }
+		})
+
+		It("should hedge once enough latencies are known", func() {
+			for i := 0; i < 20; i++ {
+				Expect(fetch()).To(Equal("fast"))
+			}
+			Expect(hedger.Stats().Hedges).To(BeZero())
+
+			Expect(fetch()).To(Equal("fast"))
+			Expect(hedger.Stats().Hedges).To(Equal(int64(1)))
+		})
+	})
+
+	It("should not hedge other methods", func() {
+		hedger = client.NewHedger(client.HedgeDelay(time.Millisecond), client.HedgeMaxRatio(1))
+		c, err := client.New(client.WithHTTPClient(fakeHTTPClient), client.WithHedging(hedger))
+		Expect(err).To(BeNil())
+
+		fakeHTTPClient.DoStub = func(req *http.Request) (*http.Response, error) {
+			time.Sleep(10 * time.Millisecond)
+			return &http.Response{StatusCode: http.StatusNoContent, Body: http.NoBody}, nil
+		}
+
+		_, err = c.Delete(ctx, path, nil)
+		Expect(err).To(BeNil())
+		Expect(fakeHTTPClient.DoCallCount()).To(Equal(1))
+		Expect(hedger.Stats().Requests).To(BeZero())
+	})
+})
@@ -0,0 +1,48 @@
+package client
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/google/uuid"
+)
+
+const idempotencyKeyHeader = "Idempotency-Key"
+
+// idempotencyNamespace is the UUID namespace used to derive idempotency keys.
+var idempotencyNamespace = uuid.MustParse("0d5b3a9e-4c1a-4c43-9f4e-0a7b1f6f3c2d")
+
+type idempotencyKeyKey struct{}
+
+// ContextWithIdempotencyKey returns a copy of ctx carrying an idempotency key.
+//
+// POST and PATCH requests created with the returned context send the key in
+// the Idempotency-Key header. The same key is sent on every retry, which also
+// makes these requests eligible for retries.
+func ContextWithIdempotencyKey(ctx context.Context, key string) context.Context {
+	return context.WithValue(ctx, idempotencyKeyKey{}, key)
+}
+
+// IdempotencyKeyFromContext returns the idempotency key carried by ctx, if any.
+func IdempotencyKeyFromContext(ctx context.Context) (string, bool) {
+	key, ok := ctx.Value(idempotencyKeyKey{}).(string)
+	return key, ok && key != ""
+}
+
+// IdempotencyKeyFor derives a deterministic idempotency key for a resource.
+//
+// The same resource type and ID always produce the same key.
+func IdempotencyKeyFor(resourceType string, id string) string {
+	return uuid.NewSHA1(idempotencyNamespace, []byte(resourceType+"/"+id)).String()
+}
+
+// injectIdempotencyKey sets the idempotency key from ctx on mutating requests.
+func injectIdempotencyKey(ctx context.Context, method string, h http.Header) {
+	if method != http.MethodPost && method != http.MethodPatch {
+		return
+	}
+
+	if key, ok := IdempotencyKeyFromContext(ctx); ok {
+		h.Set(idempotencyKeyHeader, key)
+	}
+}
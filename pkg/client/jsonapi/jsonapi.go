@@ -0,0 +1,375 @@
+// Package jsonapi exposes the JSON:API document structure used by the API.
+//
+// https://jsonapi.org/format/
+package jsonapi
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// ErrNotIncluded is returned when a related resource is not part of the
+// included resources of a document.
+var ErrNotIncluded = errors.New("resource not included")
+
+// Document is a JSON:API document whose primary data is a single resource.
+type Document[T any] struct {
+	// Data contains the primary resource.
+	Data *T `json:"data,omitempty"`
+
+	// Included contains resources related to the primary resource.
+	Included Included `json:"included,omitempty"`
+
+	// Links are always returned as part of the response.
+	// Except in cases of no content responses.
+	Links *Links `json:"links,omitempty"`
+
+	// Meta contains non-standard information about the document.
+	Meta Meta `json:"meta,omitempty"`
+
+	// Errors contains the errors returned by the API, if any.
+	Errors Errors `json:"errors,omitempty"`
+}
+
+// ListDocument is a JSON:API document whose primary data is a list of
+// resources.
+type ListDocument[T any] struct {
+	// Data contains the primary resources.
+	Data []T `json:"data"`
+
+	// Included contains resources related to the primary resources.
+	Included Included `json:"included,omitempty"`
+
+	// Links are used to page through the list.
+	Links *Links `json:"links,omitempty"`
+
+	// Meta contains non-standard information about the document,
+	// for example a total count.
+	Meta Meta `json:"meta,omitempty"`
+
+	// Errors contains the errors returned by the API, if any.
+	Errors Errors `json:"errors,omitempty"`
+}
+
+// Links represents the HATEOAS convention links sent as part of responses.
+type Links struct {
+	Self  string  `json:"self"`
+	First *string `json:"first,omitempty"`
+	Last  *string `json:"last,omitempty"`
+	Next  *string `json:"next,omitempty"`
+	Prev  *string `json:"prev,omitempty"`
+}
+
+// Meta holds non-standard meta information.
+//
+// Values are kept as raw JSON and decoded on demand.
+type Meta map[string]json.RawMessage
+
+// Lookup decodes the value of key into v.
+//
+// It reports whether key is present.
+func (m Meta) Lookup(key string, v any) (bool, error) {
+	raw, ok := m[key]
+	if !ok {
+		return false, nil
+	}
+
+	err := json.Unmarshal(raw, v)
+	if err != nil {
+		return true, fmt.Errorf("decode meta %q: %w", key, err)
+	}
+
+	return true, nil
+}
+
+// ResourceIdentifier identifies a resource by type and ID.
+type ResourceIdentifier struct {
+	Type string `json:"type"`
+	ID   string `json:"id"`
+}
+
+// Relationship holds identifiers of related resources.
+//
+// To-one relationships are decoded as a single identifier in Data.
+type Relationship struct {
+	Data  []ResourceIdentifier `json:"data"`
+	Links *Links               `json:"links,omitempty"`
+	Meta  Meta                 `json:"meta,omitempty"`
+}
+
+// UnmarshalJSON implements json.Unmarshaler.
+//
+// Data may be a single identifier, a list of identifiers or null.
+func (r *Relationship) UnmarshalJSON(data []byte) error {
+	var rel struct {
+		Data  json.RawMessage `json:"data"`
+		Links *Links          `json:"links,omitempty"`
+		Meta  Meta            `json:"meta,omitempty"`
+	}
+
+	err := json.Unmarshal(data, &rel)
+	if err != nil {
+		return err
+	}
+
+	*r = Relationship{Links: rel.Links, Meta: rel.Meta}
+
+	d := bytes.TrimSpace(rel.Data)
+	switch {
+	case len(d) == 0 || bytes.Equal(d, []byte("null")):
+		return nil
+	case d[0] == '[':
+		return json.Unmarshal(d, &r.Data)
+	default:
+		var id ResourceIdentifier
+		err = json.Unmarshal(d, &id)
+		if err != nil {
+			return err
+		}
+		r.Data = []ResourceIdentifier{id}
+
+		return nil
+	}
+}
+
+// One returns the first related resource identifier.
+//
+// It reports false if there are none.
+func (r *Relationship) One() (ResourceIdentifier, bool) {
+	if r == nil || len(r.Data) == 0 {
+		return ResourceIdentifier{}, false
+	}
+
+	return r.Data[0], true
+}
+
+// Resource is an included resource.
+//
+// Its JSON is kept so it can be decoded into the type it represents.
+type Resource struct {
+	ResourceIdentifier
+
+	raw json.RawMessage
+}
+
+// UnmarshalJSON implements json.Unmarshaler.
+func (r *Resource) UnmarshalJSON(data []byte) error {
+	err := json.Unmarshal(data, &r.ResourceIdentifier)
+	if err != nil {
+		return err
+	}
+	r.raw = append(r.raw[:0], data...)
+
+	return nil
+}
+
+// MarshalJSON implements json.Marshaler.
+func (r Resource) MarshalJSON() ([]byte, error) {
+	if r.raw == nil {
+		return json.Marshal(r.ResourceIdentifier)
+	}
+
+	return r.raw, nil
+}
+
+// Decode JSON decodes the resource into v.
+func (r *Resource) Decode(v any) error {
+	return json.Unmarshal(r.raw, v)
+}
+
+// Included holds the resources included in a document.
+type Included []Resource
+
+// Find returns the included resource identified by id.
+func (in Included) Find(id ResourceIdentifier) (*Resource, bool) {
+	for i := range in {
+		if in[i].ResourceIdentifier == id {
+			return &in[i], true
+		}
+	}
+
+	return nil, false
+}
+
+// Resolve decodes the included resource identified by id into a new R.
+//
+// ErrNotIncluded is returned if the resource is not included.
+func Resolve[R any](in Included, id ResourceIdentifier) (*R, error) {
+	res, ok := in.Find(id)
+	if !ok {
+		return nil, fmt.Errorf("%s %s: %w", id.Type, id.ID, ErrNotIncluded)
+	}
+
+	r := new(R)
+	err := res.Decode(r)
+	if err != nil {
+		return nil, fmt.Errorf("decode included %s %s: %w", id.Type, id.ID, err)
+	}
+
+	return r, nil
+}
+
+// ResolveAll resolves every resource of rel as with Resolve.
+func ResolveAll[R any](in Included, rel *Relationship) ([]*R, error) {
+	if rel == nil {
+		return nil, nil
+	}
+
+	rs := make([]*R, 0, len(rel.Data))
+	for _, id := range rel.Data {
+		r, err := Resolve[R](in, id)
+		if err != nil {
+			return nil, err
+		}
+		rs = append(rs, r)
+	}
+
+	return rs, nil
+}
+
+// Error is a JSON:API error object.
+type Error struct {
+	ID     string       `json:"id,omitempty"`
+	Status string       `json:"status,omitempty"`
+	Code   string       `json:"code,omitempty"`
+	Title  string       `json:"title,omitempty"`
+	Detail string       `json:"detail,omitempty"`
+	Source *ErrorSource `json:"source,omitempty"`
+	Meta   Meta         `json:"meta,omitempty"`
+}
+
+// ErrorSource identifies the part of the request that caused an error.
+type ErrorSource struct {
+	Pointer   string `json:"pointer,omitempty"`
+	Parameter string `json:"parameter,omitempty"`
+}
+
+// Error implements the error interface.
+func (e Error) Error() string {
+	msg := e.Title
+	if e.Detail != "" {
+		if msg != "" {
+			msg += ": "
+		}
+		msg += e.Detail
+	}
+
+	if e.Code != "" {
+		msg = fmt.Sprintf("%s: code: %s", msg, e.Code)
+	}
+
+	if e.Source != nil && e.Source.Pointer != "" {
+		msg = fmt.Sprintf("%s: source: %s", msg, e.Source.Pointer)
+	}
+
+	if msg == "" {
+		return "unknown error"
+	}
+
+	return msg
+}
+
+// Errors is a list of JSON:API error objects.
+type Errors []Error
+
+// Error implements the error interface.
+func (e Errors) Error() string {
+	msgs := make([]string, 0, len(e))
+	for _, err := range e {
+		msgs = append(msgs, err.Error())
+	}
+
+	return strings.Join(msgs, "; ")
+}
+
+// unknownFielder is implemented by resources that keep fields they do not
+// model.
+type unknownFielder interface {
+	UnknownFields() []string
+}
+
+// validator is implemented by resources that can check their values.
+type validator interface {
+	Validate() error
+}
+
+// UnknownFields returns the JSON paths of fields of the primary data that
+// are not modelled.
+//
+// Only resources that implement UnknownFields() []string report fields.
+func (d *Document[T]) UnknownFields() []string {
+	if d.Data == nil {
+		return nil
+	}
+
+	return unknownFields("data", d.Data)
+}
+
+// Validate validates the primary data.
+//
+// Only resources that implement Validate() error are validated.
+func (d *Document[T]) Validate() error {
+	if d.Data == nil {
+		return nil
+	}
+
+	return validate("data", d.Data)
+}
+
+// UnknownFields returns the JSON paths of fields of the primary data that
+// are not modelled.
+//
+// Only resources that implement UnknownFields() []string report fields.
+func (d *ListDocument[T]) UnknownFields() []string {
+	var fields []string
+	for i := range d.Data {
+		fields = append(fields, unknownFields(fmt.Sprintf("data[%d]", i), &d.Data[i])...)
+	}
+
+	return fields
+}
+
+// Validate validates the primary data.
+//
+// Only resources that implement Validate() error are validated.
+func (d *ListDocument[T]) Validate() error {
+	for i := range d.Data {
+		err := validate(fmt.Sprintf("data[%d]", i), &d.Data[i])
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func unknownFields(path string, res any) []string {
+	u, ok := res.(unknownFielder)
+	if !ok {
+		return nil
+	}
+
+	var fields []string
+	for _, f := range u.UnknownFields() {
+		fields = append(fields, path+"."+f)
+	}
+
+	return fields
+}
+
+func validate(path string, res any) error {
+	v, ok := res.(validator)
+	if !ok {
+		return nil
+	}
+
+	err := v.Validate()
+	if err != nil {
+		return fmt.Errorf("%s: %w", path, err)
+	}
+
+	return nil
+}
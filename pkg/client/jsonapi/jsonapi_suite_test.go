@@ -0,0 +1,13 @@
+package jsonapi_test
+
+import (
+	"testing"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+func TestJSONAPI(t *testing.T) {
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "JSON:API Suite")
+}
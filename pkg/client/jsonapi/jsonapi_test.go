@@ -0,0 +1,129 @@
+package jsonapi_test
+
+import (
+	"encoding/json"
+	"errors"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"github.com/vivangkumar/form3-http-go/pkg/client/jsonapi"
+)
+
+type event struct {
+	ID         string `json:"id"`
+	Type       string `json:"type"`
+	Attributes struct {
+		Kind string `json:"kind"`
+	} `json:"attributes"`
+}
+
+type resource struct {
+	ID            string `json:"id"`
+	Type          string `json:"type"`
+	Relationships struct {
+		Parent *jsonapi.Relationship `json:"parent,omitempty"`
+		Events *jsonapi.Relationship `json:"events,omitempty"`
+	} `json:"relationships"`
+}
+
+var _ = Describe("Documents", func() {
+	const body = `{
+		"data": {
+			"id": "1",
+			"type": "accounts",
+			"relationships": {
+				"parent": {"data": {"type": "accounts", "id": "0"}},
+				"events": {"data": [
+					{"type": "account_events", "id": "e1"},
+					{"type": "account_events", "id": "e2"}
+				]}
+			}
+		},
+		"included": [
+			{"type": "account_events", "id": "e1", "attributes": {"kind": "created"}},
+			{"type": "account_events", "id": "e2", "attributes": {"kind": "confirmed"}}
+		],
+		"links": {"self": "/v1/organisation/accounts/1"},
+		"meta": {"total": 2}
+	}`
+
+	var doc jsonapi.Document[resource]
+
+	BeforeEach(func() {
+		doc = jsonapi.Document[resource]{}
+		Expect(json.Unmarshal([]byte(body), &doc)).To(Succeed())
+	})
+
+	It("should decode the primary data and links", func() {
+		Expect(doc.Data.ID).To(Equal("1"))
+		Expect(doc.Links.Self).To(Equal("/v1/organisation/accounts/1"))
+	})
+
+	It("should decode meta on demand", func() {
+		var total int
+		ok, err := doc.Meta.Lookup("total", &total)
+		Expect(err).To(BeNil())
+		Expect(ok).To(BeTrue())
+		Expect(total).To(Equal(2))
+
+		ok, err = doc.Meta.Lookup("missing", &total)
+		Expect(err).To(BeNil())
+		Expect(ok).To(BeFalse())
+	})
+
+	It("should decode to-one and to-many relationships", func() {
+		parent, ok := doc.Data.Relationships.Parent.One()
+		Expect(ok).To(BeTrue())
+		Expect(parent).To(Equal(jsonapi.ResourceIdentifier{Type: "accounts", ID: "0"}))
+
+		Expect(doc.Data.Relationships.Events.Data).To(HaveLen(2))
+	})
+
+	It("should resolve included resources", func() {
+		events, err := jsonapi.ResolveAll[event](doc.Included, doc.Data.Relationships.Events)
+		Expect(err).To(BeNil())
+		Expect(events).To(HaveLen(2))
+		Expect(events[1].Attributes.Kind).To(Equal("confirmed"))
+	})
+
+	It("should return an error for resources that are not included", func() {
+		parent, _ := doc.Data.Relationships.Parent.One()
+
+		_, err := jsonapi.Resolve[resource](doc.Included, parent)
+		Expect(errors.Is(err, jsonapi.ErrNotIncluded)).To(BeTrue())
+	})
+
+	It("should encode included resources as they were received", func() {
+		b, err := json.Marshal(doc.Included)
+		Expect(err).To(BeNil())
+		Expect(b).To(MatchJSON(`[
+			{"type": "account_events", "id": "e1", "attributes": {"kind": "created"}},
+			{"type": "account_events", "id": "e2", "attributes": {"kind": "confirmed"}}
+		]`))
+	})
+
+	Context("list documents", func() {
+		It("should decode every resource", func() {
+			var list jsonapi.ListDocument[event]
+			err := json.Unmarshal([]byte(`{
+				"data": [{"id": "e1", "type": "account_events"}, {"id": "e2", "type": "account_events"}],
+				"links": {"self": "/events", "next": "/events?page[number]=1"}
+			}`), &list)
+			Expect(err).To(BeNil())
+
+			Expect(list.Data).To(HaveLen(2))
+			Expect(*list.Links.Next).To(Equal("/events?page[number]=1"))
+		})
+	})
+
+	Context("relationships without data", func() {
+		It("should decode null data", func() {
+			var rel jsonapi.Relationship
+			Expect(json.Unmarshal([]byte(`{"data": null}`), &rel)).To(Succeed())
+
+			_, ok := rel.One()
+			Expect(ok).To(BeFalse())
+		})
+	})
+})
@@ -0,0 +1,54 @@
+package client
+
+import (
+	"fmt"
+	"net/http"
+)
+
+// Doer sends HTTP requests.
+//
+// *http.Client satisfies it.
+type Doer interface {
+	Do(req *http.Request) (*http.Response, error)
+}
+
+// DoerFunc adapts a function to a Doer.
+type DoerFunc func(req *http.Request) (*http.Response, error)
+
+// Do calls f(req).
+func (f DoerFunc) Do(req *http.Request) (*http.Response, error) {
+	return f(req)
+}
+
+// Middleware wraps the Doer used to send each HTTP attempt.
+//
+// Middleware sees every attempt, including retries, after headers have
+// been set.
+type Middleware func(next Doer) Doer
+
+// WithMiddleware adds middleware around the underlying HTTP client.
+//
+// Middleware is applied in the order given, with the first one outermost,
+// regardless of where WithHTTPClient appears in the options.
+func WithMiddleware(mw ...Middleware) Opt {
+	return func(c *Client) error {
+		for _, m := range mw {
+			if m == nil {
+				return fmt.Errorf("middleware opt: middleware is nil")
+			}
+		}
+		c.middleware = append(c.middleware, mw...)
+
+		return nil
+	}
+}
+
+// wrapMiddleware wraps hc in mw, with the first middleware outermost.
+func wrapMiddleware(hc httpClient, mw []Middleware) httpClient {
+	var d Doer = hc
+	for i := len(mw) - 1; i >= 0; i-- {
+		d = mw[i](d)
+	}
+
+	return d
+}
@@ -0,0 +1,172 @@
+package client
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"sync/atomic"
+	"time"
+)
+
+const (
+	defaultRetryBackoff = 100 * time.Millisecond
+
+	// maxRetryBackoff is the longest wait between retries, however many
+	// times the backoff has doubled.
+	maxRetryBackoff = time.Minute
+)
+
+// retryPolicy configures how failed requests are retried.
+type retryPolicy struct {
+	// maxRetries is the number of retries made after the first attempt.
+	maxRetries int
+
+	// backoff is the wait before the first retry. It doubles on each
+	// subsequent retry.
+	backoff time.Duration
+}
+
+// wait returns the time to wait before the given attempt.
+//
+// The wait stops doubling once it reaches maxRetryBackoff, or backoff if
+// that is longer, so that it cannot overflow.
+func (p retryPolicy) wait(attempt int) time.Duration {
+	limit := maxRetryBackoff
+	if p.backoff > limit {
+		limit = p.backoff
+	}
+
+	d := p.backoff
+	for i := 2; i < attempt && d < limit; i++ {
+		d *= 2
+	}
+
+	if d > limit {
+		return limit
+	}
+
+	return d
+}
+
+// retryableMethods are safe to retry without an idempotency key.
+var retryableMethods = map[string]bool{
+	http.MethodGet:     true,
+	http.MethodHead:    true,
+	http.MethodOptions: true,
+	http.MethodPut:     true,
+	http.MethodDelete:  true,
+}
+
+// canRetry reports whether req may be sent more than once.
+//
+// Requests with non-idempotent methods are only retried when they carry an
+// idempotency key. Requests with bodies must be able to rewind them.
+func canRetry(req *http.Request) bool {
+	if !retryableMethods[req.Method] && req.Header.Get(idempotencyKeyHeader) == "" {
+		return false
+	}
+
+	return req.Body == nil || req.Body == http.NoBody || req.GetBody != nil
+}
+
+// retryableStatus reports whether a response with the status code
+// should be retried.
+func retryableStatus(sc int) bool {
+	switch sc {
+	case http.StatusTooManyRequests,
+		http.StatusInternalServerError,
+		http.StatusBadGateway,
+		http.StatusServiceUnavailable,
+		http.StatusGatewayTimeout:
+		return true
+	}
+
+	return false
+}
+
+// sleep waits for d or until ctx is done.
+func sleep(ctx context.Context, d time.Duration) error {
+	t := time.NewTimer(d)
+	defer t.Stop()
+
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-t.C:
+		return nil
+	}
+}
+
+// rewindBody resets the request body so that it can be sent again.
+func rewindBody(req *http.Request) error {
+	if req.GetBody == nil {
+		return nil
+	}
+
+	body, err := req.GetBody()
+	if err != nil {
+		return err
+	}
+	req.Body = body
+
+	return nil
+}
+
+// AttemptCounter counts the HTTP attempts made by the client.
+//
+// Use ContextWithAttemptCounter to obtain one.
+type AttemptCounter struct {
+	n      atomic.Int64
+	parent *AttemptCounter
+}
+
+// Attempts returns the number of attempts counted so far.
+func (a *AttemptCounter) Attempts() int {
+	return int(a.n.Load())
+}
+
+// Retries returns the number of attempts beyond the first.
+func (a *AttemptCounter) Retries() int {
+	n := a.Attempts()
+	if n == 0 {
+		return 0
+	}
+
+	return n - 1
+}
+
+func (a *AttemptCounter) inc() {
+	for c := a; c != nil; c = c.parent {
+		c.n.Add(1)
+	}
+}
+
+type attemptCounterKey struct{}
+
+// ContextWithAttemptCounter returns a copy of ctx with an AttemptCounter.
+//
+// Every HTTP attempt made for a request created with the returned context
+// is counted, including retries. Attempts are also counted against any
+// counter already present in ctx.
+func ContextWithAttemptCounter(ctx context.Context) (context.Context, *AttemptCounter) {
+	a := &AttemptCounter{parent: attemptCounterFromContext(ctx)}
+	return context.WithValue(ctx, attemptCounterKey{}, a), a
+}
+
+func attemptCounterFromContext(ctx context.Context) *AttemptCounter {
+	a, _ := ctx.Value(attemptCounterKey{}).(*AttemptCounter)
+	return a
+}
+
+// Attempts returns the number of HTTP attempts made before err was returned.
+//
+// It returns 0 if err was not returned by the client after an HTTP response
+// was received.
+func Attempts(err error) int {
+	var e interface{ Attempts() int }
+	if errors.As(err, &e) {
+		return e.Attempts()
+	}
+
+	return 0
+}
@@ -0,0 +1,212 @@
+package client_test
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"github.com/vivangkumar/form3-http-go/pkg/client"
+	"github.com/vivangkumar/form3-http-go/pkg/client/internal/fakes"
+)
+
+var _ = Describe("Retrying requests", func() {
+	var (
+		cl             *client.Client
+		fakeHTTPClient *fakes.FakeHttpClient
+
+		ctx  context.Context
+		path string
+
+		bodies []string
+		keys   []string
+	)
+
+	errorResp := func(sc int) *http.Response {
+		return &http.Response{
+			StatusCode: sc,
+			Body:       http.NoBody,
+			Request:    &http.Request{Method: http.MethodPost, URL: &url.URL{}},
+		}
+	}
+
+	BeforeEach(func() {
+		ctx = context.Background()
+		path = "/v1/organisation/accounts"
+
+		bodies = nil
+		keys = nil
+
+		fakeHTTPClient = new(fakes.FakeHttpClient)
+
+		var err error
+		cl, err = client.New(
+			client.WithHTTPClient(fakeHTTPClient),
+			client.WithRetry(2, 0),
+		)
+		Expect(err).To(BeNil())
+	})
+
+	JustBeforeEach(func() {
+		responses := []*http.Response{
+			errorResp(http.StatusServiceUnavailable),
+			{StatusCode: http.StatusCreated, Body: io.NopCloser(strings.NewReader(`{}`))},
+		}
+
+		fakeHTTPClient.DoStub = func(req *http.Request) (*http.Response, error) {
+			var b []byte
+			if req.Body != nil {
+				b, _ = io.ReadAll(req.Body)
+			}
+			bodies = append(bodies, string(b))
+			keys = append(keys, req.Header.Get("Idempotency-Key"))
+
+			n := len(bodies) - 1
+			if n < len(responses) {
+				return responses[n], nil
+			}
+
+			return errorResp(http.StatusServiceUnavailable), nil
+		}
+	})
+
+	Context("a POST request with an idempotency key", func() {
+		BeforeEach(func() {
+			ctx = client.ContextWithIdempotencyKey(ctx, "key-1")
+		})
+
+		It("should retry with the same key and body", func() {
+			var target map[string]any
+			resp, err := cl.Post(ctx, path, map[string]string{"id": "1"}, &target)
+			Expect(err).To(BeNil())
+			Expect(resp.StatusCode).To(Equal(http.StatusCreated))
+
+			Expect(fakeHTTPClient.DoCallCount()).To(Equal(2))
+			Expect(keys).To(Equal([]string{"key-1", "key-1"}))
+			Expect(bodies[0]).To(MatchJSON(`{"id":"1"}`))
+			Expect(bodies[1]).To(Equal(bodies[0]))
+		})
+	})
+
+	Context("a POST request without an idempotency key", func() {
+		It("should not retry", func() {
+			_, err := cl.Post(ctx, path, map[string]string{"id": "1"}, nil)
+			Expect(err).To(Not(BeNil()))
+
+			Expect(fakeHTTPClient.DoCallCount()).To(Equal(1))
+			Expect(keys).To(Equal([]string{""}))
+			Expect(client.Attempts(err)).To(Equal(1))
+		})
+	})
+
+	Context("a GET request", func() {
+		It("should be retried", func() {
+			var target map[string]any
+			_, err := cl.Get(ctx, path, nil, &target)
+			Expect(err).To(BeNil())
+
+			Expect(fakeHTTPClient.DoCallCount()).To(Equal(2))
+
+			By("not sending an idempotency key", func() {
+				Expect(keys).To(Equal([]string{"", ""}))
+			})
+		})
+	})
+
+	Context("retries are exhausted", func() {
+		BeforeEach(func() {
+			ctx = client.ContextWithIdempotencyKey(ctx, "key-1")
+		})
+
+		JustBeforeEach(func() {
+			fakeHTTPClient.DoReturns(errorResp(http.StatusInternalServerError), nil)
+			fakeHTTPClient.DoStub = nil
+		})
+
+		It("should return the last error with the number of attempts", func() {
+			ctx, counter := client.ContextWithAttemptCounter(ctx)
+
+			_, err := cl.Post(ctx, path, map[string]string{"id": "1"}, nil)
+			Expect(err).To(Not(BeNil()))
+
+			Expect(fakeHTTPClient.DoCallCount()).To(Equal(3))
+			Expect(client.StatusCode(err)).To(Equal(http.StatusInternalServerError))
+			Expect(client.Attempts(err)).To(Equal(3))
+			Expect(counter.Attempts()).To(Equal(3))
+			Expect(counter.Retries()).To(Equal(2))
+		})
+	})
+
+	Context("a non retryable error is returned", func() {
+		JustBeforeEach(func() {
+			fakeHTTPClient.DoReturns(errorResp(http.StatusNotFound), nil)
+			fakeHTTPClient.DoStub = nil
+		})
+
+		It("should not retry", func() {
+			_, err := cl.Get(ctx, path, nil, nil)
+			Expect(err).To(Not(BeNil()))
+			Expect(fakeHTTPClient.DoCallCount()).To(Equal(1))
+		})
+	})
+
+	Context("the context is cancelled", func() {
+		JustBeforeEach(func() {
+			fakeHTTPClient.DoReturns(nil, errors.New("connection reset"))
+			fakeHTTPClient.DoStub = nil
+		})
+
+		It("should stop retrying", func() {
+			ctx, cancel := context.WithCancel(ctx)
+			cancel()
+
+			_, err := cl.Get(ctx, path, nil, nil)
+			Expect(err).To(Not(BeNil()))
+			Expect(fakeHTTPClient.DoCallCount()).To(Equal(1))
+		})
+	})
+
+	Context("with invalid retry configuration", func() {
+		It("should return an error", func() {
+			_, err := client.New(client.WithRetry(-1, 0))
+			Expect(err).To(Not(BeNil()))
+		})
+	})
+})
+
+var _ = Describe("Idempotency keys", func() {
+	var cl *client.Client
+
+	BeforeEach(func() {
+		var err error
+		cl, err = client.New()
+		Expect(err).To(BeNil())
+	})
+
+	It("should derive the same key for the same resource", func() {
+		key := client.IdempotencyKeyFor("accounts", "id-1")
+		Expect(key).To(Equal(client.IdempotencyKeyFor("accounts", "id-1")))
+		Expect(key).To(Not(Equal(client.IdempotencyKeyFor("accounts", "id-2"))))
+		Expect(key).To(Not(Equal(client.IdempotencyKeyFor("payments", "id-1"))))
+	})
+
+	It("should only be sent on POST and PATCH requests", func() {
+		ctx := client.ContextWithIdempotencyKey(context.Background(), "key-1")
+
+		for method, expected := range map[string]string{
+			http.MethodPost:   "key-1",
+			http.MethodPatch:  "key-1",
+			http.MethodGet:    "",
+			http.MethodDelete: "",
+		} {
+			req, err := cl.NewRequest(ctx, method, "/accounts", nil, nil)
+			Expect(err).To(BeNil())
+			Expect(req.Header.Get("Idempotency-Key")).To(Equal(expected), method)
+		}
+	})
+})
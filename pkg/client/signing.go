@@ -0,0 +1,182 @@
+package client
+
+import (
+	"bytes"
+	"context"
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// signer signs requests with an RSA key, following the HTTP signatures
+// scheme used by the form3 API.
+type signer struct {
+	keyID string
+	key   *rsa.PrivateKey
+	now   func() time.Time
+}
+
+// WithRequestSigning signs every request with the PEM encoded RSA private
+// key in privateKeyPEM, sending keyID to identify the public key registered
+// with form3.
+//
+// The signature covers the request target, Host and Date headers and, for
+// requests with a body, the Content-Type, Content-Length and Digest headers.
+// Requests are signed just before they are sent, inside any middleware, so
+// retries and hedged attempts are signed again and responses served by a
+// Cache or Coalescer are never signed. Caches and coalescers still tell
+// requests signed with different keys apart.
+func WithRequestSigning(keyID string, privateKeyPEM []byte) Opt {
+	return func(c *Client) error {
+		if keyID == "" {
+			return fmt.Errorf("request signing opt: key id is empty")
+		}
+
+		key, err := parseRSAPrivateKey(privateKeyPEM)
+		if err != nil {
+			return fmt.Errorf("request signing opt: %w", err)
+		}
+
+		c.signer = &signer{keyID: keyID, key: key, now: time.Now}
+
+		return nil
+	}
+}
+
+// parseRSAPrivateKey parses a PKCS #1 or PKCS #8 RSA private key.
+func parseRSAPrivateKey(pemKey []byte) (*rsa.PrivateKey, error) {
+	block, _ := pem.Decode(pemKey)
+	if block == nil {
+		return nil, fmt.Errorf("no PEM data found")
+	}
+
+	if key, err := x509.ParsePKCS1PrivateKey(block.Bytes); err == nil {
+		return key, nil
+	}
+
+	key, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+	if err != nil {
+		return nil, fmt.Errorf("parse private key: %w", err)
+	}
+
+	rsaKey, ok := key.(*rsa.PrivateKey)
+	if !ok {
+		return nil, fmt.Errorf("private key is %T, not RSA", key)
+	}
+
+	return rsaKey, nil
+}
+
+type signingKeyIDKey struct{}
+
+// contextWithSigningKeyID returns a copy of ctx carrying the key ID that
+// requests made with it are signed with.
+func contextWithSigningKeyID(ctx context.Context, keyID string) context.Context {
+	return context.WithValue(ctx, signingKeyIDKey{}, keyID)
+}
+
+func signingKeyIDFromContext(ctx context.Context) (string, bool) {
+	keyID, ok := ctx.Value(signingKeyIDKey{}).(string)
+	return keyID, ok
+}
+
+func (s *signer) middleware(next Doer) Doer {
+	return DoerFunc(func(req *http.Request) (*http.Response, error) {
+		signed, err := s.sign(req)
+		if err != nil {
+			return nil, fmt.Errorf("sign request: %w", err)
+		}
+
+		return next.Do(signed)
+	})
+}
+
+// sign returns a copy of req carrying a signature.
+func (s *signer) sign(req *http.Request) (*http.Request, error) {
+	req = req.Clone(req.Context())
+	req.Header.Set("Date", s.now().UTC().Format(http.TimeFormat))
+
+	host := req.Host
+	if host == "" {
+		host = req.URL.Host
+	}
+
+	headers := []string{"(request-target)", "host", "date"}
+	values := map[string]string{
+		"(request-target)": strings.ToLower(req.Method) + " " + req.URL.RequestURI(),
+		"host":             host,
+		"date":             req.Header.Get("Date"),
+	}
+
+	if req.Body != nil && req.Body != http.NoBody {
+		body, err := readBody(req)
+		if err != nil {
+			return nil, err
+		}
+
+		sum := sha256.Sum256(body)
+		req.Header.Set("Digest", "SHA-256="+base64.StdEncoding.EncodeToString(sum[:]))
+
+		headers = append(headers, "content-type", "content-length", "digest")
+		values["content-type"] = req.Header.Get("Content-Type")
+		values["content-length"] = strconv.Itoa(len(body))
+		values["digest"] = req.Header.Get("Digest")
+	}
+
+	lines := make([]string, len(headers))
+	for i, h := range headers {
+		lines[i] = h + ": " + values[h]
+	}
+
+	hashed := sha256.Sum256([]byte(strings.Join(lines, "\n")))
+	sig, err := rsa.SignPKCS1v15(rand.Reader, s.key, crypto.SHA256, hashed[:])
+	if err != nil {
+		return nil, fmt.Errorf("sign: %w", err)
+	}
+
+	req.Header.Set("Authorization", fmt.Sprintf(
+		`Signature keyId="%s",algorithm="rsa-sha256",headers="%s",signature="%s"`,
+		s.keyID,
+		strings.Join(headers, " "),
+		base64.StdEncoding.EncodeToString(sig),
+	))
+
+	return req, nil
+}
+
+// readBody reads the body of req and replaces it, so that it can be sent.
+func readBody(req *http.Request) ([]byte, error) {
+	if req.GetBody != nil {
+		rc, err := req.GetBody()
+		if err != nil {
+			return nil, fmt.Errorf("get body: %w", err)
+		}
+		defer rc.Close()
+
+		body, err := io.ReadAll(rc)
+		if err != nil {
+			return nil, fmt.Errorf("read body: %w", err)
+		}
+
+		return body, nil
+	}
+
+	body, err := io.ReadAll(req.Body)
+	req.Body.Close()
+	if err != nil {
+		return nil, fmt.Errorf("read body: %w", err)
+	}
+	req.Body = io.NopCloser(bytes.NewReader(body))
+
+	return body, nil
+}
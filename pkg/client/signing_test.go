@@ -0,0 +1,159 @@
+package client_test
+
+import (
+	"context"
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"strings"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"github.com/vivangkumar/form3-http-go/pkg/client"
+)
+
+var _ = Describe("Request signing", func() {
+	var (
+		ctx      context.Context
+		key      *rsa.PrivateKey
+		keyPEM   []byte
+		server   *httptest.Server
+		requests chan *http.Request
+		bodies   chan []byte
+	)
+
+	BeforeEach(func() {
+		ctx = context.Background()
+
+		var err error
+		key, err = rsa.GenerateKey(rand.Reader, 2048)
+		Expect(err).To(BeNil())
+		keyPEM = pem.EncodeToMemory(&pem.Block{
+			Type:  "RSA PRIVATE KEY",
+			Bytes: x509.MarshalPKCS1PrivateKey(key),
+		})
+
+		requests = make(chan *http.Request, 2)
+		bodies = make(chan []byte, 2)
+		server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			body, _ := io.ReadAll(r.Body)
+			requests <- r
+			bodies <- body
+			w.WriteHeader(http.StatusNoContent)
+		}))
+		DeferCleanup(server.Close)
+	})
+
+	// verify checks the signature of r against the public key.
+	verify := func(r *http.Request) []string {
+		m := regexp.MustCompile(
+			`^Signature keyId="([^"]+)",algorithm="rsa-sha256",headers="([^"]+)",signature="([^"]+)"$`,
+		).FindStringSubmatch(r.Header.Get("Authorization"))
+		Expect(m).To(HaveLen(4))
+		Expect(m[1]).To(Equal("key-1"))
+
+		headers := strings.Split(m[2], " ")
+		lines := make([]string, len(headers))
+		for i, h := range headers {
+			switch h {
+			case "(request-target)":
+				lines[i] = fmt.Sprintf("%s: %s %s", h, strings.ToLower(r.Method), r.URL.RequestURI())
+			case "host":
+				lines[i] = "host: " + r.Host
+			default:
+				lines[i] = h + ": " + r.Header.Get(h)
+			}
+		}
+
+		sig, err := base64.StdEncoding.DecodeString(m[3])
+		Expect(err).To(BeNil())
+
+		hashed := sha256.Sum256([]byte(strings.Join(lines, "\n")))
+		Expect(rsa.VerifyPKCS1v15(&key.PublicKey, crypto.SHA256, hashed[:], sig)).To(Succeed())
+
+		return headers
+	}
+
+	It("should sign requests without a body", func() {
+		c, err := client.New(client.WithBaseURL(server.URL), client.WithRequestSigning("key-1", keyPEM))
+		Expect(err).To(BeNil())
+
+		_, err = c.Get(ctx, "/v1/organisation/accounts", map[string]string{"page[size]": "1"}, nil)
+		Expect(err).To(BeNil())
+
+		Expect(verify(<-requests)).To(Equal([]string{"(request-target)", "host", "date"}))
+	})
+
+	It("should sign the digest of the body", func() {
+		c, err := client.New(client.WithBaseURL(server.URL), client.WithRequestSigning("key-1", keyPEM))
+		Expect(err).To(BeNil())
+
+		_, err = c.Post(ctx, "/v1/organisation/accounts", map[string]string{"id": "1"}, nil)
+		Expect(err).To(BeNil())
+
+		r := <-requests
+		Expect(verify(r)).To(Equal([]string{
+			"(request-target)", "host", "date", "content-type", "content-length", "digest",
+		}))
+
+		sum := sha256.Sum256(<-bodies)
+		Expect(r.Header.Get("Digest")).To(Equal("SHA-256=" + base64.StdEncoding.EncodeToString(sum[:])))
+	})
+
+	It("should sign requests after middleware", func() {
+		var seen []string
+		c, err := client.New(
+			client.WithBaseURL(server.URL),
+			client.WithRequestSigning("key-1", keyPEM),
+			client.WithMiddleware(func(next client.Doer) client.Doer {
+				return client.DoerFunc(func(req *http.Request) (*http.Response, error) {
+					seen = append(seen, req.Header.Get("Authorization"))
+					req.Header.Set("X-Added", "1")
+					return next.Do(req)
+				})
+			}),
+		)
+		Expect(err).To(BeNil())
+
+		_, err = c.Get(ctx, "/v1/organisation/accounts", nil, nil)
+		Expect(err).To(BeNil())
+
+		Expect(seen).To(Equal([]string{""}))
+		r := <-requests
+		verify(r)
+		Expect(r.Header.Get("X-Added")).To(Equal("1"))
+	})
+
+	It("should accept PKCS #8 keys", func() {
+		der, err := x509.MarshalPKCS8PrivateKey(key)
+		Expect(err).To(BeNil())
+
+		_, err = client.New(client.WithRequestSigning(
+			"key-1",
+			pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der}),
+		))
+		Expect(err).To(BeNil())
+	})
+
+	It("should reject keys that are not RSA", func() {
+		_, keyPEM := selfSignedCertificate()
+
+		_, err := client.New(client.WithRequestSigning("key-1", keyPEM))
+		Expect(err).To(MatchError(ContainSubstring("request signing opt")))
+	})
+
+	It("should reject an empty key id", func() {
+		_, err := client.New(client.WithRequestSigning("", keyPEM))
+		Expect(err).To(MatchError(ContainSubstring("key id is empty")))
+	})
+})
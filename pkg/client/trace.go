@@ -0,0 +1,219 @@
+package client
+
+import (
+	"context"
+	"encoding/hex"
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+const (
+	traceParentHeader = "traceparent"
+	traceStateHeader  = "tracestate"
+
+	// requestIDHeader is the header the API uses to report its request ID.
+	requestIDHeader = "X-Request-Id"
+
+	traceParentVersion = "00"
+	sampledFlag        = 0x01
+)
+
+// Attribute keys recorded on spans by the client.
+const (
+	AttrHTTPMethod     = "http.method"
+	AttrHTTPURL        = "http.url"
+	AttrHTTPStatusCode = "http.status_code"
+	AttrRequestID      = "form3.request_id"
+	AttrAttempt        = "form3.attempt"
+	AttrRetries        = "form3.retries"
+)
+
+// Tracer opens spans around client operations.
+//
+// Spans are opened for each logical operation (for example accounts.create)
+// and for each HTTP attempt made as part of that operation.
+type Tracer interface {
+	// Start opens a span with the given name as a child of any span
+	// present in ctx.
+	//
+	// The returned context carries the new span's TraceContext, which is
+	// propagated to the API via the traceparent and tracestate headers.
+	Start(ctx context.Context, name string) (context.Context, Span)
+}
+
+// Span represents a single unit of traced work.
+type Span interface {
+	// SetAttribute records a key value pair against the span.
+	SetAttribute(key string, value any)
+
+	// End completes the span. err is the error that the operation
+	// finished with, if any.
+	End(err error)
+}
+
+// NoopTracer returns a Tracer that does not record spans.
+//
+// It is the default tracer used by the client.
+func NoopTracer() Tracer {
+	return noopTracer{}
+}
+
+type noopTracer struct{}
+
+func (noopTracer) Start(ctx context.Context, _ string) (context.Context, Span) {
+	return ctx, noopSpan{}
+}
+
+type noopSpan struct{}
+
+func (noopSpan) SetAttribute(string, any) {}
+
+func (noopSpan) End(error) {}
+
+// TraceContext represents W3C trace context.
+//
+// https://www.w3.org/TR/trace-context/
+type TraceContext struct {
+	// TraceID is the 16 byte trace ID.
+	TraceID [16]byte
+
+	// SpanID is the 8 byte ID of the parent span.
+	SpanID [8]byte
+
+	// Flags are the trace flags. Only the sampled flag is defined.
+	Flags byte
+
+	// State is the opaque vendor specific tracestate header value.
+	State string
+}
+
+// Sampled reports whether the sampled flag is set.
+func (tc TraceContext) Sampled() bool {
+	return tc.Flags&sampledFlag != 0
+}
+
+// IsValid reports whether both the trace ID and span ID are non-zero.
+func (tc TraceContext) IsValid() bool {
+	return tc.TraceID != [16]byte{} && tc.SpanID != [8]byte{}
+}
+
+// TraceParent returns the traceparent header value.
+func (tc TraceContext) TraceParent() string {
+	return fmt.Sprintf(
+		"%s-%s-%s-%02x",
+		traceParentVersion,
+		hex.EncodeToString(tc.TraceID[:]),
+		hex.EncodeToString(tc.SpanID[:]),
+		tc.Flags,
+	)
+}
+
+// ParseTraceParent parses a traceparent header value.
+//
+// Versions after 00 are parsed as version 00, ignoring any fields they
+// add after the flags. Version ff is invalid.
+//
+// The returned TraceContext has no State set.
+func ParseTraceParent(s string) (TraceContext, error) {
+	var tc TraceContext
+
+	parts := strings.Split(strings.TrimSpace(s), "-")
+	if len(parts) < 4 {
+		return tc, fmt.Errorf("invalid traceparent: %q", s)
+	}
+
+	var version [1]byte
+	err := decodeHex(parts[0], version[:])
+	if err != nil {
+		return tc, fmt.Errorf("version: %w", err)
+	}
+	if version[0] == 0xff || (parts[0] == traceParentVersion && len(parts) != 4) {
+		return tc, fmt.Errorf("invalid traceparent: %q", s)
+	}
+
+	err = decodeHex(parts[1], tc.TraceID[:])
+	if err != nil {
+		return tc, fmt.Errorf("trace id: %w", err)
+	}
+
+	err = decodeHex(parts[2], tc.SpanID[:])
+	if err != nil {
+		return tc, fmt.Errorf("span id: %w", err)
+	}
+
+	var flags [1]byte
+	err = decodeHex(parts[3], flags[:])
+	if err != nil {
+		return tc, fmt.Errorf("flags: %w", err)
+	}
+	tc.Flags = flags[0]
+
+	if !tc.IsValid() {
+		return tc, fmt.Errorf("invalid traceparent: %q", s)
+	}
+
+	return tc, nil
+}
+
+func decodeHex(s string, dst []byte) error {
+	if len(s) != hex.EncodedLen(len(dst)) || strings.ToLower(s) != s {
+		return fmt.Errorf("expected %d lowercase hex characters", hex.EncodedLen(len(dst)))
+	}
+
+	_, err := hex.Decode(dst, []byte(s))
+	return err
+}
+
+type traceContextKey struct{}
+
+// ContextWithTraceContext returns a copy of ctx carrying tc.
+//
+// Requests created with the returned context propagate tc to the API.
+func ContextWithTraceContext(ctx context.Context, tc TraceContext) context.Context {
+	return context.WithValue(ctx, traceContextKey{}, tc)
+}
+
+// TraceContextFromContext returns the TraceContext carried by ctx, if any.
+func TraceContextFromContext(ctx context.Context) (TraceContext, bool) {
+	tc, ok := ctx.Value(traceContextKey{}).(TraceContext)
+	if !ok || !tc.IsValid() {
+		return TraceContext{}, false
+	}
+
+	return tc, true
+}
+
+// injectTraceContext sets trace context headers from ctx on h.
+func injectTraceContext(ctx context.Context, h http.Header) {
+	tc, ok := TraceContextFromContext(ctx)
+	if !ok {
+		return
+	}
+
+	h.Set(traceParentHeader, tc.TraceParent())
+	if tc.State != "" {
+		h.Set(traceStateHeader, tc.State)
+	} else {
+		h.Del(traceStateHeader)
+	}
+}
+
+// RecordResponse records the outcome of an HTTP response on span.
+//
+// resp may be nil, in which case the response is taken from err if it
+// carries one.
+func RecordResponse(span Span, resp *http.Response, err error) {
+	if resp == nil {
+		resp = responseFromError(err)
+	}
+
+	if resp == nil {
+		return
+	}
+
+	span.SetAttribute(AttrHTTPStatusCode, resp.StatusCode)
+	if id := resp.Header.Get(requestIDHeader); id != "" {
+		span.SetAttribute(AttrRequestID, id)
+	}
+}
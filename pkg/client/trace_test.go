@@ -0,0 +1,174 @@
+package client_test
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/url"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"github.com/vivangkumar/form3-http-go/pkg/client"
+	"github.com/vivangkumar/form3-http-go/pkg/client/internal/fakes"
+	"github.com/vivangkumar/form3-http-go/pkg/client/tracetest"
+)
+
+var _ = Describe("Tracing", func() {
+	var (
+		cl             *client.Client
+		fakeHTTPClient *fakes.FakeHttpClient
+		tracer         *tracetest.Tracer
+
+		ctx  context.Context
+		path string
+
+		traceParent string
+	)
+
+	BeforeEach(func() {
+		fakeHTTPClient = new(fakes.FakeHttpClient)
+		tracer = tracetest.New()
+
+		var err error
+		cl, err = client.New(
+			client.WithHTTPClient(fakeHTTPClient),
+			client.WithTracer(tracer),
+		)
+		Expect(err).To(BeNil())
+
+		traceParent = "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01"
+		tc, err := client.ParseTraceParent(traceParent)
+		Expect(err).To(BeNil())
+		tc.State = "vendor=value"
+
+		ctx = client.ContextWithTraceContext(context.Background(), tc)
+		path = "/v1/organisation/accounts/ad27e265-9605-4b4b-a0e5-3003ea9cc4dc"
+	})
+
+	Describe("Parsing traceparent headers", func() {
+		It("should round trip a valid header", func() {
+			tc, err := client.ParseTraceParent(traceParent)
+			Expect(err).To(BeNil())
+			Expect(tc.Sampled()).To(BeTrue())
+			Expect(tc.TraceParent()).To(Equal(traceParent))
+		})
+
+		It("should parse later versions as version 00", func() {
+			for _, h := range []string{
+				"01-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01",
+				"cc-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01-extra",
+			} {
+				tc, err := client.ParseTraceParent(h)
+				Expect(err).To(BeNil(), h)
+				Expect(tc.TraceParent()).To(Equal(traceParent))
+			}
+		})
+
+		It("should reject invalid headers", func() {
+			for _, h := range []string{
+				"",
+				"ff-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01",
+				"00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01-extra",
+				"00-00000000000000000000000000000000-00f067aa0ba902b7-01",
+				"00-4BF92F3577B34DA6A3CE929D0E0E4736-00f067aa0ba902b7-01",
+				"00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7",
+			} {
+				_, err := client.ParseTraceParent(h)
+				Expect(err).To(Not(BeNil()), h)
+			}
+		})
+	})
+
+	Describe("Creating a request", func() {
+		It("should propagate trace context from the request context", func() {
+			req, err := cl.NewRequest(ctx, http.MethodGet, path, nil, nil)
+			Expect(err).To(BeNil())
+
+			Expect(req.Header.Get("traceparent")).To(Equal(traceParent))
+			Expect(req.Header.Get("tracestate")).To(Equal("vendor=value"))
+		})
+
+		It("should not set trace headers without trace context", func() {
+			req, err := cl.NewRequest(context.Background(), http.MethodGet, path, nil, nil)
+			Expect(err).To(BeNil())
+
+			Expect(req.Header.Get("traceparent")).To(BeEmpty())
+			Expect(req.Header.Get("tracestate")).To(BeEmpty())
+		})
+	})
+
+	Describe("Executing a request", func() {
+		Context("the request is successful", func() {
+			BeforeEach(func() {
+				fakeHTTPClient.DoReturns(&http.Response{
+					StatusCode: http.StatusNoContent,
+					Header:     http.Header{"X-Request-Id": []string{"req-1"}},
+					Body:       http.NoBody,
+				}, nil)
+			})
+
+			It("should record a span for the attempt", func() {
+				_, err := cl.Delete(ctx, path, nil)
+				Expect(err).To(BeNil())
+
+				spans := tracer.SpansNamed("http.request")
+				Expect(spans).To(HaveLen(1))
+
+				span := spans[0]
+				Expect(span.Ended).To(BeTrue())
+				Expect(span.Err).To(BeNil())
+				Expect(span.Parent.TraceParent()).To(Equal(traceParent))
+				Expect(span.Attributes).To(HaveKeyWithValue(client.AttrHTTPMethod, http.MethodDelete))
+				Expect(span.Attributes).To(HaveKeyWithValue(client.AttrHTTPStatusCode, http.StatusNoContent))
+				Expect(span.Attributes).To(HaveKeyWithValue(client.AttrRequestID, "req-1"))
+				Expect(span.Attributes).To(HaveKeyWithValue(client.AttrAttempt, 1))
+
+				By("sending the attempt span as the parent", func() {
+					req := fakeHTTPClient.DoArgsForCall(0)
+					Expect(req.Header.Get("traceparent")).To(Equal(span.TraceContext.TraceParent()))
+					Expect(req.Header.Get("tracestate")).To(Equal("vendor=value"))
+				})
+			})
+		})
+
+		Context("the API returns an error", func() {
+			BeforeEach(func() {
+				fakeHTTPClient.DoReturns(&http.Response{
+					StatusCode: http.StatusInternalServerError,
+					Header:     http.Header{"X-Request-Id": []string{"req-2"}},
+					Body:       http.NoBody,
+					Request:    &http.Request{Method: http.MethodGet, URL: &url.URL{}},
+				}, nil)
+			})
+
+			It("should record the error on the span", func() {
+				var b []byte
+				_, err := cl.Get(ctx, path, nil, &b)
+				Expect(err).To(Not(BeNil()))
+
+				spans := tracer.SpansNamed("http.request")
+				Expect(spans).To(HaveLen(1))
+				Expect(spans[0].Err).To(Equal(err))
+				Expect(spans[0].Attributes).To(HaveKeyWithValue(client.AttrHTTPStatusCode, http.StatusInternalServerError))
+				Expect(spans[0].Attributes).To(HaveKeyWithValue(client.AttrRequestID, "req-2"))
+			})
+		})
+
+		Context("the request fails", func() {
+			BeforeEach(func() {
+				fakeHTTPClient.DoReturns(nil, errors.New("request error"))
+			})
+
+			It("should record the error on the span", func() {
+				_, err := cl.Delete(ctx, path, nil)
+				Expect(err).To(Not(BeNil()))
+
+				spans := tracer.SpansNamed("http.request")
+				Expect(spans).To(HaveLen(1))
+				Expect(spans[0].Err).To(MatchError(ContainSubstring("request error")))
+				Expect(spans[0].Attributes).To(Not(HaveKey(client.AttrHTTPStatusCode)))
+			})
+		})
+	})
+})
@@ -0,0 +1,133 @@
+// Package tracetest provides an in-memory client.Tracer for use in tests.
+//
+// Spans are recorded in the order they are started and can be inspected
+// once the operation under test completes.
+package tracetest
+
+import (
+	"context"
+	"crypto/rand"
+	"sync"
+
+	"github.com/vivangkumar/form3-http-go/pkg/client"
+)
+
+// Tracer is a client.Tracer that records spans in memory.
+//
+// It is safe for concurrent use.
+type Tracer struct {
+	mu    sync.Mutex
+	spans []*Span
+}
+
+// New returns an in-memory tracer.
+func New() *Tracer {
+	return &Tracer{}
+}
+
+// Start implements client.Tracer.
+//
+// The new span inherits the trace ID from any trace context in ctx.
+// Otherwise, a new trace is started.
+func (t *Tracer) Start(ctx context.Context, name string) (context.Context, client.Span) {
+	s := &Span{
+		Name:       name,
+		Attributes: make(map[string]any),
+	}
+
+	parent, ok := client.TraceContextFromContext(ctx)
+	if ok {
+		s.Parent = parent
+		s.TraceContext.TraceID = parent.TraceID
+		s.TraceContext.Flags = parent.Flags
+		s.TraceContext.State = parent.State
+	} else {
+		_, _ = rand.Read(s.TraceContext.TraceID[:])
+		s.TraceContext.Flags = 0x01
+	}
+	_, _ = rand.Read(s.TraceContext.SpanID[:])
+
+	t.mu.Lock()
+	t.spans = append(t.spans, s)
+	t.mu.Unlock()
+
+	return client.ContextWithTraceContext(ctx, s.TraceContext), s
+}
+
+// Spans returns all spans started so far.
+func (t *Tracer) Spans() []*Span {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	spans := make([]*Span, len(t.spans))
+	copy(spans, t.spans)
+
+	return spans
+}
+
+// SpansNamed returns all spans started with the given name.
+func (t *Tracer) SpansNamed(name string) []*Span {
+	var spans []*Span
+	for _, s := range t.Spans() {
+		if s.Name == name {
+			spans = append(spans, s)
+		}
+	}
+
+	return spans
+}
+
+// Reset discards all recorded spans.
+func (t *Tracer) Reset() {
+	t.mu.Lock()
+	t.spans = nil
+	t.mu.Unlock()
+}
+
+// Span is a span recorded by Tracer.
+type Span struct {
+	mu sync.Mutex
+
+	// Name is the name the span was started with.
+	Name string
+
+	// TraceContext identifies the span.
+	TraceContext client.TraceContext
+
+	// Parent is the trace context the span was started from.
+	// It is the zero value for root spans.
+	Parent client.TraceContext
+
+	// Attributes holds the attributes set on the span.
+	Attributes map[string]any
+
+	// Err is the error the span was ended with.
+	Err error
+
+	// Ended reports whether End has been called.
+	Ended bool
+}
+
+// SetAttribute implements client.Span.
+func (s *Span) SetAttribute(key string, value any) {
+	s.mu.Lock()
+	s.Attributes[key] = value
+	s.mu.Unlock()
+}
+
+// End implements client.Span.
+func (s *Span) End(err error) {
+	s.mu.Lock()
+	s.Err = err
+	s.Ended = true
+	s.mu.Unlock()
+}
+
+// Attribute returns the value of the attribute with the given key.
+func (s *Span) Attribute(key string) (any, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	v, ok := s.Attributes[key]
+	return v, ok
+}
@@ -0,0 +1,257 @@
+package client
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"time"
+)
+
+// transportConfig holds the options used to build the HTTP transport.
+type transportConfig struct {
+	// opts are the names of the transport options used.
+	opts []string
+
+	proxy                 func(*http.Request) (*url.URL, error)
+	certificates          []tls.Certificate
+	rootCAs               *x509.CertPool
+	minTLSVersion         uint16
+	maxIdleConnsPerHost   int
+	idleConnTimeout       time.Duration
+	dialTimeout           time.Duration
+	responseHeaderTimeout time.Duration
+
+	// timeout is the timeout of the HTTP client rather than the transport.
+	timeout time.Duration
+}
+
+// use records that the named transport option was used.
+func (t *transportConfig) use(name string) {
+	t.opts = append(t.opts, name)
+}
+
+// used reports whether the named transport option was used.
+func (t *transportConfig) used(name string) bool {
+	for _, opt := range t.opts {
+		if opt == name {
+			return true
+		}
+	}
+
+	return false
+}
+
+// build returns an HTTP transport configured with t.
+//
+// It starts from a clone of http.DefaultTransport, so that options left
+// unset keep its defaults: the proxy from the environment, HTTP/2, the TLS
+// handshake timeout and TCP keep-alives.
+func (t *transportConfig) build() *http.Transport {
+	tr := http.DefaultTransport.(*http.Transport).Clone()
+
+	if t.proxy != nil {
+		tr.Proxy = t.proxy
+	}
+	if t.used("WithMaxIdleConnsPerHost") {
+		tr.MaxIdleConnsPerHost = t.maxIdleConnsPerHost
+	}
+	if t.used("WithIdleConnTimeout") {
+		tr.IdleConnTimeout = t.idleConnTimeout
+	}
+	if t.used("WithResponseHeaderTimeout") {
+		tr.ResponseHeaderTimeout = t.responseHeaderTimeout
+	}
+
+	if t.used("WithDialTimeout") {
+		tr.DialContext = (&net.Dialer{
+			Timeout:   t.dialTimeout,
+			KeepAlive: 30 * time.Second,
+		}).DialContext
+	}
+
+	if len(t.certificates) > 0 || t.rootCAs != nil || t.minTLSVersion != 0 {
+		tr.TLSClientConfig = &tls.Config{
+			Certificates: t.certificates,
+			RootCAs:      t.rootCAs,
+			MinVersion:   t.minTLSVersion,
+		}
+	}
+
+	return tr
+}
+
+// WithProxy sets the proxy used for requests.
+//
+// proxy has the same meaning as http.Transport.Proxy, so
+// http.ProxyFromEnvironment and http.ProxyURL can be used.
+//
+// It cannot be combined with WithHTTPClient.
+func WithProxy(proxy func(*http.Request) (*url.URL, error)) Opt {
+	return func(c *Client) error {
+		if proxy == nil {
+			return fmt.Errorf("proxy opt: proxy is nil")
+		}
+
+		c.transport.use("WithProxy")
+		c.transport.proxy = proxy
+
+		return nil
+	}
+}
+
+// WithTLSClientCertificate presents a client certificate for mutual TLS.
+//
+// certPEM and keyPEM hold the PEM encoded certificate and private key.
+//
+// It cannot be combined with WithHTTPClient.
+func WithTLSClientCertificate(certPEM []byte, keyPEM []byte) Opt {
+	return func(c *Client) error {
+		cert, err := tls.X509KeyPair(certPEM, keyPEM)
+		if err != nil {
+			return fmt.Errorf("tls client certificate opt: %w", err)
+		}
+
+		c.transport.use("WithTLSClientCertificate")
+		c.transport.certificates = append(c.transport.certificates, cert)
+
+		return nil
+	}
+}
+
+// WithRootCAs trusts the PEM encoded CA certificates in pemCerts.
+//
+// If used, the system roots are no longer trusted. It may be used more
+// than once to trust several CAs.
+//
+// It cannot be combined with WithHTTPClient.
+func WithRootCAs(pemCerts []byte) Opt {
+	return func(c *Client) error {
+		if c.transport.rootCAs == nil {
+			c.transport.rootCAs = x509.NewCertPool()
+		}
+
+		if !c.transport.rootCAs.AppendCertsFromPEM(pemCerts) {
+			return fmt.Errorf("root CAs opt: no certificates found")
+		}
+
+		c.transport.use("WithRootCAs")
+
+		return nil
+	}
+}
+
+// WithMinTLSVersion sets the minimum TLS version, for example
+// tls.VersionTLS13.
+//
+// It cannot be combined with WithHTTPClient.
+func WithMinTLSVersion(version uint16) Opt {
+	return func(c *Client) error {
+		switch version {
+		case tls.VersionTLS10, tls.VersionTLS11, tls.VersionTLS12, tls.VersionTLS13:
+		default:
+			return fmt.Errorf("min tls version opt: unknown version %#04x", version)
+		}
+
+		c.transport.use("WithMinTLSVersion")
+		c.transport.minTLSVersion = version
+
+		return nil
+	}
+}
+
+// WithMaxIdleConnsPerHost sets the number of idle connections kept per host.
+// It must be positive, as the transport reads zero as
+// http.DefaultMaxIdleConnsPerHost.
+//
+// If not used, http.DefaultMaxIdleConnsPerHost is used.
+//
+// It cannot be combined with WithHTTPClient.
+func WithMaxIdleConnsPerHost(n int) Opt {
+	return func(c *Client) error {
+		if n < 1 {
+			return fmt.Errorf("max idle conns per host opt: must be positive")
+		}
+
+		c.transport.use("WithMaxIdleConnsPerHost")
+		c.transport.maxIdleConnsPerHost = n
+
+		return nil
+	}
+}
+
+// WithIdleConnTimeout sets how long idle connections are kept.
+//
+// A timeout of zero keeps idle connections until they are closed. If not
+// used, they are kept for 90 seconds, as by http.DefaultTransport.
+//
+// It cannot be combined with WithHTTPClient.
+func WithIdleConnTimeout(d time.Duration) Opt {
+	return func(c *Client) error {
+		if d < 0 {
+			return fmt.Errorf("idle conn timeout opt: must not be negative")
+		}
+
+		c.transport.use("WithIdleConnTimeout")
+		c.transport.idleConnTimeout = d
+
+		return nil
+	}
+}
+
+// WithDialTimeout sets how long to wait for a connection to be established.
+// A timeout of zero means no timeout.
+//
+// If not used, the timeout is 30 seconds, as by http.DefaultTransport.
+//
+// It cannot be combined with WithHTTPClient.
+func WithDialTimeout(d time.Duration) Opt {
+	return func(c *Client) error {
+		if d < 0 {
+			return fmt.Errorf("dial timeout opt: must not be negative")
+		}
+
+		c.transport.use("WithDialTimeout")
+		c.transport.dialTimeout = d
+
+		return nil
+	}
+}
+
+// WithResponseHeaderTimeout sets how long to wait for response headers
+// once a request is sent.
+//
+// It cannot be combined with WithHTTPClient.
+func WithResponseHeaderTimeout(d time.Duration) Opt {
+	return func(c *Client) error {
+		if d < 0 {
+			return fmt.Errorf("response header timeout opt: must not be negative")
+		}
+
+		c.transport.use("WithResponseHeaderTimeout")
+		c.transport.responseHeaderTimeout = d
+
+		return nil
+	}
+}
+
+// WithTimeout sets the time limit for each HTTP attempt, including reading
+// the response body. A timeout of zero means no timeout.
+//
+// If not used, the timeout is 3 seconds.
+//
+// It cannot be combined with WithHTTPClient.
+func WithTimeout(d time.Duration) Opt {
+	return func(c *Client) error {
+		if d < 0 {
+			return fmt.Errorf("timeout opt: must not be negative")
+		}
+
+		c.transport.use("WithTimeout")
+		c.transport.timeout = d
+
+		return nil
+	}
+}
@@ -0,0 +1,301 @@
+package client_test
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"github.com/vivangkumar/form3-http-go/pkg/client"
+	"github.com/vivangkumar/form3-http-go/pkg/client/internal/fakes"
+)
+
+var _ = Describe("Transport options", func() {
+	var (
+		ctx     context.Context
+		handler http.HandlerFunc
+	)
+
+	BeforeEach(func() {
+		ctx = context.Background()
+		handler = func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusNoContent)
+		}
+	})
+
+	// serverCAPEM returns the PEM encoded certificate of a TLS test server.
+	serverCAPEM := func(s *httptest.Server) []byte {
+		return pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: s.Certificate().Raw})
+	}
+
+	Describe("Trusting custom CAs", func() {
+		It("should connect to servers signed by the given CA", func() {
+			server := httptest.NewTLSServer(handler)
+			defer server.Close()
+
+			c, err := client.New(client.WithBaseURL(server.URL), client.WithRootCAs(serverCAPEM(server)))
+			Expect(err).To(BeNil())
+
+			_, err = c.Delete(ctx, "/v1/organisation/accounts/1", nil)
+			Expect(err).To(BeNil())
+		})
+
+		It("should not connect to servers signed by other CAs", func() {
+			server := httptest.NewTLSServer(handler)
+			defer server.Close()
+
+			c, err := client.New(client.WithBaseURL(server.URL))
+			Expect(err).To(BeNil())
+
+			_, err = c.Delete(ctx, "/v1/organisation/accounts/1", nil)
+			Expect(err).To(MatchError(ContainSubstring("certificate")))
+		})
+
+		It("should reject input without certificates", func() {
+			_, err := client.New(client.WithRootCAs([]byte("not a certificate")))
+			Expect(err).To(MatchError(ContainSubstring("root CAs opt: no certificates found")))
+		})
+	})
+
+	Describe("Presenting a client certificate", func() {
+		var (
+			server  *httptest.Server
+			certPEM []byte
+			keyPEM  []byte
+		)
+
+		BeforeEach(func() {
+			certPEM, keyPEM = selfSignedCertificate()
+
+			pool := x509.NewCertPool()
+			pool.AppendCertsFromPEM(certPEM)
+
+			server = httptest.NewUnstartedServer(handler)
+			server.TLS = &tls.Config{ClientCAs: pool, ClientAuth: tls.RequireAndVerifyClientCert}
+			server.StartTLS()
+		})
+
+		AfterEach(func() {
+			server.Close()
+		})
+
+		It("should authenticate with the certificate", func() {
+			c, err := client.New(
+				client.WithBaseURL(server.URL),
+				client.WithRootCAs(serverCAPEM(server)),
+				client.WithTLSClientCertificate(certPEM, keyPEM),
+			)
+			Expect(err).To(BeNil())
+
+			_, err = c.Delete(ctx, "/v1/organisation/accounts/1", nil)
+			Expect(err).To(BeNil())
+		})
+
+		It("should fail without the certificate", func() {
+			c, err := client.New(client.WithBaseURL(server.URL), client.WithRootCAs(serverCAPEM(server)))
+			Expect(err).To(BeNil())
+
+			_, err = c.Delete(ctx, "/v1/organisation/accounts/1", nil)
+			Expect(err).To(Not(BeNil()))
+		})
+
+		It("should reject an invalid key pair", func() {
+			_, err := client.New(client.WithTLSClientCertificate(certPEM, []byte("bad key")))
+			Expect(err).To(MatchError(ContainSubstring("tls client certificate opt")))
+		})
+	})
+
+	Describe("Setting the minimum TLS version", func() {
+		It("should refuse servers below the minimum version", func() {
+			server := httptest.NewUnstartedServer(handler)
+			server.TLS = &tls.Config{MaxVersion: tls.VersionTLS12}
+			server.StartTLS()
+			defer server.Close()
+
+			c, err := client.New(
+				client.WithBaseURL(server.URL),
+				client.WithRootCAs(serverCAPEM(server)),
+				client.WithMinTLSVersion(tls.VersionTLS13),
+			)
+			Expect(err).To(BeNil())
+
+			_, err = c.Delete(ctx, "/v1/organisation/accounts/1", nil)
+			Expect(err).To(MatchError(ContainSubstring("protocol version")))
+		})
+
+		It("should reject unknown versions", func() {
+			_, err := client.New(client.WithMinTLSVersion(0x0999))
+			Expect(err).To(MatchError(ContainSubstring("min tls version opt")))
+		})
+	})
+
+	Describe("Using a proxy", func() {
+		It("should send requests through the proxy", func() {
+			var proxied *url.URL
+			proxy := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				proxied = r.URL
+				w.WriteHeader(http.StatusNoContent)
+			}))
+			defer proxy.Close()
+
+			proxyURL, _ := url.Parse(proxy.URL)
+			c, err := client.New(
+				client.WithBaseURL("http://accounts.invalid"),
+				client.WithProxy(http.ProxyURL(proxyURL)),
+			)
+			Expect(err).To(BeNil())
+
+			_, err = c.Delete(ctx, "/v1/organisation/accounts/1", nil)
+			Expect(err).To(BeNil())
+			Expect(proxied.String()).To(Equal("http://accounts.invalid/v1/organisation/accounts/1"))
+		})
+	})
+
+	Describe("Tuning connections", func() {
+		It("should keep the defaults of the standard transport", func() {
+			var proto string
+			server := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				proto = r.Proto
+				w.WriteHeader(http.StatusNoContent)
+			}))
+			server.EnableHTTP2 = true
+			server.StartTLS()
+			defer server.Close()
+
+			c, err := client.New(
+				client.WithBaseURL(server.URL),
+				client.WithRootCAs(serverCAPEM(server)),
+				client.WithDialTimeout(time.Second),
+			)
+			Expect(err).To(BeNil())
+
+			_, err = c.Delete(ctx, "/v1/organisation/accounts/1", nil)
+			Expect(err).To(BeNil())
+			Expect(proto).To(Equal("HTTP/2.0"))
+		})
+
+		It("should accept connection pool and timeout settings", func() {
+			server := httptest.NewServer(handler)
+			defer server.Close()
+
+			c, err := client.New(
+				client.WithBaseURL(server.URL),
+				client.WithMaxIdleConnsPerHost(10),
+				client.WithIdleConnTimeout(time.Minute),
+				client.WithDialTimeout(time.Second),
+				client.WithResponseHeaderTimeout(time.Second),
+			)
+			Expect(err).To(BeNil())
+
+			_, err = c.Delete(ctx, "/v1/organisation/accounts/1", nil)
+			Expect(err).To(BeNil())
+		})
+
+		It("should time out waiting for response headers", func() {
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				time.Sleep(100 * time.Millisecond)
+			}))
+			defer server.Close()
+
+			c, err := client.New(
+				client.WithBaseURL(server.URL),
+				client.WithResponseHeaderTimeout(10*time.Millisecond),
+			)
+			Expect(err).To(BeNil())
+
+			_, err = c.Delete(ctx, "/v1/organisation/accounts/1", nil)
+			Expect(err).To(MatchError(ContainSubstring("timeout awaiting response headers")))
+		})
+
+		It("should time out slow attempts", func() {
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				time.Sleep(100 * time.Millisecond)
+			}))
+			defer server.Close()
+
+			c, err := client.New(
+				client.WithBaseURL(server.URL),
+				client.WithTimeout(10*time.Millisecond),
+			)
+			Expect(err).To(BeNil())
+
+			_, err = c.Delete(ctx, "/v1/organisation/accounts/1", nil)
+			Expect(err).To(MatchError(ContainSubstring("Client.Timeout exceeded")))
+		})
+
+		It("should reject negative values", func() {
+			_, err := client.New(client.WithTimeout(-time.Second))
+			Expect(err).To(Not(BeNil()))
+
+			_, err = client.New(client.WithMaxIdleConnsPerHost(-1))
+			Expect(err).To(Not(BeNil()))
+
+			_, err = client.New(client.WithMaxIdleConnsPerHost(0))
+			Expect(err).To(MatchError(ContainSubstring("max idle conns per host opt: must be positive")))
+
+			_, err = client.New(client.WithDialTimeout(-time.Second))
+			Expect(err).To(Not(BeNil()))
+		})
+	})
+
+	Describe("Combining with a custom HTTP client", func() {
+		DescribeTable("should return an error whatever the order",
+			func(opts ...client.Opt) {
+				_, err := client.New(opts...)
+				Expect(err).To(MatchError(
+					"apply options: WithProxy, WithDialTimeout cannot be combined with WithHTTPClient",
+				))
+			},
+			Entry("custom client first",
+				client.WithHTTPClient(new(fakes.FakeHttpClient)),
+				client.WithProxy(http.ProxyFromEnvironment),
+				client.WithDialTimeout(time.Second),
+			),
+			Entry("custom client last",
+				client.WithProxy(http.ProxyFromEnvironment),
+				client.WithDialTimeout(time.Second),
+				client.WithHTTPClient(new(fakes.FakeHttpClient)),
+			),
+		)
+	})
+})
+
+// selfSignedCertificate returns a PEM encoded self-signed certificate and key.
+func selfSignedCertificate() ([]byte, []byte) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	Expect(err).To(BeNil())
+
+	tmpl := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "form3-http-go test client"},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		KeyUsage:              x509.KeyUsageDigitalSignature | x509.KeyUsageCertSign,
+		ExtKeyUsage:           []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth},
+		BasicConstraintsValid: true,
+		IsCA:                  true,
+	}
+
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, tmpl, &key.PublicKey, key)
+	Expect(err).To(BeNil())
+
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	Expect(err).To(BeNil())
+
+	certPEM := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der})
+	keyPEM := pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER})
+
+	return certPEM, keyPEM
+}
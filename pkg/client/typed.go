@@ -0,0 +1,121 @@
+package client
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/vivangkumar/form3-http-go/pkg/client/jsonapi"
+)
+
+// Requester executes requests against the API, decoding responses into
+// target.
+//
+// *Client satisfies it.
+type Requester interface {
+	Get(
+		ctx context.Context,
+		path string,
+		query map[string]string,
+		target any,
+	) (*http.Response, error)
+	Post(
+		ctx context.Context,
+		path string,
+		body any,
+		target any,
+	) (*http.Response, error)
+	Patch(
+		ctx context.Context,
+		path string,
+		body any,
+		target any,
+	) (*http.Response, error)
+	Delete(
+		ctx context.Context,
+		path string,
+		query map[string]string,
+	) (*http.Response, error)
+}
+
+// Get executes a GET request, decoding the response into a new T.
+func Get[T any](
+	ctx context.Context,
+	r Requester,
+	path string,
+	query map[string]string,
+) (*T, *http.Response, error) {
+	target := new(T)
+
+	resp, err := r.Get(ctx, path, query, target)
+	if err != nil {
+		return nil, resp, err
+	}
+
+	return target, resp, nil
+}
+
+// List executes a GET request for a list of JSON:API resources.
+func List[T any](
+	ctx context.Context,
+	r Requester,
+	path string,
+	query map[string]string,
+) (*jsonapi.ListDocument[T], *http.Response, error) {
+	return Get[jsonapi.ListDocument[T]](ctx, r, path, query)
+}
+
+// Post executes a POST request with body, decoding the response into a new
+// Resp.
+func Post[Req, Resp any](
+	ctx context.Context,
+	r Requester,
+	path string,
+	body *Req,
+) (*Resp, *http.Response, error) {
+	target := new(Resp)
+
+	resp, err := r.Post(ctx, path, bodyOf(body), target)
+	if err != nil {
+		return nil, resp, err
+	}
+
+	return target, resp, nil
+}
+
+// Patch executes a PATCH request with body, decoding the response into a
+// new Resp.
+func Patch[Req, Resp any](
+	ctx context.Context,
+	r Requester,
+	path string,
+	body *Req,
+) (*Resp, *http.Response, error) {
+	target := new(Resp)
+
+	resp, err := r.Patch(ctx, path, bodyOf(body), target)
+	if err != nil {
+		return nil, resp, err
+	}
+
+	return target, resp, nil
+}
+
+// Delete executes a DELETE request.
+func Delete(
+	ctx context.Context,
+	r Requester,
+	path string,
+	query map[string]string,
+) (*http.Response, error) {
+	return r.Delete(ctx, path, query)
+}
+
+// bodyOf returns body as a request body, or nil if it is a nil pointer, so
+// that no body is sent rather than null.
+func bodyOf[Req any](body *Req) any {
+	if body == nil {
+		return nil
+	}
+
+	return body
+}
@@ -0,0 +1,127 @@
+package client_test
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"github.com/vivangkumar/form3-http-go/pkg/account"
+	"github.com/vivangkumar/form3-http-go/pkg/client"
+	"github.com/vivangkumar/form3-http-go/pkg/client/internal/fakes"
+)
+
+var _ = Describe("Typed requests", func() {
+	var (
+		cl             *client.Client
+		fakeHTTPClient *fakes.FakeHttpClient
+
+		ctx context.Context
+	)
+
+	respond := func(sc int, body string) {
+		fakeHTTPClient.DoReturns(&http.Response{
+			StatusCode: sc,
+			Body:       io.NopCloser(strings.NewReader(body)),
+			Request:    &http.Request{Method: http.MethodGet, URL: &url.URL{}},
+		}, nil)
+	}
+
+	BeforeEach(func() {
+		ctx = context.Background()
+		fakeHTTPClient = new(fakes.FakeHttpClient)
+
+		c, err := client.New(client.WithHTTPClient(fakeHTTPClient))
+		Expect(err).To(BeNil())
+		cl = c
+	})
+
+	It("should decode GET responses into the given type", func() {
+		respond(http.StatusOK, `{"data": {"id": "1", "attributes": {"country": "GB"}}}`)
+
+		resp, httpResp, err := client.Get[account.Response](ctx, cl, "/v1/organisation/accounts/1", nil)
+		Expect(err).To(BeNil())
+		Expect(httpResp.StatusCode).To(Equal(http.StatusOK))
+		Expect(resp.Data.Attributes.Country).To(Equal("GB"))
+
+		req := fakeHTTPClient.DoArgsForCall(0)
+		Expect(req.Method).To(Equal(http.MethodGet))
+		Expect(req.URL.Path).To(Equal("/v1/organisation/accounts/1"))
+	})
+
+	It("should decode list responses", func() {
+		respond(http.StatusOK, `{"data": [{"id": "1"}, {"id": "2"}], "links": {"self": "/accounts"}}`)
+
+		list, _, err := client.List[account.Account](ctx, cl, "/v1/organisation/accounts", map[string]string{
+			"page[size]": "2",
+		})
+		Expect(err).To(BeNil())
+		Expect(list.Data).To(HaveLen(2))
+		Expect(list.Data[1].ID).To(Equal("2"))
+
+		req := fakeHTTPClient.DoArgsForCall(0)
+		Expect(req.URL.Query().Get("page[size]")).To(Equal("2"))
+	})
+
+	It("should encode request bodies", func() {
+		respond(http.StatusCreated, `{"data": {"id": "1"}}`)
+
+		type request struct {
+			Data *account.Account `json:"data"`
+		}
+
+		body := &request{Data: account.New("org").WithID("1")}
+		resp, _, err := client.Post[request, account.Response](ctx, cl, "/v1/organisation/accounts", body)
+		Expect(err).To(BeNil())
+		Expect(resp.Data.ID).To(Equal("1"))
+
+		req := fakeHTTPClient.DoArgsForCall(0)
+		Expect(req.Method).To(Equal(http.MethodPost))
+
+		var sent request
+		Expect(json.NewDecoder(req.Body).Decode(&sent)).To(Succeed())
+		Expect(sent.Data.OrganisationID).To(Equal("org"))
+	})
+
+	It("should not send a body for a nil request", func() {
+		respond(http.StatusOK, `{"data": {"id": "1"}}`)
+
+		type request struct {
+			Data *account.Account `json:"data"`
+		}
+
+		_, _, err := client.Patch[request, account.Response](ctx, cl, "/v1/organisation/accounts/1", nil)
+		Expect(err).To(BeNil())
+
+		req := fakeHTTPClient.DoArgsForCall(0)
+		Expect(req.Body).To(BeNil())
+		Expect(req.Header.Get("Content-Type")).To(BeEmpty())
+	})
+
+	It("should send DELETE requests", func() {
+		respond(http.StatusNoContent, "")
+
+		httpResp, err := client.Delete(ctx, cl, "/v1/organisation/accounts/1", map[string]string{"version": "0"})
+		Expect(err).To(BeNil())
+		Expect(httpResp.StatusCode).To(Equal(http.StatusNoContent))
+
+		req := fakeHTTPClient.DoArgsForCall(0)
+		Expect(req.Method).To(Equal(http.MethodDelete))
+		Expect(req.URL.Query().Get("version")).To(Equal("0"))
+	})
+
+	It("should return API errors with the response", func() {
+		respond(http.StatusNotFound, "")
+
+		resp, httpResp, err := client.Get[account.Response](ctx, cl, "/v1/organisation/accounts/1", nil)
+		Expect(err).To(Not(BeNil()))
+		Expect(resp).To(BeNil())
+		Expect(client.StatusCode(err)).To(Equal(http.StatusNotFound))
+		Expect(httpResp).To(BeNil())
+	})
+})
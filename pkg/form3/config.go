@@ -0,0 +1,687 @@
+package form3
+
+import (
+	"fmt"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"gopkg.in/yaml.v3"
+
+	baseclient "github.com/vivangkumar/form3-http-go/pkg/client"
+)
+
+// defaultRetryBackoff matches the default backoff of the base client.
+const defaultRetryBackoff = 100 * time.Millisecond
+
+// Environment is a form3 environment with a known base URL.
+type Environment string
+
+const (
+	// Local is the interview-accountapi running on localhost.
+	Local Environment = "local"
+
+	// Sandbox is the form3 staging sandbox.
+	Sandbox Environment = "sandbox"
+
+	// Production is the form3 production API.
+	Production Environment = "production"
+)
+
+var environmentURLs = map[Environment]string{
+	Local:      "http://localhost:8080",
+	Sandbox:    "https://api.staging-form3.tech",
+	Production: "https://api.form3.tech",
+}
+
+// BaseURL returns the base URL of the environment.
+//
+// It reports false if the environment is not known.
+func (e Environment) BaseURL() (string, bool) {
+	u, ok := environmentURLs[e]
+	return u, ok
+}
+
+// WithEnvironment sets the base URL to that of env.
+//
+// WithBaseURL may be used after it to point at a different host.
+func WithEnvironment(env Environment) Opt {
+	return func(c *baseclient.Client) error {
+		u, ok := env.BaseURL()
+		if !ok {
+			return fmt.Errorf("environment opt: unknown environment %q", env)
+		}
+
+		return baseclient.WithBaseURL(u)(c)
+	}
+}
+
+// Setting names, as used in config files.
+//
+// The matching environment variables are the upper cased names
+// prefixed with FORM3_, such as FORM3_BASE_URL.
+const (
+	settingEnvironment       = "environment"
+	settingBaseURL           = "base_url"
+	settingOrganisationID    = "organisation_id"
+	settingTimeout           = "timeout"
+	settingMaxRetries        = "max_retries"
+	settingRetryBackoff      = "retry_backoff"
+	settingSigningKeyID      = "signing_key_id"
+	settingSigningKeyPath    = "signing_key_path"
+	settingTLSClientCertPath = "tls_client_cert_path"
+	settingTLSClientKeyPath  = "tls_client_key_path"
+	settingRootCAPath        = "root_ca_path"
+	settingHeaders           = "headers"
+)
+
+// scalarSettings are the settings holding a single value.
+var scalarSettings = []string{
+	settingEnvironment,
+	settingBaseURL,
+	settingOrganisationID,
+	settingTimeout,
+	settingMaxRetries,
+	settingRetryBackoff,
+	settingSigningKeyID,
+	settingSigningKeyPath,
+	settingTLSClientCertPath,
+	settingTLSClientKeyPath,
+	settingRootCAPath,
+}
+
+// pathSettings are the settings naming files.
+var pathSettings = []string{
+	settingSigningKeyPath,
+	settingTLSClientCertPath,
+	settingTLSClientKeyPath,
+	settingRootCAPath,
+}
+
+// envPrefix prefixes the environment variable of each setting.
+const envPrefix = "FORM3_"
+
+// profileEnv selects the profile used by NewFromConfig.
+const profileEnv = envPrefix + "PROFILE"
+
+// defaultProfile is used when no profile is selected.
+const defaultProfile = "default"
+
+// SettingError is returned when a setting is invalid.
+type SettingError struct {
+	// Setting is the name of the setting, as the user wrote it. It is an
+	// environment variable such as FORM3_TIMEOUT, or a path within a
+	// config file such as profiles.sandbox.timeout.
+	Setting string
+
+	// Err describes what is wrong with the setting.
+	Err error
+}
+
+func (e *SettingError) Error() string {
+	return fmt.Sprintf("invalid setting %s: %s", e.Setting, e.Err)
+}
+
+func (e *SettingError) Unwrap() error {
+	return e.Err
+}
+
+// Config holds client settings read from the environment or a config file.
+//
+// Zero values mean the default of the client is used, unless the setting
+// was read by LoadEnv or LoadConfig: a timeout or max retries read as 0
+// turns off timeouts or retries.
+type Config struct {
+	// Environment selects the base URL. BaseURL takes precedence.
+	Environment Environment
+
+	BaseURL string
+
+	// OrganisationID is made available as Client.OrganisationID.
+	OrganisationID string
+
+	Timeout time.Duration
+
+	// MaxRetries and RetryBackoff configure WithRetry. The backoff
+	// defaults to 100ms.
+	MaxRetries   int
+	RetryBackoff time.Duration
+
+	// SigningKeyID and SigningKeyPath configure WithRequestSigning.
+	SigningKeyID   string
+	SigningKeyPath string
+
+	// TLSClientCertPath and TLSClientKeyPath configure
+	// WithTLSClientCertificate.
+	TLSClientCertPath string
+	TLSClientKeyPath  string
+
+	// RootCAPath configures WithRootCAs.
+	RootCAPath string
+
+	// Headers configures WithHTTPRequestHeaders.
+	Headers map[string]string
+
+	// settingName returns the name the user knows a setting by.
+	settingName func(setting string) string
+
+	// set holds the settings that were read, including those set to a
+	// zero value. It is nil if the config was not read by LoadEnv or
+	// LoadConfig.
+	set map[string]bool
+}
+
+// LoadEnv reads a Config from FORM3_ environment variables.
+//
+// The variables are FORM3_ENVIRONMENT, FORM3_BASE_URL,
+// FORM3_ORGANISATION_ID, FORM3_TIMEOUT, FORM3_MAX_RETRIES,
+// FORM3_RETRY_BACKOFF, FORM3_SIGNING_KEY_ID, FORM3_SIGNING_KEY_PATH,
+// FORM3_TLS_CLIENT_CERT_PATH, FORM3_TLS_CLIENT_KEY_PATH and
+// FORM3_ROOT_CA_PATH. Durations are written as in time.ParseDuration,
+// for example 5s.
+func LoadEnv() (*Config, error) {
+	values := make(map[string]string)
+	for _, s := range scalarSettings {
+		if v, ok := os.LookupEnv(envName(s)); ok {
+			values[s] = v
+		}
+	}
+
+	return parseConfig(values, nil, envName)
+}
+
+// LoadConfig reads the named profile of a YAML or JSON config file.
+//
+// The file holds profiles keyed by name, using the setting names of the
+// environment variables read by LoadEnv, lower cased and without the FORM3_
+// prefix. Profiles may also set headers.
+//
+//	default_profile: local
+//	profiles:
+//	  local:
+//	    environment: local
+//	  sandbox:
+//	    environment: sandbox
+//	    organisation_id: eb0bd6f5-c3f5-44b2-b677-acd23cdde73c
+//	    signing_key_id: 75a8ba12-fff2-4a52-ad8a-e8b34c5ccec8
+//	    signing_key_path: /etc/form3/sandbox.pem
+//	    timeout: 10s
+//	    max_retries: 3
+//
+// Relative file paths are relative to the directory of the config file.
+//
+// If profile is empty, the file's default_profile is used, or else the
+// profile named default.
+func LoadConfig(path string, profile string) (*Config, error) {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("read config: %w", err)
+	}
+
+	var file struct {
+		DefaultProfile string                          `yaml:"default_profile"`
+		Profiles       map[string]map[string]yaml.Node `yaml:"profiles"`
+	}
+
+	err = yaml.Unmarshal(b, &file)
+	if err != nil {
+		return nil, fmt.Errorf("decode config %s: %w", path, err)
+	}
+
+	if profile == "" {
+		profile = file.DefaultProfile
+	}
+	if profile == "" {
+		profile = defaultProfile
+	}
+
+	settings, ok := file.Profiles[profile]
+	if !ok {
+		return nil, fmt.Errorf("config %s: profile %q not found", path, profile)
+	}
+
+	name := func(setting string) string {
+		return "profiles." + profile + "." + setting
+	}
+
+	values := make(map[string]string)
+	var headers map[string]string
+
+	for s, node := range settings {
+		switch {
+		case s == settingHeaders:
+			err := node.Decode(&headers)
+			if err != nil {
+				return nil, fmt.Errorf("config %s: %w", path, &SettingError{Setting: name(s), Err: fmt.Errorf("must map header names to values")})
+			}
+		case isScalarSetting(s):
+			if node.Kind != yaml.ScalarNode {
+				return nil, fmt.Errorf("config %s: %w", path, &SettingError{Setting: name(s), Err: fmt.Errorf("must be a single value")})
+			}
+			values[s] = node.Value
+		default:
+			return nil, fmt.Errorf("config %s: %w", path, &SettingError{Setting: name(s), Err: fmt.Errorf("unknown setting")})
+		}
+	}
+
+	// Files are found relative to the config file.
+	for _, s := range pathSettings {
+		if p, ok := values[s]; ok && p != "" && !filepath.IsAbs(p) {
+			values[s] = filepath.Join(filepath.Dir(path), p)
+		}
+	}
+
+	cfg, err := parseConfig(values, headers, name)
+	if err != nil {
+		return nil, fmt.Errorf("config %s: %w", path, err)
+	}
+
+	return cfg, nil
+}
+
+// parseConfig parses and validates the settings in values.
+func parseConfig(
+	values map[string]string,
+	headers map[string]string,
+	name func(string) string,
+) (*Config, error) {
+	cfg := &Config{
+		Environment:       Environment(values[settingEnvironment]),
+		BaseURL:           values[settingBaseURL],
+		OrganisationID:    values[settingOrganisationID],
+		SigningKeyID:      values[settingSigningKeyID],
+		SigningKeyPath:    values[settingSigningKeyPath],
+		TLSClientCertPath: values[settingTLSClientCertPath],
+		TLSClientKeyPath:  values[settingTLSClientKeyPath],
+		RootCAPath:        values[settingRootCAPath],
+		Headers:           headers,
+		settingName:       name,
+		set:               make(map[string]bool, len(values)),
+	}
+
+	for s := range values {
+		cfg.set[s] = true
+	}
+
+	durations := []struct {
+		setting string
+		d       *time.Duration
+	}{
+		{settingTimeout, &cfg.Timeout},
+		{settingRetryBackoff, &cfg.RetryBackoff},
+	}
+	for _, d := range durations {
+		v, ok := values[d.setting]
+		if !ok {
+			continue
+		}
+
+		parsed, err := time.ParseDuration(v)
+		if err != nil {
+			return nil, &SettingError{Setting: name(d.setting), Err: fmt.Errorf("%q is not a duration, such as 5s", v)}
+		}
+		*d.d = parsed
+	}
+
+	if v, ok := values[settingMaxRetries]; ok {
+		n, err := strconv.Atoi(v)
+		if err != nil {
+			return nil, &SettingError{Setting: name(settingMaxRetries), Err: fmt.Errorf("%q is not a whole number", v)}
+		}
+		cfg.MaxRetries = n
+	}
+
+	err := cfg.Validate()
+	if err != nil {
+		return nil, err
+	}
+
+	return cfg, nil
+}
+
+// Merge returns a copy of c with the settings set in o taking precedence.
+//
+// Settings read by LoadEnv or LoadConfig are set even if they hold a zero
+// value, so FORM3_MAX_RETRIES=0 turns off retries configured by a profile.
+// Settings of other configs are set if they do not hold the zero value.
+//
+// Use it to layer settings, such as environment variables over a profile.
+func (c *Config) Merge(o *Config) *Config {
+	merged := *c
+	merged.set = make(map[string]bool)
+
+	// fromOther holds the settings taken from o.
+	fromOther := make(map[string]bool)
+
+	env := string(merged.Environment)
+	mergeSetting(c, o, &merged, fromOther, settingEnvironment, &env, string(o.Environment))
+	merged.Environment = Environment(env)
+
+	mergeSetting(c, o, &merged, fromOther, settingBaseURL, &merged.BaseURL, o.BaseURL)
+	mergeSetting(c, o, &merged, fromOther, settingOrganisationID, &merged.OrganisationID, o.OrganisationID)
+	mergeSetting(c, o, &merged, fromOther, settingTimeout, &merged.Timeout, o.Timeout)
+	mergeSetting(c, o, &merged, fromOther, settingMaxRetries, &merged.MaxRetries, o.MaxRetries)
+	mergeSetting(c, o, &merged, fromOther, settingRetryBackoff, &merged.RetryBackoff, o.RetryBackoff)
+	mergeSetting(c, o, &merged, fromOther, settingSigningKeyID, &merged.SigningKeyID, o.SigningKeyID)
+	mergeSetting(c, o, &merged, fromOther, settingSigningKeyPath, &merged.SigningKeyPath, o.SigningKeyPath)
+	mergeSetting(c, o, &merged, fromOther, settingTLSClientCertPath, &merged.TLSClientCertPath, o.TLSClientCertPath)
+	mergeSetting(c, o, &merged, fromOther, settingTLSClientKeyPath, &merged.TLSClientKeyPath, o.TLSClientKeyPath)
+	mergeSetting(c, o, &merged, fromOther, settingRootCAPath, &merged.RootCAPath, o.RootCAPath)
+
+	if len(o.Headers) > 0 {
+		merged.Headers = make(map[string]string, len(c.Headers)+len(o.Headers))
+		for k, v := range c.Headers {
+			merged.Headers[k] = v
+		}
+		for k, v := range o.Headers {
+			merged.Headers[k] = v
+		}
+	}
+
+	// Settings are named after where they were read from.
+	merged.settingName = func(setting string) string {
+		if fromOther[setting] {
+			return o.name(setting)
+		}
+
+		return c.name(setting)
+	}
+
+	return &merged
+}
+
+// mergeSetting sets dst, the setting of merged, to v if the setting is set
+// in o, and records whether it is set in merged.
+func mergeSetting[T comparable](
+	c, o, merged *Config,
+	fromOther map[string]bool,
+	setting string,
+	dst *T,
+	v T,
+) {
+	var zero T
+
+	if o.isSet(setting, v != zero) {
+		*dst = v
+		fromOther[setting] = true
+		merged.set[setting] = true
+	} else if c.isSet(setting, *dst != zero) {
+		merged.set[setting] = true
+	}
+}
+
+// Unset returns a copy of c without the named settings, as if they had not
+// been read. Settings are named as in config files, for example "timeout".
+func (c *Config) Unset(settings ...string) *Config {
+	u := *c
+	if c.set != nil {
+		u.set = make(map[string]bool, len(c.set))
+		for s := range c.set {
+			u.set[s] = true
+		}
+	}
+
+	for _, s := range settings {
+		delete(u.set, s)
+
+		switch s {
+		case settingEnvironment:
+			u.Environment = ""
+		case settingBaseURL:
+			u.BaseURL = ""
+		case settingOrganisationID:
+			u.OrganisationID = ""
+		case settingTimeout:
+			u.Timeout = 0
+		case settingMaxRetries:
+			u.MaxRetries = 0
+		case settingRetryBackoff:
+			u.RetryBackoff = 0
+		case settingSigningKeyID:
+			u.SigningKeyID = ""
+		case settingSigningKeyPath:
+			u.SigningKeyPath = ""
+		case settingTLSClientCertPath:
+			u.TLSClientCertPath = ""
+		case settingTLSClientKeyPath:
+			u.TLSClientKeyPath = ""
+		case settingRootCAPath:
+			u.RootCAPath = ""
+		case settingHeaders:
+			u.Headers = nil
+		}
+	}
+
+	return &u
+}
+
+// isSet reports whether setting is set in c, given whether it holds a
+// value other than the zero value.
+func (c *Config) isSet(setting string, nonZero bool) bool {
+	if c.set == nil {
+		return nonZero
+	}
+
+	return c.set[setting]
+}
+
+// Validate reports the first invalid setting, as a *SettingError.
+//
+// Files referenced by the config are not read.
+func (c *Config) Validate() error {
+	invalid := func(setting string, format string, args ...any) error {
+		return &SettingError{Setting: c.name(setting), Err: fmt.Errorf(format, args...)}
+	}
+
+	if c.Environment != "" {
+		if _, ok := c.Environment.BaseURL(); !ok {
+			return invalid(settingEnvironment, "unknown environment %q, expected one of %s", c.Environment, environmentNames())
+		}
+	}
+
+	if c.BaseURL != "" {
+		u, err := url.Parse(c.BaseURL)
+		if err != nil || u.Scheme == "" || u.Host == "" {
+			return invalid(settingBaseURL, "%q is not an absolute URL", c.BaseURL)
+		}
+	}
+
+	if c.OrganisationID != "" {
+		if _, err := uuid.Parse(c.OrganisationID); err != nil {
+			return invalid(settingOrganisationID, "%q is not a UUID", c.OrganisationID)
+		}
+	}
+
+	if c.Timeout < 0 {
+		return invalid(settingTimeout, "must not be negative")
+	}
+
+	if c.MaxRetries < 0 {
+		return invalid(settingMaxRetries, "must not be negative")
+	}
+
+	if c.RetryBackoff < 0 {
+		return invalid(settingRetryBackoff, "must not be negative")
+	}
+
+	if c.SigningKeyID != "" && c.SigningKeyPath == "" {
+		return invalid(settingSigningKeyPath, "must be set with %s", c.name(settingSigningKeyID))
+	}
+
+	if c.SigningKeyPath != "" && c.SigningKeyID == "" {
+		return invalid(settingSigningKeyID, "must be set with %s", c.name(settingSigningKeyPath))
+	}
+
+	if c.TLSClientCertPath != "" && c.TLSClientKeyPath == "" {
+		return invalid(settingTLSClientKeyPath, "must be set with %s", c.name(settingTLSClientCertPath))
+	}
+
+	if c.TLSClientKeyPath != "" && c.TLSClientCertPath == "" {
+		return invalid(settingTLSClientCertPath, "must be set with %s", c.name(settingTLSClientKeyPath))
+	}
+
+	return nil
+}
+
+// Opts returns the client options for the config.
+//
+// Files referenced by the config are read, and errors naming the setting
+// are returned if they cannot be.
+func (c *Config) Opts() ([]Opt, error) {
+	err := c.Validate()
+	if err != nil {
+		return nil, err
+	}
+
+	var opts []Opt
+
+	if c.Environment != "" {
+		opts = append(opts, WithEnvironment(c.Environment))
+	}
+
+	if c.BaseURL != "" {
+		opts = append(opts, baseclient.WithBaseURL(c.BaseURL))
+	}
+
+	// Timeouts and retries read as zero turn them off.
+	if c.isSet(settingTimeout, c.Timeout > 0) {
+		opts = append(opts, baseclient.WithTimeout(c.Timeout))
+	}
+
+	if c.isSet(settingMaxRetries, c.MaxRetries > 0) {
+		backoff := c.RetryBackoff
+		if backoff == 0 {
+			backoff = defaultRetryBackoff
+		}
+		opts = append(opts, baseclient.WithRetry(c.MaxRetries, backoff))
+	}
+
+	if len(c.Headers) > 0 {
+		opts = append(opts, baseclient.WithHTTPRequestHeaders(c.Headers))
+	}
+
+	if c.SigningKeyPath != "" {
+		key, err := c.readFile(settingSigningKeyPath, c.SigningKeyPath)
+		if err != nil {
+			return nil, err
+		}
+		opts = append(opts, baseclient.WithRequestSigning(c.SigningKeyID, key))
+	}
+
+	if c.TLSClientCertPath != "" {
+		cert, err := c.readFile(settingTLSClientCertPath, c.TLSClientCertPath)
+		if err != nil {
+			return nil, err
+		}
+
+		key, err := c.readFile(settingTLSClientKeyPath, c.TLSClientKeyPath)
+		if err != nil {
+			return nil, err
+		}
+		opts = append(opts, baseclient.WithTLSClientCertificate(cert, key))
+	}
+
+	if c.RootCAPath != "" {
+		cas, err := c.readFile(settingRootCAPath, c.RootCAPath)
+		if err != nil {
+			return nil, err
+		}
+		opts = append(opts, baseclient.WithRootCAs(cas))
+	}
+
+	return opts, nil
+}
+
+// readFile reads the file named by a setting.
+func (c *Config) readFile(setting string, path string) ([]byte, error) {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return nil, &SettingError{Setting: c.name(setting), Err: err}
+	}
+
+	return b, nil
+}
+
+// name returns the name the user knows a setting by.
+func (c *Config) name(setting string) string {
+	if c.settingName == nil {
+		return setting
+	}
+
+	return c.settingName(setting)
+}
+
+// NewFromEnv returns a form3 HTTP client configured by FORM3_ environment
+// variables. See LoadEnv for the variables read.
+//
+// opts are applied after the configured options, so they take precedence.
+func NewFromEnv(opts ...Opt) (*Client, error) {
+	cfg, err := LoadEnv()
+	if err != nil {
+		return nil, fmt.Errorf("load env: %w", err)
+	}
+
+	return NewWithConfig(cfg, opts...)
+}
+
+// NewFromConfig returns a form3 HTTP client configured by a profile of the
+// config file at path. See LoadConfig for the file format.
+//
+// The profile is named by FORM3_PROFILE, if set. opts are applied after the
+// configured options, so they take precedence.
+func NewFromConfig(path string, opts ...Opt) (*Client, error) {
+	cfg, err := LoadConfig(path, os.Getenv(profileEnv))
+	if err != nil {
+		return nil, fmt.Errorf("load config: %w", err)
+	}
+
+	return NewWithConfig(cfg, opts...)
+}
+
+// NewWithConfig returns a form3 HTTP client configured by cfg.
+//
+// opts are applied after the configured options, so they take precedence.
+func NewWithConfig(cfg *Config, opts ...Opt) (*Client, error) {
+	cfgOpts, err := cfg.Opts()
+	if err != nil {
+		return nil, err
+	}
+
+	c, err := New(append(cfgOpts, opts...)...)
+	if err != nil {
+		return nil, err
+	}
+	c.OrganisationID = cfg.OrganisationID
+
+	return c, nil
+}
+
+func envName(setting string) string {
+	return envPrefix + strings.ToUpper(setting)
+}
+
+func isScalarSetting(setting string) bool {
+	for _, s := range scalarSettings {
+		if s == setting {
+			return true
+		}
+	}
+
+	return false
+}
+
+// environmentNames lists the known environments.
+func environmentNames() string {
+	names := make([]string, 0, len(environmentURLs))
+	for env := range environmentURLs {
+		names = append(names, string(env))
+	}
+	sort.Strings(names)
+
+	return strings.Join(names, ", ")
+}
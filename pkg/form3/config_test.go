@@ -0,0 +1,307 @@
+package form3_test
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/pem"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"github.com/vivangkumar/form3-http-go/pkg/client"
+	"github.com/vivangkumar/form3-http-go/pkg/form3"
+)
+
+var _ = Describe("Configuration", func() {
+	var dir string
+
+	BeforeEach(func() {
+		dir = GinkgoT().TempDir()
+	})
+
+	// setenv sets an environment variable for the current spec.
+	setenv := func(key string, value string) {
+		prev, ok := os.LookupEnv(key)
+		Expect(os.Setenv(key, value)).To(Succeed())
+
+		DeferCleanup(func() {
+			if ok {
+				os.Setenv(key, prev)
+			} else {
+				os.Unsetenv(key)
+			}
+		})
+	}
+
+	writeFile := func(name string, content string) string {
+		path := filepath.Join(dir, name)
+		Expect(os.WriteFile(path, []byte(content), 0o600)).To(Succeed())
+
+		return path
+	}
+
+	writeKey := func(name string) string {
+		key, err := rsa.GenerateKey(rand.Reader, 2048)
+		Expect(err).To(BeNil())
+
+		return writeFile(name, string(pem.EncodeToMemory(&pem.Block{
+			Type:  "RSA PRIVATE KEY",
+			Bytes: x509.MarshalPKCS1PrivateKey(key),
+		})))
+	}
+
+	// settingOf returns the setting named by err.
+	settingOf := func(err error) string {
+		var se *form3.SettingError
+		Expect(errors.As(err, &se)).To(BeTrue(), "expected a setting error, got %v", err)
+
+		return se.Setting
+	}
+
+	Describe("Environments", func() {
+		It("should know the base URL of each environment", func() {
+			for env, want := range map[form3.Environment]string{
+				form3.Local:      "http://localhost:8080",
+				form3.Sandbox:    "https://api.staging-form3.tech",
+				form3.Production: "https://api.form3.tech",
+			} {
+				u, ok := env.BaseURL()
+				Expect(ok).To(BeTrue())
+				Expect(u).To(Equal(want))
+
+				_, err := form3.New(form3.WithEnvironment(env))
+				Expect(err).To(BeNil())
+			}
+		})
+
+		It("should reject unknown environments", func() {
+			_, err := form3.New(form3.WithEnvironment("staging"))
+			Expect(err).To(MatchError(ContainSubstring(`unknown environment "staging"`)))
+		})
+	})
+
+	Describe("Loading from the environment", func() {
+		It("should read settings", func() {
+			keyPath := writeKey("key.pem")
+
+			setenv("FORM3_ENVIRONMENT", "sandbox")
+			setenv("FORM3_ORGANISATION_ID", "eb0bd6f5-c3f5-44b2-b677-acd23cdde73c")
+			setenv("FORM3_TIMEOUT", "5s")
+			setenv("FORM3_MAX_RETRIES", "3")
+			setenv("FORM3_RETRY_BACKOFF", "250ms")
+			setenv("FORM3_SIGNING_KEY_ID", "key-1")
+			setenv("FORM3_SIGNING_KEY_PATH", keyPath)
+
+			cfg, err := form3.LoadEnv()
+			Expect(err).To(BeNil())
+			Expect(cfg.Environment).To(Equal(form3.Sandbox))
+			Expect(cfg.Timeout).To(Equal(5 * time.Second))
+			Expect(cfg.MaxRetries).To(Equal(3))
+			Expect(cfg.RetryBackoff).To(Equal(250 * time.Millisecond))
+
+			c, err := form3.NewFromEnv()
+			Expect(err).To(BeNil())
+			Expect(c.OrganisationID).To(Equal("eb0bd6f5-c3f5-44b2-b677-acd23cdde73c"))
+		})
+
+		It("should turn off the timeout when it is read as zero", func() {
+			setenv("FORM3_TIMEOUT", "0")
+
+			cfg, err := form3.LoadEnv()
+			Expect(err).To(BeNil())
+
+			// The timeout is applied, so a custom HTTP client is rejected.
+			_, err = form3.NewWithConfig(cfg, client.WithHTTPClient(http.DefaultClient))
+			Expect(err).To(MatchError(ContainSubstring("WithTimeout cannot be combined with WithHTTPClient")))
+
+			_, err = form3.NewWithConfig(cfg.Unset("timeout"), client.WithHTTPClient(http.DefaultClient))
+			Expect(err).To(BeNil())
+		})
+
+		It("should create a default client without settings", func() {
+			c, err := form3.NewFromEnv()
+			Expect(err).To(BeNil())
+			Expect(c.OrganisationID).To(BeEmpty())
+		})
+
+		DescribeTable("should name the invalid setting",
+			func(key string, value string, want string) {
+				setenv(key, value)
+
+				_, err := form3.NewFromEnv()
+				Expect(err).To(Not(BeNil()))
+				Expect(settingOf(err)).To(Equal(want))
+			},
+			Entry("environment", "FORM3_ENVIRONMENT", "staging", "FORM3_ENVIRONMENT"),
+			Entry("base url", "FORM3_BASE_URL", "localhost", "FORM3_BASE_URL"),
+			Entry("organisation id", "FORM3_ORGANISATION_ID", "org", "FORM3_ORGANISATION_ID"),
+			Entry("timeout", "FORM3_TIMEOUT", "5", "FORM3_TIMEOUT"),
+			Entry("max retries", "FORM3_MAX_RETRIES", "three", "FORM3_MAX_RETRIES"),
+			Entry("negative max retries", "FORM3_MAX_RETRIES", "-1", "FORM3_MAX_RETRIES"),
+			Entry("signing key without id", "FORM3_SIGNING_KEY_PATH", "key.pem", "FORM3_SIGNING_KEY_ID"),
+			Entry("signing key id without key", "FORM3_SIGNING_KEY_ID", "key-1", "FORM3_SIGNING_KEY_PATH"),
+		)
+
+		It("should name settings whose files cannot be read", func() {
+			setenv("FORM3_ROOT_CA_PATH", filepath.Join(dir, "missing.pem"))
+
+			_, err := form3.NewFromEnv()
+			Expect(settingOf(err)).To(Equal("FORM3_ROOT_CA_PATH"))
+		})
+
+		It("should let options take precedence", func() {
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(http.StatusNoContent)
+			}))
+			defer server.Close()
+
+			setenv("FORM3_BASE_URL", "http://localhost:1")
+
+			c, err := form3.NewFromEnv(client.WithBaseURL(server.URL))
+			Expect(err).To(BeNil())
+
+			_, err = c.Get(context.Background(), "/v1/organisation/accounts", nil, nil)
+			Expect(err).To(BeNil())
+		})
+	})
+
+	Describe("Merging configs", func() {
+		It("should let set settings take precedence", func() {
+			base := &form3.Config{
+				Environment: form3.Sandbox,
+				Timeout:     time.Second,
+				MaxRetries:  3,
+				Headers:     map[string]string{"X-Team": "payments", "X-Env": "sandbox"},
+			}
+
+			merged := base.Merge(&form3.Config{
+				Timeout: 5 * time.Second,
+				Headers: map[string]string{"X-Env": "local"},
+			})
+
+			Expect(merged.Environment).To(Equal(form3.Sandbox))
+			Expect(merged.Timeout).To(Equal(5 * time.Second))
+			Expect(merged.MaxRetries).To(Equal(3))
+			Expect(merged.Headers).To(Equal(map[string]string{"X-Team": "payments", "X-Env": "local"}))
+			Expect(base.Timeout).To(Equal(time.Second))
+		})
+
+		It("should let settings read as zero values take precedence", func() {
+			path := writeFile("form3.yaml", "profiles:\n  default:\n    max_retries: 3\n    timeout: 5s\n")
+			file, err := form3.LoadConfig(path, "")
+			Expect(err).To(BeNil())
+
+			setenv("FORM3_MAX_RETRIES", "0")
+			env, err := form3.LoadEnv()
+			Expect(err).To(BeNil())
+
+			merged := file.Merge(env)
+			Expect(merged.MaxRetries).To(Equal(0))
+			Expect(merged.Timeout).To(Equal(5 * time.Second))
+		})
+
+		It("should name settings after where they were read from", func() {
+			path := writeFile("form3.yaml", "profiles:\n  default:\n    signing_key_id: key-1\n    signing_key_path: key.pem\n")
+			file, err := form3.LoadConfig(path, "")
+			Expect(err).To(BeNil())
+
+			setenv("FORM3_ROOT_CA_PATH", filepath.Join(dir, "missing.pem"))
+			env, err := form3.LoadEnv()
+			Expect(err).To(BeNil())
+
+			_, err = form3.NewWithConfig(file.Merge(env))
+			Expect(settingOf(err)).To(Equal("profiles.default.signing_key_path"))
+
+			writeKey("key.pem")
+			_, err = form3.NewWithConfig(file.Merge(env))
+			Expect(settingOf(err)).To(Equal("FORM3_ROOT_CA_PATH"))
+		})
+	})
+
+	Describe("Loading from a config file", func() {
+		var path string
+
+		BeforeEach(func() {
+			writeKey("sandbox.pem")
+
+			path = writeFile("form3.yaml", `
+default_profile: local
+profiles:
+  local:
+    environment: local
+  sandbox:
+    environment: sandbox
+    organisation_id: eb0bd6f5-c3f5-44b2-b677-acd23cdde73c
+    signing_key_id: key-1
+    signing_key_path: sandbox.pem
+    timeout: 10s
+    max_retries: 3
+    headers:
+      X-Team: payments
+`)
+		})
+
+		It("should use the default profile", func() {
+			cfg, err := form3.LoadConfig(path, "")
+			Expect(err).To(BeNil())
+			Expect(cfg.Environment).To(Equal(form3.Local))
+		})
+
+		It("should read the named profile", func() {
+			cfg, err := form3.LoadConfig(path, "sandbox")
+			Expect(err).To(BeNil())
+			Expect(cfg.Environment).To(Equal(form3.Sandbox))
+			Expect(cfg.Timeout).To(Equal(10 * time.Second))
+			Expect(cfg.MaxRetries).To(Equal(3))
+			Expect(cfg.Headers).To(Equal(map[string]string{"X-Team": "payments"}))
+			Expect(cfg.SigningKeyPath).To(Equal(filepath.Join(dir, "sandbox.pem")))
+		})
+
+		It("should select the profile with FORM3_PROFILE", func() {
+			setenv("FORM3_PROFILE", "sandbox")
+
+			c, err := form3.NewFromConfig(path)
+			Expect(err).To(BeNil())
+			Expect(c.OrganisationID).To(Equal("eb0bd6f5-c3f5-44b2-b677-acd23cdde73c"))
+		})
+
+		It("should read JSON files", func() {
+			path := writeFile("form3.json", `{"profiles": {"default": {"base_url": "http://localhost:9090", "max_retries": 2}}}`)
+
+			cfg, err := form3.LoadConfig(path, "")
+			Expect(err).To(BeNil())
+			Expect(cfg.BaseURL).To(Equal("http://localhost:9090"))
+			Expect(cfg.MaxRetries).To(Equal(2))
+		})
+
+		It("should return an error for unknown profiles", func() {
+			_, err := form3.LoadConfig(path, "production")
+			Expect(err).To(MatchError(ContainSubstring(`profile "production" not found`)))
+		})
+
+		DescribeTable("should name the invalid setting",
+			func(profile string, want string) {
+				path := writeFile("invalid.yaml", "profiles:\n  default:\n"+profile)
+
+				_, err := form3.NewFromConfig(path)
+				Expect(err).To(Not(BeNil()))
+				Expect(settingOf(err)).To(Equal(want))
+			},
+			Entry("unknown setting", "    timeuot: 5s\n", "profiles.default.timeuot"),
+			Entry("invalid duration", "    retry_backoff: fast\n", "profiles.default.retry_backoff"),
+			Entry("list instead of value", "    timeout: [5s]\n", "profiles.default.timeout"),
+			Entry("invalid headers", "    headers: [X-Team]\n", "profiles.default.headers"),
+			Entry("certificate without key", "    tls_client_cert_path: cert.pem\n", "profiles.default.tls_client_key_path"),
+			Entry("missing signing key", "    signing_key_id: key-1\n    signing_key_path: missing.pem\n",
+				"profiles.default.signing_key_path"),
+		)
+	})
+})
@@ -13,10 +13,19 @@ import (
 
 	"github.com/vivangkumar/form3-http-go/pkg/account"
 	accountclient "github.com/vivangkumar/form3-http-go/pkg/account/client"
+	"github.com/vivangkumar/form3-http-go/pkg/account/outbox"
 	baseclient "github.com/vivangkumar/form3-http-go/pkg/client"
 )
 
 type baseClient interface {
+	NewRequest(
+		ctx context.Context,
+		method string,
+		path string,
+		query map[string]string,
+		body any,
+	) (*http.Request, error)
+	Do(req *http.Request, target any) (*http.Response, error)
 	Get(
 		ctx context.Context,
 		path string,
@@ -29,6 +38,12 @@ type baseClient interface {
 		body any,
 		target any,
 	) (*http.Response, error)
+	Patch(
+		ctx context.Context,
+		path string,
+		body any,
+		target any,
+	) (*http.Response, error)
 	Delete(
 		ctx context.Context,
 		path string,
@@ -45,10 +60,57 @@ type accountsClient interface {
 		ctx context.Context,
 		params account.FetchAccountParams,
 	) (*account.Response, error)
+	List(
+		ctx context.Context,
+		params account.ListAccountParams,
+	) (*account.ListResponse, error)
+	Pages(params account.ListAccountParams) *account.Pages
+	Update(
+		ctx context.Context,
+		acc *account.Account,
+	) (*account.Response, error)
 	Delete(
 		ctx context.Context,
 		params account.DeleteAccountParams,
 	) (*account.DeleteResponse, error)
+	Ensure(
+		ctx context.Context,
+		acc *account.Account,
+		opts ...accountclient.EnsureOpt,
+	) (*account.Response, error)
+	DeleteLatest(
+		ctx context.Context,
+		id string,
+		opts ...accountclient.DeleteLatestOpt,
+	) (*account.DeleteResponse, error)
+	CreateMany(
+		ctx context.Context,
+		accs []*account.Account,
+		opts ...accountclient.BulkOpt,
+	) ([]accountclient.BulkResult, error)
+	CreateManyFrom(
+		ctx context.Context,
+		accs <-chan *account.Account,
+		opts ...accountclient.BulkOpt,
+	) ([]accountclient.BulkResult, error)
+	DeleteMany(
+		ctx context.Context,
+		params []account.DeleteAccountParams,
+		opts ...accountclient.BulkOpt,
+	) ([]accountclient.BulkResult, error)
+	DeleteManyFrom(
+		ctx context.Context,
+		params <-chan account.DeleteAccountParams,
+		opts ...accountclient.BulkOpt,
+	) ([]accountclient.BulkResult, error)
+	OpenOutbox(
+		dir string,
+		opts ...outbox.Opt,
+	) (*outbox.Outbox, error)
+	CreateAsync(
+		ctx context.Context,
+		acc *account.Account,
+	) (outbox.Ticket, error)
 }
 
 // Client represents an abstraction over the base client and the accounts API.
@@ -61,6 +123,11 @@ type Client struct {
 
 	// Expose accounts related functionality.
 	Accounts accountsClient
+
+	// OrganisationID is the organisation configured by NewFromEnv or
+	// NewFromConfig, if any. It is not sent with requests; use it when
+	// creating accounts.
+	OrganisationID string
 }
 
 // New returns a form3 HTTP client.
@@ -70,7 +137,9 @@ func New(opts ...Opt) (*Client, error) {
 		return nil, fmt.Errorf("create client: %w", err)
 	}
 
-	return &Client{c, accountclient.New(c)}, nil
+	accounts := accountclient.New(c, accountclient.WithTracer(c.Tracer()))
+
+	return &Client{baseClient: c, Accounts: accounts}, nil
 }
 
 // Opt aliases client.Opt to delegate application of options
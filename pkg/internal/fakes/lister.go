@@ -0,0 +1,111 @@
+// Package fakes consists of in-memory fakes shared by tests.
+package fakes
+
+import (
+	"context"
+	"fmt"
+	"sync"
+
+	"github.com/vivangkumar/form3-http-go/pkg/account"
+)
+
+// Lister lists a slice of accounts, page by page. It satisfies
+// account.Lister.
+type Lister struct {
+	mu       sync.Mutex
+	accounts []account.Account
+	err      error
+	calls    int
+
+	// maxPageSize caps the page size, as the API does, if it is set.
+	maxPageSize int
+
+	// onList is called before each page is returned.
+	onList func(page int)
+}
+
+// NewLister returns a lister of accs.
+func NewLister(accs ...account.Account) *Lister {
+	return &Lister{accounts: accs}
+}
+
+// List returns the page of accounts of params, or the error set with
+// SetErr. Pages only have links if the page size is capped with
+// SetMaxPageSize.
+func (l *Lister) List(_ context.Context, params account.ListAccountParams) (*account.ListResponse, error) {
+	l.mu.Lock()
+	l.calls++
+	onList, err := l.onList, l.err
+	size := params.PageSize
+	if l.maxPageSize > 0 && size > l.maxPageSize {
+		size = l.maxPageSize
+	}
+	from := params.PageNumber * size
+	to := from + size
+	if from > len(l.accounts) {
+		from = len(l.accounts)
+	}
+	if to > len(l.accounts) {
+		to = len(l.accounts)
+	}
+	resp := &account.ListResponse{Data: append([]account.Account(nil), l.accounts[from:to]...)}
+	if l.maxPageSize > 0 {
+		resp.Links = &account.Links{}
+		if to < len(l.accounts) {
+			next := fmt.Sprintf("/v1/organisation/accounts?page[number]=%d&page[size]=%d", params.PageNumber+1, size)
+			resp.Links.Next = &next
+		}
+	}
+	l.mu.Unlock()
+
+	if onList != nil {
+		onList(params.PageNumber)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return resp, nil
+}
+
+// Set replaces the listed accounts.
+func (l *Lister) Set(accs ...account.Account) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	l.accounts = accs
+}
+
+// SetErr makes List fail with err, or succeed again if err is nil.
+func (l *Lister) SetErr(err error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	l.err = err
+}
+
+// OnList calls fn with the page number before each page is returned, and
+// before an error set with SetErr. fn may call Set.
+func (l *Lister) OnList(fn func(page int)) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	l.onList = fn
+}
+
+// SetMaxPageSize caps the number of accounts returned per page to n, and
+// returns pages with links, as the API does.
+func (l *Lister) SetMaxPageSize(n int) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	l.maxPageSize = n
+}
+
+// Calls returns the number of times List was called.
+func (l *Lister) Calls() int {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	return l.calls
+}
@@ -78,3 +78,87 @@ func AccountsResponseMinFields(
     }
 }`, orgID, accountID, country, currency)
 }
+
+// AccountsResponseAllAttributes returns a JSON representation of an accounts entity.
+// It returns every attribute and relationship that the accounts resource models.
+func AccountsResponseAllAttributes(
+	accountID string,
+	orgID string,
+	country string,
+	currency string,
+) string {
+	return fmt.Sprintf(`{
+	"data": {
+		"type": "accounts",
+		"id": "%[1]s",
+		"version": 2,
+		"organisation_id": "%[2]s",
+		"attributes": {
+			"country": "%[3]s",
+			"base_currency": "%[4]s",
+			"bank_id": "400300",
+			"bank_id_code": "GBDSC",
+			"account_number": "41426819",
+			"customer_id": "999",
+			"iban": "%[3]s11NWBK40030041426819",
+			"bic": "NWBKGB22",
+			"account_classification": "Business",
+			"joint_account": true,
+			"account_matching_opt_out": true,
+			"switched": true,
+			"status": "pending",
+			"secondary_identification": "A1B2C3D4",
+			"name": ["Samantha Holder", "Sam Holder"],
+			"alternative_names": ["Sam", "Sammy"],
+			"name_matching_status": "supported",
+			"processing_service": "ABC Bank",
+			"user_defined_information": "Some important info",
+			"validation_type": "card",
+			"reference_mask": "############",
+			"acceptance_qualifier": "same_day",
+			"organisation_identification": {
+				"identification": "123654",
+				"actors": [
+					{
+						"name": ["Jeff Page"],
+						"birth_date": "2017-07-23",
+						"residency": "GB"
+					}
+				],
+				"address": ["10 Avenue des Champs"],
+				"city": "London",
+				"country": "%[3]s"
+			},
+			"private_identification": {
+				"birth_date": "2017-07-23",
+				"birth_country": "GB",
+				"identification": "13YH458762",
+				"address": ["10 Avenue des Champs"],
+				"city": "London",
+				"country": "%[3]s"
+			}
+		},
+		"relationships": {
+			"master_account": {
+				"data": [
+					{
+						"type": "accounts",
+						"id": "a52d13a4-f435-4c00-cfad-f5e7ac5972df"
+					}
+				]
+			},
+			"account_events": {
+				"data": [
+					{
+						"type": "account_events",
+						"id": "c1023677-70ee-417a-9a6a-e211241f1e9c"
+					}
+				]
+			}
+		}
+	},
+	"links": {
+		"self": "/accounts/%[1]s"
+	}
+}`, accountID, orgID, country, currency)
+}
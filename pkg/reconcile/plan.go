@@ -0,0 +1,116 @@
+package reconcile
+
+import (
+	"fmt"
+
+	"github.com/vivangkumar/form3-http-go/pkg/account"
+)
+
+// Op is the kind of call an action makes.
+type Op string
+
+// Operations of actions.
+const (
+	OpCreate Op = "create"
+	OpUpdate Op = "update"
+	OpDelete Op = "delete"
+)
+
+// Action is a call that removes a difference found by Run.
+type Action struct {
+	Op Op     `json:"op"`
+	ID string `json:"id"`
+
+	// Version is the version of the account to update or delete.
+	Version *int64 `json:"version,omitempty"`
+
+	// Fields are the fields an update changes.
+	Fields []string `json:"fields,omitempty"`
+
+	// Account is the account to create, or the account to update it with.
+	// Updates only set the fields that differ.
+	Account *account.Account `json:"account,omitempty"`
+}
+
+// PlanOpt represents an option that can be passed to Plan.
+type PlanOpt func(o *planOptions)
+
+type planOptions struct {
+	deletes bool
+}
+
+// WithDeletes plans deletes of the unexpected accounts.
+//
+// If not used, unexpected accounts are left alone, since they may have been
+// created after the expected accounts were exported.
+func WithDeletes() PlanOpt {
+	return func(o *planOptions) {
+		o.deletes = true
+	}
+}
+
+// Plan returns the calls that would make the API hold the expected
+// accounts: creates of the missing accounts, updates of the mismatched
+// accounts and, with WithDeletes, deletes of the unexpected accounts.
+//
+// Updates carry the version that was listed, so an update of an account
+// changed since fails with 409 Conflict rather than overwriting it.
+func (r *Report) Plan(opts ...PlanOpt) ([]Action, error) {
+	o := &planOptions{}
+	for _, opt := range opts {
+		opt(o)
+	}
+
+	var actions []Action
+
+	for _, acc := range r.Missing {
+		actions = append(actions, Action{Op: OpCreate, ID: acc.ID, Account: acc})
+	}
+
+	for _, m := range r.Mismatched {
+		a, err := update(m)
+		if err != nil {
+			return nil, fmt.Errorf("plan update of %s: %w", m.ID, err)
+		}
+		actions = append(actions, a)
+	}
+
+	if o.deletes {
+		for _, acc := range r.Unexpected {
+			actions = append(actions, Action{Op: OpDelete, ID: acc.ID, Version: version(acc)})
+		}
+	}
+
+	return actions, nil
+}
+
+// update returns the update of a mismatched account, setting only the
+// fields that differ to their expected values.
+func update(m Mismatch) (Action, error) {
+	current := *m.Actual
+	current.Version = version(m.Actual)
+
+	changes := make([]account.FieldChange, 0, len(m.Fields))
+	fields := make([]string, 0, len(m.Fields))
+	for _, f := range m.Fields {
+		changes = append(changes, account.FieldChange{Field: f.Field, From: f.Actual, To: f.Expected})
+		fields = append(fields, f.Field)
+	}
+
+	patch, err := account.Patch(&current, changes)
+	if err != nil {
+		return Action{}, err
+	}
+
+	return Action{Op: OpUpdate, ID: m.ID, Version: patch.Version, Fields: fields, Account: patch}, nil
+}
+
+// version returns a copy of the version of acc, 0 if it has none.
+func version(acc *account.Account) *int64 {
+	var v int64
+	if acc.Version != nil {
+		v = *acc.Version
+	}
+
+	return &v
+}
@@ -0,0 +1,226 @@
+// Package reconcile compares the accounts an organisation expects to hold,
+// such as the accounts of an internal ledger, with the accounts held by the
+// API.
+//
+// Run lists every account and reports the accounts missing on either side
+// and the fields that differ. Report.Plan turns a report into the creates,
+// updates and deletes that would remove the differences.
+package reconcile
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+
+	"github.com/vivangkumar/form3-http-go/pkg/account"
+)
+
+// Opt represents an option that can be passed to Run.
+type Opt func(o *options)
+
+type options struct {
+	pageSize int
+	filter   map[string]string
+	orgID    string
+	ignored  map[string]bool
+}
+
+// WithPageSize sets the number of accounts listed per request, instead of
+// account.DefaultPageSize.
+func WithPageSize(n int) Opt {
+	return func(o *options) {
+		if n > 0 {
+			o.pageSize = n
+		}
+	}
+}
+
+// WithFilter lists only the accounts matching filter, for example
+// {"country": "GB"}. Expected accounts should match it too, or they are
+// reported missing.
+func WithFilter(filter map[string]string) Opt {
+	return func(o *options) {
+		o.filter = filter
+	}
+}
+
+// WithOrganisation ignores listed accounts of organisations other than
+// orgID.
+func WithOrganisation(orgID string) Opt {
+	return func(o *options) {
+		o.orgID = orgID
+	}
+}
+
+// WithIgnoredFields does not compare the given fields, named as they are
+// in FieldMismatch, for example "attributes.status".
+func WithIgnoredFields(fields ...string) Opt {
+	return func(o *options) {
+		for _, f := range fields {
+			o.ignored[f] = true
+		}
+	}
+}
+
+// FieldMismatch is a field whose expected and actual values differ.
+type FieldMismatch struct {
+	// Field is the JSON path of the field, for example "attributes.iban".
+	Field string `json:"field"`
+
+	Expected any `json:"expected"`
+	Actual   any `json:"actual"`
+}
+
+// Mismatch is an account held by the API whose fields differ from the
+// expected account.
+type Mismatch struct {
+	ID     string          `json:"id"`
+	Fields []FieldMismatch `json:"fields"`
+
+	Expected *account.Account `json:"expected"`
+	Actual   *account.Account `json:"actual"`
+}
+
+// Report is the outcome of a reconciliation. Accounts are ordered by ID.
+type Report struct {
+	// Expected and Actual count the expected accounts and the accounts
+	// listed.
+	Expected int `json:"expected"`
+	Actual   int `json:"actual"`
+
+	// Matched counts the accounts held by the API as expected.
+	Matched int `json:"matched"`
+
+	// Missing are the expected accounts the API does not hold.
+	Missing []*account.Account `json:"missing"`
+
+	// Unexpected are the accounts the API holds that are not expected.
+	Unexpected []*account.Account `json:"unexpected"`
+
+	// Mismatched are the accounts whose fields differ.
+	Mismatched []Mismatch `json:"mismatched"`
+}
+
+// Clean reports whether the API holds exactly the expected accounts.
+func (r *Report) Clean() bool {
+	return len(r.Missing) == 0 && len(r.Unexpected) == 0 && len(r.Mismatched) == 0
+}
+
+// Run lists every account with l, page by page, and compares them with
+// expected by ID.
+//
+// Only the fields set on an expected account are compared, since the API
+// fills in defaults for fields that were not sent. IBANs are compared
+// ignoring white space and case.
+func Run(ctx context.Context, l account.Lister, expected []*account.Account, opts ...Opt) (*Report, error) {
+	o := &options{ignored: make(map[string]bool)}
+	for _, opt := range opts {
+		opt(o)
+	}
+
+	want := make(map[string]*account.Account, len(expected))
+	for i, acc := range expected {
+		if acc.ID == "" {
+			return nil, fmt.Errorf("expected account %d has no ID", i)
+		}
+		if _, ok := want[acc.ID]; ok {
+			return nil, fmt.Errorf("expected account %s is listed twice", acc.ID)
+		}
+		want[acc.ID] = acc
+	}
+
+	// Differences are listed as empty rather than null in JSON.
+	report := &Report{
+		Expected:   len(expected),
+		Missing:    []*account.Account{},
+		Unexpected: []*account.Account{},
+		Mismatched: []Mismatch{},
+	}
+	seen := make(map[string]bool, len(expected))
+
+	pages := account.NewPages(l, account.ListAccountParams{PageSize: o.pageSize, Filter: o.filter})
+	for {
+		resp, err := pages.Next(ctx)
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		for i := range resp.Data {
+			got := &resp.Data[i]
+			if o.orgID != "" && got.OrganisationID != o.orgID {
+				continue
+			}
+
+			// Pages may overlap if accounts are created during the listing.
+			if seen[got.ID] {
+				continue
+			}
+			seen[got.ID] = true
+			report.Actual++
+
+			w, ok := want[got.ID]
+			if !ok {
+				report.Unexpected = append(report.Unexpected, got)
+				continue
+			}
+
+			fields := compare(w, got, o.ignored)
+			if len(fields) == 0 {
+				report.Matched++
+				continue
+			}
+
+			report.Mismatched = append(report.Mismatched, Mismatch{
+				ID:       got.ID,
+				Fields:   fields,
+				Expected: w,
+				Actual:   got,
+			})
+		}
+	}
+
+	for _, acc := range expected {
+		if !seen[acc.ID] {
+			report.Missing = append(report.Missing, acc)
+		}
+	}
+
+	sortAccounts(report.Missing)
+	sortAccounts(report.Unexpected)
+	sort.Slice(report.Mismatched, func(i, j int) bool {
+		return report.Mismatched[i].ID < report.Mismatched[j].ID
+	})
+
+	return report, nil
+}
+
+// compare returns the fields set on want that differ on got.
+func compare(want *account.Account, got *account.Account, ignored map[string]bool) []FieldMismatch {
+	var fields []FieldMismatch
+	for _, c := range account.Diff(want, got) {
+		if c.From == nil || ignored[c.Field] {
+			continue
+		}
+
+		if c.Field == "attributes.iban" {
+			from, _ := c.From.(string)
+			to, _ := c.To.(string)
+			if account.NormaliseIBAN(from) == account.NormaliseIBAN(to) {
+				continue
+			}
+		}
+
+		fields = append(fields, FieldMismatch{Field: c.Field, Expected: c.From, Actual: c.To})
+	}
+
+	return fields
+}
+
+func sortAccounts(accs []*account.Account) {
+	sort.Slice(accs, func(i, j int) bool { return accs[i].ID < accs[j].ID })
+}
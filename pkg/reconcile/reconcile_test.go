@@ -0,0 +1,156 @@
+package reconcile_test
+
+import (
+	"context"
+	"errors"
+
+	"github.com/google/uuid"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"github.com/vivangkumar/form3-http-go/pkg/account"
+	"github.com/vivangkumar/form3-http-go/pkg/internal/fakes"
+	"github.com/vivangkumar/form3-http-go/pkg/reconcile"
+)
+
+func newAccount(orgID string, attrs *account.Attributes) *account.Account {
+	return account.New(orgID).WithID(uuid.NewString()).WithAttributes(attrs)
+}
+
+// listed returns acc as the API lists it.
+func listed(acc *account.Account, v int64, attrs *account.Attributes) account.Account {
+	got := *acc
+	got.Type = "accounts"
+	got.Version = &v
+	got.Attributes = attrs
+
+	return got
+}
+
+var _ = Describe("Run", func() {
+	var (
+		ctx    context.Context
+		orgID  string
+		lister *fakes.Lister
+
+		matched, mismatched, missing *account.Account
+		unexpected                   account.Account
+	)
+
+	BeforeEach(func() {
+		ctx = context.Background()
+		orgID = uuid.NewString()
+
+		customerID := "customer-1"
+		matched = newAccount(orgID, &account.Attributes{Iban: "gb11 nwbk 4003 0041 4268 19", Name: []string{"Samantha Holder"}})
+		mismatched = newAccount(orgID, &account.Attributes{AccountNumber: "31926819", CustomerID: &customerID, Name: []string{"Jane Doe"}})
+		missing = newAccount(orgID, &account.Attributes{Country: "GB"})
+		unexpected = listed(newAccount(orgID, nil), 3, &account.Attributes{Country: "FR"})
+
+		other := "customer-2"
+		lister = fakes.NewLister(
+			listed(matched, 0, &account.Attributes{Iban: "GB11NWBK40030041426819", Name: []string{"Samantha Holder"}, Country: "GB"}),
+			listed(mismatched, 2, &account.Attributes{AccountNumber: "31926819", CustomerID: &other, Name: []string{"Jane", "Doe"}}),
+			unexpected,
+			listed(newAccount(uuid.NewString(), nil), 0, nil),
+		)
+	})
+
+	run := func(opts ...reconcile.Opt) *reconcile.Report {
+		opts = append([]reconcile.Opt{reconcile.WithOrganisation(orgID), reconcile.WithPageSize(2)}, opts...)
+
+		report, err := reconcile.Run(ctx, lister, []*account.Account{missing, mismatched, matched}, opts...)
+		Expect(err).NotTo(HaveOccurred())
+
+		return report
+	}
+
+	It("reports accounts missing on either side", func() {
+		report := run()
+		Expect(lister.Calls()).To(Equal(3))
+
+		Expect(report.Expected).To(Equal(3))
+		Expect(report.Actual).To(Equal(3))
+		Expect(report.Matched).To(Equal(1))
+		Expect(report.Missing).To(ConsistOf(missing))
+		Expect(report.Unexpected).To(HaveLen(1))
+		Expect(report.Unexpected[0].ID).To(Equal(unexpected.ID))
+		Expect(report.Clean()).To(BeFalse())
+	})
+
+	It("reports the fields that differ, ignoring IBAN spacing and case", func() {
+		report := run()
+
+		Expect(report.Mismatched).To(HaveLen(1))
+		m := report.Mismatched[0]
+		Expect(m.ID).To(Equal(mismatched.ID))
+		Expect(m.Fields).To(Equal([]reconcile.FieldMismatch{
+			{Field: "attributes.customer_id", Expected: "customer-1", Actual: "customer-2"},
+			{Field: "attributes.name", Expected: []string{"Jane Doe"}, Actual: []string{"Jane", "Doe"}},
+		}))
+	})
+
+	It("does not compare ignored fields", func() {
+		report := run(reconcile.WithIgnoredFields("attributes.customer_id", "attributes.name"))
+
+		Expect(report.Matched).To(Equal(2))
+		Expect(report.Mismatched).To(BeEmpty())
+	})
+
+	It("is clean when the API holds exactly the expected accounts", func() {
+		lister.Set(listed(matched, 0, &account.Attributes{Iban: "GB11NWBK40030041426819", Name: []string{"Samantha Holder"}, Country: "GB"}))
+
+		report, err := reconcile.Run(ctx, lister, []*account.Account{matched})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(report.Clean()).To(BeTrue())
+	})
+
+	It("rejects expected accounts without an ID or listed twice", func() {
+		_, err := reconcile.Run(ctx, lister, []*account.Account{account.New(orgID)})
+		Expect(err).To(MatchError("expected account 0 has no ID"))
+
+		_, err = reconcile.Run(ctx, lister, []*account.Account{matched, matched})
+		Expect(err).To(MatchError("expected account " + matched.ID + " is listed twice"))
+	})
+
+	It("returns listing errors", func() {
+		lister.SetErr(errors.New("unavailable"))
+
+		_, err := reconcile.Run(ctx, lister, nil)
+		Expect(err).To(MatchError("list page 0: unavailable"))
+	})
+
+	Describe("Plan", func() {
+		It("creates missing accounts and updates the fields that differ", func() {
+			plan, err := run().Plan()
+			Expect(err).NotTo(HaveOccurred())
+			Expect(plan).To(HaveLen(2))
+
+			Expect(plan[0].Op).To(Equal(reconcile.OpCreate))
+			Expect(plan[0].Account).To(Equal(missing))
+
+			update := plan[1]
+			Expect(update.Op).To(Equal(reconcile.OpUpdate))
+			Expect(update.ID).To(Equal(mismatched.ID))
+			Expect(*update.Version).To(Equal(int64(2)))
+			Expect(update.Fields).To(Equal([]string{"attributes.customer_id", "attributes.name"}))
+
+			attrs := update.Account.Attributes
+			Expect(*attrs.CustomerID).To(Equal("customer-1"))
+			Expect(attrs.Name).To(Equal([]string{"Jane Doe"}))
+			Expect(attrs.AccountNumber).To(BeEmpty())
+		})
+
+		It("deletes unexpected accounts if asked to", func() {
+			plan, err := run().Plan(reconcile.WithDeletes())
+			Expect(err).NotTo(HaveOccurred())
+			Expect(plan).To(HaveLen(3))
+
+			Expect(plan[2]).To(Equal(reconcile.Action{
+				Op:      reconcile.OpDelete,
+				ID:      unexpected.ID,
+				Version: unexpected.Version,
+			}))
+		})
+	})
+})
@@ -0,0 +1,383 @@
+// Package resource provides a typed client for JSON:API resources.
+//
+// A Client gives any resource served at a base path Create, Fetch, List,
+// Update and Delete operations. Resource specific clients, such as the
+// accounts client, wrap it to add their own behaviour.
+//
+// To use the client in this package, a client.Requester is required. The
+// client exported from the client package is suitable for use here.
+package resource
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+
+	baseclient "github.com/vivangkumar/form3-http-go/pkg/client"
+	"github.com/vivangkumar/form3-http-go/pkg/client/jsonapi"
+)
+
+const defaultIDAttribute = "form3.resource_id"
+
+// Config describes a resource of type T.
+type Config[T any] struct {
+	// Type is the JSON:API type of the resource, for example "accounts".
+	//
+	// It is used to name spans and to derive idempotency keys.
+	Type string
+
+	// BasePath is the path the resource is served at,
+	// for example "/v1/organisation/accounts/".
+	BasePath string
+
+	// ID returns the ID of a resource.
+	//
+	// It is required for Update.
+	ID func(res *T) string
+
+	// Name is the singular name of the resource used in error messages.
+	//
+	// If not set, Type is used.
+	Name string
+
+	// IDAttribute is the span attribute the resource ID is recorded under.
+	//
+	// If not set, "form3.resource_id" is used.
+	IDAttribute string
+
+	// Match compares a resource that was sent with one returned by the API.
+	//
+	// It returns the JSON names of fields that differ. When set, a create
+	// rejected as a duplicate after a retry is treated as successful if the
+	// existing resource matches.
+	Match func(want *T, got *T) []string
+
+	// Tracer opens a span for each operation.
+	//
+	// If not set, no spans are recorded.
+	Tracer baseclient.Tracer
+}
+
+// Client represents a client for a JSON:API resource of type T.
+type Client[T any] struct {
+	requester baseclient.Requester
+	cfg       Config[T]
+}
+
+// New creates a new resource client.
+//
+// It requires an underlying client that satisfies client.Requester.
+func New[T any](requester baseclient.Requester, cfg Config[T]) *Client[T] {
+	if cfg.Name == "" {
+		cfg.Name = cfg.Type
+	}
+
+	if cfg.IDAttribute == "" {
+		cfg.IDAttribute = defaultIDAttribute
+	}
+
+	if cfg.Tracer == nil {
+		cfg.Tracer = baseclient.NoopTracer()
+	}
+
+	return &Client[T]{requester: requester, cfg: cfg}
+}
+
+type request[T any] struct {
+	Data *T `json:"data,omitempty"`
+}
+
+// ListParams represents parameters to pass when listing resources.
+type ListParams struct {
+	// PageNumber is the page to return, starting at 0.
+	PageNumber int
+
+	// PageSize is the number of resources per page.
+	//
+	// If not set, the API default is used.
+	PageSize int
+
+	// Filter filters resources by attribute, for example
+	// {"bank_id": "400300"}.
+	Filter map[string]string
+}
+
+// query returns the query parameters for p.
+func (p ListParams) query() map[string]string {
+	q := make(map[string]string)
+	if p.PageNumber > 0 {
+		q["page[number]"] = strconv.Itoa(p.PageNumber)
+	}
+
+	if p.PageSize > 0 {
+		q["page[size]"] = strconv.Itoa(p.PageSize)
+	}
+
+	for k, v := range p.Filter {
+		q["filter["+k+"]"] = v
+	}
+
+	return q
+}
+
+// Create creates a new resource.
+//
+// Create requests carry an idempotency key so that they can be retried
+// safely. A key set with client.ContextWithIdempotencyKey is used if present.
+// Otherwise, one is derived from the resource ID, if set.
+//
+// If a retried request is rejected as a duplicate and Match is configured,
+// the existing resource is fetched. The create is treated as successful if
+// the existing resource matches res.
+func (c *Client[T]) Create(
+	ctx context.Context,
+	res *T,
+) (_ *jsonapi.Document[T], err error) {
+	if res == nil {
+		return nil, fmt.Errorf("%s entity is nil", c.cfg.Name)
+	}
+
+	id := c.id(res)
+
+	ctx, op := c.startOperation(ctx, "create", id)
+	defer func() { op.end(err) }()
+
+	_, ok := baseclient.IdempotencyKeyFromContext(ctx)
+	if !ok && id != "" {
+		key := baseclient.IdempotencyKeyFor(c.cfg.Type, id)
+		ctx = baseclient.ContextWithIdempotencyKey(ctx, key)
+	}
+
+	doc, resp, err := baseclient.Post[request[T], jsonapi.Document[T]](
+		ctx, c.requester, c.cfg.BasePath, &request[T]{Data: res},
+	)
+	op.record(resp, err)
+	if err != nil {
+		if isRetriedDuplicate(err) && id != "" && c.cfg.Match != nil {
+			return c.resolveDuplicate(ctx, res, id, err)
+		}
+
+		return nil, fmt.Errorf("create %s: %w", c.cfg.Name, err)
+	}
+
+	return doc, nil
+}
+
+// Fetch retrieves the resource with the given ID.
+func (c *Client[T]) Fetch(
+	ctx context.Context,
+	id string,
+) (_ *jsonapi.Document[T], err error) {
+	ctx, op := c.startOperation(ctx, "fetch", id)
+	defer func() { op.end(err) }()
+
+	doc, resp, err := baseclient.Get[jsonapi.Document[T]](ctx, c.requester, c.cfg.BasePath+id, nil)
+	op.record(resp, err)
+	if err != nil {
+		return nil, fmt.Errorf("fetch %s: %w", c.cfg.Name, err)
+	}
+
+	return doc, nil
+}
+
+// List retrieves a page of resources.
+//
+// The links of the returned document can be used to page through the list.
+func (c *Client[T]) List(
+	ctx context.Context,
+	params ListParams,
+) (_ *jsonapi.ListDocument[T], err error) {
+	ctx, op := c.startOperation(ctx, "list", "")
+	defer func() { op.end(err) }()
+
+	list, resp, err := baseclient.List[T](ctx, c.requester, c.cfg.BasePath, params.query())
+	op.record(resp, err)
+	if err != nil {
+		return nil, fmt.Errorf("list %s: %w", c.cfg.Type, err)
+	}
+
+	return list, nil
+}
+
+// Update patches the resource with the ID of res.
+func (c *Client[T]) Update(
+	ctx context.Context,
+	res *T,
+) (_ *jsonapi.Document[T], err error) {
+	if res == nil {
+		return nil, fmt.Errorf("%s entity is nil", c.cfg.Name)
+	}
+
+	id := c.id(res)
+	if id == "" {
+		return nil, fmt.Errorf("%s ID is empty", c.cfg.Name)
+	}
+
+	ctx, op := c.startOperation(ctx, "update", id)
+	defer func() { op.end(err) }()
+
+	doc, resp, err := baseclient.Patch[request[T], jsonapi.Document[T]](
+		ctx, c.requester, c.cfg.BasePath+id, &request[T]{Data: res},
+	)
+	op.record(resp, err)
+	if err != nil {
+		return nil, fmt.Errorf("update %s: %w", c.cfg.Name, err)
+	}
+
+	return doc, nil
+}
+
+// Delete deletes the resource with the given ID and version.
+func (c *Client[T]) Delete(
+	ctx context.Context,
+	id string,
+	version int64,
+) (err error) {
+	ctx, op := c.startOperation(ctx, "delete", id)
+	defer func() { op.end(err) }()
+
+	query := map[string]string{
+		"version": strconv.FormatInt(version, 10),
+	}
+
+	resp, err := baseclient.Delete(ctx, c.requester, c.cfg.BasePath+id, query)
+	op.record(resp, err)
+	if err != nil {
+		return fmt.Errorf("delete %s: %w", c.cfg.Name, err)
+	}
+
+	return nil
+}
+
+// id returns the ID of res, if known.
+func (c *Client[T]) id(res *T) string {
+	if c.cfg.ID == nil {
+		return ""
+	}
+
+	return c.cfg.ID(res)
+}
+
+// resolveDuplicate fetches the resource that caused a duplicate ID conflict.
+//
+// It returns the existing resource if it matches res, otherwise it returns
+// the conflict error.
+func (c *Client[T]) resolveDuplicate(
+	ctx context.Context,
+	res *T,
+	id string,
+	conflict error,
+) (*jsonapi.Document[T], error) {
+	existing, err := c.Fetch(ctx, id)
+	if err != nil {
+		return nil, fmt.Errorf("create %s: %w", c.cfg.Name, &duplicateError{conflict: conflict, fetch: err})
+	}
+
+	fields := c.cfg.Match(res, existing.Data)
+	if len(fields) > 0 {
+		return nil, fmt.Errorf("create %s: %w", c.cfg.Name, &MismatchError[T]{
+			Name:     c.cfg.Name,
+			Fields:   fields,
+			Existing: existing,
+			conflict: conflict,
+		})
+	}
+
+	return existing, nil
+}
+
+// MismatchError is returned by Create when a retried create is rejected as
+// a duplicate, and the existing resource does not match the one sent.
+//
+// It wraps the conflict error.
+type MismatchError[T any] struct {
+	// Name is the name of the resource, as in Config.
+	Name string
+
+	// Fields are the fields that differ, as returned by Config.Match.
+	Fields []string
+
+	// Existing is the existing resource, as fetched.
+	Existing *jsonapi.Document[T]
+
+	conflict error
+}
+
+func (e *MismatchError[T]) Error() string {
+	return fmt.Sprintf("%s: existing %s differs in %s", e.conflict, e.Name, strings.Join(e.Fields, ", "))
+}
+
+func (e *MismatchError[T]) Unwrap() error {
+	return e.conflict
+}
+
+// duplicateError is a duplicate ID conflict whose existing resource could
+// not be fetched. Both errors can be inspected with errors.Is and errors.As,
+// and the conflict is matched first.
+type duplicateError struct {
+	conflict error
+	fetch    error
+}
+
+func (e *duplicateError) Error() string {
+	return e.conflict.Error() + ": " + e.fetch.Error()
+}
+
+func (e *duplicateError) Unwrap() error {
+	return e.conflict
+}
+
+func (e *duplicateError) Is(target error) bool {
+	return errors.Is(e.fetch, target)
+}
+
+func (e *duplicateError) As(target any) bool {
+	return errors.As(e.conflict, target) || errors.As(e.fetch, target)
+}
+
+// isRetriedDuplicate reports whether err is a conflict returned for a
+// request that was retried.
+//
+// A conflict on the first attempt means that the ID was already taken.
+// A conflict on a later attempt may have been caused by an earlier attempt
+// that succeeded without the response being received.
+func isRetriedDuplicate(err error) bool {
+	return baseclient.StatusCode(err) == http.StatusConflict &&
+		baseclient.Attempts(err) > 1
+}
+
+// operation tracks the span and HTTP attempts of a resource operation.
+type operation struct {
+	span     baseclient.Span
+	attempts *baseclient.AttemptCounter
+}
+
+// startOperation opens a span named after the resource type and action.
+func (c *Client[T]) startOperation(
+	ctx context.Context,
+	action string,
+	id string,
+) (context.Context, *operation) {
+	ctx, span := c.cfg.Tracer.Start(ctx, c.cfg.Type+"."+action)
+	if id != "" {
+		span.SetAttribute(c.cfg.IDAttribute, id)
+	}
+
+	ctx, attempts := baseclient.ContextWithAttemptCounter(ctx)
+
+	return ctx, &operation{span: span, attempts: attempts}
+}
+
+// record records the outcome of an HTTP request made by the operation.
+func (o *operation) record(resp *http.Response, err error) {
+	baseclient.RecordResponse(o.span, resp, err)
+}
+
+// end completes the operation span.
+func (o *operation) end(err error) {
+	o.span.SetAttribute(baseclient.AttrRetries, o.attempts.Retries())
+	o.span.End(err)
+}
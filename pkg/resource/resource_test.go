@@ -0,0 +1,281 @@
+package resource_test
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sync"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	baseclient "github.com/vivangkumar/form3-http-go/pkg/client"
+	"github.com/vivangkumar/form3-http-go/pkg/client/tracetest"
+	"github.com/vivangkumar/form3-http-go/pkg/resource"
+)
+
+type payment struct {
+	ID         string `json:"id,omitempty"`
+	Type       string `json:"type,omitempty"`
+	Version    int64  `json:"version,omitempty"`
+	Attributes struct {
+		Amount string `json:"amount,omitempty"`
+	} `json:"attributes"`
+}
+
+type recordedRequest struct {
+	method string
+	url    *url.URL
+	header http.Header
+	body   []byte
+}
+
+var _ = Describe("Resource client", func() {
+	var (
+		ctx context.Context
+
+		server   *httptest.Server
+		mu       sync.Mutex
+		requests []recordedRequest
+		handler  http.HandlerFunc
+
+		tracer *tracetest.Tracer
+		cl     *resource.Client[payment]
+	)
+
+	BeforeEach(func() {
+		ctx = context.Background()
+		requests = nil
+
+		server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			body, _ := io.ReadAll(r.Body)
+
+			mu.Lock()
+			requests = append(requests, recordedRequest{
+				method: r.Method,
+				url:    r.URL,
+				header: r.Header,
+				body:   body,
+			})
+			mu.Unlock()
+
+			handler(w, r)
+		}))
+
+		base, err := baseclient.New(baseclient.WithBaseURL(server.URL), baseclient.WithRetry(1, 0))
+		Expect(err).To(BeNil())
+
+		tracer = tracetest.New()
+		cl = resource.New(base, resource.Config[payment]{
+			Type:     "payments",
+			BasePath: "/v1/transaction/payments/",
+			ID:       func(p *payment) string { return p.ID },
+			Name:     "payment",
+			Tracer:   tracer,
+		})
+	})
+
+	AfterEach(func() {
+		server.Close()
+	})
+
+	respond := func(sc int, body string) http.HandlerFunc {
+		return func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(sc)
+			_, _ = w.Write([]byte(body))
+		}
+	}
+
+	Describe("Creating a resource", func() {
+		BeforeEach(func() {
+			handler = respond(http.StatusCreated, `{"data": {"id": "p1", "type": "payments", "attributes": {"amount": "10.00"}}}`)
+		})
+
+		It("should post the resource and return the created resource", func() {
+			p := &payment{ID: "p1", Type: "payments"}
+
+			doc, err := cl.Create(ctx, p)
+			Expect(err).To(BeNil())
+			Expect(doc.Data.Attributes.Amount).To(Equal("10.00"))
+
+			Expect(requests).To(HaveLen(1))
+			Expect(requests[0].method).To(Equal(http.MethodPost))
+			Expect(requests[0].url.Path).To(Equal("/v1/transaction/payments/"))
+			Expect(requests[0].body).To(MatchJSON(`{"data": {"id": "p1", "type": "payments", "attributes": {}}}`))
+
+			By("deriving an idempotency key from the ID", func() {
+				Expect(requests[0].header.Get("Idempotency-Key")).To(Equal(baseclient.IdempotencyKeyFor("payments", "p1")))
+			})
+
+			By("recording a span named after the resource type", func() {
+				spans := tracer.SpansNamed("payments.create")
+				Expect(spans).To(HaveLen(1))
+				Expect(spans[0].Attributes).To(HaveKeyWithValue("form3.resource_id", "p1"))
+			})
+		})
+
+		It("should reject a nil resource", func() {
+			_, err := cl.Create(ctx, nil)
+			Expect(err).To(MatchError("payment entity is nil"))
+		})
+	})
+
+	Describe("Fetching a resource", func() {
+		It("should return the resource", func() {
+			handler = respond(http.StatusOK, `{"data": {"id": "p1", "version": 2}}`)
+
+			doc, err := cl.Fetch(ctx, "p1")
+			Expect(err).To(BeNil())
+			Expect(doc.Data.Version).To(Equal(int64(2)))
+			Expect(requests[0].url.Path).To(Equal("/v1/transaction/payments/p1"))
+		})
+
+		It("should wrap errors with the resource name", func() {
+			handler = respond(http.StatusNotFound, "")
+
+			_, err := cl.Fetch(ctx, "p1")
+			Expect(err).To(MatchError(HavePrefix("fetch payment: ")))
+			Expect(baseclient.StatusCode(err)).To(Equal(http.StatusNotFound))
+		})
+	})
+
+	Describe("Listing resources", func() {
+		It("should pass paging and filter parameters", func() {
+			handler = respond(http.StatusOK, `{
+				"data": [{"id": "p1"}, {"id": "p2"}],
+				"links": {"self": "/v1/transaction/payments", "next": "/v1/transaction/payments?page[number]=2"}
+			}`)
+
+			list, err := cl.List(ctx, resource.ListParams{
+				PageNumber: 1,
+				PageSize:   2,
+				Filter:     map[string]string{"amount": "10.00"},
+			})
+			Expect(err).To(BeNil())
+			Expect(list.Data).To(HaveLen(2))
+			Expect(*list.Links.Next).To(Equal("/v1/transaction/payments?page[number]=2"))
+
+			q := requests[0].url.Query()
+			Expect(q.Get("page[number]")).To(Equal("1"))
+			Expect(q.Get("page[size]")).To(Equal("2"))
+			Expect(q.Get("filter[amount]")).To(Equal("10.00"))
+		})
+	})
+
+	Describe("Updating a resource", func() {
+		It("should patch the resource at its ID", func() {
+			handler = respond(http.StatusOK, `{"data": {"id": "p1", "version": 1}}`)
+
+			doc, err := cl.Update(ctx, &payment{ID: "p1"})
+			Expect(err).To(BeNil())
+			Expect(doc.Data.Version).To(Equal(int64(1)))
+
+			Expect(requests[0].method).To(Equal(http.MethodPatch))
+			Expect(requests[0].url.Path).To(Equal("/v1/transaction/payments/p1"))
+		})
+
+		It("should require an ID", func() {
+			_, err := cl.Update(ctx, &payment{})
+			Expect(err).To(MatchError("payment ID is empty"))
+			Expect(requests).To(BeEmpty())
+		})
+	})
+
+	Describe("Deleting a resource", func() {
+		It("should delete the given version", func() {
+			handler = respond(http.StatusNoContent, "")
+
+			err := cl.Delete(ctx, "p1", 3)
+			Expect(err).To(BeNil())
+
+			Expect(requests[0].method).To(Equal(http.MethodDelete))
+			Expect(requests[0].url.Query().Get("version")).To(Equal("3"))
+		})
+	})
+
+	Describe("Resolving retried duplicates", func() {
+		var existing string
+
+		BeforeEach(func() {
+			existing = `{"data": {"id": "p1", "attributes": {"amount": "10.00"}}}`
+
+			handler = func(w http.ResponseWriter, r *http.Request) {
+				mu.Lock()
+				n := len(requests)
+				mu.Unlock()
+
+				switch {
+				case r.Method == http.MethodGet:
+					respond(http.StatusOK, existing)(w, r)
+				case n == 1:
+					// The first attempt succeeds, but the response is lost.
+					respond(http.StatusServiceUnavailable, "")(w, r)
+				default:
+					respond(http.StatusConflict, `{"error_message": "duplicate"}`)(w, r)
+				}
+			}
+
+			base, err := baseclient.New(baseclient.WithBaseURL(server.URL), baseclient.WithRetry(1, 0))
+			Expect(err).To(BeNil())
+
+			cl = resource.New(base, resource.Config[payment]{
+				Type:     "payments",
+				BasePath: "/v1/transaction/payments/",
+				ID:       func(p *payment) string { return p.ID },
+				Name:     "payment",
+				Match: func(want, got *payment) []string {
+					if want.Attributes.Amount != got.Attributes.Amount {
+						return []string{"attributes.amount"}
+					}
+
+					return nil
+				},
+			})
+		})
+
+		It("should return the existing resource when it matches", func() {
+			p := &payment{ID: "p1"}
+			p.Attributes.Amount = "10.00"
+
+			doc, err := cl.Create(ctx, p)
+			Expect(err).To(BeNil())
+			Expect(doc.Data.ID).To(Equal("p1"))
+		})
+
+		It("should return the conflict when the existing resource differs", func() {
+			p := &payment{ID: "p1"}
+			p.Attributes.Amount = "20.00"
+
+			_, err := cl.Create(ctx, p)
+			Expect(err).To(MatchError(ContainSubstring("existing payment differs in attributes.amount")))
+			Expect(baseclient.StatusCode(err)).To(Equal(http.StatusConflict))
+		})
+
+		It("should keep both errors when the existing resource cannot be fetched", func() {
+			existing = `{"data": `
+
+			_, err := cl.Create(ctx, &payment{ID: "p1"})
+			Expect(baseclient.StatusCode(err)).To(Equal(http.StatusConflict))
+
+			Expect(errors.Is(err, io.ErrUnexpectedEOF)).To(BeTrue(), err.Error())
+		})
+	})
+
+	Describe("Strict decoding", func() {
+		It("should report unknown fields of the resource", func() {
+			handler = respond(http.StatusOK, `{"data": {"id": "p1"}, "unknown": 1}`)
+
+			base, err := baseclient.New(baseclient.WithBaseURL(server.URL), baseclient.WithStrictDecoding())
+			Expect(err).To(BeNil())
+
+			_, err = resource.New(base, resource.Config[payment]{
+				Type:     "payments",
+				BasePath: "/v1/transaction/payments/",
+			}).Fetch(ctx, "p1")
+			Expect(err).To(MatchError(ContainSubstring(`unknown field "unknown"`)))
+		})
+	})
+})